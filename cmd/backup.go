@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/codeready-toolchain/devcluster/pkg/cluster"
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/mongodb"
+
+	"github.com/spf13/pflag"
+)
+
+// backup dumps the requests, clusters, users and the archive to the file or to the standard output.
+// The backup is encrypted if the backup passphrase is configured.
+func backup(config *configuration.Config, args []string) error {
+	flags := pflag.NewFlagSet("backup", pflag.ExitOnError)
+	output := flags.StringP("output", "o", "", "file to write the backup to; the standard output if not set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	disconnect, err := mongodb.InitDefaultClient(config)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if config.GetBackupPassphrase() == "" {
+		log.Info(nil, "No backup passphrase configured, the backup is not encrypted")
+	}
+	return cluster.Backup(w, config.GetBackupPassphrase())
+}
+
+// restore loads the requests, clusters, users and the archive from the file or from the standard input
+// and reconciles them with IBM Cloud
+func restore(config *configuration.Config, args []string) error {
+	flags := pflag.NewFlagSet("restore", pflag.ExitOnError)
+	input := flags.StringP("input", "i", "", "file to read the backup from; the standard input if not set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	disconnect, err := mongodb.InitDefaultClient(config)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	if err := mongodb.EnsureIndexes(context.Background()); err != nil {
+		return err
+	}
+	if err := cluster.RunMigrations(); err != nil {
+		return err
+	}
+	if err := cluster.InitDefaultClusterService(config); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	report, err := cluster.DefaultClusterService.Restore(r, config.GetBackupPassphrase())
+	if err != nil {
+		return err
+	}
+	log.Info(nil, fmt.Sprintf("Reconciled the restored clusters: %d orphan clusters, %d adopted clusters, %d missing clusters, %d errors",
+		len(report.Drift.OrphanClusters), len(report.Drift.AdoptedClusters), len(report.Drift.MissingClusters), len(report.Drift.Errors)))
+	return nil
+}
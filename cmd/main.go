@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,15 +13,53 @@ import (
 	"github.com/codeready-toolchain/devcluster/pkg/configuration"
 	"github.com/codeready-toolchain/devcluster/pkg/log"
 	"github.com/codeready-toolchain/devcluster/pkg/mongodb"
+	"github.com/codeready-toolchain/devcluster/pkg/secrets"
 	"github.com/codeready-toolchain/devcluster/pkg/server"
 )
 
 func main() {
 	// create logger and registry
 	log.Init("devcluster-service")
+	config := configuration.New()
+	// The configuration file is rejected if the service would reject it on startup
+	config.AddValidator(func(c *configuration.Config) error {
+		return cluster.ValidateConfiguration(c)
+	})
+	if file := config.GetConfigFile(); file != "" {
+		if err := config.LoadFile(file); err != nil {
+			panic(err.Error())
+		}
+	}
+	if err := log.SetLevel(config.GetLogLevel()); err != nil {
+		panic(err.Error())
+	}
+
+	// Read the API keys and the Mongo credentials from the secrets provider if configured
+	secretsProvider, err := secrets.NewProvider(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	defer secretsProvider.Close()
+	config.SetSecretsProvider(secretsProvider)
+
+	// devcluster backup|restore [flags]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			err = backup(config, os.Args[2:])
+		case "restore":
+			err = restore(config, os.Args[2:])
+		default:
+			panic(fmt.Sprintf("unknown command: %s", os.Args[1]))
+		}
+		if err != nil {
+			log.Error(nil, err, fmt.Sprintf("%s failed", os.Args[1]))
+			os.Exit(1)
+		}
+		return
+	}
 
 	log.Info(nil, "Starting DevCluster service...")
-	config := configuration.New()
 	log.Info(nil, "Initiating MongoDB client...")
 	disconnect, err := mongodb.InitDefaultClient(config)
 	if err != nil {
@@ -28,10 +67,62 @@ func main() {
 	}
 	defer disconnect()
 
+	log.Info(nil, "Creating MongoDB indexes...")
+	err = mongodb.EnsureIndexes(context.Background())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	log.Info(nil, "Migrating stored documents...")
+	err = cluster.RunMigrations()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	log.Info(nil, "Seeding zones if none stored...")
+	err = cluster.SeedZones(config.GetZonesAllowed())
+	if err != nil {
+		panic(err.Error())
+	}
+
 	log.Info(nil, "Initiating IBMCloud client...")
-	cluster.InitDefaultClusterService(config)
+	err = cluster.InitDefaultClusterService(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	if config.GetConfigFile() != "" {
+		log.Info(nil, "Watching configuration file...")
+		err = config.WatchFile(func(err error) {
+			if err != nil {
+				log.Error(nil, err, "configuration file change rejected")
+				return
+			}
+			if err := log.SetLevel(config.GetLogLevel()); err != nil {
+				log.Error(nil, err, "unable to change log level")
+			}
+			if err := cluster.DefaultClusterService.ReloadAccounts(); err != nil {
+				log.Error(nil, err, "unable to reload IBM Cloud accounts")
+				return
+			}
+			log.Info(nil, "Configuration reloaded")
+		})
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+	// Complete or roll back the user assignments and recycles interrupted by a crash
+	log.Info(nil, "Recovering pending user operations if any...")
+	if _, err := cluster.DefaultClusterService.RecoverPendingUserOperations(); err != nil {
+		log.Error(nil, err, "unable to recover some pending user operations")
+	}
 	log.Info(nil, "Starting deleting expired clusters routine...")
-	cluster.DefaultClusterService.StartDeletingExpiredClusters(600) // Re-check every 10 minutes
+	cluster.DefaultClusterService.StartDeletingExpiredClusters()
+	log.Info(nil, "Starting reconciliation routine...")
+	cluster.DefaultClusterService.StartReconciling()
+	log.Info(nil, "Starting user pool autoscaling routine...")
+	cluster.DefaultClusterService.StartUserPoolAutoscaling()
+	log.Info(nil, "Starting archival routine...")
+	cluster.DefaultClusterService.StartArchiving()
 	// If there are still provisioning requests left from previous sessions then resume them
 	log.Info(nil, "Resuming provisioning requests if any...")
 	err = cluster.DefaultClusterService.ResumeProvisioningRequests()
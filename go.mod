@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/cheekybits/is v0.0.0-20150225183255-68e9c0620927 // indirect
 	github.com/codeready-toolchain/toolchain-common v0.0.0-20210816150728-75450e8d842e
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-contrib/gzip v0.0.3
 	github.com/gin-gonic/gin v1.7.4
@@ -21,6 +22,9 @@ require (
 	github.com/spf13/viper v1.7.0
 	github.com/stretchr/testify v1.6.1
 	go.mongodb.org/mongo-driver v1.7.1
+	go.uber.org/zap v1.15.0
+	golang.org/x/crypto v0.0.0-20201012173705-84dcc777aaee
+	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
 	gopkg.in/h2non/gock.v1 v1.0.14
 	gopkg.in/square/go-jose.v2 v2.3.0
 	k8s.io/apimachinery v0.20.2
@@ -71,3 +71,17 @@ func DefaultTokenParser() (*TokenParser, error) { //nolint:unparam
 	}
 	return defaultTokenParserHolder, nil
 }
+
+// CheckDefaultKeyManager returns an error if the default KeyManager has not been created yet or has no public keys loaded
+func CheckDefaultKeyManager() error {
+	muKM.Lock()
+	defer muKM.Unlock()
+	km, err := defaultKeyManager()
+	if err != nil {
+		return err
+	}
+	if len(km.keyMap) == 0 {
+		return errors.New("no public keys loaded")
+	}
+	return nil
+}
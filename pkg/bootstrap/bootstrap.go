@@ -0,0 +1,161 @@
+// Package bootstrap runs the configured steps against the clusters which got provisioned,
+// for example creating the projects used by the workshops, installing operators or applying manifests.
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Configuration represents a partition of the configuration that is used by the bootstrap runner
+type Configuration interface {
+	GetBootstrapSteps() string
+	GetBootstrapCLIPath() string
+	GetBootstrapStepTimeoutSec() int
+}
+
+// MaxLogSize is the max size of the step logs which are kept. Only the tail of longer logs is kept.
+const MaxLogSize = 32 * 1024
+
+// Step is a bootstrap step. Either manifests to apply or a command to run.
+type Step struct {
+	Name string `json:"name"`
+	// File, directory or URL with the Kubernetes manifests to apply to the cluster
+	Manifests string `json:"manifests,omitempty"`
+	// Command to run. The command is run with KUBECONFIG pointing to a kubeconfig logged in the cluster
+	// and with the CLUSTER_ID, CLUSTER_MASTER_URL and CLUSTER_HOSTNAME environment variables set.
+	// The environment of the service is not passed to the command except PATH so the service secrets are never exposed.
+	Command []string `json:"command,omitempty"`
+	// Timeout of the step. The configured default timeout is used if not set.
+	TimeoutSec int `json:"timeout_sec,omitempty"`
+}
+
+// ParseSteps parses the steps from the given JSON array. Returns no steps if the given string is empty.
+func ParseSteps(raw string) ([]Step, error) {
+	steps := make([]Step, 0)
+	if strings.TrimSpace(raw) == "" {
+		return steps, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &steps); err != nil {
+		return nil, errors.Wrap(err, "unable to parse bootstrap steps")
+	}
+	names := make(map[string]bool)
+	for i, step := range steps {
+		if step.Name == "" {
+			return nil, errors.Errorf("bootstrap step #%d has no name", i+1)
+		}
+		if names[step.Name] {
+			return nil, errors.Errorf("duplicate bootstrap step %s", step.Name)
+		}
+		names[step.Name] = true
+		if (step.Manifests == "") == (len(step.Command) == 0) {
+			return nil, errors.Errorf("bootstrap step %s must have either manifests or command", step.Name)
+		}
+		if step.TimeoutSec < 0 {
+			return nil, errors.Errorf("bootstrap step %s has negative timeout", step.Name)
+		}
+	}
+	return steps, nil
+}
+
+// Target is the cluster the bootstrap steps are run against
+type Target struct {
+	ClusterID string
+	MasterURL string
+	Hostname  string
+	Account   string // name of the IBM Cloud account the cluster is provisioned in
+	APIKey    string // API key of the account used to log in the cluster
+}
+
+// Runner runs bootstrap steps
+type Runner interface {
+	// Run runs the step against the target cluster and returns the logs of the step
+	Run(target Target, step Step) (string, error)
+}
+
+// NewRunner returns a runner which uses the configured oc CLI to log in the cluster and to apply the manifests
+func NewRunner(config Configuration) Runner {
+	return &cliRunner{config: config}
+}
+
+type cliRunner struct {
+	config Configuration
+}
+
+func (r *cliRunner) Run(target Target, step Step) (string, error) {
+	timeout := step.TimeoutSec
+	if timeout == 0 {
+		timeout = r.config.GetBootstrapStepTimeoutSec()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// Every step gets its own kubeconfig so the steps for different clusters don't interfere
+	dir, err := ioutil.TempDir("", "devcluster-bootstrap-")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create bootstrap working directory")
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+
+	env := []string{
+		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
+		fmt.Sprintf("HOME=%s", dir),
+		fmt.Sprintf("KUBECONFIG=%s", kubeconfig),
+		fmt.Sprintf("CLUSTER_ID=%s", target.ClusterID),
+		fmt.Sprintf("CLUSTER_MASTER_URL=%s", target.MasterURL),
+		fmt.Sprintf("CLUSTER_HOSTNAME=%s", target.Hostname),
+	}
+
+	logs := &bytes.Buffer{}
+	// The API key is passed via stdin which the CLI reads the password from. The command line can be seen by any local user.
+	login := exec.CommandContext(ctx, r.config.GetBootstrapCLIPath(), "login", target.MasterURL, "-u", "apikey", "--kubeconfig", kubeconfig)
+	login.Dir = dir
+	login.Env = env
+	login.Stdin = strings.NewReader(target.APIKey + "\n")
+	if err := run(login, logs); err != nil {
+		return tail(logs.String()), errors.Wrapf(err, "unable to log in cluster %s with the API key of IBM Cloud account %s", target.ClusterID, target.Account)
+	}
+
+	var cmd *exec.Cmd
+	if step.Manifests != "" {
+		cmd = exec.CommandContext(ctx, r.config.GetBootstrapCLIPath(), "apply", "--kubeconfig", kubeconfig, "-f", step.Manifests)
+	} else {
+		cmd = exec.CommandContext(ctx, step.Command[0], step.Command[1:]...)
+	}
+	cmd.Dir = dir
+	cmd.Env = env
+	if err := run(cmd, logs); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("timed out after %d seconds", timeout)
+		}
+		return tail(logs.String()), errors.Wrapf(err, "bootstrap step %s failed", step.Name)
+	}
+	return tail(logs.String()), nil
+}
+
+// run runs the command and writes its combined output to the logs
+func run(cmd *exec.Cmd, logs *bytes.Buffer) error {
+	fmt.Fprintf(logs, "$ %s %s\n", filepath.Base(cmd.Path), strings.Join(cmd.Args[1:], " "))
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	return cmd.Run()
+}
+
+// tail returns the last MaxLogSize bytes of the logs
+func tail(logs string) string {
+	if len(logs) <= MaxLogSize {
+		return logs
+	}
+	return logs[len(logs)-MaxLogSize:]
+}
@@ -0,0 +1,149 @@
+package bootstrap_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/bootstrap"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestBootstrapSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunBootstrapSuite(t *testing.T) {
+	suite.Run(t, &TestBootstrapSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestBootstrapSuite) TestParseSteps() {
+	s.T().Run("no steps", func(t *testing.T) {
+		steps, err := bootstrap.ParseSteps("")
+		require.NoError(t, err)
+		assert.Empty(t, steps)
+	})
+
+	s.T().Run("valid steps", func(t *testing.T) {
+		steps, err := bootstrap.ParseSteps(`[
+			{"name": "workshop", "manifests": "/etc/bootstrap/workshop"},
+			{"name": "operators", "command": ["install.sh", "--all"], "timeout_sec": 900}
+		]`)
+		require.NoError(t, err)
+		assert.Equal(t, []bootstrap.Step{
+			{Name: "workshop", Manifests: "/etc/bootstrap/workshop"},
+			{Name: "operators", Command: []string{"install.sh", "--all"}, TimeoutSec: 900},
+		}, steps)
+	})
+
+	s.T().Run("invalid steps", func(t *testing.T) {
+		for raw, msg := range map[string]string{
+			`{"name": "workshop"}`:                       "unable to parse bootstrap steps",
+			`[{"manifests": "/etc/bootstrap/workshop"}]`: "bootstrap step #1 has no name",
+			`[{"name": "workshop"}]`:                     "bootstrap step workshop must have either manifests or command",
+			`[{"name": "workshop", "manifests": "/etc/bootstrap", "command": ["a.sh"]}]`:         "bootstrap step workshop must have either manifests or command",
+			`[{"name": "workshop", "manifests": "/a"}, {"name": "workshop", "manifests": "/b"}]`: "duplicate bootstrap step workshop",
+			`[{"name": "workshop", "manifests": "/a", "timeout_sec": -1}]`:                       "bootstrap step workshop has negative timeout",
+		} {
+			_, err := bootstrap.ParseSteps(raw)
+			require.Error(t, err, raw)
+			assert.Contains(t, err.Error(), msg)
+		}
+	})
+}
+
+func (s *TestBootstrapSuite) TestRunner() {
+	dir, err := ioutil.TempDir("", "bootstrap-test-")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	// Fake CLI which records its arguments and the password read from stdin
+	cli := filepath.Join(dir, "oc")
+	calls := filepath.Join(dir, "calls")
+	password := filepath.Join(dir, "password")
+	require.NoError(s.T(), ioutil.WriteFile(cli, []byte("#!/bin/sh\necho \"$@\" >> "+calls+"\n"+
+		"if [ \"$1\" = login ]; then read pw; echo \"$pw\" > "+password+"; fi\n"+
+		"echo \"oc $1 done\"\n"), 0755))
+	config := &mockConfig{cliPath: cli, timeout: 10}
+	runner := bootstrap.NewRunner(config)
+	target := bootstrap.Target{ClusterID: "cluster-1", MasterURL: "https://cluster-1:100", Hostname: "cluster-1.example.com", Account: "us", APIKey: "secret-key"}
+
+	s.T().Run("apply manifests", func(t *testing.T) {
+		defer os.Remove(calls)
+		logs, err := runner.Run(target, bootstrap.Step{Name: "workshop", Manifests: "/etc/bootstrap/workshop"})
+		require.NoError(t, err)
+		assert.Contains(t, logs, "oc login done")
+		assert.Contains(t, logs, "oc apply done")
+		assert.NotContains(t, logs, "secret-key")
+
+		recorded, err := ioutil.ReadFile(calls)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(recorded)), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "login https://cluster-1:100 -u apikey --kubeconfig "))
+		assert.NotContains(t, string(recorded), "secret-key")
+		assert.True(t, strings.HasPrefix(lines[1], "apply --kubeconfig "))
+		assert.True(t, strings.HasSuffix(lines[1], " -f /etc/bootstrap/workshop"))
+
+		// the API key is passed via stdin
+		pw, err := ioutil.ReadFile(password)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-key", strings.TrimSpace(string(pw)))
+	})
+
+	s.T().Run("run command", func(t *testing.T) {
+		defer os.Remove(calls)
+		logs, err := runner.Run(target, bootstrap.Step{Name: "env", Command: []string{"sh", "-c", "echo $CLUSTER_ID $CLUSTER_HOSTNAME; test -n \"$KUBECONFIG\""}})
+		require.NoError(t, err)
+		assert.Contains(t, logs, "cluster-1 cluster-1.example.com")
+	})
+
+	s.T().Run("service environment not passed", func(t *testing.T) {
+		defer os.Remove(calls)
+		os.Setenv("DEVCLUSTER_BOOTSTRAP_TEST_SECRET", "leaked")
+		defer os.Unsetenv("DEVCLUSTER_BOOTSTRAP_TEST_SECRET")
+		logs, err := runner.Run(target, bootstrap.Step{Name: "env", Command: []string{"sh", "-c", "echo secret=$DEVCLUSTER_BOOTSTRAP_TEST_SECRET"}})
+		require.NoError(t, err)
+		assert.Contains(t, logs, "secret=\n")
+		assert.NotContains(t, logs, "leaked")
+	})
+
+	s.T().Run("command fails", func(t *testing.T) {
+		defer os.Remove(calls)
+		logs, err := runner.Run(target, bootstrap.Step{Name: "failing", Command: []string{"sh", "-c", "echo oops; exit 3"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bootstrap step failing failed")
+		assert.Contains(t, logs, "oops")
+	})
+
+	s.T().Run("command times out", func(t *testing.T) {
+		defer os.Remove(calls)
+		_, err := runner.Run(target, bootstrap.Step{Name: "slow", Command: []string{"sleep", "5"}, TimeoutSec: 1})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out after 1 seconds")
+	})
+}
+
+type mockConfig struct {
+	cliPath string
+	steps   string
+	timeout int
+}
+
+func (c *mockConfig) GetBootstrapSteps() string {
+	return c.steps
+}
+
+func (c *mockConfig) GetBootstrapCLIPath() string {
+	return c.cliPath
+}
+
+func (c *mockConfig) GetBootstrapStepTimeoutSec() int {
+	return c.timeout
+}
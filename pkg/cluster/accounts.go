@@ -0,0 +1,300 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/identity"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CloudAccount is an IBM Cloud account with its own client, token cache and identity provider.
+// Every account has its own user pool. The requests, clusters and users are tagged with the name of their account.
+type CloudAccount struct {
+	ibmcloud.Account
+	Client   ibmcloud.ICClient
+	Identity identity.Provider
+}
+
+// accountConfig is the service configuration with the credentials and the tenant of the account
+type accountConfig struct {
+	Configuration
+	account ibmcloud.Account
+}
+
+// GetIBMCloudAPIKey returns the current API key of the account. The API key read from a secret may be rotated.
+// The default account configured by the top level settings uses the top level API key.
+func (c accountConfig) GetIBMCloudAPIKey() string {
+	if c.account.APIKeySecret != "" {
+		return c.Configuration.GetSecret(c.account.APIKeySecret)
+	}
+	if c.account.APIKey != "" {
+		return c.account.APIKey
+	}
+	return c.Configuration.GetIBMCloudAPIKey()
+}
+
+func (c accountConfig) GetIBMCloudAccountID() string {
+	return c.account.AccountID
+}
+
+func (c accountConfig) GetIBMCloudTenantID() string {
+	return c.account.TenantID
+}
+
+// newCloudAccounts creates the clients and the identity providers of all the configured accounts
+func newCloudAccounts(config Configuration) ([]CloudAccount, error) {
+	// The API key of the default account is not copied so the rotated key is picked up
+	accounts, err := ibmcloud.ParseAccounts(config.GetIBMCloudAccounts(), ibmcloud.Account{
+		AccountID: config.GetIBMCloudAccountID(),
+		TenantID:  config.GetIBMCloudTenantID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cloudAccounts := make([]CloudAccount, 0, len(accounts))
+	for _, a := range accounts {
+		c := accountConfig{Configuration: config, account: a}
+		client := ibmcloud.NewClient(c)
+		identityProvider, err := identity.NewProvider(c, client)
+		if err != nil {
+			return nil, err
+		}
+		cloudAccounts = append(cloudAccounts, CloudAccount{
+			Account:  a,
+			Client:   client,
+			Identity: identityProvider,
+		})
+	}
+	return cloudAccounts, nil
+}
+
+// ReloadAccounts applies the teams, the zones and the quotas of the accounts from the reloaded configuration.
+// The accounts are replaced at once. Adding or removing accounts and changing their credentials require a restart
+// so such changes are logged and ignored.
+func (s *ClusterService) ReloadAccounts() error {
+	accounts, err := ibmcloud.ParseAccounts(s.Config.GetIBMCloudAccounts(), ibmcloud.Account{
+		AccountID: s.Config.GetIBMCloudAccountID(),
+		TenantID:  s.Config.GetIBMCloudTenantID(),
+	})
+	if err != nil {
+		return err
+	}
+	configured := make(map[string]ibmcloud.Account, len(accounts))
+	for _, a := range accounts {
+		configured[a.Name] = a
+	}
+	s.accountsMux.Lock()
+	defer s.accountsMux.Unlock()
+	if len(s.Accounts) == 0 {
+		// The default account using the service client is not reloadable
+		return nil
+	}
+	reloaded := make([]CloudAccount, 0, len(s.Accounts))
+	for _, a := range s.Accounts {
+		c, found := configured[a.Name]
+		if !found {
+			log.Infof(nil, "IBM Cloud account %s has been removed from the configuration; restart the service to remove it", a.Name)
+		} else {
+			if c.APIKey != a.APIKey || c.APIKeySecret != a.APIKeySecret || c.AccountID != a.AccountID || c.TenantID != a.TenantID {
+				log.Infof(nil, "credentials of IBM Cloud account %s have been changed in the configuration; restart the service to apply them", a.Name)
+			}
+			a.Teams = c.Teams
+			a.Zones = c.Zones
+			a.MaxClusters = c.MaxClusters
+			delete(configured, a.Name)
+		}
+		reloaded = append(reloaded, a)
+	}
+	for name := range configured {
+		log.Infof(nil, "IBM Cloud account %s has been added to the configuration; restart the service to add it", name)
+	}
+	s.Accounts = reloaded
+	return nil
+}
+
+// cloudAccounts returns all the accounts. The first one is the default account.
+// If no accounts are set then the only account is the default one using the service client and identity provider.
+func (s *ClusterService) cloudAccounts() []CloudAccount {
+	s.accountsMux.RLock()
+	defer s.accountsMux.RUnlock()
+	if len(s.Accounts) > 0 {
+		return s.Accounts
+	}
+	return []CloudAccount{{
+		Account: ibmcloud.Account{
+			Name:      ibmcloud.DefaultAccount,
+			AccountID: s.Config.GetIBMCloudAccountID(),
+			TenantID:  s.Config.GetIBMCloudTenantID(),
+		},
+		Client:   s.IbmCloudClient,
+		Identity: s.Identity,
+	}}
+}
+
+// CheckAccounts obtains the IBM Cloud token with the credentials of every account.
+// Returns the errors by the account name. The error is nil if the credentials of the account are valid.
+func (s *ClusterService) CheckAccounts() map[string]error {
+	accounts := s.cloudAccounts()
+	errs := make(map[string]error, len(accounts))
+	for _, a := range accounts {
+		_, err := a.Client.Token()
+		errs[a.Name] = err
+	}
+	return errs
+}
+
+// cloudAccount returns the account with the given name. The documents stored before the accounts
+// have been introduced have no account and belong to the default account.
+func (s *ClusterService) cloudAccount(name string) (CloudAccount, error) {
+	accounts := s.cloudAccounts()
+	if name == "" {
+		return accounts[0], nil
+	}
+	for _, a := range accounts {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return CloudAccount{}, devclustererr.NewBadRequestError(fmt.Sprintf("unknown IBM Cloud account: %s", name), "")
+}
+
+// withAccount returns the filter of the documents belonging to the account
+func (s *ClusterService) withAccount(a CloudAccount) bson.E {
+	if a.Name == s.cloudAccounts()[0].Name {
+		return bson.E{Key: "account", Value: bson.D{{"$in", bson.A{"", a.Name}}}}
+	}
+	return bson.E{Key: "account", Value: a.Name}
+}
+
+// routeRequest returns the account the request for n clusters in the zone is provisioned in.
+// The account is either given explicitly or picked from the accounts allowing the zone:
+// the accounts of the requester's team are preferred as long as they have room for the clusters
+// and the account with the most quota headroom wins.
+// Returns a Bad Request error if no account can take the request.
+func (s *ClusterService) routeRequest(account, requestedBy, zone string, n int) (CloudAccount, error) {
+	if account != "" {
+		a, err := s.cloudAccount(account)
+		if err != nil {
+			return a, err
+		}
+		if !a.AllowsZone(zone) {
+			return a, devclustererr.NewBadRequestError(fmt.Sprintf("zone %s is not allowed in IBM Cloud account %s", zone, a.Name), "")
+		}
+		return s.mostHeadroom([]CloudAccount{a}, zone, n)
+	}
+	candidates := make([]CloudAccount, 0)
+	teamCandidates := make([]CloudAccount, 0)
+	for _, a := range s.cloudAccounts() {
+		if a.AllowsZone(zone) {
+			candidates = append(candidates, a)
+			if a.HasTeam(requestedBy) {
+				teamCandidates = append(teamCandidates, a)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return CloudAccount{}, devclustererr.NewBadRequestError(fmt.Sprintf("zone %s is not allowed in any IBM Cloud account", zone), "")
+	}
+	if len(teamCandidates) > 0 {
+		if a, err := s.mostHeadroom(teamCandidates, zone, n); err == nil || !devclustererr.IsBadRequest(err) {
+			return a, err
+		}
+	}
+	return s.mostHeadroom(candidates, zone, n)
+}
+
+// mostHeadroom returns the first of the given accounts with the most quota headroom.
+// Returns a Bad Request error if none of the accounts has room for n more clusters.
+func (s *ClusterService) mostHeadroom(accounts []CloudAccount, zone string, n int) (CloudAccount, error) {
+	var best CloudAccount
+	bestHeadroom := -1
+	for _, a := range accounts {
+		headroom, err := s.headroom(a)
+		if err != nil {
+			return CloudAccount{}, err
+		}
+		if headroom >= n && headroom > bestHeadroom {
+			best = a
+			bestHeadroom = headroom
+		}
+	}
+	if bestHeadroom < 0 {
+		return CloudAccount{}, devclustererr.NewBadRequestError(fmt.Sprintf("no IBM Cloud account has room for %d more clusters in zone %s", n, zone), "")
+	}
+	return best, nil
+}
+
+// headroom returns the number of clusters which can still be provisioned in the account
+// besides the active clusters and the clusters reserved in the account
+func (s *ClusterService) headroom(a CloudAccount) (int, error) {
+	if a.MaxClusters <= 0 {
+		return math.MaxInt32, nil
+	}
+	d, err := getAccountDocument(a.Name)
+	if err != nil {
+		return 0, err
+	}
+	reserved := 0
+	if d != nil {
+		reserved = d.Reserved
+	}
+	active, err := countActiveClusters(s.withAccount(a))
+	if err != nil {
+		return 0, err
+	}
+	return a.MaxClusters - active - reserved, nil
+}
+
+// maxAccountReservationAttempts is the max number of attempts to reserve clusters in an account changed concurrently
+const maxAccountReservationAttempts = 10
+
+// reserveAccountCapacity atomically reserves n clusters in the account so the concurrent requests can't exceed the quota
+// of the account together. Every reserved cluster must be released by releaseAccountCapacity once it's stored or failed
+// to get provisioned. Returns a Bad Request error if the account has no room for n more clusters.
+func (s *ClusterService) reserveAccountCapacity(a CloudAccount, n int) error {
+	for attempt := 0; attempt < maxAccountReservationAttempts; attempt++ {
+		d, err := getAccountDocument(a.Name)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			d = &accountDocument{ID: a.Name}
+		}
+		if a.MaxClusters > 0 {
+			// The same as for the zones a cluster stored after the reservations are read is counted twice
+			// until its reservation is released which changes the version so the reservation is attempted again.
+			active, err := countActiveClusters(s.withAccount(a))
+			if err != nil {
+				return err
+			}
+			if room := a.MaxClusters - active - d.Reserved; n > room {
+				if room < 0 {
+					room = 0
+				}
+				return devclustererr.NewBadRequestError(fmt.Sprintf("IBM Cloud account %s has room for %d more clusters only", a.Name, room), "")
+			}
+		}
+		reserved, err := reserveAccount(a.Name, d.Version, n)
+		if err != nil || reserved {
+			return err
+		}
+	}
+	return errors.Errorf("unable to reserve %d clusters in IBM Cloud account %s; the account is being changed concurrently", n, a.Name)
+}
+
+// releaseAccountCapacity releases n clusters reserved in the account with the given name
+func (s *ClusterService) releaseAccountCapacity(account string, n int) {
+	a, err := s.cloudAccount(account)
+	if err == nil {
+		err = releaseAccount(a.Name, n)
+	}
+	if err != nil {
+		log.Error(nil, err, fmt.Sprintf("unable to release %d clusters reserved in IBM Cloud account %s", n, account))
+	}
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ArchivedRequest represents a request moved to the archive together with its clusters
+type ArchivedRequest struct {
+	Request  `json:",inline"`
+	Clusters []Cluster
+	Archived int64 // timestamp when the request was archived
+}
+
+// archivableRequestStatuses are the terminal statuses of the requests which can be archived
+var archivableRequestStatuses = bson.A{StatusExpired, StatusDeleted, StatusFailed}
+
+// StartArchiving starts a goroutine to archive the old requests.
+// The interval is read from the configuration before every run so it can be changed while running.
+func (s *ClusterService) StartArchiving() {
+	go func() {
+		for {
+			interval := time.Duration(s.Config.GetArchiveIntervalSec()) * time.Second
+			s.routineIteration(RoutineArchival, interval)
+			if _, err := s.Archive(); err != nil {
+				log.Error(nil, err, "unable to archive requests")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// Archive moves the requests in a terminal state (expired, deleted or failed) created before the retention period
+// to the archive together with their clusters. The requests with clusters which are neither deleted nor failed are kept.
+// Returns the IDs of the archived requests. Does nothing if the archival is disabled.
+func (s *ClusterService) Archive() ([]string, error) {
+	archived := make([]string, 0)
+	days := s.Config.GetArchiveRetentionDays()
+	if days <= 0 {
+		return archived, nil
+	}
+	now := time.Now()
+	before := now.Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	requests, err := getRequestsWithFilter(
+		bson.E{Key: "status", Value: bson.D{{"$in", archivableRequestStatuses}}},
+		bson.E{Key: "created", Value: bson.D{{"$lt", before}}},
+	)
+	if err != nil {
+		return archived, err
+	}
+	for _, r := range requests {
+		clusters, err := getClusters(r.ID)
+		if err != nil {
+			return archived, err
+		}
+		if c, found := unarchivableCluster(clusters); found {
+			log.Infof(nil, "request %s is not archived because its cluster %s is %s", r.ID, c.ID, c.Status)
+			continue
+		}
+		if err := archiveRequest(r, clusters, now.Unix()); err != nil {
+			return archived, err
+		}
+		archived = append(archived, r.ID)
+	}
+	if len(archived) > 0 {
+		log.Info(nil, fmt.Sprintf("archived %d requests", len(archived)))
+	}
+	return archived, nil
+}
+
+// unarchivableCluster returns the first cluster which may still exist in IBM Cloud
+func unarchivableCluster(clusters []Cluster) (Cluster, bool) {
+	for _, c := range clusters {
+		if c.Status != StatusDeleted && c.Status != StatusFailed {
+			return c, true
+		}
+	}
+	return Cluster{}, false
+}
+
+// ListArchive returns the page of the archived requests matching the options with their archived clusters.
+// The archived requests can be filtered and sorted the same way as the requests.
+func (s *ClusterService) ListArchive(opts ListOptions) ([]ArchivedRequest, PageInfo, error) {
+	q, err := newRequestPageQuery(opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	return listArchive(q)
+}
@@ -0,0 +1,232 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Operations on the users which are recorded as pending until completed
+const (
+	UserOperationAssigning = "assigning"
+	UserOperationRecycling = "recycling"
+)
+
+// pendingOperationGracePeriod is the minimal age of a pending user operation started by another service instance
+// before it's considered interrupted. It prevents recovering operations which are still in progress in other replicas.
+const pendingOperationGracePeriod = 10 * time.Minute
+
+// instanceID identifies this service instance as the owner of the pending user operations.
+// The hostname (the pod name) is kept when the service is restarted in the same pod so the operations interrupted
+// by a crash can be recovered on startup without waiting for the grace period.
+var instanceID = func() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return uuid.NewV4().String()
+}()
+
+// assignUsers assigns users from the user pool of the account to the cluster until the cluster has n users assigned.
+// The users reserved for the request of the cluster are assigned first.
+func (s *ClusterService) assignUsers(clusterID, requestID, account string, n int) error {
+	assigned, err := getUsersByClusterID(clusterID)
+	if err != nil {
+		return err
+	}
+	for i := len(assigned); i < n; i++ {
+		if err := s.assignUser(clusterID, requestID, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignUser claims a free user from the user pool of the account and grants access to the cluster to that user
+// via the identity provider. The user is claimed atomically so the same user is never assigned to two clusters
+// even if several instances of the service are running.
+func (s *ClusterService) assignUser(clusterID, requestID, account string) error {
+	user, err := s.claimFreeUser(clusterID, requestID, account)
+	if err != nil {
+		return err
+	}
+	return s.completeAssignment(user)
+}
+
+// claimFreeUser claims a user reserved for the request or a free user of the account for the cluster.
+// If there is no such user then the user pool is scaled up if enabled.
+func (s *ClusterService) claimFreeUser(clusterID, requestID, account string) (*User, error) {
+	a, err := s.cloudAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	user, err := claimFreeUser(clusterID, requestID, instanceID, s.withAccount(a))
+	if devclustererr.IsNotFound(err) && s.userPoolAutoscalingEnabled() {
+		log.Infof(nil, "no free user found in the user pool of account %s; scaling up the pool", a.Name)
+		if err := s.scaleUserPool(a, 1); err != nil {
+			return nil, err
+		}
+		user, err = claimFreeUser(clusterID, requestID, instanceID, s.withAccount(a))
+	}
+	return user, err
+}
+
+// completeAssignment grants the claimed user access to the cluster and clears the pending assignment.
+// The granted policy is recorded right away so the assignment interrupted by a crash reuses the policy
+// instead of granting another one, and the rollback revokes it.
+// The assignment is rolled back if the access can't be granted.
+func (s *ClusterService) completeAssignment(user *User) error {
+	a, err := s.cloudAccount(user.Account)
+	if err != nil {
+		return err
+	}
+	if user.PolicyID == "" {
+		policyID, err := a.Identity.GrantAccess(user.identity(), user.ClusterID)
+		if err != nil {
+			log.Error(nil, err, fmt.Sprintf("unable to grant cluster access to user ID: %s", user.ID))
+			if e := s.rollBackAssignment(user); e != nil {
+				log.Error(nil, e, fmt.Sprintf("unable to roll back cluster assigment for the user with id: %s", user.ID))
+			}
+			return err
+		}
+		user.PolicyID = policyID
+		if err := updateUserPolicyID(user.ID, policyID); err != nil {
+			if e := s.rollBackAssignment(user); e != nil {
+				log.Error(nil, e, fmt.Sprintf("unable to roll back cluster assigment for the user with id: %s", user.ID))
+			}
+			return err
+		}
+	} else {
+		log.Infof(nil, "reusing access policy %s granted to user %s", user.PolicyID, user.ID)
+	}
+	user.PendingOperation = ""
+	return completeUserAssignment(user.ID, user.PolicyID)
+}
+
+// rollBackAssignment revokes the access granted to the user if any and returns the user to the user pool
+func (s *ClusterService) rollBackAssignment(user *User) error {
+	a, err := s.cloudAccount(user.Account)
+	if err != nil {
+		return err
+	}
+	if user.PolicyID != "" {
+		if err := a.Identity.RevokeAccess(user.PolicyID); err != nil {
+			return err
+		}
+	}
+	user.ClusterID = ""
+	user.PolicyID = ""
+	user.PendingOperation = ""
+	return releaseUser(user.ID)
+}
+
+// recycleUsers changes the passwords of all the users assigned to the cluster and returns the users to the user pool
+// so they can be assigned to another cluster.
+func (s *ClusterService) recycleUsers(clusterID string) error {
+	users, err := getUsersByClusterID(clusterID)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		log.Infof(nil, "cluster %s has no user to recycle", clusterID)
+		return nil
+	}
+	for i := range users {
+		if err := s.recycle(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recycle revokes the cluster access of the user, changes the password and returns the user to the user pool.
+// Every step is recorded so a recycle interrupted by a crash can be resumed by the recovery.
+func (s *ClusterService) recycle(user *User) error {
+	a, err := s.cloudAccount(user.Account)
+	if err != nil {
+		return err
+	}
+	if err := startUserOperation(user.ID, UserOperationRecycling, instanceID); err != nil {
+		return err
+	}
+	user.PendingOperation = UserOperationRecycling
+	if user.PolicyID != "" {
+		if err := a.Identity.RevokeAccess(user.PolicyID); err != nil {
+			return err
+		}
+		if err := updateUserPolicyID(user.ID, ""); err != nil {
+			return err
+		}
+		user.PolicyID = ""
+	}
+	password, err := a.Identity.RotateCredentials(user.identity())
+	if err != nil {
+		log.Error(nil, err, fmt.Sprintf("unable to rotate credentials for user: %s", user.ID))
+		return err
+	}
+	user.ClusterID = ""
+	user.Password = password
+	user.Recycled = time.Now().Unix()
+	user.PendingOperation = ""
+	return completeUserRecycle(user.ID, password)
+}
+
+// RecoverPendingUserOperations completes or rolls back the user assignments and recycles interrupted by a crash
+// of this service instance. It's supposed to be called on startup before any new operations are started.
+// Returns the IDs of the recovered users.
+func (s *ClusterService) RecoverPendingUserOperations() ([]string, error) {
+	return s.recoverPendingUserOperations(instanceID)
+}
+
+// recoverPendingUserOperations recovers the operations started by the given owner
+// and the operations started by any owner earlier than the grace period ago.
+func (s *ClusterService) recoverPendingUserOperations(owner string) ([]string, error) {
+	users, err := getUsersWithPendingOperation(owner, time.Now().Add(-pendingOperationGracePeriod).Unix())
+	if err != nil {
+		return nil, err
+	}
+	recovered := make([]string, 0, len(users))
+	failed := make([]string, 0)
+	for i := range users {
+		u := users[i]
+		if err := s.recoverPendingUserOperation(&u); err != nil {
+			log.Error(nil, err, fmt.Sprintf("unable to recover pending %s operation for user %s", u.PendingOperation, u.ID))
+			failed = append(failed, u.ID)
+			continue
+		}
+		recovered = append(recovered, u.ID)
+	}
+	if len(failed) > 0 {
+		return recovered, errors.Errorf("unable to recover pending operations for users: %s", strings.Join(failed, ", "))
+	}
+	return recovered, nil
+}
+
+// recoverPendingUserOperation completes the interrupted assignment if the cluster still exists or rolls it back otherwise.
+// The interrupted recycle is resumed.
+func (s *ClusterService) recoverPendingUserOperation(u *User) error {
+	switch u.PendingOperation {
+	case UserOperationAssigning:
+		c, err := getCluster(u.ClusterID)
+		if err != nil {
+			return err
+		}
+		if c == nil || c.Status == StatusDeleted {
+			log.Infof(nil, "rolling back interrupted assignment of user %s to cluster %s", u.ID, u.ClusterID)
+			return s.rollBackAssignment(u)
+		}
+		log.Infof(nil, "completing interrupted assignment of user %s to cluster %s", u.ID, u.ClusterID)
+		return s.completeAssignment(u)
+	case UserOperationRecycling:
+		log.Infof(nil, "resuming interrupted recycle of user %s", u.ID)
+		return s.recycle(u)
+	default:
+		return errors.Errorf("unknown pending operation: %s", u.PendingOperation)
+	}
+}
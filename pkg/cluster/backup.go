@@ -0,0 +1,301 @@
+package cluster
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/scrypt"
+)
+
+// BackupVersion is the version of the backup format written by this version of the service.
+// The backups written by the newer versions can't be restored.
+const BackupVersion = 1
+
+// backupEnvelope is the JSON wrapper of the backup. The data is the gzipped BSON backupDocument,
+// encrypted with AES-256-GCM if the backup is encrypted.
+type backupEnvelope struct {
+	Version   int    `json:"version"`
+	Created   int64  `json:"created"`
+	Encrypted bool   `json:"encrypted"`
+	Salt      []byte `json:"salt,omitempty"`  // salt of the key derived from the passphrase
+	Nonce     []byte `json:"nonce,omitempty"` // nonce of the AES-GCM encryption
+	Data      []byte `json:"data"`
+}
+
+// backupDocument contains the documents as stored in MongoDB so they are restored unchanged.
+// The archive is missing in the backups written before it has been included.
+type backupDocument struct {
+	Requests         []requestDocument         `bson:"requests"`
+	Clusters         []clusterDocument         `bson:"clusters"`
+	Users            []userDocument            `bson:"users"`
+	ArchivedRequests []archivedRequestDocument `bson:"archived_requests,omitempty"`
+	ArchivedClusters []archivedClusterDocument `bson:"archived_clusters,omitempty"`
+}
+
+// RestoreReport is the result of a restore
+type RestoreReport struct {
+	Requests int // number of restored requests
+	Clusters int // number of restored clusters
+	Users    int // number of restored users
+	// ArchivedRequests and ArchivedClusters are the numbers of the restored archived requests and clusters
+	ArchivedRequests int
+	ArchivedClusters int
+	// Drift is the report of the reconciliation run against IBM Cloud after the documents have been restored
+	Drift *DriftReport
+}
+
+// scrypt parameters of the key derivation recommended for interactive logins
+const (
+	backupKeyLength  = 32 // AES-256
+	backupSaltLength = 16
+	scryptN          = 32768
+	scryptR          = 8
+	scryptP          = 1
+)
+
+// Backup writes all the requests, clusters and users and the archive to the given writer.
+// The backup is encrypted with the passphrase unless the passphrase is empty.
+func Backup(w io.Writer, passphrase string) error {
+	doc, err := loadBackupDocument()
+	if err != nil {
+		return err
+	}
+	envelope, err := sealBackup(doc, passphrase, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(envelope); err != nil {
+		return errors.Wrap(err, "unable to write backup")
+	}
+	log.Info(nil, fmt.Sprintf("Backed up %d requests, %d clusters, %d users, %d archived requests and %d archived clusters",
+		len(doc.Requests), len(doc.Clusters), len(doc.Users), len(doc.ArchivedRequests), len(doc.ArchivedClusters)))
+	return nil
+}
+
+// Restore loads the requests, clusters and users and the archive from the backup read from the given reader.
+// The backup is rejected if any cluster refers to a missing request or any user refers to a missing cluster.
+// The users may refer to the archived clusters.
+// The restored documents replace the stored ones with the same IDs. The other stored documents are kept.
+// The restored state is then reconciled with IBM Cloud, i.e. the clusters which are gone are marked as deleted.
+func (s *ClusterService) Restore(r io.Reader, passphrase string) (*RestoreReport, error) {
+	var envelope backupEnvelope
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, devclustererr.NewBadRequestError("invalid backup", err.Error())
+	}
+	doc, err := openBackup(envelope, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	if err := restoreDocuments(doc.Requests, doc.Clusters, doc.Users); err != nil {
+		return nil, err
+	}
+	if err := restoreArchivedDocuments(doc.ArchivedRequests, doc.ArchivedClusters); err != nil {
+		return nil, err
+	}
+	report := &RestoreReport{
+		Requests:         len(doc.Requests),
+		Clusters:         len(doc.Clusters),
+		Users:            len(doc.Users),
+		ArchivedRequests: len(doc.ArchivedRequests),
+		ArchivedClusters: len(doc.ArchivedClusters),
+	}
+	log.Info(nil, fmt.Sprintf("Restored %d requests, %d clusters, %d users, %d archived requests and %d archived clusters",
+		report.Requests, report.Clusters, report.Users, report.ArchivedRequests, report.ArchivedClusters))
+	drift, err := s.Reconcile()
+	if err != nil {
+		return report, errors.Wrap(err, "documents restored but unable to reconcile them with IBM Cloud")
+	}
+	report.Drift = drift
+	return report, nil
+}
+
+func loadBackupDocument() (backupDocument, error) {
+	doc := backupDocument{}
+	requests, err := getAllRequests()
+	if err != nil {
+		return doc, err
+	}
+	clusters, err := getClustersWithFilter()
+	if err != nil {
+		return doc, err
+	}
+	users, err := getAllUsers()
+	if err != nil {
+		return doc, err
+	}
+	doc.Requests = make([]requestDocument, 0, len(requests))
+	for _, r := range requests {
+		doc.Requests = append(doc.Requests, newRequestDocument(r))
+	}
+	doc.Clusters = make([]clusterDocument, 0, len(clusters))
+	for _, c := range clusters {
+		doc.Clusters = append(doc.Clusters, newClusterDocument(c))
+	}
+	doc.Users = make([]userDocument, 0, len(users))
+	for _, u := range users {
+		doc.Users = append(doc.Users, newUserDocument(u))
+	}
+	doc.ArchivedRequests, doc.ArchivedClusters, err = getArchivedDocuments()
+	return doc, err
+}
+
+// validate checks that the IDs are unique and all the references between the documents can be resolved
+func (d backupDocument) validate() error {
+	requests := make(map[string]bool, len(d.Requests))
+	for _, r := range d.Requests {
+		if r.ID == "" || requests[r.ID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("missing or duplicate request ID: '%s'", r.ID))
+		}
+		requests[r.ID] = true
+	}
+	clusters := make(map[string]bool, len(d.Clusters))
+	for _, c := range d.Clusters {
+		if c.ID == "" || clusters[c.ID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("missing or duplicate cluster ID: '%s'", c.ID))
+		}
+		if !requests[c.RequestID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("cluster %s refers to missing request '%s'", c.ID, c.RequestID))
+		}
+		clusters[c.ID] = true
+	}
+	for _, c := range d.Clusters {
+		if c.ReplacedBy != "" && !clusters[c.ReplacedBy] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("cluster %s is replaced by missing cluster %s", c.ID, c.ReplacedBy))
+		}
+	}
+	archivedRequests := make(map[string]bool, len(d.ArchivedRequests))
+	for _, r := range d.ArchivedRequests {
+		if r.Request.ID == "" || archivedRequests[r.Request.ID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("missing or duplicate archived request ID: '%s'", r.Request.ID))
+		}
+		archivedRequests[r.Request.ID] = true
+	}
+	archivedClusters := make(map[string]bool, len(d.ArchivedClusters))
+	for _, c := range d.ArchivedClusters {
+		if c.Cluster.ID == "" || archivedClusters[c.Cluster.ID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("missing or duplicate archived cluster ID: '%s'", c.Cluster.ID))
+		}
+		if !archivedRequests[c.Cluster.RequestID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("archived cluster %s refers to missing archived request '%s'", c.Cluster.ID, c.Cluster.RequestID))
+		}
+		archivedClusters[c.Cluster.ID] = true
+	}
+	users := make(map[string]bool, len(d.Users))
+	for _, u := range d.Users {
+		if u.ID == "" || users[u.ID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("missing or duplicate user ID: '%s'", u.ID))
+		}
+		// The users assigned to the clusters which have been archived since are not recycled
+		if u.ClusterID != "" && !clusters[u.ClusterID] && !archivedClusters[u.ClusterID] {
+			return devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("user %s is assigned to missing cluster %s", u.ID, u.ClusterID))
+		}
+		users[u.ID] = true
+	}
+	return nil
+}
+
+// sealBackup compresses the backup and encrypts it with the passphrase if set
+func sealBackup(doc backupDocument, passphrase string, created int64) (backupEnvelope, error) {
+	envelope := backupEnvelope{
+		Version: BackupVersion,
+		Created: created,
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return envelope, errors.Wrap(err, "unable to encode backup")
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return envelope, errors.Wrap(err, "unable to compress backup")
+	}
+	if err := zw.Close(); err != nil {
+		return envelope, errors.Wrap(err, "unable to compress backup")
+	}
+	envelope.Data = buf.Bytes()
+	if passphrase == "" {
+		return envelope, nil
+	}
+	envelope.Encrypted = true
+	envelope.Salt = make([]byte, backupSaltLength)
+	if _, err := rand.Read(envelope.Salt); err != nil {
+		return envelope, errors.Wrap(err, "unable to generate backup salt")
+	}
+	aead, err := backupCipher(passphrase, envelope.Salt)
+	if err != nil {
+		return envelope, err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return envelope, errors.Wrap(err, "unable to generate backup nonce")
+	}
+	envelope.Data = aead.Seal(nil, envelope.Nonce, envelope.Data, nil)
+	return envelope, nil
+}
+
+// openBackup decrypts the backup if encrypted and decompresses it
+func openBackup(envelope backupEnvelope, passphrase string) (backupDocument, error) {
+	var doc backupDocument
+	if envelope.Version < 1 || envelope.Version > BackupVersion {
+		return doc, devclustererr.NewBadRequestError("invalid backup", fmt.Sprintf("unsupported backup version: %d", envelope.Version))
+	}
+	data := envelope.Data
+	if envelope.Encrypted {
+		if passphrase == "" {
+			return doc, devclustererr.NewBadRequestError("invalid backup", "the backup is encrypted but no passphrase is set")
+		}
+		aead, err := backupCipher(passphrase, envelope.Salt)
+		if err != nil {
+			return doc, err
+		}
+		if len(envelope.Nonce) != aead.NonceSize() {
+			return doc, devclustererr.NewBadRequestError("invalid backup", "invalid nonce")
+		}
+		data, err = aead.Open(nil, envelope.Nonce, data, nil)
+		if err != nil {
+			return doc, devclustererr.NewBadRequestError("invalid backup", "wrong passphrase or corrupted backup")
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return doc, devclustererr.NewBadRequestError("invalid backup", err.Error())
+	}
+	raw, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return doc, devclustererr.NewBadRequestError("invalid backup", err.Error())
+	}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return doc, devclustererr.NewBadRequestError("invalid backup", err.Error())
+	}
+	return doc, nil
+}
+
+// backupCipher returns the AES-256-GCM cipher with the key derived from the passphrase
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, backupKeyLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to derive backup key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create backup cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "unable to create backup cipher")
+}
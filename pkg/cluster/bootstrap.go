@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/bootstrap"
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+)
+
+// Statuses of the cluster bootstrap and of the individual bootstrap steps
+const (
+	BootstrapPending   = "pending"
+	BootstrapRunning   = "running"
+	BootstrapSucceeded = "succeeded"
+	BootstrapFailed    = "failed"
+)
+
+// BootstrapStep represents the status of one bootstrap step run against the cluster
+type BootstrapStep struct {
+	Name     string
+	Status   string
+	Logs     string // output of the step; only the tail of long outputs is kept
+	Error    string
+	Started  int64 // timestamp when the step was started
+	Finished int64 // timestamp when the step was finished
+}
+
+// bootstrapSteps returns the configured bootstrap steps
+func (s *ClusterService) bootstrapSteps() ([]bootstrap.Step, error) {
+	return bootstrap.ParseSteps(s.Config.GetBootstrapSteps())
+}
+
+// bootstrapTarget returns the target of the bootstrap steps for the cluster.
+// The cluster is logged in with the API key of the account the cluster is provisioned in.
+func (s *ClusterService) bootstrapTarget(c Cluster) (bootstrap.Target, error) {
+	a, err := s.cloudAccount(c.Account)
+	if err != nil {
+		return bootstrap.Target{}, err
+	}
+	return bootstrap.Target{
+		ClusterID: c.ID,
+		MasterURL: c.MasterURL,
+		Hostname:  c.Hostname,
+		Account:   a.Name,
+		APIKey:    accountConfig{Configuration: s.Config, account: a.Account}.GetIBMCloudAPIKey(),
+	}, nil
+}
+
+// bootstrapCluster runs the configured bootstrap steps against the provisioned cluster if the bootstrap is pending
+// or has been interrupted. The steps are run in order and the bootstrap stops on the first failed step.
+// The status and the logs of every step are stored in the cluster.
+func (s *ClusterService) bootstrapCluster(c Cluster) error {
+	if c.BootstrapStatus != BootstrapPending && c.BootstrapStatus != BootstrapRunning {
+		return nil
+	}
+	steps, err := s.bootstrapSteps()
+	if err != nil {
+		return err
+	}
+	statuses := make([]BootstrapStep, 0, len(steps))
+	for _, step := range steps {
+		statuses = append(statuses, BootstrapStep{Name: step.Name, Status: BootstrapPending})
+	}
+	target, err := s.bootstrapTarget(c)
+	if err != nil {
+		return err
+	}
+	for i, step := range steps {
+		statuses[i].Status = BootstrapRunning
+		statuses[i].Started = time.Now().Unix()
+		if err := updateClusterBootstrap(c.ID, BootstrapRunning, statuses, "", ActorSystem); err != nil {
+			return err
+		}
+		log.Infof(nil, "running bootstrap step %s for cluster %s", step.Name, c.ID)
+		logs, stepErr := s.Bootstrap.Run(target, step)
+		statuses[i].Logs = logs
+		statuses[i].Finished = time.Now().Unix()
+		if stepErr != nil {
+			statuses[i].Status = BootstrapFailed
+			statuses[i].Error = stepErr.Error()
+			if err := updateClusterBootstrap(c.ID, BootstrapFailed, statuses, stepErr.Error(), ActorSystem); err != nil {
+				return err
+			}
+			s.notify(fmt.Sprintf("Unable to bootstrap cluster for request %s", c.RequestID),
+				fmt.Sprintf("Bootstrap step %s failed for cluster %s (%s): %s. Use POST /api/v1/cluster/%s/bootstrap to try again.", step.Name, c.Name, c.ID, stepErr.Error(), c.ID))
+			return stepErr
+		}
+		statuses[i].Status = BootstrapSucceeded
+	}
+	log.Infof(nil, "cluster %s is bootstrapped", c.ID)
+	if err := updateClusterBootstrap(c.ID, BootstrapSucceeded, statuses, "", ActorSystem); err != nil {
+		return err
+	}
+	return setClusterReady(c.ID, time.Now().Unix())
+}
+
+// RetryBootstrap runs the bootstrap steps again for the cluster which failed to get bootstrapped on behalf of the given actor.
+// The steps are run in background and the request gets ready when all its clusters are bootstrapped.
+func (s *ClusterService) RetryBootstrap(id, actor string) error {
+	c, err := getCluster(id)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("cluster %s not found", id), "")
+	}
+	if c.BootstrapStatus != BootstrapFailed {
+		return devclustererr.NewBadRequestError(fmt.Sprintf("cluster %s is not in the '%s' bootstrap status", id, BootstrapFailed), fmt.Sprintf("current bootstrap status: %s", c.BootstrapStatus))
+	}
+	r, err := getRequest(c.RequestID)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("request %s not found", c.RequestID), "")
+	}
+	if err := updateClusterBootstrap(id, BootstrapPending, c.BootstrapSteps, "", actor); err != nil {
+		return err
+	}
+	c.BootstrapStatus = BootstrapPending
+	go func() {
+		if err := s.bootstrapCluster(*c); err != nil {
+			log.Error(nil, err, "failed to bootstrap the cluster")
+			return
+		}
+		if err := setRequestStatusToSuccessIfDone(*r); err != nil {
+			log.Error(nil, err, "unable to update the request status")
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,387 @@
+package cluster
+
+// SchemaVersion is the version of the schema of the documents written by this version of the service.
+// The documents written by the older versions are upgraded by the migrations when the service starts.
+const SchemaVersion = 1
+
+// The documents as stored in MongoDB. The missing fields are decoded to their zero values
+// so the documents written by the older versions or missing some fields can always be read.
+
+type requestDocument struct {
+	ID              string   `bson:"_id"`
+	SchemaVersion   int      `bson:"schema_version"`
+	Status          string   `bson:"status"`
+	Requested       int      `bson:"requested"`
+	Error           string   `bson:"error"`
+	Created         int64    `bson:"created"`
+	RequestedBy     string   `bson:"requested_by"`
+	Zone            string   `bson:"zone"`
+	DeleteInHours   int      `bson:"delete_in_hours"`
+	NoSubnet        bool     `bson:"no_subnet"`
+	UsersPerCluster int      `bson:"users_per_cluster"`
+	URLTemplates    []string `bson:"url_templates"`
+	Account         string   `bson:"account"`
+}
+
+func newRequestDocument(r Request) requestDocument {
+	return requestDocument{
+		ID:              r.ID,
+		SchemaVersion:   SchemaVersion,
+		Status:          r.Status,
+		Requested:       r.Requested,
+		Error:           r.Error,
+		Created:         r.Created,
+		RequestedBy:     r.RequestedBy,
+		Zone:            r.Zone,
+		DeleteInHours:   r.DeleteInHours,
+		NoSubnet:        r.NoSubnet,
+		UsersPerCluster: r.UsersPerCluster,
+		URLTemplates:    r.URLTemplates,
+		Account:         r.Account,
+	}
+}
+
+func (d requestDocument) request() Request {
+	urlTemplates := d.URLTemplates
+	if urlTemplates == nil {
+		urlTemplates = []string{}
+	}
+	return Request{
+		ID:              d.ID,
+		RequestedBy:     d.RequestedBy,
+		Created:         d.Created,
+		Error:           d.Error,
+		Requested:       d.Requested,
+		Status:          d.Status,
+		Zone:            d.Zone,
+		DeleteInHours:   d.DeleteInHours,
+		NoSubnet:        d.NoSubnet,
+		UsersPerCluster: d.UsersPerCluster,
+		URLTemplates:    urlTemplates,
+		Account:         d.Account,
+	}
+}
+
+type clusterDocument struct {
+	ID                  string                  `bson:"_id"`
+	SchemaVersion       int                     `bson:"schema_version"`
+	Status              string                  `bson:"status"`
+	Name                string                  `bson:"name"`
+	Error               string                  `bson:"error"`
+	Hostname            string                  `bson:"hostname"`
+	MasterURL           string                  `bson:"master_url"`
+	RequestID           string                  `bson:"request_id"`
+	IBMClusterRequestID string                  `bson:"ic_request_id"`
+	PublicVlan          string                  `bson:"public_vlan"`
+	PrivateVlan         string                  `bson:"private_vlan"`
+	DeleteAttempts      int                     `bson:"delete_attempts"`
+	NextDeleteAttempt   int64                   `bson:"next_delete_attempt"`
+	ReplaceAttempt      int                     `bson:"replace_attempt"`
+	ReplacedBy          string                  `bson:"replaced_by"`
+	BootstrapStatus     string                  `bson:"bootstrap_status"`
+	BootstrapSteps      []bootstrapStepDocument `bson:"bootstrap_steps"`
+	MachineType         string                  `bson:"machine_type"`
+	WorkerCount         int                     `bson:"worker_count"`
+	Created             int64                   `bson:"created"`
+	Ready               int64                   `bson:"ready"`
+	Deleted             int64                   `bson:"deleted"`
+	Account             string                  `bson:"account"`
+}
+
+type bootstrapStepDocument struct {
+	Name     string `bson:"name"`
+	Status   string `bson:"status"`
+	Logs     string `bson:"logs"`
+	Error    string `bson:"error"`
+	Started  int64  `bson:"started"`
+	Finished int64  `bson:"finished"`
+}
+
+func newClusterDocument(c Cluster) clusterDocument {
+	return clusterDocument{
+		ID:                  c.ID,
+		SchemaVersion:       SchemaVersion,
+		Status:              c.Status,
+		Name:                c.Name,
+		Error:               c.Error,
+		Hostname:            c.Hostname,
+		MasterURL:           c.MasterURL,
+		RequestID:           c.RequestID,
+		IBMClusterRequestID: c.IBMClusterRequestID,
+		PublicVlan:          c.PublicVlan,
+		PrivateVlan:         c.PrivateVlan,
+		DeleteAttempts:      c.DeleteAttempts,
+		NextDeleteAttempt:   c.NextDeleteAttempt,
+		ReplaceAttempt:      c.ReplaceAttempt,
+		ReplacedBy:          c.ReplacedBy,
+		BootstrapStatus:     c.BootstrapStatus,
+		BootstrapSteps:      newBootstrapStepDocuments(c.BootstrapSteps),
+		MachineType:         c.MachineType,
+		WorkerCount:         c.WorkerCount,
+		Created:             c.Created,
+		Ready:               c.Ready,
+		Deleted:             c.Deleted,
+		Account:             c.Account,
+	}
+}
+
+func (d clusterDocument) cluster() Cluster {
+	c := Cluster{
+		ID:                  d.ID,
+		RequestID:           d.RequestID,
+		IBMClusterRequestID: d.IBMClusterRequestID,
+		Hostname:            d.Hostname,
+		MasterURL:           d.MasterURL,
+		Error:               d.Error,
+		Name:                d.Name,
+		Status:              d.Status,
+		PublicVlan:          d.PublicVlan,
+		PrivateVlan:         d.PrivateVlan,
+		DeleteAttempts:      d.DeleteAttempts,
+		NextDeleteAttempt:   d.NextDeleteAttempt,
+		ReplaceAttempt:      d.ReplaceAttempt,
+		ReplacedBy:          d.ReplacedBy,
+		BootstrapStatus:     d.BootstrapStatus,
+		MachineType:         d.MachineType,
+		WorkerCount:         d.WorkerCount,
+		Created:             d.Created,
+		Ready:               d.Ready,
+		Deleted:             d.Deleted,
+		Account:             d.Account,
+	}
+	if d.BootstrapSteps != nil {
+		c.BootstrapSteps = make([]BootstrapStep, 0, len(d.BootstrapSteps))
+		for _, step := range d.BootstrapSteps {
+			c.BootstrapSteps = append(c.BootstrapSteps, BootstrapStep(step))
+		}
+	}
+	return c
+}
+
+func newBootstrapStepDocuments(steps []BootstrapStep) []bootstrapStepDocument {
+	docs := make([]bootstrapStepDocument, 0, len(steps))
+	for _, step := range steps {
+		docs = append(docs, bootstrapStepDocument(step))
+	}
+	return docs
+}
+
+// archivedRequestDocument is a request moved to the archive
+type archivedRequestDocument struct {
+	Request  requestDocument `bson:",inline"`
+	Archived int64           `bson:"archived"`
+}
+
+// archivedClusterDocument is a cluster moved to the archive together with its request
+type archivedClusterDocument struct {
+	Cluster  clusterDocument `bson:",inline"`
+	Archived int64           `bson:"archived"`
+}
+
+// archiveListingDocument is an archived request joined with its archived clusters by the archive listing aggregation
+type archiveListingDocument struct {
+	Request  requestDocument   `bson:",inline"`
+	Archived int64             `bson:"archived"`
+	Clusters []clusterDocument `bson:"clusters"`
+}
+
+// clusterListingDocument is a cluster joined with its request and its users by the cluster listing aggregation
+type clusterListingDocument struct {
+	Cluster clusterDocument `bson:",inline"`
+	Request requestDocument `bson:"request"`
+	Users   []userDocument  `bson:"users"`
+}
+
+// userListingDocument is a user joined with the cluster it's assigned to by the user listing aggregation
+type userListingDocument struct {
+	User    userDocument      `bson:",inline"`
+	Cluster []clusterDocument `bson:"cluster"`
+}
+
+type userDocument struct {
+	ID               string `bson:"_id"`
+	SchemaVersion    int    `bson:"schema_version"`
+	CloudDirectID    string `bson:"cloud_direct_id"`
+	Email            string `bson:"email"`
+	Password         string `bson:"password"`
+	ClusterID        string `bson:"cluster_id"`
+	PolicyID         string `bson:"policy_id"`
+	Recycled         int64  `bson:"recycled"`
+	Disabled         bool   `bson:"disabled"`
+	PendingOperation string `bson:"pending_operation"`
+	PendingOwner     string `bson:"pending_owner"`
+	PendingSince     int64  `bson:"pending_since"`
+	Account          string `bson:"account"`
+	ReservedFor      string `bson:"reserved_for"`
+}
+
+func newUserDocument(u User) userDocument {
+	return userDocument{
+		ID:               u.ID,
+		SchemaVersion:    SchemaVersion,
+		CloudDirectID:    u.CloudDirectID,
+		Email:            u.Email,
+		Password:         u.Password,
+		ClusterID:        u.ClusterID,
+		PolicyID:         u.PolicyID,
+		Recycled:         u.Recycled,
+		Disabled:         u.Disabled,
+		PendingOperation: u.PendingOperation,
+		PendingOwner:     u.PendingOwner,
+		PendingSince:     u.PendingSince,
+		Account:          u.Account,
+		ReservedFor:      u.ReservedFor,
+	}
+}
+
+func (d userDocument) user() User {
+	return User{
+		ID:               d.ID,
+		CloudDirectID:    d.CloudDirectID,
+		Email:            d.Email,
+		Password:         d.Password,
+		ClusterID:        d.ClusterID,
+		PolicyID:         d.PolicyID,
+		Recycled:         d.Recycled,
+		Disabled:         d.Disabled,
+		PendingOperation: d.PendingOperation,
+		PendingOwner:     d.PendingOwner,
+		PendingSince:     d.PendingSince,
+		Account:          d.Account,
+		ReservedFor:      d.ReservedFor,
+	}
+}
+
+type driftReportDocument struct {
+	ID              string   `bson:"_id"`
+	SchemaVersion   int      `bson:"schema_version"`
+	Started         int64    `bson:"started"`
+	Finished        int64    `bson:"finished"`
+	OrphanClusters  []string `bson:"orphan_clusters"`
+	AdoptedClusters []string `bson:"adopted_clusters"`
+	MissingClusters []string `bson:"missing_clusters"`
+	OrphanPolicies  []string `bson:"orphan_policies"`
+	RecoveredUsers  []string `bson:"recovered_users"`
+	Errors          []string `bson:"errors"`
+}
+
+func newDriftReportDocument(r DriftReport) driftReportDocument {
+	return driftReportDocument{
+		ID:              r.ID,
+		SchemaVersion:   SchemaVersion,
+		Started:         r.Started,
+		Finished:        r.Finished,
+		OrphanClusters:  r.OrphanClusters,
+		AdoptedClusters: r.AdoptedClusters,
+		MissingClusters: r.MissingClusters,
+		OrphanPolicies:  r.OrphanPolicies,
+		RecoveredUsers:  r.RecoveredUsers,
+		Errors:          r.Errors,
+	}
+}
+
+func (d driftReportDocument) driftReport() DriftReport {
+	return DriftReport{
+		ID:              d.ID,
+		Started:         d.Started,
+		Finished:        d.Finished,
+		OrphanClusters:  nonNilStrings(d.OrphanClusters),
+		AdoptedClusters: nonNilStrings(d.AdoptedClusters),
+		MissingClusters: nonNilStrings(d.MissingClusters),
+		OrphanPolicies:  nonNilStrings(d.OrphanPolicies),
+		RecoveredUsers:  nonNilStrings(d.RecoveredUsers),
+		Errors:          nonNilStrings(d.Errors),
+	}
+}
+
+type zoneDocument struct {
+	ID            string `bson:"_id"`
+	SchemaVersion int    `bson:"schema_version"`
+	Enabled       bool   `bson:"enabled"`
+	MaxClusters   int    `bson:"max_clusters"`
+	Default       bool   `bson:"default"`
+	Reserved      int    `bson:"reserved"` // number of the clusters admitted to the zone but not stored yet
+	Version       int64  `bson:"version"`  // incremented on every change of the reservations
+}
+
+func newZoneDocument(z Zone) zoneDocument {
+	return zoneDocument{
+		ID:            z.ID,
+		SchemaVersion: SchemaVersion,
+		Enabled:       z.Enabled,
+		MaxClusters:   z.MaxClusters,
+		Default:       z.Default,
+	}
+}
+
+func (d zoneDocument) zone() Zone {
+	return Zone{
+		ID:          d.ID,
+		Enabled:     d.Enabled,
+		MaxClusters: d.MaxClusters,
+		Default:     d.Default,
+		Reserved:    d.Reserved,
+	}
+}
+
+// accountDocument holds the reservations of an IBM Cloud account. The accounts are configured, not stored,
+// so the document is created on the first reservation.
+type accountDocument struct {
+	ID            string `bson:"_id"` // name of the account
+	SchemaVersion int    `bson:"schema_version"`
+	Reserved      int    `bson:"reserved"` // number of the clusters admitted to the account but not stored yet
+	Version       int64  `bson:"version"`  // incremented on every change of the reservations
+}
+
+type eventDocument struct {
+	ID            string `bson:"_id"`
+	SchemaVersion int    `bson:"schema_version"`
+	Object        string `bson:"object"`
+	ObjectID      string `bson:"object_id"`
+	RequestID     string `bson:"request_id"`
+	Field         string `bson:"field"`
+	OldStatus     string `bson:"old_status"`
+	NewStatus     string `bson:"new_status"`
+	Error         string `bson:"error"`
+	Actor         string `bson:"actor"`
+	Timestamp     int64  `bson:"timestamp"`
+}
+
+func newEventDocument(e Event) eventDocument {
+	return eventDocument{
+		ID:            e.ID,
+		SchemaVersion: SchemaVersion,
+		Object:        e.Object,
+		ObjectID:      e.ObjectID,
+		RequestID:     e.RequestID,
+		Field:         e.Field,
+		OldStatus:     e.OldStatus,
+		NewStatus:     e.NewStatus,
+		Error:         e.Error,
+		Actor:         e.Actor,
+		Timestamp:     e.Timestamp,
+	}
+}
+
+func (d eventDocument) event() Event {
+	return Event{
+		ID:        d.ID,
+		Object:    d.Object,
+		ObjectID:  d.ObjectID,
+		RequestID: d.RequestID,
+		Field:     d.Field,
+		OldStatus: d.OldStatus,
+		NewStatus: d.NewStatus,
+		Error:     d.Error,
+		Actor:     d.Actor,
+		Timestamp: d.Timestamp,
+	}
+}
+
+// nonNilStrings returns an empty slice instead of nil so the missing arrays are rendered as empty arrays in JSON
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
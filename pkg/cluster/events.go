@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Objects the events are recorded for
+const (
+	EventObjectCluster = "cluster"
+	EventObjectRequest = "request"
+)
+
+// Fields the status changes are recorded for
+const (
+	EventFieldStatus          = "status"
+	EventFieldBootstrapStatus = "bootstrap_status"
+)
+
+// ActorSystem is the actor of the changes done by the service itself, for example by the background routines
+const ActorSystem = "system"
+
+// Event represents a change of the status of a cluster or of a request
+type Event struct {
+	ID        string // the events are sorted by their IDs in the order they have been recorded
+	Object    string // cluster or request
+	ObjectID  string
+	RequestID string // ID of the request; for the cluster events it's the request the cluster belongs to
+	Field     string // the changed field: status or bootstrap_status
+	OldStatus string // empty if the object has just been created
+	NewStatus string
+	Error     string
+	Actor     string // name of the user who triggered the change or "system"
+	Timestamp int64
+}
+
+// recordStatusChange records the event if the status has changed.
+// The status is already changed at this point so the errors are only logged.
+func recordStatusChange(object, objectID, requestID, field, oldStatus, newStatus, error, actor string) {
+	if oldStatus == newStatus {
+		return
+	}
+	e := Event{
+		ID:        primitive.NewObjectID().Hex(),
+		Object:    object,
+		ObjectID:  objectID,
+		RequestID: requestID,
+		Field:     field,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Error:     error,
+		Actor:     actor,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := insertEvent(e); err != nil {
+		log.Error(nil, err, fmt.Sprintf("unable to record %s change of %s %s from '%s' to '%s'", field, object, objectID, oldStatus, newStatus))
+	}
+}
+
+// ClusterEvents returns the status changes of the cluster with the given ID in the order they happened
+func (s *ClusterService) ClusterEvents(id string) ([]Event, error) {
+	c, err := getCluster(id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("cluster %s not found", id), "")
+	}
+	return getEvents(withObject(EventObjectCluster), withObjectID(id))
+}
+
+// RequestEvents returns the status changes of the request with the given ID
+// and of all the clusters of the request in the order they happened
+func (s *ClusterService) RequestEvents(id string) ([]Event, error) {
+	r, err := getRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("request %s not found", id), "")
+	}
+	return getEvents(withRequestID(id))
+}
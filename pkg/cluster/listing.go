@@ -0,0 +1,393 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ListOptions are the filters, the order and the page of a listing.
+// The zero value lists all the items in the default order of the listing.
+type ListOptions struct {
+	Status      string
+	RequestedBy string
+	Zone        string
+	CreatedFrom int64 // inclusive, unix time; 0 if not limited
+	CreatedTo   int64 // exclusive, unix time; 0 if not limited
+	NamePrefix  string
+	SortBy      string // one of the sort keys of the listing; the default sort key of the listing if empty
+	Descending  bool
+	Limit       int    // max number of the items in the page; 0 for all the items
+	Cursor      string // the Next cursor of the previous page; empty for the first page
+}
+
+// PageInfo describes the page returned by a listing
+type PageInfo struct {
+	Total int64  // number of all the items matching the filters
+	Next  string // cursor of the next page; empty if there are no more items
+}
+
+// sortKey is a field the listing can be sorted by. The items with equal values are sorted by their IDs.
+type sortKey struct {
+	field   string // the document field, e.g. "request.zone" for the field of the request joined with a cluster
+	numeric bool
+	joined  bool // the field is a field of the joined documents
+}
+
+// Keys the listings can be sorted by. The first key is the default one.
+var (
+	requestSortKeys = []string{"created", "id", "requested", "requested_by", "delete_in_hours", "status", "zone"}
+	clusterSortKeys = []string{"created", "id", "name", "status", "zone", "requested_by"}
+	userSortKeys    = []string{"id", "email", "cluster_id", "recycled"}
+)
+
+var sortKeys = map[string]map[string]sortKey{
+	"requests": {
+		"created":         {field: "created", numeric: true},
+		"id":              {field: "_id"},
+		"requested":       {field: "requested", numeric: true},
+		"requested_by":    {field: "requested_by"},
+		"delete_in_hours": {field: "delete_in_hours", numeric: true},
+		"status":          {field: "status"},
+		"zone":            {field: "zone"},
+	},
+	"clusters": {
+		"created":      {field: "created", numeric: true},
+		"id":           {field: "_id"},
+		"name":         {field: "name"},
+		"status":       {field: "status"},
+		"zone":         {field: "request.zone", joined: true},
+		"requested_by": {field: "request.requested_by", joined: true},
+	},
+	"users": {
+		"id":         {field: "_id"},
+		"email":      {field: "email"},
+		"cluster_id": {field: "cluster_id"},
+		"recycled":   {field: "recycled", numeric: true},
+	},
+}
+
+// pageCursor points to the last item of the previous page
+type pageCursor struct {
+	SortBy string          `json:"s"`
+	Value  json.RawMessage `json:"v"`
+	ID     string          `json:"id"`
+}
+
+// pageQuery is the query of one page of a listing
+type pageQuery struct {
+	filter     bson.D
+	joinFilter bson.D // the filters of the fields of the joined documents
+	sortBy     string
+	sort       sortKey
+	descending bool
+	limit      int
+	after      *pageCursor
+}
+
+// newPageQuery validates the sorting and the page of the options and returns the query with no filters
+func newPageQuery(listing string, keys []string, opts ListOptions) (pageQuery, error) {
+	q := pageQuery{
+		filter:     bson.D{},
+		joinFilter: bson.D{},
+		sortBy:     opts.SortBy,
+		descending: opts.Descending,
+		limit:      opts.Limit,
+	}
+	if q.sortBy == "" {
+		q.sortBy = keys[0]
+	}
+	key, found := sortKeys[listing][q.sortBy]
+	if !found {
+		return q, devclustererr.NewBadRequestError(fmt.Sprintf("unable to sort %s by %s", listing, q.sortBy),
+			fmt.Sprintf("supported keys: %s", strings.Join(keys, ", ")))
+	}
+	q.sort = key
+	if q.limit < 0 {
+		return q, devclustererr.NewBadRequestError("limit must not be negative", "")
+	}
+	if opts.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+		var c pageCursor
+		if err == nil {
+			err = json.Unmarshal(raw, &c)
+		}
+		if err != nil || c.SortBy != q.sortBy {
+			return q, devclustererr.NewBadRequestError("invalid cursor", "the cursor must be the one returned for the same sort key")
+		}
+		q.after = &c
+	}
+	return q, nil
+}
+
+// where adds the filter to the query
+func (q *pageQuery) where(filter ...bson.E) {
+	q.filter = append(q.filter, filter...)
+}
+
+// whereJoined adds the filter of the fields of the joined documents to the query
+func (q *pageQuery) whereJoined(filter ...bson.E) {
+	q.joinFilter = append(q.joinFilter, filter...)
+}
+
+// needsJoins returns true if the query filters or sorts by the fields of the joined documents
+func (q pageQuery) needsJoins() bool {
+	return len(q.joinFilter) > 0 || q.sort.joined
+}
+
+// withCreatedBetween adds the created date range filter if the range is limited
+func (q *pageQuery) withCreatedBetween(from, to int64) {
+	created := bson.D{}
+	if from != 0 {
+		created = append(created, bson.E{Key: "$gte", Value: from})
+	}
+	if to != 0 {
+		created = append(created, bson.E{Key: "$lt", Value: to})
+	}
+	if len(created) > 0 {
+		q.where(bson.E{Key: "created", Value: created})
+	}
+}
+
+// afterCursor returns the filter matching the items after the cursor in the sort order
+func (q pageQuery) afterCursor() (bson.D, error) {
+	op := "$gt"
+	if q.descending {
+		op = "$lt"
+	}
+	var value interface{}
+	if q.sort.numeric {
+		var v int64
+		if err := json.Unmarshal(q.after.Value, &v); err != nil {
+			return nil, devclustererr.NewBadRequestError("invalid cursor", err.Error())
+		}
+		value = v
+	} else {
+		var v string
+		if err := json.Unmarshal(q.after.Value, &v); err != nil {
+			return nil, devclustererr.NewBadRequestError("invalid cursor", err.Error())
+		}
+		value = v
+	}
+	if q.sort.field == "_id" {
+		return bson.D{{"_id", bson.D{{op, value}}}}, nil
+	}
+	return bson.D{{"$or", bson.A{
+		bson.D{{q.sort.field, bson.D{{op, value}}}},
+		bson.D{{q.sort.field, value}, {"_id", bson.D{{op, q.after.ID}}}},
+	}}}, nil
+}
+
+// sortOrder returns the $sort stage specification
+func (q pageQuery) sortOrder() bson.D {
+	dir := 1
+	if q.descending {
+		dir = -1
+	}
+	if q.sort.field == "_id" {
+		return bson.D{{"_id", dir}}
+	}
+	return bson.D{{q.sort.field, dir}, {"_id", dir}}
+}
+
+// cursorOf returns the cursor pointing to the given document
+func (q pageQuery) cursorOf(doc bson.Raw) (string, error) {
+	c := pageCursor{SortBy: q.sortBy}
+	if id, ok := doc.Lookup("_id").StringValueOK(); ok {
+		c.ID = id
+	}
+	var value interface{}
+	v := doc.Lookup(strings.Split(q.sort.field, ".")...)
+	switch v.Type {
+	case bsontype.Int32:
+		value = int64(v.Int32())
+	case bsontype.Int64:
+		value = v.Int64()
+	case bsontype.Double:
+		value = int64(v.Double())
+	case bsontype.String:
+		value = v.StringValue()
+	default: // missing
+		if q.sort.numeric {
+			value = int64(0)
+		} else {
+			value = ""
+		}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	c.Value = raw
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// unsupportedFilters returns a Bad Request error if any of the given filters is set
+func unsupportedFilters(listing string, filters map[string]bool) error {
+	var set []string
+	for name, isSet := range filters {
+		if isSet {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	sort.Strings(set)
+	return devclustererr.NewBadRequestError(fmt.Sprintf("unable to filter %s by %s", listing, strings.Join(set, ", ")), "")
+}
+
+// withPrefix returns the filter matching the string fields starting with the given prefix
+func withPrefix(field, prefix string) bson.E {
+	return bson.E{Key: field, Value: bson.D{{"$regex", "^" + regexp.QuoteMeta(prefix)}}}
+}
+
+// ListRequests returns the page of the requests matching the options.
+// The requests can be filtered by status, requester, zone and created date.
+func (s *ClusterService) ListRequests(opts ListOptions) ([]Request, PageInfo, error) {
+	q, err := newRequestPageQuery(opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	return listRequests(q)
+}
+
+// newRequestPageQuery returns the query of the page of the requests or of the archived requests matching the options
+func newRequestPageQuery(opts ListOptions) (pageQuery, error) {
+	if err := unsupportedFilters("requests", map[string]bool{"name prefix": opts.NamePrefix != ""}); err != nil {
+		return pageQuery{}, err
+	}
+	q, err := newPageQuery("requests", requestSortKeys, opts)
+	if err != nil {
+		return q, err
+	}
+	if opts.Status != "" {
+		q.where(withStatus(opts.Status))
+	}
+	if opts.RequestedBy != "" {
+		q.where(bson.E{Key: "requested_by", Value: opts.RequestedBy})
+	}
+	if opts.Zone != "" {
+		q.where(withZone(opts.Zone))
+	}
+	q.withCreatedBetween(opts.CreatedFrom, opts.CreatedTo)
+	return q, nil
+}
+
+// ListClusters returns the page of the clusters matching the options with their users and URLs.
+// The clusters can be filtered by status, name prefix, created date and by the requester and zone of their requests.
+// The deleted clusters are only listed if filtered by the deleted status.
+func (s *ClusterService) ListClusters(opts ListOptions) ([]Cluster, PageInfo, error) {
+	q, err := newPageQuery("clusters", clusterSortKeys, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	if opts.Status != "" {
+		q.where(withStatus(opts.Status))
+	} else {
+		q.where(withNotDeletedStatus())
+	}
+	if opts.RequestedBy != "" {
+		q.whereJoined(bson.E{Key: "request.requested_by", Value: opts.RequestedBy})
+	}
+	if opts.Zone != "" {
+		q.whereJoined(bson.E{Key: "request.zone", Value: opts.Zone})
+	}
+	if opts.NamePrefix != "" {
+		q.where(withPrefix("name", opts.NamePrefix))
+	}
+	q.withCreatedBetween(opts.CreatedFrom, opts.CreatedTo)
+	found, page, err := listClusters(q)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	clusters := make([]Cluster, 0, len(found))
+	for _, f := range found {
+		c, err := s.enrichClusterWithUsers(f.cluster, f.users, f.request)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, page, nil
+}
+
+// ListUsers returns the page of the users matching the options with their statuses in the user pool.
+// The users can be filtered by the status in the user pool and by the ID prefix.
+func (s *ClusterService) ListUsers(opts ListOptions) ([]UserWithStatus, PageInfo, error) {
+	if err := unsupportedFilters("users", map[string]bool{
+		"requested by": opts.RequestedBy != "",
+		"zone":         opts.Zone != "",
+		"created date": opts.CreatedFrom != 0 || opts.CreatedTo != 0,
+	}); err != nil {
+		return nil, PageInfo{}, err
+	}
+	q, err := newPageQuery("users", userSortKeys, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	if opts.Status != "" {
+		filter, joined, err := withUserPoolStatus(opts.Status)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		if joined {
+			q.whereJoined(filter)
+		} else {
+			q.where(filter)
+		}
+	}
+	if opts.NamePrefix != "" {
+		q.where(withPrefix("_id", opts.NamePrefix))
+	}
+	return listUsers(q)
+}
+
+// withUserPoolStatus returns the filter matching the users with the given status in the user pool.
+// It's the equivalent of userPoolStatus for the users joined with their clusters as "cluster".
+// Returns true if the filter needs the users to be joined with their clusters.
+func withUserPoolStatus(status string) (bson.E, bool, error) {
+	notPending := bson.D{{"pending_operation", ""}}
+	stuck := bson.D{
+		{"cluster_id", bson.D{{"$ne", ""}}},
+		{"$or", bson.A{
+			bson.D{{"cluster", bson.D{{"$size", 0}}}},
+			bson.D{{"cluster.status", StatusDeleted}},
+		}},
+	}
+	notStuck := bson.D{{"$nor", bson.A{stuck}}}
+	notDisabled := bson.D{{"disabled", bson.D{{"$ne", true}}}}
+	var conditions bson.A
+	joined := false
+	switch status {
+	case UserStatusPending:
+		conditions = bson.A{bson.D{{"pending_operation", bson.D{{"$ne", ""}}}}}
+	case UserStatusStuck:
+		conditions = bson.A{notPending, stuck}
+		joined = true
+	case UserStatusDisabled:
+		conditions = bson.A{notPending, notStuck, bson.D{{"disabled", true}}}
+		joined = true
+	case UserStatusAssigned:
+		conditions = bson.A{notPending, notStuck, notDisabled, bson.D{{"cluster_id", bson.D{{"$ne", ""}}}}}
+		joined = true
+	case UserStatusFree:
+		conditions = bson.A{notPending, notDisabled, bson.D{{"cluster_id", ""}}}
+	default:
+		return bson.E{}, false, devclustererr.NewBadRequestError(fmt.Sprintf("unknown user status: %s", status),
+			fmt.Sprintf("supported statuses: %s, %s, %s, %s, %s", UserStatusFree, UserStatusAssigned, UserStatusDisabled, UserStatusPending, UserStatusStuck))
+	}
+	return bson.E{Key: "$and", Value: conditions}, joined, nil
+}
@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/mongodb"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration upgrades the documents stored by the older versions of the service.
+// Every migration must be idempotent since several instances of the service may run it at the same time.
+type migration struct {
+	id          string
+	description string
+	migrate     func() error
+}
+
+// migrations are applied in this order. The applied migrations are recorded and never run again
+// so the existing migrations must not be changed; add a new one instead.
+var migrations = []migration{
+	{
+		id:          "001-schema-version-1",
+		description: "set the missing fields to their defaults and the schema version to 1",
+		migrate:     migrateToSchemaVersion1,
+	},
+	{
+		id:          "002-user-reservations",
+		description: "set the missing request reservations of the users",
+		migrate: func() error {
+			return setMissingField(mongodb.Users(), bson.E{Key: "reserved_for", Value: ""})
+		},
+	},
+	{
+		id:          "003-accounts",
+		description: "assign the documents stored before the accounts were introduced to the default account",
+		migrate: func() error {
+			for _, collection := range []*mongo.Collection{mongodb.ClusterRequests(), mongodb.Clusters(), mongodb.Users()} {
+				if err := setMissingField(collection, bson.E{Key: "account", Value: ""}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// RunMigrations applies all the migrations which have not been applied yet
+func RunMigrations() error {
+	for _, m := range migrations {
+		res := mongodb.Migrations().FindOne(context.Background(), bson.D{{"_id", m.id}})
+		if err := res.Err(); err == nil {
+			continue
+		} else if err != mongo.ErrNoDocuments {
+			return errors.Wrapf(err, "unable to check migration %s", m.id)
+		}
+		log.Infof(nil, "Applying migration %s: %s", m.id, m.description)
+		if err := m.migrate(); err != nil {
+			return errors.Wrapf(err, "unable to apply migration %s", m.id)
+		}
+		opts := options.Replace().SetUpsert(true)
+		if _, err := mongodb.Migrations().ReplaceOne(
+			context.Background(),
+			bson.D{{"_id", m.id}},
+			bson.D{
+				{"_id", m.id},
+				{"description", m.description},
+				{"applied", time.Now().Unix()},
+			},
+			opts,
+		); err != nil {
+			return errors.Wrapf(err, "unable to record migration %s", m.id)
+		}
+	}
+	return nil
+}
+
+// schemaVersion1Defaults are the values of the fields missing in the documents stored before the schema was versioned.
+// They are frozen at schema version 1 and must not follow later changes of the documents.
+// The keys are the names of the collections.
+var schemaVersion1Defaults = map[string]bson.D{
+	"clusterRequests": {
+		{"status", ""},
+		{"requested", 0},
+		{"error", ""},
+		{"created", int64(0)},
+		{"requested_by", ""},
+		{"zone", ""},
+		{"delete_in_hours", 0},
+		{"no_subnet", false},
+		{"users_per_cluster", 1},
+		{"url_templates", bson.A{}},
+	},
+	"clusters": {
+		{"status", ""},
+		{"name", ""},
+		{"error", ""},
+		{"hostname", ""},
+		{"master_url", ""},
+		{"request_id", ""},
+		{"ic_request_id", ""},
+		{"public_vlan", ""},
+		{"private_vlan", ""},
+		{"delete_attempts", 0},
+		{"next_delete_attempt", int64(0)},
+		{"replace_attempt", 0},
+		{"replaced_by", ""},
+		{"bootstrap_status", ""},
+		{"bootstrap_steps", bson.A{}},
+		{"machine_type", ""},
+		{"worker_count", 0},
+		{"created", int64(0)},
+		{"ready", int64(0)},
+		{"deleted", int64(0)},
+	},
+	"users": {
+		{"cloud_direct_id", ""},
+		{"email", ""},
+		{"password", ""},
+		{"cluster_id", ""},
+		{"policy_id", ""},
+		{"recycled", int64(0)},
+		{"disabled", false},
+		{"pending_operation", ""},
+		{"pending_owner", ""},
+		{"pending_since", int64(0)},
+	},
+}
+
+func migrateToSchemaVersion1() error {
+	collections := []*mongo.Collection{
+		mongodb.ClusterRequests(),
+		mongodb.Clusters(),
+		mongodb.Users(),
+		mongodb.DriftReports(),
+		mongodb.Events(),
+	}
+	for _, collection := range collections {
+		for _, field := range schemaVersion1Defaults[collection.Name()] {
+			if err := setMissingField(collection, field); err != nil {
+				return err
+			}
+		}
+		if err := setMissingField(collection, bson.E{Key: "schema_version", Value: 1}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMissingField sets the field to the given value in all the documents of the collection which don't have the field
+func setMissingField(collection *mongo.Collection, field bson.E) error {
+	res, err := collection.UpdateMany(
+		context.Background(),
+		bson.D{{field.Key, bson.D{{"$exists", false}}}},
+		bson.D{{"$set", bson.D{field}}},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to set missing %s in %s", field.Key, collection.Name())
+	}
+	if res.ModifiedCount > 0 {
+		log.Info(nil, fmt.Sprintf("Set missing %s in %d %s", field.Key, res.ModifiedCount, collection.Name()))
+	}
+	return nil
+}
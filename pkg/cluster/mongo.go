@@ -3,6 +3,8 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	devclustererrors "github.com/codeready-toolchain/devcluster/pkg/errors"
 	"github.com/codeready-toolchain/devcluster/pkg/log"
@@ -15,8 +17,12 @@ import (
 )
 
 func insertRequest(req Request) error {
-	_, err := mongodb.ClusterRequests().InsertOne(context.Background(), convertClusterRequestToBSON(req))
-	return errors.Wrap(err, "unable to insert request")
+	_, err := mongodb.ClusterRequests().InsertOne(context.Background(), newRequestDocument(req))
+	if err != nil {
+		return errors.Wrap(err, "unable to insert request")
+	}
+	recordStatusChange(EventObjectRequest, req.ID, req.ID, EventFieldStatus, "", req.Status, req.Error, req.RequestedBy)
+	return nil
 }
 
 func getRequest(id string) (*Request, error) {
@@ -27,15 +33,15 @@ func getRequest(id string) (*Request, error) {
 	if res == nil {
 		return nil, errors.New(fmt.Sprintf("unable to find Request with such ID: %s", id))
 	}
-	var m bson.M
-	err := res.Decode(&m)
+	var d requestDocument
+	err := res.Decode(&d)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, errors.Wrap(err, "unable to get cluster request from mongo")
 	}
-	r := convertBSONToRequest(m)
+	r := d.request()
 	return &r, nil
 }
 
@@ -61,19 +67,20 @@ func getRequestsWithFilter(filters ...bson.E) ([]Request, error) {
 		log.Error(nil, err, "something wrong")
 		return requests, errors.Wrap(err, "unable to load cluster requests from mongo")
 	}
-	var rqs []bson.M
+	var rqs []requestDocument
 	if err = cursor.All(context.Background(), &rqs); err != nil {
 		log.Error(nil, err, "something wrong")
 		return requests, errors.Wrap(err, "unable to load cluster requests from mongo")
 	}
-	for _, m := range rqs {
-		requests = append(requests, convertBSONToRequest(m))
+	for _, d := range rqs {
+		requests = append(requests, d.request())
 	}
 	return requests, err
 }
 
-func updateRequestStatus(id, status, error string) error {
-	_, err := mongodb.ClusterRequests().UpdateOne(
+func updateRequestStatus(id, status, error, actor string) error {
+	// The document before the update is returned so the status change can be recorded
+	res := mongodb.ClusterRequests().FindOneAndUpdate(
 		context.Background(),
 		bson.D{
 			{"_id", id},
@@ -85,7 +92,59 @@ func updateRequestStatus(id, status, error string) error {
 			}},
 		},
 	)
-	return errors.Wrap(err, "unable to update request status")
+	var old requestDocument
+	if err := res.Decode(&old); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return errors.Wrap(err, "unable to update request status")
+	}
+	recordStatusChange(EventObjectRequest, id, id, EventFieldStatus, old.Status, status, error, actor)
+	return nil
+}
+
+// updateClusterBootstrap updates the bootstrap status and steps of the cluster and sets the cluster error
+func updateClusterBootstrap(id, status string, steps []BootstrapStep, error, actor string) error {
+	// The document before the update is returned so the status change can be recorded
+	res := mongodb.Clusters().FindOneAndUpdate(
+		context.Background(),
+		bson.D{
+			{"_id", id},
+		},
+		bson.D{
+			{"$set", bson.D{
+				{"bootstrap_status", status},
+				{"bootstrap_steps", newBootstrapStepDocuments(steps)},
+				{"error", error},
+			}},
+		},
+	)
+	var old clusterDocument
+	if err := res.Decode(&old); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return errors.Wrap(err, "unable to update cluster bootstrap")
+	}
+	recordStatusChange(EventObjectCluster, id, old.RequestID, EventFieldBootstrapStatus, old.BootstrapStatus, status, error, actor)
+	return nil
+}
+
+// setClusterReady sets the timestamp when the cluster got ready unless the cluster has already been ready before
+func setClusterReady(id string, ready int64) error {
+	_, err := mongodb.Clusters().UpdateOne(
+		context.Background(),
+		bson.D{
+			{"_id", id},
+			{"ready", bson.D{{"$in", bson.A{0, nil}}}},
+		},
+		bson.D{
+			{"$set", bson.D{
+				{"ready", ready},
+			}},
+		},
+	)
+	return errors.Wrap(err, "unable to update cluster ready timestamp")
 }
 
 func setRequestStatusToSuccessIfDone(req Request) error {
@@ -97,12 +156,13 @@ func setRequestStatusToSuccessIfDone(req Request) error {
 		return nil
 	}
 	for _, c := range clusters {
-		if c.Status != StatusDeleted && !clusterReady(c) {
+		// Replaced clusters are ignored even if they failed to get deleted
+		if c.ReplacedBy == "" && c.Status != StatusDeleted && !clusterReady(c) {
 			return nil
 		}
 	}
 	log.Infof(nil, "request %s is ready", req.ID)
-	return updateRequestStatus(req.ID, StatusReady, "")
+	return updateRequestStatus(req.ID, StatusReady, "", ActorSystem)
 }
 
 func replaceRequest(req Request) error {
@@ -112,23 +172,32 @@ func replaceRequest(req Request) error {
 		bson.D{
 			{"_id", req.ID},
 		},
-		convertClusterRequestToBSON(req),
+		newRequestDocument(req),
 		opts,
 	)
 	return errors.Wrap(err, "unable to replace request")
 }
 
-func replaceCluster(c Cluster) error {
-	opts := options.Replace().SetUpsert(true)
-	_, err := mongodb.Clusters().ReplaceOne(
+// replaceCluster replaces the whole cluster document and records the status change made by the given actor
+func replaceCluster(c Cluster, actor string) error {
+	// The document before the replacement is returned so the status change can be recorded
+	opts := options.FindOneAndReplace().SetUpsert(true)
+	res := mongodb.Clusters().FindOneAndReplace(
 		context.Background(),
 		bson.D{
 			{"_id", c.ID},
 		},
-		convertClusterToBSON(c),
+		newClusterDocument(c),
 		opts,
 	)
-	return errors.Wrap(err, "unable to replace cluster")
+	// old is empty if the cluster has just been inserted
+	var old clusterDocument
+	if err := res.Decode(&old); err != nil && err != mongo.ErrNoDocuments {
+		return errors.Wrap(err, "unable to replace cluster")
+	}
+	recordStatusChange(EventObjectCluster, c.ID, c.RequestID, EventFieldStatus, old.Status, c.Status, c.Error, actor)
+	recordStatusChange(EventObjectCluster, c.ID, c.RequestID, EventFieldBootstrapStatus, old.BootstrapStatus, c.BootstrapStatus, c.Error, actor)
+	return nil
 }
 
 // getCluster finds the cluster by its id. Returns nil, nil if there is no cluster with that id.
@@ -140,15 +209,15 @@ func getCluster(id string) (*Cluster, error) {
 	if res == nil {
 		return nil, errors.New(fmt.Sprintf("unable to find Cluster with such ID: %s", id))
 	}
-	var m bson.M
-	err := res.Decode(&m)
+	var d clusterDocument
+	err := res.Decode(&d)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, errors.Wrap(err, "unable to get cluster from mongo")
 	}
-	c := convertBSONToCluster(m)
+	c := d.cluster()
 	return &c, nil
 }
 
@@ -161,15 +230,15 @@ func getClusterByName(name string) (*Cluster, error) {
 	if res == nil {
 		return nil, errors.New(fmt.Sprintf("unable to find Cluster with such name: %s", name))
 	}
-	var m bson.M
-	err := res.Decode(&m)
+	var d clusterDocument
+	err := res.Decode(&d)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, errors.Wrap(err, "unable to get cluster from mongo")
 	}
-	c := convertBSONToCluster(m)
+	c := d.cluster()
 	return &c, nil
 }
 
@@ -201,6 +270,17 @@ func getClusters(requestID string) ([]Cluster, error) {
 	return getClustersWithFilter(withRequestID(requestID))
 }
 
+// countActiveClusters returns the number of the clusters matching the filters which are neither deleted nor failed
+func countActiveClusters(filters ...bson.E) (int, error) {
+	f := bson.D{{"status", bson.D{{"$nin", bson.A{StatusDeleted, StatusFailed}}}}}
+	f = append(f, filters...)
+	n, err := mongodb.Clusters().CountDocuments(context.Background(), f)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to count active clusters")
+	}
+	return int(n), nil
+}
+
 func getClustersWithFilter(filters ...bson.E) ([]Cluster, error) {
 	clusters := make([]Cluster, 0, 0)
 	p := bson.D{}
@@ -217,64 +297,333 @@ func getClustersWithFilter(filters ...bson.E) ([]Cluster, error) {
 		}
 		return clusters, errors.Wrap(err, "unable to load clusters from mongo")
 	}
-	var cls []bson.M
+	var cls []clusterDocument
 	if err = cursor.All(context.Background(), &cls); err != nil {
 		return clusters, errors.Wrap(err, "unable to load clusters from mongo")
 	}
-	for _, m := range cls {
-		clusters = append(clusters, convertBSONToCluster(m))
+	for _, d := range cls {
+		clusters = append(clusters, d.cluster())
 	}
 	return clusters, err
 }
 
-func getClustersWithRequestFilter(requestFilter bson.E, clusterFilters ...bson.E) ([]Cluster, error) {
-	clusters := make([]Cluster, 0, 0)
-	requests, err := getRequestsWithFilter(requestFilter)
-	if err != nil {
-		return nil, err
+// listPage returns the raw documents of the page and the total number of the documents matching the query.
+// The joins are the pipeline stages joining the related documents the query can filter and sort by.
+// The joins are applied to the documents of the page only unless the query filters or sorts by the joined fields.
+// The lateJoins are the stages joining the related documents to the documents of the page only.
+// The page and the total are computed by a single aggregation.
+func listPage(collection *mongo.Collection, joins []bson.D, q pageQuery, lateJoins []bson.D) ([]bson.Raw, PageInfo, error) {
+	var page PageInfo
+	// The filters which don't need the joins go first so the joins are applied to the matching documents only
+	pipeline := mongo.Pipeline{{{"$match", q.filter}}}
+	if q.needsJoins() {
+		pipeline = append(pipeline, joins...)
+		pipeline = append(pipeline, bson.D{{"$match", q.joinFilter}})
+		joins = nil
 	}
-	for _, r := range requests {
-		cl, err := getClustersWithFilter(append(clusterFilters, withRequestID(r.ID))...)
+
+	pageStages := mongo.Pipeline{}
+	if q.after != nil {
+		after, err := q.afterCursor()
 		if err != nil {
-			return nil, err
+			return nil, page, err
 		}
-		clusters = append(clusters, cl...)
+		pageStages = append(pageStages, bson.D{{"$match", after}})
 	}
-	return clusters, nil
+	pageStages = append(pageStages, bson.D{{"$sort", q.sortOrder()}})
+	if q.limit > 0 {
+		// one more to find out if there is a next page
+		pageStages = append(pageStages, bson.D{{"$limit", q.limit + 1}})
+	}
+	pageStages = append(pageStages, joins...)
+	pageStages = append(pageStages, lateJoins...)
+
+	var docs []bson.Raw
+	if q.limit == 0 && q.after == nil {
+		// All the matching documents are listed so the total is the number of the documents
+		pipeline = append(pipeline, pageStages...)
+		cursor, err := collection.Aggregate(context.Background(), pipeline)
+		if err != nil {
+			return nil, page, errors.Wrapf(err, "unable to load %s from mongo", collection.Name())
+		}
+		if err = cursor.All(context.Background(), &docs); err != nil {
+			return nil, page, errors.Wrapf(err, "unable to load %s from mongo", collection.Name())
+		}
+		page.Total = int64(len(docs))
+		return docs, page, nil
+	}
+
+	pipeline = append(pipeline, bson.D{{"$facet", bson.D{
+		{"total", bson.A{bson.D{{"$count", "total"}}}},
+		{"page", pageStages},
+	}}})
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, page, errors.Wrapf(err, "unable to load %s from mongo", collection.Name())
+	}
+	var results []struct {
+		Total []struct {
+			Total int64 `bson:"total"`
+		} `bson:"total"`
+		Page []bson.Raw `bson:"page"`
+	}
+	if err = cursor.All(context.Background(), &results); err != nil {
+		return nil, page, errors.Wrapf(err, "unable to load %s from mongo", collection.Name())
+	}
+	if len(results) > 0 {
+		if len(results[0].Total) > 0 {
+			page.Total = results[0].Total[0].Total
+		}
+		docs = results[0].Page
+	}
+	if q.limit > 0 && len(docs) > q.limit {
+		docs = docs[:q.limit]
+		if page.Next, err = q.cursorOf(docs[len(docs)-1]); err != nil {
+			return nil, page, errors.Wrap(err, "unable to encode page cursor")
+		}
+	}
+	return docs, page, nil
+}
+
+func listRequests(q pageQuery) ([]Request, PageInfo, error) {
+	docs, page, err := listPage(mongodb.ClusterRequests(), nil, q, nil)
+	if err != nil {
+		return nil, page, err
+	}
+	requests := make([]Request, 0, len(docs))
+	for _, raw := range docs {
+		var d requestDocument
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, page, errors.Wrap(err, "unable to decode cluster request")
+		}
+		requests = append(requests, d.request())
+	}
+	return requests, page, nil
+}
+
+// clusterWithRelations is a cluster with its request and the users assigned to the cluster ordered by the user index
+type clusterWithRelations struct {
+	cluster Cluster
+	request *Request // nil if the request is not found
+	users   []User
+}
+
+// listClusters returns the page of the clusters joined with their requests and users.
+// The query can filter and sort by the fields of the request joined as "request".
+func listClusters(q pageQuery) ([]clusterWithRelations, PageInfo, error) {
+	joins := []bson.D{
+		{{"$lookup", bson.D{
+			{"from", mongodb.ClusterRequests().Name()},
+			{"localField", "request_id"},
+			{"foreignField", "_id"},
+			{"as", "request"},
+		}}},
+		{{"$unwind", bson.D{{"path", "$request"}, {"preserveNullAndEmptyArrays", true}}}},
+	}
+	lateJoins := []bson.D{
+		{{"$lookup", bson.D{
+			{"from", mongodb.Users().Name()},
+			{"localField", "_id"},
+			{"foreignField", "cluster_id"},
+			{"as", "users"},
+		}}},
+	}
+	docs, page, err := listPage(mongodb.Clusters(), joins, q, lateJoins)
+	if err != nil {
+		return nil, page, err
+	}
+	clusters := make([]clusterWithRelations, 0, len(docs))
+	for _, raw := range docs {
+		var d clusterListingDocument
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, page, errors.Wrap(err, "unable to decode cluster")
+		}
+		users := make([]User, 0, len(d.Users))
+		for _, u := range d.Users {
+			users = append(users, u.user())
+		}
+		sortUsersByIndex(users)
+		c := clusterWithRelations{
+			cluster: d.Cluster.cluster(),
+			users:   users,
+		}
+		if d.Request.ID != "" {
+			r := d.Request.request()
+			c.request = &r
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, page, nil
+}
+
+// listUsers returns the page of the users with their statuses in the user pool.
+// The query can filter by the cluster the user is assigned to joined as "cluster".
+func listUsers(q pageQuery) ([]UserWithStatus, PageInfo, error) {
+	joins := []bson.D{
+		{{"$lookup", bson.D{
+			{"from", mongodb.Clusters().Name()},
+			{"localField", "cluster_id"},
+			{"foreignField", "_id"},
+			{"as", "cluster"},
+		}}},
+	}
+	docs, page, err := listPage(mongodb.Users(), joins, q, nil)
+	if err != nil {
+		return nil, page, err
+	}
+	users := make([]UserWithStatus, 0, len(docs))
+	for _, raw := range docs {
+		var d userListingDocument
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, page, errors.Wrap(err, "unable to decode user")
+		}
+		u := d.User.user()
+		var c *Cluster
+		if len(d.Cluster) > 0 {
+			found := d.Cluster[0].cluster()
+			c = &found
+		}
+		users = append(users, UserWithStatus{
+			User:       u,
+			PoolStatus: userPoolStatus(u, c),
+		})
+	}
+	return users, page, nil
+}
+
+// freeUserFilter matches the users which are not assigned to any cluster, not reserved for any request and not disabled
+func freeUserFilter() bson.D {
+	return reservedUserFilter("")
+}
+
+// reservedUserFilter matches the users reserved for the request which are not assigned to any cluster and not disabled
+func reservedUserFilter(requestID string) bson.D {
+	return bson.D{{"cluster_id", ""}, {"reserved_for", requestID}, {"disabled", bson.D{{"$ne", true}}}}
 }
 
-// getUserWithoutCluster returns the first found user with no cluster_id set and with the earliest "recycled" timestamp
-// returns an error if no user found
-func getUserWithoutCluster() (*User, error) {
-	return GetUserByClusterID("")
+// countFreeUsers returns the number of users of the account which are not assigned to any cluster, not reserved and not disabled
+func countFreeUsers(account bson.E) (int, error) {
+	n, err := mongodb.Users().CountDocuments(context.Background(), append(freeUserFilter(), account))
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to count free users")
+	}
+	return int(n), nil
 }
 
-// getUserByClusterID returns the first found user with the given cluster_id and with the earliest "recycled" timestamp
-// returns an error if no user found
+const userIndexCounter = "user_index"
+
+// reserveUserIndexes atomically reserves n consecutive user indexes and returns the first one.
+// The counter never goes below the given highest index of the existing users so the reserved indexes never collide
+// with the existing users or with the indexes reserved concurrently by other service instances.
+func reserveUserIndexes(n, highestExisting int) (int, error) {
+	_, err := mongodb.Counters().UpdateOne(
+		context.Background(),
+		bson.D{
+			{"_id", userIndexCounter},
+		},
+		bson.D{
+			{"$max", bson.D{{"value", int64(highestExisting)}}},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to initialize user index counter")
+	}
+	res := mongodb.Counters().FindOneAndUpdate(
+		context.Background(),
+		bson.D{
+			{"_id", userIndexCounter},
+		},
+		bson.D{
+			{"$inc", bson.D{{"value", int64(n)}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	if err := res.Decode(&counter); err != nil {
+		return 0, errors.Wrap(err, "unable to reserve user indexes")
+	}
+	return int(counter.Value) - n + 1, nil
+}
+
+// GetUserByClusterID returns the user assigned to the cluster with the given cluster_id.
+// If multiple users are assigned to the cluster then the user with the lowest index is returned.
+// Returns a Not Found error if no user found
 func GetUserByClusterID(clusterID string) (*User, error) {
+	users, err := getUsersByClusterID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, devclustererrors.NewNotFoundError(fmt.Sprintf("no User with cluster_id %s found", clusterID), "")
+	}
+	return &users[0], nil
+}
+
+// getUsersByClusterID returns all the users assigned to the cluster ordered by the user index
+func getUsersByClusterID(clusterID string) ([]User, error) {
+	users, err := getUsers(bson.D{{"cluster_id", clusterID}})
+	if err != nil {
+		return nil, err
+	}
+	sortUsersByIndex(users)
+	return users, nil
+}
+
+func sortUsersByIndex(users []User) {
+	sort.Slice(users, func(i, j int) bool {
+		if userIndex(users[i].ID) != userIndex(users[j].ID) {
+			return userIndex(users[i].ID) < userIndex(users[j].ID)
+		}
+		return users[i].ID < users[j].ID
+	})
+}
+
+// findUser returns the first found user matching the filter and with the earliest "recycled" timestamp
+// returns a Not Found error with the given message if no user found
+func findUser(filter bson.D, notFoundMsg string) (*User, error) {
 	findOptions := options.FindOne()
 	// Sort by `recycled` field ascending
 	findOptions.SetSort(bson.D{{"recycled", 1}})
 	res := mongodb.Users().FindOne(
 		context.Background(),
-		bson.D{{"cluster_id", clusterID}},
+		filter,
 		findOptions,
 	)
 	if res == nil {
-		return nil, errors.New(fmt.Sprintf("unable to find User with cluster_id: %s", clusterID))
+		return nil, errors.New(fmt.Sprintf("unable to find User: %v", filter))
 	}
-	var m bson.M
-	err := res.Decode(&m)
+	var d userDocument
+	err := res.Decode(&d)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, devclustererrors.NewNotFoundError(fmt.Sprintf("no User with cluster_id %s found", clusterID), err.Error())
+			return nil, devclustererrors.NewNotFoundError(notFoundMsg, err.Error())
 		}
-		return nil, errors.New(fmt.Sprintf("unable to find User with cluster_id: %s", clusterID))
+		return nil, errors.Wrapf(err, "unable to find User: %v", filter)
 	}
-	u := convertBSONToUser(m)
+	u := d.user()
 	return &u, nil
 }
 
+// getUser returns the user with the given ID or nil if not found
+func getUser(id string) (*User, error) {
+	u, err := findUser(bson.D{{"_id", id}}, fmt.Sprintf("no User with id %s found", id))
+	if err != nil {
+		if devclustererrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+func deleteUser(id string) error {
+	_, err := mongodb.Users().DeleteOne(context.Background(), bson.D{{"_id", id}})
+	return errors.Wrap(err, "unable to delete user")
+}
+
 func getAllUsers() ([]User, error) {
 	return getUsers(bson.D{})
 }
@@ -285,112 +634,613 @@ func getUsers(d bson.D) ([]User, error) {
 	if err != nil {
 		return users, errors.Wrap(err, "unable to load users from mongo")
 	}
-	var usrs []bson.M
+	var usrs []userDocument
 	if err = cursor.All(context.Background(), &usrs); err != nil {
 		return users, errors.Wrap(err, "unable to load users from mongo")
 	}
-	for _, m := range usrs {
-		users = append(users, convertBSONToUser(m))
+	for _, d := range usrs {
+		users = append(users, d.user())
 	}
 	return users, err
 }
 
-func replaceUser(u User) error {
-	opts := options.Replace().SetUpsert(true)
-	_, err := mongodb.Users().ReplaceOne(
+// reserveFreeUser atomically reserves the free user of the account with the earliest "recycled" timestamp for the request.
+// Returns false if there is no free user.
+func reserveFreeUser(requestID string, account bson.E) (bool, error) {
+	res := mongodb.Users().FindOneAndUpdate(
 		context.Background(),
+		append(freeUserFilter(), account),
 		bson.D{
-			{"_id", u.ID},
+			{"$set", bson.D{
+				{"reserved_for", requestID},
+			}},
+		},
+		options.FindOneAndUpdate().SetSort(bson.D{{"recycled", 1}}),
+	)
+	if err := res.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "unable to reserve free user")
+	}
+	return true, nil
+}
+
+// releaseReservedUsers returns the users reserved for the request and not assigned yet to the user pool.
+// Returns the number of released users.
+func releaseReservedUsers(requestID string) (int, error) {
+	res, err := mongodb.Users().UpdateMany(
+		context.Background(),
+		bson.D{
+			{"cluster_id", ""},
+			{"reserved_for", requestID},
+		},
+		bson.D{
+			{"$set", bson.D{
+				{"reserved_for", ""},
+			}},
+		},
+	)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to release users reserved for request %s", requestID)
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// claimFreeUser atomically assigns a user of the account to the cluster and marks the assignment as pending.
+// The users reserved for the request are claimed first, then the free user with the earliest "recycled" timestamp.
+// Returns a Not Found error if there is no reserved or free user.
+func claimFreeUser(clusterID, requestID, owner string, account bson.E) (*User, error) {
+	if requestID != "" {
+		user, err := claimUser(append(reservedUserFilter(requestID), account), clusterID, owner)
+		if !devclustererrors.IsNotFound(err) {
+			return user, err
+		}
+	}
+	return claimUser(append(freeUserFilter(), account), clusterID, owner)
+}
+
+// claimUser atomically assigns the user matching the filter with the earliest "recycled" timestamp to the cluster
+// and marks the assignment as pending. Returns a Not Found error if no user matches.
+func claimUser(filter bson.D, clusterID, owner string) (*User, error) {
+	res := mongodb.Users().FindOneAndUpdate(
+		context.Background(),
+		filter,
+		bson.D{
+			{"$set", bson.D{
+				{"cluster_id", clusterID},
+				{"reserved_for", ""},
+				{"pending_operation", UserOperationAssigning},
+				{"pending_owner", owner},
+				{"pending_since", time.Now().Unix()},
+			}},
+		},
+		options.FindOneAndUpdate().SetSort(bson.D{{"recycled", 1}}).SetReturnDocument(options.After),
+	)
+	var d userDocument
+	if err := res.Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, devclustererrors.NewNotFoundError("no free User found", err.Error())
+		}
+		return nil, errors.Wrap(err, "unable to claim free user")
+	}
+	u := d.user()
+	return &u, nil
+}
+
+// completeUserAssignment records the access granted to the user and clears the pending assignment
+func completeUserAssignment(id, policyID string) error {
+	return updateUser(id, bson.D{
+		{"policy_id", policyID},
+		{"pending_operation", ""},
+		{"pending_owner", ""},
+	})
+}
+
+// releaseUser returns the user to the user pool without recycling it
+func releaseUser(id string) error {
+	return updateUser(id, bson.D{
+		{"cluster_id", ""},
+		{"policy_id", ""},
+		{"pending_operation", ""},
+		{"pending_owner", ""},
+	})
+}
+
+// startUserOperation marks the operation as pending for the user
+func startUserOperation(id, operation, owner string) error {
+	return updateUser(id, bson.D{
+		{"pending_operation", operation},
+		{"pending_owner", owner},
+		{"pending_since", time.Now().Unix()},
+	})
+}
+
+func updateUserPolicyID(id, policyID string) error {
+	return updateUser(id, bson.D{{"policy_id", policyID}})
+}
+
+func updateUserDisabled(id string, disabled bool) error {
+	return updateUser(id, bson.D{{"disabled", disabled}})
+}
+
+// completeUserRecycle stores the new password, returns the user to the user pool and clears the pending recycle
+func completeUserRecycle(id, password string) error {
+	return updateUser(id, bson.D{
+		{"cluster_id", ""},
+		{"policy_id", ""},
+		{"password", password},
+		{"recycled", time.Now().Unix()},
+		{"pending_operation", ""},
+		{"pending_owner", ""},
+	})
+}
+
+// updateUser sets the given fields of the user. Returns a Not Found error if the user doesn't exist.
+func updateUser(id string, fields bson.D) error {
+	res, err := mongodb.Users().UpdateOne(
+		context.Background(),
+		bson.D{
+			{"_id", id},
+		},
+		bson.D{
+			{"$set", fields},
 		},
-		convertUserToBSON(u),
-		opts,
 	)
-	return errors.Wrap(err, "unable to replace user")
+	if err != nil {
+		return errors.Wrap(err, "unable to update user")
+	}
+	if res.MatchedCount == 0 {
+		return devclustererrors.NewNotFoundError(fmt.Sprintf("user %s not found", id), "")
+	}
+	return nil
+}
+
+// getUsersWithPendingOperation returns the users with a pending operation which was either started
+// by the given owner (if set) or started before the given timestamp
+func getUsersWithPendingOperation(owner string, startedBefore int64) ([]User, error) {
+	started := bson.A{
+		bson.D{{"pending_since", bson.D{{"$lt", startedBefore}}}},
+	}
+	if owner != "" {
+		started = append(started, bson.D{{"pending_owner", owner}})
+	}
+	return getUsers(bson.D{
+		{"pending_operation", bson.D{{"$in", bson.A{UserOperationAssigning, UserOperationRecycling}}}},
+		{"$or", started},
+	})
 }
 
 func insertUser(u User) error {
-	_, err := mongodb.Users().InsertOne(context.Background(), convertUserToBSON(u))
+	_, err := mongodb.Users().InsertOne(context.Background(), newUserDocument(u))
 	return errors.Wrap(err, "unable to insert user")
 }
 
-func convertBSONToRequest(m bson.M) Request {
-	return Request{
-		ID:            fmt.Sprintf("%v", m["_id"]),
-		RequestedBy:   fmt.Sprintf("%v", m["requested_by"]),
-		Created:       m["created"].(int64),
-		Error:         fmt.Sprintf("%v", m["error"]),
-		Requested:     int(m["requested"].(int32)),
-		Status:        fmt.Sprintf("%v", m["status"]),
-		Zone:          fmt.Sprintf("%v", m["zone"]),
-		DeleteInHours: int(m["delete_in_hours"].(int32)),
-		NoSubnet:      m["no_subnet"].(bool),
-	}
-}
-
-func convertClusterRequestToBSON(req Request) bson.D {
-	return bson.D{
-		{"_id", req.ID},
-		{"status", req.Status},
-		{"requested", req.Requested},
-		{"error", req.Error},
-		{"created", req.Created},
-		{"requested_by", req.RequestedBy},
-		{"zone", req.Zone},
-		{"delete_in_hours", req.DeleteInHours},
-		{"no_subnet", req.NoSubnet},
-	}
-}
-
-func convertBSONToCluster(m bson.M) Cluster {
-	return Cluster{
-		ID:                  fmt.Sprintf("%v", m["_id"]),
-		RequestID:           fmt.Sprintf("%v", m["request_id"]),
-		IBMClusterRequestID: fmt.Sprintf("%v", m["ic_request_id"]),
-		Hostname:            fmt.Sprintf("%v", m["hostname"]),
-		MasterURL:           fmt.Sprintf("%v", m["master_url"]),
-		Error:               fmt.Sprintf("%v", m["error"]),
-		Name:                fmt.Sprintf("%v", m["name"]),
-		Status:              fmt.Sprintf("%v", m["status"]),
-		PublicVlan:          fmt.Sprintf("%v", m["public_vlan"]),
-		PrivateVlan:         fmt.Sprintf("%v", m["private_vlan"]),
-	}
-}
-
-func convertClusterToBSON(c Cluster) bson.D {
-	return bson.D{
-		{"_id", c.ID},
-		{"status", c.Status},
-		{"name", c.Name},
-		{"error", c.Error},
-		{"hostname", c.Hostname},
-		{"master_url", c.MasterURL},
-		{"request_id", c.RequestID},
-		{"ic_request_id", c.IBMClusterRequestID},
-		{"public_vlan", c.PublicVlan},
-		{"private_vlan", c.PrivateVlan},
-	}
-}
-
-func convertBSONToUser(m bson.M) User {
-	return User{
-		ID:            fmt.Sprintf("%v", m["_id"]),
-		CloudDirectID: fmt.Sprintf("%v", m["cloud_direct_id"]),
-		Email:         fmt.Sprintf("%v", m["email"]),
-		Password:      fmt.Sprintf("%v", m["password"]),
-		ClusterID:     fmt.Sprintf("%v", m["cluster_id"]),
-		PolicyID:      fmt.Sprintf("%v", m["policy_id"]),
-		Recycled:      m["recycled"].(int64),
-	}
-}
-
-func convertUserToBSON(u User) bson.D {
-	return bson.D{
-		{"_id", u.ID},
-		{"cloud_direct_id", u.CloudDirectID},
-		{"email", u.Email},
-		{"password", u.Password},
-		{"cluster_id", u.ClusterID},
-		{"policy_id", u.PolicyID},
-		{"recycled", u.Recycled},
+func insertEvent(e Event) error {
+	_, err := mongodb.Events().InsertOne(context.Background(), newEventDocument(e))
+	return errors.Wrap(err, "unable to insert event")
+}
+
+func withObject(object string) bson.E {
+	return bson.E{Key: "object", Value: object}
+}
+
+func withObjectID(id string) bson.E {
+	return bson.E{Key: "object_id", Value: id}
+}
+
+// getEvents returns the events matching the filters in the order they have been recorded
+func getEvents(filters ...bson.E) ([]Event, error) {
+	events := make([]Event, 0)
+	p := bson.D{}
+	for _, f := range filters {
+		p = append(p, f)
+	}
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{"_id", 1}})
+	cursor, err := mongodb.Events().Find(context.Background(), p, findOptions)
+	if err != nil {
+		return events, errors.Wrap(err, "unable to load events from mongo")
+	}
+	var evs []eventDocument
+	if err = cursor.All(context.Background(), &evs); err != nil {
+		return events, errors.Wrap(err, "unable to load events from mongo")
+	}
+	for _, d := range evs {
+		events = append(events, d.event())
+	}
+	return events, nil
+}
+
+func insertDriftReport(r DriftReport) error {
+	_, err := mongodb.DriftReports().InsertOne(context.Background(), newDriftReportDocument(r))
+	return errors.Wrap(err, "unable to insert drift report")
+}
+
+// getDriftReports returns the latest n drift reports sorted by the start time descending
+func getDriftReports(n int) ([]DriftReport, error) {
+	reports := make([]DriftReport, 0, 0)
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{"started", -1}})
+	findOptions.SetLimit(int64(n))
+	cursor, err := mongodb.DriftReports().Find(context.Background(), bson.D{}, findOptions)
+	if err != nil {
+		return reports, errors.Wrap(err, "unable to load drift reports from mongo")
+	}
+	var rps []driftReportDocument
+	if err = cursor.All(context.Background(), &rps); err != nil {
+		return reports, errors.Wrap(err, "unable to load drift reports from mongo")
+	}
+	for _, d := range rps {
+		reports = append(reports, d.driftReport())
+	}
+	return reports, nil
+}
+
+// archiveRequest moves the request and its clusters to the archive.
+// The archived copies are written before the originals are deleted so the archival interrupted by a crash
+// is completed by the next one.
+func archiveRequest(r Request, clusters []Cluster, archived int64) error {
+	opts := options.Replace().SetUpsert(true)
+	ids := make(bson.A, 0, len(clusters))
+	for _, c := range clusters {
+		if _, err := mongodb.ArchivedClusters().ReplaceOne(
+			context.Background(),
+			bson.D{{"_id", c.ID}},
+			archivedClusterDocument{Cluster: newClusterDocument(c), Archived: archived},
+			opts,
+		); err != nil {
+			return errors.Wrapf(err, "unable to archive cluster %s", c.ID)
+		}
+		ids = append(ids, c.ID)
+	}
+	if _, err := mongodb.ArchivedClusterRequests().ReplaceOne(
+		context.Background(),
+		bson.D{{"_id", r.ID}},
+		archivedRequestDocument{Request: newRequestDocument(r), Archived: archived},
+		opts,
+	); err != nil {
+		return errors.Wrapf(err, "unable to archive request %s", r.ID)
+	}
+	if len(ids) > 0 {
+		if _, err := mongodb.Clusters().DeleteMany(context.Background(), bson.D{{"_id", bson.D{{"$in", ids}}}}); err != nil {
+			return errors.Wrapf(err, "unable to delete archived clusters of request %s", r.ID)
+		}
+	}
+	if _, err := mongodb.ClusterRequests().DeleteOne(context.Background(), bson.D{{"_id", r.ID}}); err != nil {
+		return errors.Wrapf(err, "unable to delete archived request %s", r.ID)
+	}
+	return nil
+}
+
+// listArchive returns the page of the archived requests joined with their archived clusters
+func listArchive(q pageQuery) ([]ArchivedRequest, PageInfo, error) {
+	lateJoins := []bson.D{
+		{{"$lookup", bson.D{
+			{"from", mongodb.ArchivedClusters().Name()},
+			{"localField", "_id"},
+			{"foreignField", "request_id"},
+			{"as", "clusters"},
+		}}},
+	}
+	docs, page, err := listPage(mongodb.ArchivedClusterRequests(), nil, q, lateJoins)
+	if err != nil {
+		return nil, page, err
+	}
+	requests := make([]ArchivedRequest, 0, len(docs))
+	for _, raw := range docs {
+		var d archiveListingDocument
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, page, errors.Wrap(err, "unable to decode archived request")
+		}
+		r := ArchivedRequest{
+			Request:  d.Request.request(),
+			Clusters: make([]Cluster, 0, len(d.Clusters)),
+			Archived: d.Archived,
+		}
+		for _, c := range d.Clusters {
+			r.Clusters = append(r.Clusters, c.cluster())
+		}
+		requests = append(requests, r)
+	}
+	return requests, page, nil
+}
+
+// getAllArchivedRequests returns all the archived requests
+func getAllArchivedRequests() ([]Request, error) {
+	cursor, err := mongodb.ArchivedClusterRequests().Find(context.Background(), bson.D{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load archived requests from mongo")
+	}
+	var docs []archivedRequestDocument
+	if err = cursor.All(context.Background(), &docs); err != nil {
+		return nil, errors.Wrap(err, "unable to load archived requests from mongo")
+	}
+	requests := make([]Request, 0, len(docs))
+	for _, d := range docs {
+		requests = append(requests, d.Request.request())
+	}
+	return requests, nil
+}
+
+// getAllArchivedClusters returns all the archived clusters
+func getAllArchivedClusters() ([]Cluster, error) {
+	cursor, err := mongodb.ArchivedClusters().Find(context.Background(), bson.D{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load archived clusters from mongo")
+	}
+	var docs []archivedClusterDocument
+	if err = cursor.All(context.Background(), &docs); err != nil {
+		return nil, errors.Wrap(err, "unable to load archived clusters from mongo")
+	}
+	clusters := make([]Cluster, 0, len(docs))
+	for _, d := range docs {
+		clusters = append(clusters, d.Cluster.cluster())
+	}
+	return clusters, nil
+}
+
+// getArchivedDocuments returns all the archived requests and clusters as stored
+func getArchivedDocuments() ([]archivedRequestDocument, []archivedClusterDocument, error) {
+	requests := make([]archivedRequestDocument, 0)
+	cursor, err := mongodb.ArchivedClusterRequests().Find(context.Background(), bson.D{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to load archived requests from mongo")
+	}
+	if err = cursor.All(context.Background(), &requests); err != nil {
+		return nil, nil, errors.Wrap(err, "unable to load archived requests from mongo")
+	}
+	clusters := make([]archivedClusterDocument, 0)
+	cursor, err = mongodb.ArchivedClusters().Find(context.Background(), bson.D{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to load archived clusters from mongo")
+	}
+	if err = cursor.All(context.Background(), &clusters); err != nil {
+		return nil, nil, errors.Wrap(err, "unable to load archived clusters from mongo")
+	}
+	return requests, clusters, nil
+}
+
+// restoreArchivedDocuments replaces the stored archived requests and clusters with the given ones.
+// The documents missing in the DB are inserted.
+func restoreArchivedDocuments(requests []archivedRequestDocument, clusters []archivedClusterDocument) error {
+	opts := options.Replace().SetUpsert(true)
+	for _, r := range requests {
+		if _, err := mongodb.ArchivedClusterRequests().ReplaceOne(context.Background(), bson.D{{"_id", r.Request.ID}}, r, opts); err != nil {
+			return errors.Wrapf(err, "unable to restore archived request %s", r.Request.ID)
+		}
+	}
+	for _, c := range clusters {
+		if _, err := mongodb.ArchivedClusters().ReplaceOne(context.Background(), bson.D{{"_id", c.Cluster.ID}}, c, opts); err != nil {
+			return errors.Wrapf(err, "unable to restore archived cluster %s", c.Cluster.ID)
+		}
+	}
+	return nil
+}
+
+// restoreDocuments replaces the stored requests, clusters and users with the given ones.
+// The documents missing in the DB are inserted.
+func restoreDocuments(requests []requestDocument, clusters []clusterDocument, users []userDocument) error {
+	opts := options.Replace().SetUpsert(true)
+	for _, r := range requests {
+		if _, err := mongodb.ClusterRequests().ReplaceOne(context.Background(), bson.D{{"_id", r.ID}}, r, opts); err != nil {
+			return errors.Wrapf(err, "unable to restore request %s", r.ID)
+		}
+	}
+	for _, c := range clusters {
+		if _, err := mongodb.Clusters().ReplaceOne(context.Background(), bson.D{{"_id", c.ID}}, c, opts); err != nil {
+			return errors.Wrapf(err, "unable to restore cluster %s", c.ID)
+		}
+	}
+	for _, u := range users {
+		if _, err := mongodb.Users().ReplaceOne(context.Background(), bson.D{{"_id", u.ID}}, u, opts); err != nil {
+			return errors.Wrapf(err, "unable to restore user %s", u.ID)
+		}
+	}
+	return nil
+}
+
+// getZones returns all the stored zones sorted by ID
+func getZones() ([]Zone, error) {
+	zones := make([]Zone, 0)
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{"_id", 1}})
+	cursor, err := mongodb.Zones().Find(context.Background(), bson.D{}, findOptions)
+	if err != nil {
+		return zones, errors.Wrap(err, "unable to load zones from mongo")
+	}
+	var docs []zoneDocument
+	if err = cursor.All(context.Background(), &docs); err != nil {
+		return zones, errors.Wrap(err, "unable to load zones from mongo")
+	}
+	for _, d := range docs {
+		zones = append(zones, d.zone())
+	}
+	return zones, nil
+}
+
+// replaceZone inserts or updates the zone. The reservations of the existing zone are kept.
+// If the zone is the default one then the other zones are not default anymore.
+func replaceZone(z Zone) error {
+	d := newZoneDocument(z)
+	opts := options.Update().SetUpsert(true)
+	if _, err := mongodb.Zones().UpdateOne(
+		context.Background(),
+		bson.D{{"_id", z.ID}},
+		bson.D{
+			{"$set", bson.D{
+				{"schema_version", d.SchemaVersion},
+				{"enabled", d.Enabled},
+				{"max_clusters", d.MaxClusters},
+				{"default", d.Default},
+			}},
+			{"$setOnInsert", bson.D{
+				{"reserved", 0},
+				{"version", int64(0)},
+			}},
+		},
+		opts,
+	); err != nil {
+		return errors.Wrapf(err, "unable to save zone %s", z.ID)
+	}
+	if z.Default {
+		if _, err := mongodb.Zones().UpdateMany(
+			context.Background(),
+			bson.D{{"_id", bson.D{{"$ne", z.ID}}}, {"default", true}},
+			bson.D{{"$set", bson.D{{"default", false}}}},
+		); err != nil {
+			return errors.Wrapf(err, "unable to unset the default zone other than %s", z.ID)
+		}
+	}
+	return nil
+}
+
+// getZoneDocument returns the zone with the given ID or nil if not found
+func getZoneDocument(id string) (*zoneDocument, error) {
+	var d zoneDocument
+	if err := mongodb.Zones().FindOne(context.Background(), bson.D{{"_id", id}}).Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to load zone %s", id)
+	}
+	return &d, nil
+}
+
+// reserveZone reserves n clusters in the zone if the zone has not been changed since the given version was read.
+// Returns false if the zone has been changed in the meantime.
+func reserveZone(id string, version int64, n int) (bool, error) {
+	res, err := mongodb.Zones().UpdateOne(
+		context.Background(),
+		bson.D{{"_id", id}, {"version", version}},
+		bson.D{{"$inc", bson.D{{"reserved", n}, {"version", int64(1)}}}},
+	)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to reserve %d clusters in zone %s", n, id)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// releaseZone releases n clusters reserved in the zone. Nothing is released if the zone has less reservations,
+// e.g. if the zone has been deleted and created again in the meantime.
+func releaseZone(id string, n int) error {
+	_, err := mongodb.Zones().UpdateOne(
+		context.Background(),
+		bson.D{{"_id", id}, {"reserved", bson.D{{"$gte", n}}}},
+		bson.D{{"$inc", bson.D{{"reserved", -n}, {"version", int64(1)}}}},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to release %d clusters reserved in zone %s", n, id)
+	}
+	return nil
+}
+
+// deleteZone deletes the zone. Returns false if the zone is not found.
+func deleteZone(id string) (bool, error) {
+	res, err := mongodb.Zones().DeleteOne(context.Background(), bson.D{{"_id", id}})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to delete zone %s", id)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// insertZones inserts the zones if no zones are stored yet. Returns false if some zones are already stored.
+func insertZones(zones []Zone) (bool, error) {
+	n, err := mongodb.Zones().CountDocuments(context.Background(), bson.D{})
+	if err != nil {
+		return false, errors.Wrap(err, "unable to count zones")
+	}
+	if n > 0 {
+		return false, nil
+	}
+	docs := make([]interface{}, 0, len(zones))
+	for _, z := range zones {
+		docs = append(docs, newZoneDocument(z))
+	}
+	if _, err := mongodb.Zones().InsertMany(context.Background(), docs); err != nil {
+		return false, errors.Wrap(err, "unable to insert zones")
+	}
+	return true, nil
+}
+
+// getAccountDocument returns the reservations of the account or nil if nothing has been reserved in the account yet
+func getAccountDocument(name string) (*accountDocument, error) {
+	var d accountDocument
+	if err := mongodb.Accounts().FindOne(context.Background(), bson.D{{"_id", name}}).Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to load reservations of account %s", name)
+	}
+	return &d, nil
+}
+
+// reserveAccount reserves n clusters in the account if the reservations have not been changed since the given version was read.
+// The document is created if it doesn't exist yet. Returns false if the reservations have been changed in the meantime.
+func reserveAccount(name string, version int64, n int) (bool, error) {
+	opts := options.Update().SetUpsert(true)
+	_, err := mongodb.Accounts().UpdateOne(
+		context.Background(),
+		bson.D{{"_id", name}, {"version", version}},
+		bson.D{
+			{"$set", bson.D{{"schema_version", SchemaVersion}}},
+			{"$inc", bson.D{{"reserved", n}, {"version", int64(1)}}},
+		},
+		opts,
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// The document exists with another version
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to reserve %d clusters in account %s", n, name)
+	}
+	return true, nil
+}
+
+// releaseAccount releases n clusters reserved in the account. Nothing is released if the account has less reservations.
+func releaseAccount(name string, n int) error {
+	_, err := mongodb.Accounts().UpdateOne(
+		context.Background(),
+		bson.D{{"_id", name}, {"reserved", bson.D{{"$gte", n}}}},
+		bson.D{{"$inc", bson.D{{"reserved", -n}, {"version", int64(1)}}}},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to release %d clusters reserved in account %s", n, name)
+	}
+	return nil
+}
+
+// resetZoneReservations sets the number of the clusters reserved in the zones to the given numbers by the zone ID.
+// The reservations of the other zones are cleared.
+func resetZoneReservations(reserved map[string]int) error {
+	return resetReservations(mongodb.Zones(), reserved)
+}
+
+// resetAccountReservations sets the number of the clusters reserved in the accounts to the given numbers by the account name.
+// The reservations of the other accounts are cleared.
+func resetAccountReservations(reserved map[string]int) error {
+	return resetReservations(mongodb.Accounts(), reserved)
+}
+
+func resetReservations(collection *mongo.Collection, reserved map[string]int) error {
+	ids := make(bson.A, 0, len(reserved))
+	for id, n := range reserved {
+		ids = append(ids, id)
+		if _, err := collection.UpdateOne(
+			context.Background(),
+			bson.D{{"_id", id}},
+			bson.D{{"$set", bson.D{{"reserved", n}}}, {"$inc", bson.D{{"version", int64(1)}}}},
+		); err != nil {
+			return errors.Wrapf(err, "unable to reset reservations of %s in %s", id, collection.Name())
+		}
+	}
+	if _, err := collection.UpdateMany(
+		context.Background(),
+		bson.D{{"_id", bson.D{{"$nin", ids}}}, {"reserved", bson.D{{"$ne", 0}}}},
+		bson.D{{"$set", bson.D{{"reserved", 0}}}, {"$inc", bson.D{{"version", int64(1)}}}},
+	); err != nil {
+		return errors.Wrapf(err, "unable to clear reservations in %s", collection.Name())
 	}
+	return nil
 }
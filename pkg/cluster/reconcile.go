@@ -0,0 +1,246 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// clusterNamePrefix is the prefix of the names of all the clusters provisioned by the service
+const clusterNamePrefix = "rhd-"
+
+// policyGracePeriod is the minimal age of an access policy without a matching user assignment
+// before it's considered an orphan. It prevents deleting policies which have just been created
+// but not yet stored in the DB by assignUser().
+const policyGracePeriod = 10 * time.Minute
+
+// DriftReport represents the result of a single reconciliation of the DB with IBM Cloud
+type DriftReport struct {
+	ID              string
+	Started         int64
+	Finished        int64
+	OrphanClusters  []string // IDs of the clusters found in IBM Cloud but missing in the DB
+	AdoptedClusters []string // IDs of the orphan clusters which have been added to the DB
+	MissingClusters []string // IDs of the clusters which are gone from IBM Cloud and have been marked as deleted in the DB
+	OrphanPolicies  []string // IDs of the access policies with no matching user assignment which have been deleted
+	RecoveredUsers  []string // IDs of the users with interrupted assignments or recycles which have been completed or rolled back
+	Errors          []string
+}
+
+func newDriftReport() *DriftReport {
+	return &DriftReport{
+		ID:              uuid.NewV4().String(),
+		Started:         time.Now().Unix(),
+		OrphanClusters:  make([]string, 0),
+		AdoptedClusters: make([]string, 0),
+		MissingClusters: make([]string, 0),
+		OrphanPolicies:  make([]string, 0),
+		RecoveredUsers:  make([]string, 0),
+		Errors:          make([]string, 0),
+	}
+}
+
+func (r *DriftReport) addError(err error, msg string) {
+	log.Error(nil, err, msg)
+	r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", msg, err.Error()))
+}
+
+// StartReconciling starts a goroutine to reconcile the DB with IBM Cloud.
+// The interval is read from the configuration before every run so it can be changed while running.
+func (s *ClusterService) StartReconciling() {
+	go func() {
+		for {
+			interval := time.Duration(s.Config.GetReconcileIntervalSec()) * time.Second
+			s.routineIteration(RoutineReconciliation, interval)
+			if _, err := s.Reconcile(); err != nil {
+				log.Error(nil, err, "unable to reconcile clusters")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// Reconcile compares the clusters and access policies in IBM Cloud with the ones stored in the DB and fixes the drift
+// in every account:
+// - clusters found in IBM Cloud but missing in the DB are reported and, if configured, adopted,
+// - clusters which are gone from IBM Cloud are marked as deleted and their users are recycled,
+// - access policies with no matching user assignment are deleted.
+// The drift report is stored in the DB and returned.
+// Returns an error only if the reconciliation could not be performed at all.
+func (s *ClusterService) Reconcile() (*DriftReport, error) {
+	report := newDriftReport()
+	accounts := s.cloudAccounts()
+	reconciled := make([]accountClusters, 0, len(accounts))
+	for i, a := range accounts {
+		clusters, err := s.reconcileClusters(report, a)
+		if err != nil {
+			// The reconciliation fails only if none of the accounts could be reconciled
+			if len(reconciled) == 0 && i == len(accounts)-1 {
+				return nil, err
+			}
+			report.addError(err, fmt.Sprintf("unable to reconcile clusters of account %s", a.Name))
+			continue
+		}
+		reconciled = append(reconciled, clusters)
+	}
+	s.reconcilePendingUserOperations(report)
+	for _, c := range reconciled {
+		s.reconcileAccessPolicies(report, c.account, c.db, c.ic)
+	}
+
+	report.Finished = time.Now().Unix()
+	if err := insertDriftReport(*report); err != nil {
+		return nil, err
+	}
+	log.Info(nil, fmt.Sprintf("reconciliation done; orphan clusters: %d; missing clusters: %d; orphan policies: %d; recovered users: %d; errors: %d",
+		len(report.OrphanClusters), len(report.MissingClusters), len(report.OrphanPolicies), len(report.RecoveredUsers), len(report.Errors)))
+	return report, nil
+}
+
+// accountClusters are the clusters of the account stored in the DB and found in IBM Cloud by their IDs
+type accountClusters struct {
+	account CloudAccount
+	db      map[string]Cluster
+	ic      map[string]ibmcloud.Cluster
+}
+
+// reconcileClusters fixes the drift between the clusters stored in the DB and the clusters in the account.
+// Returns an error if the clusters of the account could not be loaded.
+func (s *ClusterService) reconcileClusters(report *DriftReport, a CloudAccount) (accountClusters, error) {
+	// Load the DB clusters before the IBM Cloud ones so a cluster created in between
+	// can be only reported as an orphan but never marked as deleted.
+	dbClusters, err := getClustersWithFilter(s.withAccount(a))
+	if err != nil {
+		return accountClusters{}, err
+	}
+	icClusters, err := a.Client.GetClusters()
+	if err != nil {
+		return accountClusters{}, err
+	}
+	dbClustersByID := make(map[string]Cluster, len(dbClusters))
+	for _, c := range dbClusters {
+		dbClustersByID[c.ID] = c
+	}
+	icClustersByID := make(map[string]ibmcloud.Cluster, len(icClusters))
+	for _, c := range icClusters {
+		if strings.HasPrefix(c.Name, clusterNamePrefix) {
+			icClustersByID[c.ID] = c
+		}
+	}
+
+	for id, c := range icClustersByID {
+		if _, found := dbClustersByID[id]; !found {
+			s.reconcileOrphanCluster(report, a, c)
+		}
+	}
+	for id, c := range dbClustersByID {
+		if _, found := icClustersByID[id]; !found && c.Status != StatusDeleted && c.Status != StatusDeleting {
+			s.reconcileMissingCluster(report, a, c)
+		}
+	}
+	return accountClusters{account: a, db: dbClustersByID, ic: icClustersByID}, nil
+}
+
+// reconcileOrphanCluster reports the cluster which exists in IBM Cloud but is not stored in the DB
+// and adds it to the DB if adopting orphans is enabled
+func (s *ClusterService) reconcileOrphanCluster(report *DriftReport, a CloudAccount, c ibmcloud.Cluster) {
+	// Re-check the DB in case the cluster has been stored since the clusters were loaded
+	found, err := getCluster(c.ID)
+	if err != nil {
+		report.addError(err, fmt.Sprintf("unable to get cluster %s", c.ID))
+		return
+	}
+	if found != nil {
+		return
+	}
+	log.Infof(nil, "orphan cluster found: %s (%s)", c.Name, c.ID)
+	report.OrphanClusters = append(report.OrphanClusters, c.ID)
+	if !s.Config.GetReconcileAdoptOrphanClusters() {
+		return
+	}
+	adopted := s.convertCluster(c, Cluster{}, "")
+	adopted.Error = "adopted by reconciliation"
+	adopted.Account = a.Name
+	if err := replaceCluster(adopted, ActorSystem); err != nil {
+		report.addError(err, fmt.Sprintf("unable to adopt orphan cluster %s", c.ID))
+		return
+	}
+	report.AdoptedClusters = append(report.AdoptedClusters, c.ID)
+}
+
+// reconcileMissingCluster marks the DB cluster which is gone from IBM Cloud as deleted and recycles its user
+func (s *ClusterService) reconcileMissingCluster(report *DriftReport, a CloudAccount, c Cluster) {
+	// Double check the cluster is really gone
+	if _, err := a.Client.GetCluster(c.ID); err == nil || !devclustererr.IsNotFound(err) {
+		if err != nil {
+			report.addError(err, fmt.Sprintf("unable to get cluster %s", c.ID))
+		}
+		return
+	}
+	log.Infof(nil, "cluster %s (%s) is gone from IBM Cloud; marking it as deleted", c.Name, c.ID)
+	report.MissingClusters = append(report.MissingClusters, c.ID)
+	if err := s.recycleUsers(c.ID); err != nil {
+		report.addError(err, fmt.Sprintf("unable to recycle users for cluster %s", c.ID))
+	}
+	c.Status = StatusDeleted
+	c.Deleted = time.Now().Unix()
+	c.Error = "cluster not found in IBM Cloud"
+	if err := replaceCluster(c, ActorSystem); err != nil {
+		report.addError(err, fmt.Sprintf("unable to mark cluster %s as deleted", c.ID))
+	}
+}
+
+// reconcilePendingUserOperations recovers the user assignments and recycles which have been pending for longer than the grace period,
+// i.e. interrupted in any service instance
+func (s *ClusterService) reconcilePendingUserOperations(report *DriftReport) {
+	recovered, err := s.recoverPendingUserOperations("")
+	report.RecoveredUsers = append(report.RecoveredUsers, recovered...)
+	if err != nil {
+		report.addError(err, "unable to recover pending user operations")
+	}
+}
+
+// reconcileAccessPolicies deletes the access policies of the account for the known clusters which are not assigned to any user
+func (s *ClusterService) reconcileAccessPolicies(report *DriftReport, a CloudAccount, dbClusters map[string]Cluster, icClusters map[string]ibmcloud.Cluster) {
+	policies, err := a.Client.GetAccessPolicies(a.AccountID)
+	if err != nil {
+		report.addError(err, fmt.Sprintf("unable to get access policies of account %s", a.Name))
+		return
+	}
+	users, err := getAllUsers()
+	if err != nil {
+		report.addError(err, "unable to get users")
+		return
+	}
+	assigned := make(map[string]bool, len(users))
+	for _, u := range users {
+		if u.PolicyID != "" {
+			assigned[u.PolicyID] = true
+		}
+	}
+	for _, p := range policies {
+		clusterID := p.ServiceInstance()
+		_, inDB := dbClusters[clusterID]
+		_, inIC := icClusters[clusterID]
+		if assigned[p.ID] || !(inDB || inIC) || time.Since(p.Created()) < policyGracePeriod {
+			continue
+		}
+		log.Infof(nil, "deleting orphan access policy %s for cluster %s", p.ID, clusterID)
+		if err := a.Client.DeleteAccessPolicy(p.ID); err != nil {
+			report.addError(err, fmt.Sprintf("unable to delete orphan access policy %s", p.ID))
+			continue
+		}
+		report.OrphanPolicies = append(report.OrphanPolicies, p.ID)
+	}
+}
+
+// DriftReports returns the latest n drift reports, the most recent first
+func (s *ClusterService) DriftReports(n int) ([]DriftReport, error) {
+	return getDriftReports(n)
+}
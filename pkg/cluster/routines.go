@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"time"
+)
+
+// Names of the background routines run by the cluster service
+const (
+	RoutineExpiredClustersDeletion = "expired-clusters-deletion"
+	RoutineReconciliation          = "reconciliation"
+	RoutineUserPoolAutoscaling     = "user-pool-autoscaling"
+	RoutineArchival                = "archival"
+)
+
+// BackgroundRoutines are the names of all the background routines expected to be running
+var BackgroundRoutines = []string{RoutineExpiredClustersDeletion, RoutineReconciliation, RoutineUserPoolAutoscaling, RoutineArchival}
+
+// routineGracePeriod is added to the double of the routine interval before the routine is considered stuck
+const routineGracePeriod = 10 * time.Minute
+
+// RoutineStatus represents the status of a background routine
+type RoutineStatus struct {
+	Name    string
+	Running bool
+	LastRun int64 // timestamp of the beginning of the last iteration
+}
+
+type routine struct {
+	interval time.Duration
+	lastRun  time.Time
+}
+
+// routineIteration records the beginning of a new iteration of the background routine
+func (s *ClusterService) routineIteration(name string, interval time.Duration) {
+	s.routinesMux.Lock()
+	defer s.routinesMux.Unlock()
+	if s.routines == nil {
+		s.routines = make(map[string]*routine)
+	}
+	s.routines[name] = &routine{
+		interval: interval,
+		lastRun:  time.Now(),
+	}
+}
+
+// RoutineStatus returns the status of the background routine with the given name.
+// The routine is running if it has been started and its last iteration began no later than two intervals ago (plus a grace period).
+func (s *ClusterService) RoutineStatus(name string) RoutineStatus {
+	s.routinesMux.RLock()
+	defer s.routinesMux.RUnlock()
+	status := RoutineStatus{Name: name}
+	r, found := s.routines[name]
+	if !found {
+		return status
+	}
+	status.LastRun = r.lastRun.Unix()
+	status.Running = time.Since(r.lastRun) <= 2*r.interval+routineGracePeriod
+	return status
+}
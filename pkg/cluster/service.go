@@ -2,15 +2,19 @@ package cluster
 
 import (
 	"fmt"
-	"net/url"
 	"sync"
 	"time"
 
 	"github.com/codeready-toolchain/devcluster/pkg/auth"
-
+	"github.com/codeready-toolchain/devcluster/pkg/bootstrap"
+	"github.com/codeready-toolchain/devcluster/pkg/cost"
 	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/export"
 	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/identity"
 	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/notification"
+	"github.com/codeready-toolchain/devcluster/pkg/urls"
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
@@ -30,15 +34,30 @@ const (
 
 // Request represents a cluster request
 type Request struct {
-	ID            string
-	Requested     int // Number of clusters requested
-	Created       int64
-	Status        string
-	Error         string
-	RequestedBy   string
-	Zone          string
-	DeleteInHours int
-	NoSubnet      bool
+	ID              string
+	Requested       int // Number of clusters requested
+	Created         int64
+	Status          string
+	Error           string
+	RequestedBy     string
+	Zone            string
+	DeleteInHours   int
+	NoSubnet        bool
+	UsersPerCluster int      // Number of users assigned to every cluster
+	URLTemplates    []string // Names of the URL templates rendered for the clusters. The configured default templates are used if empty.
+	Account         string   // Name of the IBM Cloud account the clusters are provisioned in. The default account if empty.
+}
+
+// MaxUsersPerCluster is the max number of users which can be assigned to one cluster
+const MaxUsersPerCluster = 10
+
+// usersPerCluster returns the number of users to be assigned to every cluster of the request.
+// The requests created before the users per cluster have been introduced have one user per cluster.
+func (r Request) usersPerCluster() int {
+	if r.UsersPerCluster < 1 {
+		return 1
+	}
+	return r.UsersPerCluster
 }
 
 // Request represents a cluster request with detailed information about all request clusters
@@ -57,45 +76,191 @@ type Cluster struct {
 	LoginURL            string
 	WorkshopURL         string
 	IdentityProviderURL string
+	URLs                []urls.URL // URLs rendered from the cluster URL templates
 	MasterURL           string
 	Status              string
 	Error               string
-	User                User
+	User                User          // the first user assigned to the cluster
+	Users               []ClusterUser // all the users assigned to the cluster
 	PublicVlan          string
 	PrivateVlan         string
+	DeleteAttempts      int    // number of failed attempts to delete the cluster
+	NextDeleteAttempt   int64  // timestamp of the next attempt to delete the cluster if the previous one failed
+	ReplaceAttempt      int    // 0 for the originally requested cluster, N for the N-th replacement of a failed cluster
+	ReplacedBy          string // ID of the cluster which replaced this cluster
+	// Status of the bootstrap of the provisioned cluster: pending, running, succeeded or failed.
+	// Empty if the cluster is not bootstrapped because no bootstrap steps were configured when the cluster got provisioned.
+	BootstrapStatus string
+	BootstrapSteps  []BootstrapStep
+	MachineType     string
+	WorkerCount     int
+	Created         int64  // timestamp when the cluster provisioning was started
+	Ready           int64  // timestamp when the cluster got ready; zero if it has never got ready
+	Deleted         int64  // timestamp when the cluster was deleted
+	Account         string // name of the IBM Cloud account the cluster is provisioned in; the default account if empty
+}
+
+// ClusterUser represents a user assigned to the cluster with the user specific URLs.
+// Every user gets its own project in the cluster.
+type ClusterUser struct {
+	User        `json:",inline"`
+	Project     string
+	WorkshopURL string
+	URLs        []urls.URL // URLs rendered from the user URL templates
+}
+
+// URLTemplateData is the data the URL templates are rendered with
+type URLTemplateData struct {
+	Cluster Cluster
+	User    ClusterUser       // the user the URL is rendered for; only set for the user templates
+	URLs    map[string]string // the rendered cluster URLs by the template name; only set for the user templates
+	IDPName string            // name of the identity provider used to log in the clusters
 }
 
 type User struct {
 	ID            string // <iam_object>.user_id & <cloud_direct_object>.username
-	CloudDirectID string // <cloud_direct_object>.id
+	CloudDirectID string // ID of the user in the identity provider, i.e. <cloud_direct_object>.id
 	Email         string
 	Password      string
 	ClusterID     string
-	PolicyID      string
-	Recycled      int64 // last recycle timestamp
+	PolicyID      string // ID of the cluster access granted by the identity provider, i.e. the IAM access policy ID
+	Recycled      int64  // last recycle timestamp
+	Disabled      bool   // disabled users are not assigned to new clusters
+	// The operation which has been started but not yet completed for the user: assigning or recycling.
+	// The operations interrupted by a crash are completed or rolled back by the recovery.
+	PendingOperation string
+	PendingOwner     string // the service instance which started the pending operation
+	PendingSince     int64  // timestamp when the pending operation was started
+	Account          string // name of the IBM Cloud account the user is created in; the default account if empty
+	// The request the free user is reserved for. The reserved users are assigned to the clusters of that request only.
+	ReservedFor string
+}
+
+// identity returns the user as represented in the identity provider
+func (u User) identity() identity.User {
+	return identity.User{
+		ID:         u.ID,
+		ExternalID: u.CloudDirectID,
+		Email:      u.Email,
+		Password:   u.Password,
+	}
 }
 
 var DefaultClusterService *ClusterService
 
+// Configuration represents a partition of the configuration that is used by the cluster service
+type Configuration interface {
+	ibmcloud.Configuration
+	notification.Configuration
+	identity.Configuration
+	bootstrap.Configuration
+	urls.Configuration
+	cost.Configuration
+	GetReconcileAdoptOrphanClusters() bool
+	GetDeleteRetryBaseSec() int
+	GetDeleteRetryMaxAttempts() int
+	GetAutoReplaceFailedClusters() bool
+	GetAutoReplaceMaxAttempts() int
+	GetUserPoolMinFree() int
+	GetUserPoolBatchSize() int
+	GetUserPoolShortagePolicy() string
+	GetArchiveRetentionDays() int
+	GetReconcileIntervalSec() int
+	GetUserPoolCheckIntervalSec() int
+	GetArchiveIntervalSec() int
+	GetExpiryCheckIntervalSec() int
+	GetIBMCloudAccounts() string
+	GetSecret(name string) string
+}
+
 // ClusterService represents a registry of all cluster resources
 type ClusterService struct {
-	IbmCloudClient ibmcloud.ICClient
-	Config         ibmcloud.Configuration
+	IbmCloudClient ibmcloud.ICClient // client of the default account
+	Identity       identity.Provider // identity provider of the default account
+	// Accounts are all the IBM Cloud accounts, the default one first.
+	// If not set then the default account is the only account.
+	Accounts     []CloudAccount
+	accountsMux  sync.RWMutex
+	Bootstrap    bootstrap.Runner
+	Config       Configuration
+	Notifier     notification.Notifier
+	routines     map[string]*routine
+	routinesMux  sync.RWMutex
+	urlTemplates urlTemplatesCache
+}
+
+// urlTemplatesCache keeps the URL templates parsed from the configuration so they are not parsed for every cluster
+type urlTemplatesCache struct {
+	mux sync.Mutex
+	raw string
+	set *urls.Set
+}
+
+// ValidateConfiguration checks the settings parsed by the service: the IBM Cloud accounts, the bootstrap steps,
+// the URL templates and the cost price table. The same checks are run on startup and on every configuration reload.
+func ValidateConfiguration(config Configuration) error {
+	if _, err := ibmcloud.ParseAccounts(config.GetIBMCloudAccounts(), ibmcloud.Account{
+		AccountID: config.GetIBMCloudAccountID(),
+		TenantID:  config.GetIBMCloudTenantID(),
+	}); err != nil {
+		return err
+	}
+	if _, err := bootstrap.ParseSteps(config.GetBootstrapSteps()); err != nil {
+		return err
+	}
+	templates, err := urls.Parse(config.GetURLTemplates())
+	if err != nil {
+		return err
+	}
+	if err := templates.Validate(config.GetURLDefaultTemplates()); err != nil {
+		return err
+	}
+	if _, err := cost.ParsePriceTable(config.GetCostPriceTable()); err != nil {
+		return err
+	}
+	return nil
 }
 
-func InitDefaultClusterService(config ibmcloud.Configuration) {
+func InitDefaultClusterService(config Configuration) error {
+	if err := ValidateConfiguration(config); err != nil {
+		return err
+	}
+	accounts, err := newCloudAccounts(config)
+	if err != nil {
+		return err
+	}
 	DefaultClusterService = &ClusterService{
-		IbmCloudClient: ibmcloud.NewClient(config),
+		IbmCloudClient: accounts[0].Client,
+		Identity:       accounts[0].Identity,
+		Accounts:       accounts,
+		Bootstrap:      bootstrap.NewRunner(config),
 		Config:         config,
+		Notifier:       notification.NewNotifier(config),
 	}
+	return nil
 }
 
 func (s *ClusterService) Requests() ([]Request, error) {
 	return getAllRequests()
 }
 
+// GetZones returns the zones the clusters can be provisioned in by any account
 func (s *ClusterService) GetZones() ([]ibmcloud.Location, error) {
-	return s.IbmCloudClient.GetZones()
+	zones := make([]ibmcloud.Location, 0)
+	found := make(map[string]bool)
+	for _, a := range s.cloudAccounts() {
+		accountZones, err := a.Client.GetZones()
+		if err != nil {
+			return nil, err
+		}
+		for _, z := range accountZones {
+			if a.AllowsZone(z.ID) && !found[z.ID] {
+				found[z.ID] = true
+				zones = append(zones, z)
+			}
+		}
+	}
+	return zones, nil
 }
 
 func (s *ClusterService) GetRequestWithClusters(requestID string) (*RequestWithClusters, error) {
@@ -109,7 +274,7 @@ func (s *ClusterService) GetRequestWithClusters(requestID string) (*RequestWithC
 	}
 	clusters, err := getClusters(requestID)
 	for i, _ := range clusters {
-		clusters[i], err = s.enrichCluster(clusters[i])
+		clusters[i], err = s.enrichCluster(clusters[i], request)
 		if err != nil {
 			return nil, err
 		}
@@ -123,51 +288,194 @@ func (s *ClusterService) GetRequestWithClusters(requestID string) (*RequestWithC
 	}, nil
 }
 
-func (s *ClusterService) enrichCluster(c Cluster) (Cluster, error) {
-	user, err := GetUserByClusterID(c.ID)
+// Handouts returns the handouts for all the users assigned to the clusters of the request, one handout per user.
+// The deleted clusters and the clusters replaced by other clusters are skipped.
+func (s *ClusterService) Handouts(requestID string) ([]export.Handout, error) {
+	r, err := s.GetRequestWithClusters(requestID)
 	if err != nil {
-		if devclustererr.IsNotFound(err) {
-			return c, nil // Ignore not found users
+		return nil, err
+	}
+	if r == nil {
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("request %s not found", requestID), "")
+	}
+	handouts := make([]export.Handout, 0, len(r.Clusters))
+	for _, c := range r.Clusters {
+		if c.Status == StatusDeleted || c.ReplacedBy != "" {
+			continue
 		}
+		for _, u := range c.Users {
+			handouts = append(handouts, export.Handout{
+				ClusterName: c.Name,
+				ConsoleURL:  c.ConsoleURL,
+				LoginURL:    c.LoginURL,
+				Username:    u.ID,
+				Password:    u.Password,
+				WorkshopURL: u.WorkshopURL,
+			})
+		}
+	}
+	return handouts, nil
+}
+
+func (s *ClusterService) enrichCluster(c Cluster, r *Request) (Cluster, error) {
+	users, err := getUsersByClusterID(c.ID)
+	if err != nil {
 		return c, err
 	}
-	c.User = *user
-	c = s.withURLs(c)
-	return c, nil
+	return s.enrichClusterWithUsers(c, users, r)
 }
 
-func (s *ClusterService) withURLs(c Cluster) Cluster {
-	c.IdentityProviderURL = fmt.Sprintf("https://cloud.ibm.com/authorize/%s", s.Config.GetIBMCloudIDPName())
-	dashboard := url.QueryEscape(fmt.Sprintf("https://cloud.ibm.com/kubernetes/clusters/%s/overview", c.ID))
-	redirect := url.QueryEscape("https://cloud.ibm.com/login/callback")
-	c.LoginURL = fmt.Sprintf("https://iam.cloud.ibm.com/identity/devcluster/authorize?client_id=HOP55v1CCT&response_type=code&state=%s&redirect_uri=%s", dashboard, redirect)
-	encodedLoginURL := url.QueryEscape(c.LoginURL)
+// enrichClusterWithUsers sets the given users ordered by the user index and the URLs of the cluster
+func (s *ClusterService) enrichClusterWithUsers(c Cluster, users []User, r *Request) (Cluster, error) {
+	if len(users) == 0 {
+		return c, nil // Ignore not found users
+	}
+	c.User = users[0]
+	c.Users = make([]ClusterUser, 0, len(users))
+	for _, u := range users {
+		c.Users = append(c.Users, ClusterUser{User: u})
+	}
+	return s.withURLs(c, r)
+}
+
+func (s *ClusterService) withURLs(c Cluster, r *Request) (Cluster, error) {
+	templates, err := s.urlTemplateSet()
+	if err != nil {
+		return c, err
+	}
+	names := s.Config.GetURLDefaultTemplates()
+	if r != nil && len(r.URLTemplates) > 0 {
+		names = r.URLTemplates
+	}
+	templates = templates.Select(names)
+	data := URLTemplateData{
+		Cluster: c,
+		IDPName: s.Config.GetIBMCloudIDPName(),
+	}
+	if c.URLs, err = templates.Render(urls.ScopeCluster, data); err != nil {
+		return c, err
+	}
+	c.IdentityProviderURL = urls.Get(c.URLs, "identity-provider")
+	c.LoginURL = urls.Get(c.URLs, "login")
+	c.ConsoleURL = urls.Get(c.URLs, "console")
 	if c.Hostname != "" && c.User.ID != "" {
-		c.WorkshopURL = fmt.Sprintf("https://redhat-scholars.github.io/openshift-starter-guides/rhs-openshift-starter-guides/4.8/index.html?CLUSTER_SUBDOMAIN=%s&USERNAME=%s&PASSWORD=%s&LOGIN=%s&PROJECT=workshop", c.Hostname, c.User.ID, c.User.Password, encodedLoginURL)
-		c.ConsoleURL = fmt.Sprintf("https://console-openshift-console.%s", c.Hostname)
+		data.URLs = urls.ToMap(c.URLs)
+		// With a single user the user gets the "workshop" project. With multiple users every user gets its own project.
+		users := make([]ClusterUser, 0, len(c.Users))
+		for _, u := range c.Users {
+			u.Project = "workshop"
+			if len(c.Users) > 1 {
+				u.Project = fmt.Sprintf("workshop-%s", u.ID)
+			}
+			data.User = u
+			if u.URLs, err = templates.Render(urls.ScopeUser, data); err != nil {
+				return c, err
+			}
+			u.WorkshopURL = urls.Get(u.URLs, "workshop")
+			users = append(users, u)
+		}
+		c.Users = users
+		if len(c.Users) > 0 {
+			c.WorkshopURL = c.Users[0].WorkshopURL
+		}
 	}
-	return c
+	return c, nil
+}
+
+// urlTemplateSet returns the configured URL templates. The templates are parsed again only if the configuration changes.
+func (s *ClusterService) urlTemplateSet() (*urls.Set, error) {
+	s.urlTemplates.mux.Lock()
+	defer s.urlTemplates.mux.Unlock()
+	raw := s.Config.GetURLTemplates()
+	if s.urlTemplates.set == nil || s.urlTemplates.raw != raw {
+		set, err := urls.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		s.urlTemplates.raw = raw
+		s.urlTemplates.set = set
+	}
+	return s.urlTemplates.set, nil
+}
+
+// URLTemplates returns the configured URL templates
+func (s *ClusterService) URLTemplates() ([]urls.Template, error) {
+	set, err := s.urlTemplateSet()
+	if err != nil {
+		return nil, err
+	}
+	return set.Templates(), nil
 }
 
 // CreateNewRequest creates a new request and starts provisioning clusters
-func (s *ClusterService) CreateNewRequest(requestedBy string, n int, zone string, deleteInHours int, noSubnet bool) (Request, error) {
+// Every cluster gets the given number of users assigned.
+// The URLs of the clusters are rendered from the URL templates with the given names or from the default templates if no names given.
+// The clusters are provisioned in the given IBM Cloud account or, if empty, in the account the request is routed to.
+// Returns a Bad Request error if the zone is not enabled or the zone or the account has no room for n more clusters.
+func (s *ClusterService) CreateNewRequest(requestedBy string, n int, zone string, deleteInHours int, noSubnet bool, usersPerCluster int, urlTemplates []string, account string) (Request, error) {
+	if usersPerCluster < 1 || usersPerCluster > MaxUsersPerCluster {
+		return Request{}, devclustererr.NewBadRequestError(fmt.Sprintf("the number of users per cluster must be between 1 and %d", MaxUsersPerCluster), "")
+	}
+	templates, err := s.urlTemplateSet()
+	if err != nil {
+		return Request{}, err
+	}
+	if err := templates.Validate(urlTemplates); err != nil {
+		return Request{}, devclustererr.NewBadRequestError(err.Error(), "")
+	}
+	a, err := s.routeRequest(account, requestedBy, zone, n)
+	if err != nil {
+		return Request{}, err
+	}
+	if err := s.reserveAccountCapacity(a, n); err != nil {
+		return Request{}, err
+	}
+	if err := s.reserveZoneCapacity(zone, n); err != nil {
+		s.releaseAccountCapacity(a.Name, n)
+		return Request{}, err
+	}
+	id := uuid.NewV4().String()
+	missingUsers, err := s.reserveUserPool(a, id, n*usersPerCluster)
+	if err != nil {
+		s.releaseZoneCapacity(zone, n)
+		s.releaseAccountCapacity(a.Name, n)
+		return Request{}, err
+	}
 	r := Request{
-		ID:            uuid.NewV4().String(),
-		Requested:     n,
-		Created:       time.Now().Unix(),
-		Status:        StatusProvisioning,
-		RequestedBy:   requestedBy,
-		Zone:          zone,
-		DeleteInHours: deleteInHours,
-		NoSubnet:      noSubnet,
+		ID:              id,
+		Requested:       n,
+		Created:         time.Now().Unix(),
+		Status:          StatusProvisioning,
+		RequestedBy:     requestedBy,
+		Zone:            zone,
+		DeleteInHours:   deleteInHours,
+		NoSubnet:        noSubnet,
+		UsersPerCluster: usersPerCluster,
+		URLTemplates:    urlTemplates,
+		Account:         a.Name,
 	}
 
-	err := insertRequest(r)
+	err = insertRequest(r)
 	if err != nil {
+		s.releaseCapacity(r, n)
+		s.releaseUserPool(r.ID)
 		return Request{}, errors.Wrap(err, "unable to start new request")
 	}
 
 	go func() {
+		// The users reserved for the request and not assigned to its clusters, e.g. if a cluster failed, are returned to the pool
+		defer s.releaseUserPool(r.ID)
+		if missingUsers > 0 {
+			// Wait for the user pool to be scaled up before provisioning the clusters
+			if err := s.scaleUserPool(a, missingUsers); err != nil {
+				log.Error(nil, err, "unable to scale up the user pool for the request")
+				s.releaseCapacity(r, r.Requested)
+				if e := updateRequestStatus(r.ID, StatusFailed, err.Error(), ActorSystem); e != nil {
+					log.Error(nil, e, "unable to update request status")
+				}
+				return
+			}
+		}
 		for i := 0; i < r.Requested; i++ {
 			err := s.provisionNewCluster(r)
 			if err != nil {
@@ -179,36 +487,62 @@ func (s *ClusterService) CreateNewRequest(requestedBy string, n int, zone string
 	return r, nil
 }
 
-// GetClusters returns an array of the clusters with status not equal to "deleted" for the given zone.
+// GetClusters returns an array of the clusters with status not equal to "deleted" for the given zone or for all the zones if the zone is empty.
 func (s *ClusterService) GetClusters(zone string) ([]Cluster, error) {
-	clusters, err := getClustersWithRequestFilter(withZone(zone), withNotDeletedStatus())
+	clusters, _, err := s.ListClusters(ListOptions{Zone: zone})
+	return clusters, err
+}
+
+// DeleteCluster deletes the cluster with the given ID on behalf of the given actor
+// If the cluster is already gone from IBM Cloud then it's still marked as deleted and its user is recycled.
+func (s *ClusterService) DeleteCluster(id, actor string) error {
+	c, err := getCluster(id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for i, c := range clusters {
-		clusters[i], err = s.enrichCluster(c)
-		if err != nil {
-			return nil, err
+	if c == nil {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("cluster %s not found", id), "")
+	}
+	a, err := s.cloudAccount(c.Account)
+	if err != nil {
+		return err
+	}
+	if err := a.Client.DeleteCluster(id); err != nil {
+		if !devclustererr.IsNotFound(err) {
+			return err
 		}
+		log.Infof(nil, "cluster %s is already gone from IBM Cloud", id)
 	}
-	return clusters, nil
-}
-
-// DeleteCluster deletes the cluster with the given ID
-func (s *ClusterService) DeleteCluster(id string) error {
-	if err := s.IbmCloudClient.DeleteCluster(id); err != nil {
+	c.Error = ""
+	c.Status = StatusDeleted
+	c.Deleted = time.Now().Unix()
+	c.DeleteAttempts = 0
+	c.NextDeleteAttempt = 0
+	if err := s.recycleUsers(id); err != nil {
 		return err
 	}
+	return replaceCluster(*c, actor)
+}
+
+// RetryDeleteCluster retries deleting the cluster which failed to get deleted.
+// The counter of the failed attempts is reset so if this attempt fails too then the cluster is retried with the full backoff again.
+func (s *ClusterService) RetryDeleteCluster(id, actor string) error {
 	c, err := getCluster(id)
 	if err != nil {
 		return err
 	}
-	c.Error = ""
-	c.Status = StatusDeleted
-	if err := s.recycleUser(id); err != nil {
+	if c == nil {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("cluster %s not found", id), "")
+	}
+	if c.Status != StatusFailedToDelete {
+		return devclustererr.NewBadRequestError(fmt.Sprintf("cluster %s is not in the '%s' status", id, StatusFailedToDelete), fmt.Sprintf("current status: %s", c.Status))
+	}
+	c.DeleteAttempts = 0
+	if err := s.DeleteCluster(id, actor); err != nil {
+		s.clusterFailedToDelete(*c, err)
 		return err
 	}
-	return replaceCluster(*c)
+	return nil
 }
 
 // GetCluster returns the cluster with the given ID
@@ -216,21 +550,49 @@ func (s *ClusterService) GetCluster(id string) (*Cluster, error) {
 	return getCluster(id)
 }
 
-// ResumeProvisioningRequests load requests that are still provisioning and wait for their clusters to be ready to update the status
+// ResumeProvisioningRequests load requests that are still provisioning and wait for their clusters to be ready to update the status.
+// The zone and account reservations are recomputed from the clusters still to be provisioned so the reservations
+// of the clusters interrupted by a crash are not leaked. It's supposed to be called on startup before any new requests are accepted.
 func (s *ClusterService) ResumeProvisioningRequests() error {
 	requests, err := getRequestsWithStatus(StatusProvisioning)
 	if err != nil {
 		return err
 	}
+	requestClusters := make([][]Cluster, 0, len(requests))
+	zoneReserved := make(map[string]int)
+	accountReserved := make(map[string]int)
 	for _, r := range requests {
-		resumeRequest := r // need to use a copy in goroutine
-		clusters, err := getClusters(resumeRequest.ID)
+		clusters, err := getClusters(r.ID)
 		if err != nil {
 			return err
 		}
+		requestClusters = append(requestClusters, clusters)
+		if missing := r.Requested - len(clusters); missing > 0 {
+			// The missing clusters are provisioned below and release their reservations once stored or failed
+			zoneReserved[r.Zone] += missing
+			if a, err := s.cloudAccount(r.Account); err == nil {
+				accountReserved[a.Name] += missing
+			}
+		}
+	}
+	if err := resetZoneReservations(zoneReserved); err != nil {
+		return err
+	}
+	if err := resetAccountReservations(accountReserved); err != nil {
+		return err
+	}
+	for i, r := range requests {
+		resumeRequest := r // need to use a copy in goroutine
+		clusters := requestClusters[i]
 		// Update provisioning clusters
 		for _, cluster := range clusters {
 			resumeCluster := cluster // need to use a copy in goroutine
+			if resumeCluster.Status != StatusDeleted && resumeCluster.Status != StatusFailed {
+				// Assign the missing users if the assignment was interrupted
+				if err := s.assignUsers(resumeCluster.ID, resumeRequest.ID, resumeRequest.Account, resumeRequest.usersPerCluster()); err != nil {
+					log.Error(nil, err, fmt.Sprintf("unable to assign users to cluster %s", resumeCluster.ID))
+				}
+			}
 			if clusterProvisioningPending(resumeCluster) {
 				go func() {
 					log.Infof(nil, "resuming provisioning cluster %s", resumeCluster.Name)
@@ -251,15 +613,19 @@ func (s *ClusterService) ResumeProvisioningRequests() error {
 				}
 			}
 		}
+		s.releaseUserPool(resumeRequest.ID)
 	}
 
 	return nil
 }
 
-// StartDeletingExpiredClusters starts a goroutine to check expired clusters every n seconds and delete them
-func (s *ClusterService) StartDeletingExpiredClusters(intervalInSec int) {
+// StartDeletingExpiredClusters starts a goroutine to check expired clusters and delete them.
+// The interval is read from the configuration before every check so it can be changed while running.
+func (s *ClusterService) StartDeletingExpiredClusters() {
 	go func() {
 		for {
+			interval := time.Duration(s.Config.GetExpiryCheckIntervalSec()) * time.Second
+			s.routineIteration(RoutineExpiredClustersDeletion, interval)
 			reqs, err := getAllRequests()
 			if err != nil {
 				log.Error(nil, err, "unable to get request to check expired clusters")
@@ -273,21 +639,26 @@ func (s *ClusterService) StartDeletingExpiredClusters(intervalInSec int) {
 							allDeleted := true
 							for _, c := range clusters {
 								if c.Status != StatusDeleted && c.Status != StatusDeleting {
+									if c.Status == StatusFailedToDelete && !s.deleteRetryDue(c) {
+										// Wait for the next attempt or for the manual retry if all the attempts are exhausted
+										allDeleted = false
+										continue
+									}
 									// Delete the expired cluster
-									err := s.DeleteCluster(c.ID)
+									err := s.DeleteCluster(c.ID, ActorSystem)
 									if err != nil {
 										// Set the error status for the cluster
-										clusterFailedToDelete(c, err)
+										s.clusterFailedToDelete(c, err)
 										allDeleted = false
 									}
 								}
 							}
 							if allDeleted {
 								// All clusters deleted. Mark the request as expired.
-								err = updateRequestStatus(r.ID, StatusExpired, "")
+								err = updateRequestStatus(r.ID, StatusExpired, "", ActorSystem)
 							} else {
 								// Failed to delete at least one cluster. Mark the request as failed to expire.
-								err = updateRequestStatus(r.ID, StatusFailedToExpire, "unable to delete some clusters")
+								err = updateRequestStatus(r.ID, StatusFailedToExpire, "unable to delete some clusters", ActorSystem)
 							}
 							if err != nil {
 								log.Error(nil, err, "unable to update request status")
@@ -296,7 +667,7 @@ func (s *ClusterService) StartDeletingExpiredClusters(intervalInSec int) {
 					}
 				}
 			}
-			time.Sleep(time.Duration(intervalInSec) * time.Second)
+			time.Sleep(interval)
 		}
 	}()
 }
@@ -308,6 +679,32 @@ func expired(r Request) bool {
 // provisionNewCluster creates one new cluster and starts a new go routine to check the cluster status
 // returns an error if the creation failed
 func (s *ClusterService) provisionNewCluster(r Request) error {
+	_, err := s.provisionCluster(r, 0)
+	return err
+}
+
+// releaseCapacity releases n clusters reserved in the zone and in the account of the request
+func (s *ClusterService) releaseCapacity(r Request, n int) {
+	s.releaseZoneCapacity(r.Zone, n)
+	s.releaseAccountCapacity(r.Account, n)
+}
+
+// provisionCluster creates one new cluster for the request and starts a new go routine to check the cluster status.
+// replaceAttempt is 0 for the originally requested clusters and N for the N-th replacement of a failed cluster.
+// The cluster must be reserved in the zone and in the account by the caller. The reservations are released once the cluster is stored or failed.
+// Returns the created cluster or an error if the creation failed.
+func (s *ClusterService) provisionCluster(r Request, replaceAttempt int) (*Cluster, error) {
+	// The cluster is counted in the zone and account capacity as reserved until it's stored
+	reserved := true
+	defer func() {
+		if reserved {
+			s.releaseCapacity(r, 1)
+		}
+	}()
+	a, err := s.cloudAccount(r.Account)
+	if err != nil {
+		return nil, err
+	}
 	var name string
 	var uniqueNameGenerated bool
 	// Try to generate an unique cluster name
@@ -315,7 +712,7 @@ func (s *ClusterService) provisionNewCluster(r Request) error {
 		name = auth.GenerateShortIDWithDate("rhd-" + r.Zone)
 		c, err := getClusterByName(name)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if c == nil || c.Status == StatusDeleted {
 			uniqueNameGenerated = true
@@ -324,15 +721,15 @@ func (s *ClusterService) provisionNewCluster(r Request) error {
 		log.Infof(nil, "generated cluster name %s already taken; will try again", name)
 	}
 	if !uniqueNameGenerated {
-		return errors.New("unable to generate a unique cluster name")
+		return nil, errors.New("unable to generate a unique cluster name")
 	}
 	log.Infof(nil, "starting provisioning cluster %s", name)
 	var idObj *ibmcloud.IBMCloudClusterRequest
 	var c Cluster
-	var err error
 	// Try to create a cluster. If failing then we will make six attempts for one minute before giving up.
 	for i := 0; i < 6; i++ {
-		idObj, err = s.IbmCloudClient.CreateCluster(name, r.Zone, r.NoSubnet)
+		s.waitForIBMCloudToRecover(a)
+		idObj, err = a.Client.CreateCluster(name, r.Zone, r.NoSubnet)
 		if err != nil {
 			log.Error(nil, err, "unable to create cluster")
 			time.Sleep(10 * time.Second)
@@ -345,14 +742,21 @@ func (s *ClusterService) provisionNewCluster(r Request) error {
 				RequestID:           r.ID,
 				PublicVlan:          idObj.PublicVlan,
 				PrivateVlan:         idObj.PrivateVlan,
+				ReplaceAttempt:      replaceAttempt,
+				MachineType:         ibmcloud.MachineType,
+				WorkerCount:         ibmcloud.WorkerCount,
+				Created:             time.Now().Unix(),
+				Account:             a.Name,
 			}
-			if err := replaceCluster(c); err != nil {
+			if err := replaceCluster(c, ActorSystem); err != nil {
 				log.Error(nil, err, "unable to persist the created cluster in the DB")
-				return err
+				return nil, err
 			}
-			if err := s.assignUser(idObj.ClusterID); err != nil {
-				log.Error(nil, err, "unable to assign a user to the cluster")
-				return err
+			s.releaseCapacity(r, 1)
+			reserved = false
+			if err := s.assignUsers(idObj.ClusterID, r.ID, a.Name, r.usersPerCluster()); err != nil {
+				log.Error(nil, err, "unable to assign users to the cluster")
+				return nil, err
 			}
 			break
 		}
@@ -361,8 +765,10 @@ func (s *ClusterService) provisionNewCluster(r Request) error {
 		// Set request status to failed and break
 		r.Status = StatusFailed
 		r.Error = err.Error()
-		err := updateRequestStatus(r.ID, StatusFailed, err.Error())
-		return err
+		if e := updateRequestStatus(r.ID, StatusFailed, err.Error(), ActorSystem); e != nil {
+			return nil, e
+		}
+		return nil, err
 	}
 	go func() {
 		err := s.waitForClusterToBeReady(r, c)
@@ -371,86 +777,27 @@ func (s *ClusterService) provisionNewCluster(r Request) error {
 		}
 	}()
 
-	return nil
-}
-
-// Controls access to the user pool for assigning clusters
-var clusterAssigneeMux sync.Mutex
-
-// assignUser picks a free user from the user pool and grands access to the cluster to that user
-// by creating an Access Policy.
-func (s *ClusterService) assignUser(clusterID string) error {
-	user, err := s.obtainFreeUser(clusterID)
-	if err != nil {
-		return err
-	}
-	policyID, err := s.IbmCloudClient.CreateAccessPolicy(s.Config.GetIBMCloudAccountID(), user.ID, clusterID)
-	if err != nil {
-		rollBackClusterAssigment(*user)
-		log.Error(nil, err, fmt.Sprintf("unable to create access policy for user ID: %s", user.ID))
-		return err
-	}
-	user.PolicyID = policyID
-	return replaceUser(*user)
-}
-
-// rollBackClusterAssigment rolls back cluster assigment for the user
-func rollBackClusterAssigment(user User) {
-	user.ClusterID = ""
-	if e := replaceUser(user); e != nil {
-		log.Error(nil, e, fmt.Sprintf("unable to roll back cluster assigment for the user with id: %s", user.ID))
-	}
-}
-
-// obtainFreeUser obtains a free user from the user pool and sets the cluster ID to that user so it can not be assigned to another cluster
-func (s *ClusterService) obtainFreeUser(clusterID string) (*User, error) {
-	clusterAssigneeMux.Lock()
-	defer clusterAssigneeMux.Unlock()
-	user, err := getUserWithoutCluster()
-	if err != nil {
-		return nil, err
-	}
-	user.ClusterID = clusterID
-
-	return user, replaceUser(*user)
-}
-
-// recycleUser change the password of the user assigned to the cluster and returns that user to the user pool
-// so it can be assigned to another cluster.
-func (s *ClusterService) recycleUser(clusterID string) error {
-	user, err := GetUserByClusterID(clusterID)
-	if err != nil {
-		if devclustererr.IsNotFound(err) {
-			log.Infof(nil, "cluster %s has no user to recycle", clusterID)
-			return nil
-		}
-		return err
-	}
-	if err := s.IbmCloudClient.DeleteAccessPolicy(user.PolicyID); err != nil {
-		return err
-	}
-	cloudDirUser, err := s.IbmCloudClient.UpdateCloudDirectoryUserPassword(user.CloudDirectID)
-	if err != nil {
-		log.Error(nil, err, fmt.Sprintf("unable to update cloud directory user password for user: %s", user.ID))
-		return err
-	}
-	user.PolicyID = ""
-	user.ClusterID = ""
-	user.Password = cloudDirUser.Password
-	user.Recycled = time.Now().Unix()
-
-	return replaceUser(*user)
+	return &c, nil
 }
 
 // waitForClusterToBeReady for the cluster to be ready
 func (s *ClusterService) waitForClusterToBeReady(r Request, clst Cluster) error {
 	clusterID := clst.ID
 	clusterName := clst.Name
+	a, err := s.cloudAccount(r.Account)
+	if err != nil {
+		return err
+	}
 	timeout := time.Now().Add(time.Duration(s.Config.GetIBMCloudApiCallTimeoutSec()) * time.Second)
 	for time.Now().Before(timeout) {
-		c, err := s.IbmCloudClient.GetCluster(clusterID)
+		c, err := a.Client.GetCluster(clusterID)
 		if err != nil {
 			log.Errorf(nil, err, "unable to get cluster %s", clusterID)
+			if errors.Is(err, ibmcloud.ErrCircuitOpen) {
+				// IBM Cloud is degraded. It doesn't mean the cluster failed. Just try again later.
+				time.Sleep(time.Duration(s.Config.GetIBMCloudApiCallRetrySec()) * time.Second)
+				continue
+			}
 			if devclustererr.IsNotFound(err) {
 				// set the state to "deleted" but only if it's not in the "deleted" state already (in case of manual deletion) and return.
 				// otherwise set the status to "deleted" with the error message from IBM Cloud and try again in s.config.GetIBMCloudApiCallRetrySec() seconds.
@@ -472,35 +819,191 @@ func (s *ClusterService) waitForClusterToBeReady(r Request, clst Cluster) error
 			}
 			// Do not return. Try again in s.config.GetIBMCloudApiCallRetrySec() seconds.
 		} else {
+			stored, err := getCluster(clusterID)
+			if err != nil {
+				return err
+			}
+			if stored != nil && stored.ReplacedBy != "" {
+				log.Infof(nil, "cluster %s has been replaced by %s; stop waiting for it", clusterID, stored.ReplacedBy)
+				return nil
+			}
+			if clusterFailedInIBMCloud(*c) {
+				return s.clusterProvisioningFailed(r, clst, errors.Errorf("cluster %s failed to get provisioned; IBM Cloud state: %s", clusterID, c.State))
+			}
 			clusterToAdd := s.convertCluster(*c, clst, r.ID)
-			if err := replaceCluster(clusterToAdd); err != nil {
+			if stored != nil {
+				clusterToAdd.BootstrapStatus = stored.BootstrapStatus
+				clusterToAdd.BootstrapSteps = stored.BootstrapSteps
+				clusterToAdd.Ready = stored.Ready
+			}
+			provisioned := clusterProvisioned(clusterToAdd)
+			if provisioned && clusterToAdd.BootstrapStatus == "" {
+				steps, err := s.bootstrapSteps()
+				if err != nil {
+					return err
+				}
+				if len(steps) > 0 {
+					clusterToAdd.BootstrapStatus = BootstrapPending
+				}
+			}
+			if clusterReady(clusterToAdd) && clusterToAdd.Ready == 0 {
+				clusterToAdd.Ready = time.Now().Unix()
+			}
+			if err := replaceCluster(clusterToAdd, ActorSystem); err != nil {
 				return err
 			}
-			if clusterReady(clusterToAdd) { // Ready
+			if provisioned {
+				// The cluster is ready only after it's bootstrapped
+				if err := s.bootstrapCluster(clusterToAdd); err != nil {
+					return err
+				}
 				return setRequestStatusToSuccessIfDone(r)
 			}
 		}
 		time.Sleep(time.Duration(s.Config.GetIBMCloudApiCallRetrySec()) * time.Second)
 	}
 	// Timeout
-	return clusterFailed(errors.Errorf("cluster %s is still not ready after waiting for %d seconds", clusterID, s.Config.GetIBMCloudApiCallTimeoutSec()), StatusFailed, clusterID, clusterName, r.ID)
+	return s.clusterProvisioningFailed(r, clst, errors.Errorf("cluster %s is still not ready after waiting for %d seconds", clusterID, s.Config.GetIBMCloudApiCallTimeoutSec()))
+}
+
+// waitForIBMCloudToRecover pauses the caller while IBM Cloud is considered degraded and the API calls to the account are paused
+func (s *ClusterService) waitForIBMCloudToRecover(a CloudAccount) {
+	for a.Client.CircuitBreakerState() == ibmcloud.CircuitOpen {
+		log.Info(nil, "IBM Cloud API is degraded; provisioning is paused")
+		time.Sleep(time.Duration(s.Config.GetIBMCloudApiCallRetrySec()) * time.Second)
+	}
+}
+
+// ibmCloudFailedStates are the IBM Cloud cluster states which mean the cluster will never get ready
+var ibmCloudFailedStates = map[string]bool{"deploy_failed": true, "aborted": true}
+
+func clusterFailedInIBMCloud(c ibmcloud.Cluster) bool {
+	return ibmCloudFailedStates[c.State]
+}
+
+// clusterProvisioningFailed sets the "failed" status for the cluster which failed to get provisioned
+// and replaces the cluster by a new one if auto-replacing is enabled and the max number of replacements is not reached yet.
+func (s *ClusterService) clusterProvisioningFailed(r Request, c Cluster, clErr error) error {
+	if err := clusterFailed(clErr, StatusFailed, c.ID, c.Name, r.ID); err != nil {
+		return err
+	}
+	if !s.Config.GetAutoReplaceFailedClusters() {
+		return clErr
+	}
+	if c.ReplaceAttempt >= s.Config.GetAutoReplaceMaxAttempts() {
+		s.notify(fmt.Sprintf("Unable to provision cluster for request %s", r.ID),
+			fmt.Sprintf("Cluster %s (%s) failed to get provisioned and has already been replaced %d times. Last error: %s. Use POST /api/v1/cluster/%s/replace to try again.", c.Name, c.ID, c.ReplaceAttempt, clErr.Error(), c.ID))
+		return clErr
+	}
+	log.Infof(nil, "replacing failed cluster %s", c.ID)
+	if _, err := s.ReplaceCluster(c.ID, ActorSystem); err != nil {
+		return errors.Wrapf(err, "unable to replace failed cluster %s", c.ID)
+	}
+	return clErr
 }
 
-// CreateUsers creates n number of users
-// For example if n == 3 and startIndex == 1000 then the following users will be created:
-// rd-dev-1001, rd-dev-1002, rd-dev-1003
-func (s *ClusterService) CreateUsers(n, startIndex int) ([]User, error) {
+// terminalRequestStatuses are the statuses of the requests which are over and can't get new clusters anymore
+var terminalRequestStatuses = map[string]bool{StatusExpired: true, StatusFailedToExpire: true, StatusDeleting: true, StatusDeleted: true}
+
+// ReplaceCluster provisions a new cluster within the same request and deletes the given cluster on behalf of the given actor.
+// Returns the new cluster. Returns a Bad Request error if the request has expired.
+func (s *ClusterService) ReplaceCluster(id, actor string) (*Cluster, error) {
+	c, err := getCluster(id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("cluster %s not found", id), "")
+	}
+	if c.ReplacedBy != "" {
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("cluster %s has already been replaced", id), fmt.Sprintf("replaced by: %s", c.ReplacedBy))
+	}
+	if c.Status == StatusDeleted || c.Status == StatusDeleting {
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("cluster %s has been deleted", id), fmt.Sprintf("current status: %s", c.Status))
+	}
+	r, err := getRequest(c.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("cluster %s does not belong to any request", id), "")
+	}
+	if terminalRequestStatuses[r.Status] || expired(*r) {
+		// Replacing would bring the request back and provision a cluster nobody is going to delete
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("request %s of cluster %s has expired", r.ID, id), fmt.Sprintf("current status: %s", r.Status))
+	}
+	a, err := s.cloudAccount(r.Account)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reserveAccountCapacity(a, 1); err != nil {
+		return nil, err
+	}
+	if err := s.reserveZoneCapacity(r.Zone, 1); err != nil {
+		s.releaseAccountCapacity(a.Name, 1)
+		return nil, err
+	}
+	if r.Status != StatusProvisioning {
+		// The request is not done until the replacement gets ready
+		if err := updateRequestStatus(r.ID, StatusProvisioning, "", actor); err != nil {
+			s.releaseCapacity(*r, 1)
+			return nil, err
+		}
+		r.Status = StatusProvisioning
+		r.Error = ""
+	}
+	replacement, err := s.provisionCluster(*r, c.ReplaceAttempt+1)
+	if err != nil {
+		return nil, err
+	}
+	c.ReplacedBy = replacement.ID
+	if err := replaceCluster(*c, actor); err != nil {
+		return nil, err
+	}
+	if err := s.DeleteCluster(c.ID, actor); err != nil {
+		// The deletion will be retried when the request expires or can be retried manually
+		log.Error(nil, err, fmt.Sprintf("unable to delete replaced cluster %s", c.ID))
+		replaced, e := getCluster(c.ID)
+		if e != nil {
+			return nil, e
+		}
+		s.clusterFailedToDelete(*replaced, err)
+	}
+	return replacement, nil
+}
+
+// CreateUsers creates n number of users in the given IBM Cloud account or in the default account if empty.
+// The users are named rh-dev-<index> where the indexes are allocated automatically and never collide with the existing users.
+// For example if the highest index of the existing users is 1000 and n == 3 then the following users will be created:
+// rh-dev-1001, rh-dev-1002, rh-dev-1003
+func (s *ClusterService) CreateUsers(account string, n int) ([]User, error) {
 	users := make([]User, 0, 0)
-	for i := startIndex + 1; i <= startIndex+n; i++ {
-		cdu, err := s.IbmCloudClient.CreateCloudDirectoryUser(fmt.Sprintf("rh-dev-%d", i))
+	if n <= 0 {
+		return users, nil
+	}
+	a, err := s.cloudAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	highest, err := highestUserIndex()
+	if err != nil {
+		return nil, err
+	}
+	start, err := reserveUserIndexes(n, highest)
+	if err != nil {
+		return nil, err
+	}
+	for i := start; i < start+n; i++ {
+		iu, err := a.Identity.CreateUser(fmt.Sprintf("%s%d", userIDPrefix, i), "")
 		if err != nil {
 			return nil, err
 		}
 		user := User{
-			ID:            cdu.Username,
-			CloudDirectID: cdu.ID,
-			Email:         cdu.Email(),
-			Password:      cdu.Password,
+			ID:            iu.ID,
+			CloudDirectID: iu.ExternalID,
+			Email:         iu.Email,
+			Password:      iu.Password,
+			Account:       a.Name,
 		}
 		err = insertUser(user)
 		if err != nil {
@@ -516,12 +1019,17 @@ func (s *ClusterService) Users() ([]User, error) {
 }
 
 func clusterReady(c Cluster) bool {
+	return clusterProvisioned(c) && (c.BootstrapStatus == "" || c.BootstrapStatus == BootstrapSucceeded)
+}
+
+// clusterProvisioned returns true if the cluster is provisioned in IBM Cloud. The cluster may still need to be bootstrapped.
+func clusterProvisioned(c Cluster) bool {
 	return c.Status == StatusNormal && c.Hostname != "" && c.MasterURL != ""
 }
 
 // clusterProvisioningPending returns true if cluster is still provisioning
 func clusterProvisioningPending(c Cluster) bool {
-	return !clusterReady(c) && c.Status != StatusFailed && c.Status != StatusDeleted
+	return !clusterReady(c) && c.Status != StatusFailed && c.Status != StatusDeleted && c.BootstrapStatus != BootstrapFailed
 }
 
 func clusterFailed(clErr error, status, id, name, reqID string) error {
@@ -538,28 +1046,72 @@ func clusterFailed(clErr error, status, id, name, reqID string) error {
 	}
 	clToUpdate.Error = clErr.Error()
 	clToUpdate.Status = status
-	return replaceCluster(*clToUpdate)
+	if status == StatusDeleted && clToUpdate.Deleted == 0 {
+		clToUpdate.Deleted = time.Now().Unix()
+	}
+	return replaceCluster(*clToUpdate, ActorSystem)
 }
 
-func clusterFailedToDelete(c Cluster, e error) {
+// maxDeleteRetryDelay is the max delay between two attempts to delete a cluster
+const maxDeleteRetryDelay = 24 * time.Hour
+
+// deleteRetryDelay returns the delay before the next attempt to delete a cluster after the given number of failed attempts.
+// The delay is doubled with every failed attempt starting from baseSec seconds and is capped at maxDeleteRetryDelay.
+func deleteRetryDelay(baseSec, attempts int) time.Duration {
+	delay := time.Duration(baseSec) * time.Second
+	for i := 1; i < attempts && delay < maxDeleteRetryDelay; i++ {
+		delay = delay * 2
+	}
+	if delay > maxDeleteRetryDelay {
+		return maxDeleteRetryDelay
+	}
+	return delay
+}
+
+// deleteRetryDue returns true if the cluster which failed to get deleted should be retried now
+func (s *ClusterService) deleteRetryDue(c Cluster) bool {
+	return c.DeleteAttempts < s.Config.GetDeleteRetryMaxAttempts() && time.Now().Unix() >= c.NextDeleteAttempt
+}
+
+// clusterFailedToDelete sets the "failed to delete" status for the cluster and schedules the next attempt.
+// If the max number of attempts is reached then the admins are notified and the cluster is not retried automatically anymore.
+func (s *ClusterService) clusterFailedToDelete(c Cluster, e error) {
 	log.Error(nil, e, "unable to delete expired cluster")
-	err := replaceCluster(Cluster{
-		ID:                  c.ID,
-		RequestID:           c.RequestID,
-		IBMClusterRequestID: c.IBMClusterRequestID,
-		Name:                c.Name,
-		Hostname:            c.Hostname,
-		MasterURL:           c.MasterURL,
-		Status:              StatusFailedToDelete,
-		Error:               e.Error(),
-		PublicVlan:          c.PublicVlan,
-		PrivateVlan:         c.PrivateVlan,
-	})
+	attempts := c.DeleteAttempts + 1
+	var nextAttempt int64
+	if attempts < s.Config.GetDeleteRetryMaxAttempts() {
+		nextAttempt = time.Now().Add(deleteRetryDelay(s.Config.GetDeleteRetryBaseSec(), attempts)).Unix()
+	} else {
+		s.notify(fmt.Sprintf("Unable to delete cluster %s", c.Name),
+			fmt.Sprintf("Giving up deleting cluster %s (%s) after %d attempts. Last error: %s. Use POST /api/v1/cluster/%s/retry-delete to retry.", c.Name, c.ID, attempts, e.Error(), c.ID))
+	}
+	// The other fields like the account are kept so the deletion is retried in the account of the cluster
+	c.Status = StatusFailedToDelete
+	c.Error = e.Error()
+	c.DeleteAttempts = attempts
+	c.NextDeleteAttempt = nextAttempt
+	err := replaceCluster(c, ActorSystem)
 	if err != nil {
 		log.Error(nil, err, "unable to update status for failed to delete cluster")
 	}
 }
 
+// notify sends the notification to the admins. Errors are logged but otherwise ignored.
+func (s *ClusterService) notify(subject, message string) {
+	if s.Notifier == nil {
+		log.Info(nil, fmt.Sprintf("%s: %s", subject, message))
+		return
+	}
+	if err := s.Notifier.Notify(subject, message); err != nil {
+		log.Error(nil, err, "unable to send notification")
+	}
+}
+
+// ibmCloudTimeLayout is the layout of the timestamps returned by IBM Cloud, e.g. 2021-03-10T08:00:00+0000
+const ibmCloudTimeLayout = "2006-01-02T15:04:05-0700"
+
+// convertCluster returns the cluster fetched from IBM Cloud merged with the fields of the stored cluster
+// which are not known to IBM Cloud, like the account the cluster is provisioned in
 func (s *ClusterService) convertCluster(from ibmcloud.Cluster, mergeTo Cluster, requestID string) Cluster {
 	c := Cluster{
 		ID:                  from.ID,
@@ -570,6 +1122,26 @@ func (s *ClusterService) convertCluster(from ibmcloud.Cluster, mergeTo Cluster,
 		IBMClusterRequestID: mergeTo.IBMClusterRequestID,
 		PublicVlan:          mergeTo.PublicVlan,
 		PrivateVlan:         mergeTo.PrivateVlan,
+		ReplaceAttempt:      mergeTo.ReplaceAttempt,
+		ReplacedBy:          mergeTo.ReplacedBy,
+		MachineType:         mergeTo.MachineType,
+		WorkerCount:         mergeTo.WorkerCount,
+		Created:             mergeTo.Created,
+		Ready:               mergeTo.Ready,
+		Deleted:             mergeTo.Deleted,
+		Account:             mergeTo.Account,
+	}
+	// The clusters adopted from IBM Cloud or stored before their usage was tracked are created by this service
+	if c.MachineType == "" {
+		c.MachineType = ibmcloud.MachineType
+	}
+	if c.WorkerCount == 0 {
+		c.WorkerCount = from.WorkerCount
+	}
+	if c.Created == 0 {
+		if created, err := time.Parse(ibmCloudTimeLayout, from.CreatedDate); err == nil {
+			c.Created = created.Unix()
+		}
 	}
 	hostname := from.Ingress.Hostname
 	if hostname != "" {
@@ -1,13 +1,24 @@
 package cluster
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/codeready-toolchain/devcluster/pkg/bootstrap"
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
 	"github.com/codeready-toolchain/devcluster/test"
+	testibmcloud "github.com/codeready-toolchain/devcluster/test/ibmcloud"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type TestServiceSuite struct {
@@ -35,3 +46,474 @@ func (s *TestServiceSuite) TestExpired() {
 		assert.False(t, expired(r))
 	})
 }
+
+func (s *TestServiceSuite) TestDeleteRetryDelay() {
+	assert.Equal(s.T(), 10*time.Minute, deleteRetryDelay(600, 0))
+	assert.Equal(s.T(), 10*time.Minute, deleteRetryDelay(600, 1))
+	assert.Equal(s.T(), 20*time.Minute, deleteRetryDelay(600, 2))
+	assert.Equal(s.T(), 80*time.Minute, deleteRetryDelay(600, 4))
+	assert.Equal(s.T(), 24*time.Hour, deleteRetryDelay(600, 10))
+	assert.Equal(s.T(), 24*time.Hour, deleteRetryDelay(600, 1000))
+}
+
+func (s *TestServiceSuite) TestRoutineStatus() {
+	service := &ClusterService{}
+
+	s.T().Run("not started", func(t *testing.T) {
+		status := service.RoutineStatus(RoutineReconciliation)
+		assert.Equal(t, RoutineStatus{Name: RoutineReconciliation}, status)
+	})
+
+	s.T().Run("running", func(t *testing.T) {
+		service.routineIteration(RoutineReconciliation, time.Hour)
+		status := service.RoutineStatus(RoutineReconciliation)
+		assert.True(t, status.Running)
+		assert.NotZero(t, status.LastRun)
+	})
+
+	s.T().Run("stuck", func(t *testing.T) {
+		service.routineIteration(RoutineExpiredClustersDeletion, time.Minute)
+		service.routines[RoutineExpiredClustersDeletion].lastRun = time.Now().Add(-time.Hour)
+		status := service.RoutineStatus(RoutineExpiredClustersDeletion)
+		assert.False(t, status.Running)
+	})
+}
+
+func (s *TestServiceSuite) TestUserPoolStatus() {
+	active := &Cluster{ID: "c1", Status: StatusNormal}
+	deleted := &Cluster{ID: "c1", Status: StatusDeleted}
+
+	assert.Equal(s.T(), UserStatusFree, userPoolStatus(User{}, nil))
+	assert.Equal(s.T(), UserStatusDisabled, userPoolStatus(User{Disabled: true}, nil))
+	assert.Equal(s.T(), UserStatusAssigned, userPoolStatus(User{ClusterID: "c1"}, active))
+	assert.Equal(s.T(), UserStatusDisabled, userPoolStatus(User{ClusterID: "c1", Disabled: true}, active))
+	assert.Equal(s.T(), UserStatusStuck, userPoolStatus(User{ClusterID: "c1"}, nil))
+	assert.Equal(s.T(), UserStatusStuck, userPoolStatus(User{ClusterID: "c1"}, deleted))
+	assert.Equal(s.T(), UserStatusPending, userPoolStatus(User{ClusterID: "c1", PendingOperation: UserOperationAssigning}, active))
+	assert.Equal(s.T(), UserStatusPending, userPoolStatus(User{ClusterID: "c1", PendingOperation: UserOperationRecycling}, deleted))
+}
+
+func (s *TestServiceSuite) TestUserIndex() {
+	assert.Equal(s.T(), 1, userIndex("rh-dev-1"))
+	assert.Equal(s.T(), 1024, userIndex("rh-dev-1024"))
+	assert.Equal(s.T(), 0, userIndex("rh-dev-"))
+	assert.Equal(s.T(), 0, userIndex("rh-dev-abc"))
+	assert.Equal(s.T(), 0, userIndex("someone-else"))
+}
+
+func (s *TestServiceSuite) TestDecodeLegacyDocuments() {
+	s.T().Run("missing fields are decoded to defaults", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{{"_id", "r1"}, {"status", StatusProvisioning}})
+		require.NoError(t, err)
+		var d requestDocument
+		require.NoError(t, bson.Unmarshal(raw, &d))
+		r := d.request()
+		assert.Equal(t, "r1", r.ID)
+		assert.Equal(t, StatusProvisioning, r.Status)
+		assert.Equal(t, []string{}, r.URLTemplates)
+		assert.Equal(t, 1, r.usersPerCluster())
+		assert.Equal(t, 0, d.SchemaVersion)
+	})
+
+	s.T().Run("int32 numbers and null values", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{
+			{"_id", "c1"},
+			{"request_id", nil},
+			{"delete_attempts", int32(2)},
+			{"created", int32(1600000000)},
+			{"bootstrap_steps", bson.A{bson.D{{"name", "step"}, {"started", int32(10)}}}},
+		})
+		require.NoError(t, err)
+		var d clusterDocument
+		require.NoError(t, bson.Unmarshal(raw, &d))
+		c := d.cluster()
+		assert.Equal(t, "", c.RequestID)
+		assert.Equal(t, 2, c.DeleteAttempts)
+		assert.Equal(t, int64(1600000000), c.Created)
+		assert.Equal(t, []BootstrapStep{{Name: "step", Started: 10}}, c.BootstrapSteps)
+	})
+
+	s.T().Run("missing arrays of drift reports are empty", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{{"_id", "d1"}})
+		require.NoError(t, err)
+		var d driftReportDocument
+		require.NoError(t, bson.Unmarshal(raw, &d))
+		assert.Equal(t, []string{}, d.driftReport().OrphanClusters)
+		assert.Equal(t, []string{}, d.driftReport().Errors)
+	})
+
+	s.T().Run("written documents have current schema version", func(t *testing.T) {
+		assert.Equal(t, SchemaVersion, newRequestDocument(Request{}).SchemaVersion)
+		assert.Equal(t, SchemaVersion, newClusterDocument(Cluster{}).SchemaVersion)
+		assert.Equal(t, SchemaVersion, newUserDocument(User{}).SchemaVersion)
+	})
+}
+
+func (s *TestServiceSuite) TestPageQuery() {
+	s.T().Run("default sort key", func(t *testing.T) {
+		q, err := newPageQuery("requests", requestSortKeys, ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "created", q.sortBy)
+		assert.Equal(t, bson.D{{"created", 1}, {"_id", 1}}, q.sortOrder())
+	})
+
+	s.T().Run("unknown sort key", func(t *testing.T) {
+		_, err := newPageQuery("users", userSortKeys, ListOptions{SortBy: "zone"})
+		require.Error(t, err)
+		assert.True(t, devclustererr.IsBadRequest(err))
+	})
+
+	s.T().Run("cursor round trip", func(t *testing.T) {
+		q, err := newPageQuery("clusters", clusterSortKeys, ListOptions{SortBy: "zone", Descending: true})
+		require.NoError(t, err)
+		raw, err := bson.Marshal(bson.D{{"_id", "c1"}, {"request", bson.D{{"zone", "wdc04"}}}})
+		require.NoError(t, err)
+		cursor, err := q.cursorOf(raw)
+		require.NoError(t, err)
+
+		next, err := newPageQuery("clusters", clusterSortKeys, ListOptions{SortBy: "zone", Descending: true, Cursor: cursor})
+		require.NoError(t, err)
+		after, err := next.afterCursor()
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{{"$or", bson.A{
+			bson.D{{"request.zone", bson.D{{"$lt", "wdc04"}}}},
+			bson.D{{"request.zone", "wdc04"}, {"_id", bson.D{{"$lt", "c1"}}}},
+		}}}, after)
+
+		// the cursor can't be used with another sort key
+		_, err = newPageQuery("clusters", clusterSortKeys, ListOptions{SortBy: "name", Cursor: cursor})
+		require.Error(t, err)
+		assert.True(t, devclustererr.IsBadRequest(err))
+	})
+
+	s.T().Run("numeric cursor", func(t *testing.T) {
+		q, err := newPageQuery("requests", requestSortKeys, ListOptions{})
+		require.NoError(t, err)
+		raw, err := bson.Marshal(bson.D{{"_id", "r1"}, {"created", int32(1600000000)}})
+		require.NoError(t, err)
+		cursor, err := q.cursorOf(raw)
+		require.NoError(t, err)
+
+		next, err := newPageQuery("requests", requestSortKeys, ListOptions{Cursor: cursor})
+		require.NoError(t, err)
+		after, err := next.afterCursor()
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{{"$or", bson.A{
+			bson.D{{"created", bson.D{{"$gt", int64(1600000000)}}}},
+			bson.D{{"created", int64(1600000000)}, {"_id", bson.D{{"$gt", "r1"}}}},
+		}}}, after)
+	})
+
+	s.T().Run("invalid cursor", func(t *testing.T) {
+		_, err := newPageQuery("requests", requestSortKeys, ListOptions{Cursor: "not a cursor"})
+		require.Error(t, err)
+		assert.True(t, devclustererr.IsBadRequest(err))
+	})
+
+	s.T().Run("unknown user pool status", func(t *testing.T) {
+		_, _, err := withUserPoolStatus("lost")
+		require.Error(t, err)
+		assert.True(t, devclustererr.IsBadRequest(err))
+	})
+
+	s.T().Run("joins needed", func(t *testing.T) {
+		q, err := newPageQuery("clusters", clusterSortKeys, ListOptions{SortBy: "name"})
+		require.NoError(t, err)
+		assert.False(t, q.needsJoins())
+
+		// sorted by a field of the joined request
+		q, err = newPageQuery("clusters", clusterSortKeys, ListOptions{SortBy: "requested_by"})
+		require.NoError(t, err)
+		assert.True(t, q.needsJoins())
+
+		// filtered by a field of the joined request
+		q, err = newPageQuery("clusters", clusterSortKeys, ListOptions{})
+		require.NoError(t, err)
+		q.whereJoined(bson.E{Key: "request.zone", Value: "lon06"})
+		assert.True(t, q.needsJoins())
+
+		// only the statuses depending on the cluster of the user need the join
+		for status, joined := range map[string]bool{
+			UserStatusFree:     false,
+			UserStatusPending:  false,
+			UserStatusAssigned: true,
+			UserStatusDisabled: true,
+			UserStatusStuck:    true,
+		} {
+			_, needsJoin, err := withUserPoolStatus(status)
+			require.NoError(t, err)
+			assert.Equal(t, joined, needsJoin, status)
+		}
+	})
+}
+
+func (s *TestServiceSuite) TestUnarchivableCluster() {
+	_, found := unarchivableCluster([]Cluster{{ID: "c1", Status: StatusDeleted}, {ID: "c2", Status: StatusFailed}})
+	assert.False(s.T(), found)
+
+	c, found := unarchivableCluster([]Cluster{{ID: "c1", Status: StatusDeleted}, {ID: "c2", Status: StatusFailedToDelete}})
+	assert.True(s.T(), found)
+	assert.Equal(s.T(), "c2", c.ID)
+
+	_, found = unarchivableCluster(nil)
+	assert.False(s.T(), found)
+}
+
+func (s *TestServiceSuite) TestSealAndOpenBackup() {
+	doc := backupDocument{
+		Requests: []requestDocument{{ID: "r1", Status: StatusReady, URLTemplates: []string{}}},
+		Clusters: []clusterDocument{{ID: "c1", RequestID: "r1", Status: StatusNormal}},
+		Users:    []userDocument{{ID: "u1", ClusterID: "c1"}, {ID: "u2"}},
+		ArchivedRequests: []archivedRequestDocument{
+			{Request: requestDocument{ID: "r0", Status: StatusExpired, URLTemplates: []string{}}, Archived: 900},
+		},
+		ArchivedClusters: []archivedClusterDocument{
+			{Cluster: clusterDocument{ID: "c0", RequestID: "r0", Status: StatusDeleted}, Archived: 900},
+		},
+	}
+
+	s.Run("not encrypted", func() {
+		envelope, err := sealBackup(doc, "", 1000)
+		require.NoError(s.T(), err)
+		assert.False(s.T(), envelope.Encrypted)
+		assert.Equal(s.T(), BackupVersion, envelope.Version)
+
+		opened, err := openBackup(envelope, "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), doc, opened)
+	})
+
+	s.Run("encrypted", func() {
+		envelope, err := sealBackup(doc, "secret", 1000)
+		require.NoError(s.T(), err)
+		assert.True(s.T(), envelope.Encrypted)
+
+		opened, err := openBackup(envelope, "secret")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), doc, opened)
+
+		_, err = openBackup(envelope, "wrong")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		_, err = openBackup(envelope, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("unsupported version", func() {
+		envelope, err := sealBackup(doc, "", 1000)
+		require.NoError(s.T(), err)
+		envelope.Version = BackupVersion + 1
+		_, err = openBackup(envelope, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+}
+
+func (s *TestServiceSuite) TestValidateBackup() {
+	valid := func() backupDocument {
+		return backupDocument{
+			Requests: []requestDocument{{ID: "r1"}},
+			Clusters: []clusterDocument{{ID: "c1", RequestID: "r1", ReplacedBy: "c2"}, {ID: "c2", RequestID: "r1"}},
+			Users:    []userDocument{{ID: "u1", ClusterID: "c2"}, {ID: "u2"}},
+		}
+	}
+	require.NoError(s.T(), valid().validate())
+
+	doc := valid()
+	doc.Requests = append(doc.Requests, requestDocument{ID: "r1"})
+	assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+	doc = valid()
+	doc.Clusters[1].RequestID = "r2"
+	assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+	doc = valid()
+	doc.Clusters[0].ReplacedBy = "c3"
+	assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+	doc = valid()
+	doc.Users[1].ClusterID = "c3"
+	assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+	s.Run("archive", func() {
+		withArchive := func() backupDocument {
+			doc := valid()
+			doc.ArchivedRequests = []archivedRequestDocument{{Request: requestDocument{ID: "r0"}, Archived: 1000}}
+			doc.ArchivedClusters = []archivedClusterDocument{{Cluster: clusterDocument{ID: "c0", RequestID: "r0"}, Archived: 1000}}
+			// The user is still assigned to the archived cluster
+			doc.Users[1].ClusterID = "c0"
+			return doc
+		}
+		require.NoError(s.T(), withArchive().validate())
+
+		doc := withArchive()
+		doc.ArchivedClusters[0].Cluster.RequestID = "r1"
+		assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+		doc = withArchive()
+		doc.ArchivedClusters = append(doc.ArchivedClusters, doc.ArchivedClusters[0])
+		assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+
+		doc = withArchive()
+		doc.ArchivedRequests = append(doc.ArchivedRequests, archivedRequestDocument{})
+		assert.True(s.T(), devclustererr.IsBadRequest(doc.validate()))
+	})
+}
+
+func (s *TestServiceSuite) TestReloadAccounts() {
+	config := &accountsConfig{
+		accounts: `[{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1", "max_clusters": 10},
+			{"name": "us", "apikey": "k2", "account_id": "a2", "tenant_id": "t2"}]`,
+	}
+	service := &ClusterService{
+		Config: config,
+		Accounts: []CloudAccount{
+			{Account: ibmcloud.Account{Name: "emea", APIKey: "k1", AccountID: "a1", TenantID: "t1", MaxClusters: 10}},
+			{Account: ibmcloud.Account{Name: "us", APIKey: "k2", AccountID: "a2", TenantID: "t2"}},
+		},
+	}
+
+	s.Run("quotas, teams and zones reloaded", func() {
+		config.accounts = `[{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1", "max_clusters": 20, "teams": ["@redhat.com"]},
+			{"name": "us", "apikey": "changed", "account_id": "a2", "tenant_id": "t2", "zones": ["wdc04"]},
+			{"name": "apac", "apikey": "k3", "account_id": "a3", "tenant_id": "t3"}]`
+		require.NoError(s.T(), service.ReloadAccounts())
+		accounts := service.cloudAccounts()
+		require.Len(s.T(), accounts, 2)
+		assert.Equal(s.T(), ibmcloud.Account{Name: "emea", APIKey: "k1", AccountID: "a1", TenantID: "t1", MaxClusters: 20, Teams: []string{"@redhat.com"}}, accounts[0].Account)
+		// The credentials are not changed while running
+		assert.Equal(s.T(), ibmcloud.Account{Name: "us", APIKey: "k2", AccountID: "a2", TenantID: "t2", Zones: []string{"wdc04"}}, accounts[1].Account)
+	})
+
+	s.Run("invalid accounts rejected", func() {
+		config.accounts = `[{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1", "max_clusters": -1}]`
+		require.Error(s.T(), service.ReloadAccounts())
+		assert.Equal(s.T(), 20, service.cloudAccounts()[0].MaxClusters)
+	})
+}
+
+func (s *TestServiceSuite) TestCheckAccounts() {
+	emea := testibmcloud.NewMockIBMCloudClient()
+	us := testibmcloud.NewMockIBMCloudClient()
+	us.SetTokenError(errors.New("invalid API key"))
+	service := &ClusterService{
+		Accounts: []CloudAccount{
+			{Account: ibmcloud.Account{Name: ibmcloud.DefaultAccount}, Client: emea},
+			{Account: ibmcloud.Account{Name: "us"}, Client: us},
+		},
+	}
+
+	errs := service.CheckAccounts()
+	require.Len(s.T(), errs, 2)
+	assert.NoError(s.T(), errs[ibmcloud.DefaultAccount])
+	assert.EqualError(s.T(), errs["us"], "invalid API key")
+}
+
+func (s *TestServiceSuite) TestConvertCluster() {
+	service := &ClusterService{}
+	stored := Cluster{
+		ID:                  "c1",
+		IBMClusterRequestID: "ic1",
+		MachineType:         "b3c.8x32",
+		WorkerCount:         3,
+		Created:             100,
+		Ready:               200,
+		Deleted:             300,
+		Account:             "us",
+	}
+	c := service.convertCluster(ibmcloud.Cluster{ID: "c1", Name: "cluster-1", State: StatusNormal, Ingress: ibmcloud.Ingress{Hostname: "cluster-1.example.com"}}, stored, "r1")
+	// The fields not known to IBM Cloud are kept
+	assert.Equal(s.T(), Cluster{
+		ID:                  "c1",
+		Name:                "cluster-1",
+		Status:              StatusNormal,
+		Hostname:            "cluster-1.example.com",
+		RequestID:           "r1",
+		IBMClusterRequestID: "ic1",
+		MachineType:         "b3c.8x32",
+		WorkerCount:         3,
+		Created:             100,
+		Ready:               200,
+		Deleted:             300,
+		Account:             "us",
+	}, c)
+}
+
+func (s *TestServiceSuite) TestBootstrapTarget() {
+	service := &ClusterService{
+		Config: &accountsConfig{apiKey: "default-key"},
+		Accounts: []CloudAccount{
+			{Account: ibmcloud.Account{Name: ibmcloud.DefaultAccount}},
+			{Account: ibmcloud.Account{Name: "us", APIKey: "us-key"}},
+		},
+	}
+
+	s.Run("default account", func() {
+		target, err := service.bootstrapTarget(Cluster{ID: "c1", MasterURL: "https://c1:100", Hostname: "c1.example.com"})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), bootstrap.Target{ClusterID: "c1", MasterURL: "https://c1:100", Hostname: "c1.example.com", Account: ibmcloud.DefaultAccount, APIKey: "default-key"}, target)
+	})
+
+	s.Run("second account", func() {
+		target, err := service.bootstrapTarget(Cluster{ID: "c2", MasterURL: "https://c2:100", Hostname: "c2.example.com", Account: "us"})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), bootstrap.Target{ClusterID: "c2", MasterURL: "https://c2:100", Hostname: "c2.example.com", Account: "us", APIKey: "us-key"}, target)
+	})
+
+	s.Run("unknown account", func() {
+		_, err := service.bootstrapTarget(Cluster{ID: "c3", Account: "apac"})
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+}
+
+func (s *TestServiceSuite) TestValidateConfiguration() {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	config := configuration.New()
+	config.AddValidator(func(c *configuration.Config) error {
+		return ValidateConfiguration(c)
+	})
+
+	s.Run("valid", func() {
+		require.NoError(s.T(), ioutil.WriteFile(path, []byte("ibmcloud:\n  accounts: '[{\"name\": \"us\", \"apikey\": \"k1\", \"account_id\": \"a1\", \"tenant_id\": \"t1\"}]'\n"), 0600))
+		require.NoError(s.T(), config.LoadFile(path))
+		require.NoError(s.T(), ValidateConfiguration(config))
+	})
+
+	s.Run("invalid settings of the service rejected", func() {
+		for _, content := range []string{
+			"ibmcloud:\n  accounts: '[{\"apikey\": \"k1\"}]'\n",
+			"bootstrap:\n  steps: '[{\"name\": \"workshop\"}]'\n",
+			"urls:\n  templates: '[{\"template\": \"https://{{.Hostname}}\"}]'\n",
+			"cost:\n  price_table: '[{\"hourly_price\": 1}]'\n",
+		} {
+			require.NoError(s.T(), ioutil.WriteFile(path, []byte(content), 0600))
+			assert.Error(s.T(), config.LoadFile(path), content)
+		}
+		// The last valid configuration is kept
+		require.NoError(s.T(), ValidateConfiguration(config))
+	})
+}
+
+// accountsConfig is the configuration with the IBM Cloud accounts only
+type accountsConfig struct {
+	Configuration
+	accounts string
+	apiKey   string
+}
+
+func (c *accountsConfig) GetIBMCloudAPIKey() string {
+	return c.apiKey
+}
+
+func (c *accountsConfig) GetIBMCloudAccounts() string {
+	return c.accounts
+}
+
+func (c *accountsConfig) GetIBMCloudAccountID() string {
+	return ""
+}
+
+func (c *accountsConfig) GetIBMCloudTenantID() string {
+	return ""
+}
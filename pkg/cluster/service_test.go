@@ -1,19 +1,26 @@
 package cluster_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/codeready-toolchain/devcluster/pkg/bootstrap"
 	"github.com/codeready-toolchain/devcluster/pkg/cluster"
 	"github.com/codeready-toolchain/devcluster/pkg/configuration"
 	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/export"
 	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/identity"
 	"github.com/codeready-toolchain/devcluster/pkg/mongodb"
+	"github.com/codeready-toolchain/devcluster/pkg/urls"
 	"github.com/codeready-toolchain/devcluster/test"
 	ibmcloudmock "github.com/codeready-toolchain/devcluster/test/ibmcloud"
 
@@ -21,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -103,6 +111,7 @@ func (s *TestIntegrationSuite) TestRequestClusters() {
 	s.Run("timeout", func() {
 		service, mockClient, mockConfig := s.prepareService()
 		mockConfig.timeout = 2 // timeout in 2 seconds
+		s.newUsers(service, 2)
 		request := s.newRequest(service, 2, 100)
 
 		reqWithClusters, err := waitForClustersToFail(service, request)
@@ -138,7 +147,7 @@ func (s *TestIntegrationSuite) TestGetClusters() {
 	// One more in wdc02 is deleted
 	toDelete := prepareProvisionedClusters("wdc02")
 	for _, c := range toDelete {
-		err := service.DeleteCluster(c.ID)
+		err := service.DeleteCluster(c.ID, "admin")
 		require.NoError(s.T(), err)
 	}
 	// One more in wdc02 is still provisioning
@@ -160,6 +169,535 @@ func (s *TestIntegrationSuite) TestGetClusters() {
 	}
 }
 
+func (s *TestIntegrationSuite) TestEnsureIndexes() {
+	// creating the existing indexes again is a no-op
+	require.NoError(s.T(), mongodb.EnsureIndexes(context.Background()))
+	require.NoError(s.T(), mongodb.EnsureIndexes(context.Background()))
+
+	indexedFields := func(collection *mongo.Collection) []string {
+		cursor, err := collection.Indexes().List(context.Background())
+		require.NoError(s.T(), err)
+		var indexes []struct {
+			Key bson.D `bson:"key"`
+		}
+		require.NoError(s.T(), cursor.All(context.Background(), &indexes))
+		fields := make([]string, 0, len(indexes))
+		for _, index := range indexes {
+			fields = append(fields, index.Key[0].Key)
+		}
+		return fields
+	}
+	assert.ElementsMatch(s.T(), []string{"_id", "request_id", "status", "name", "account"}, indexedFields(mongodb.Clusters()))
+	assert.ElementsMatch(s.T(), []string{"_id", "cluster_id", "recycled", "account"}, indexedFields(mongodb.Users()))
+}
+
+func (s *TestIntegrationSuite) TestListing() {
+	service, cl, _ := s.prepareService()
+
+	s.Run("requests", func() {
+		for i, r := range []struct{ zone, requestedBy string }{
+			{"wdc04", "john"}, {"wdc04", "jane"}, {"fra02", "john"}, {"wdc04", "john"}, {"lon06", "john"},
+		} {
+			_, err := mongodb.ClusterRequests().InsertOne(context.Background(), bson.D{
+				{"_id", fmt.Sprintf("list-%d", i)},
+				{"zone", r.zone},
+				{"requested_by", r.requestedBy},
+				{"status", cluster.StatusReady},
+				{"created", int64(1600000000 + i*3600)},
+			})
+			require.NoError(s.T(), err)
+		}
+
+		// all the pages of john's requests, the most recent first
+		var ids []string
+		opts := cluster.ListOptions{RequestedBy: "john", Descending: true, Limit: 2}
+		for {
+			requests, page, err := service.ListRequests(opts)
+			require.NoError(s.T(), err)
+			assert.EqualValues(s.T(), 4, page.Total)
+			for _, r := range requests {
+				ids = append(ids, r.ID)
+			}
+			if page.Next == "" {
+				break
+			}
+			opts.Cursor = page.Next
+		}
+		assert.Equal(s.T(), []string{"list-4", "list-3", "list-2", "list-0"}, ids)
+
+		// filtered by zone and created date
+		requests, page, err := service.ListRequests(cluster.ListOptions{
+			Zone:        "wdc04",
+			CreatedFrom: 1600000000 + 3600,
+			CreatedTo:   1600000000 + 4*3600,
+		})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 2, page.Total)
+		assert.Empty(s.T(), page.Next)
+		require.Len(s.T(), requests, 2)
+		assert.Equal(s.T(), "list-1", requests[0].ID)
+		assert.Equal(s.T(), "list-3", requests[1].ID)
+
+		_, _, err = service.ListRequests(cluster.ListOptions{NamePrefix: "list"})
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("clusters and users", func() {
+		s.newUsers(service, 4)
+		req, r := s.provisionClusters(service, cl, 3, 100)
+		require.NoError(s.T(), service.DeleteCluster(r.Clusters[0].ID, "admin"))
+
+		clusters, page, err := service.ListClusters(cluster.ListOptions{Zone: req.Zone, SortBy: "name", Limit: 1})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 2, page.Total) // the deleted cluster is not listed
+		require.Len(s.T(), clusters, 1)
+		assert.NotEmpty(s.T(), page.Next)
+		assert.NotEmpty(s.T(), clusters[0].User.ID) // the clusters are listed with their users
+
+		clusters, page, err = service.ListClusters(cluster.ListOptions{Zone: req.Zone, SortBy: "name", Limit: 1, Cursor: page.Next})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), clusters, 1)
+		assert.Empty(s.T(), page.Next)
+
+		clusters, _, err = service.ListClusters(cluster.ListOptions{Status: cluster.StatusDeleted, NamePrefix: r.Clusters[0].Name})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), clusters, 1)
+		assert.Equal(s.T(), r.Clusters[0].ID, clusters[0].ID)
+
+		users, page, err := service.ListUsers(cluster.ListOptions{Status: cluster.UserStatusAssigned})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 2, page.Total)
+		for _, u := range users {
+			assert.Equal(s.T(), cluster.UserStatusAssigned, u.PoolStatus)
+		}
+		users, page, err = service.ListUsers(cluster.ListOptions{Status: cluster.UserStatusFree})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 2, page.Total) // one user never assigned and one recycled from the deleted cluster
+		for _, u := range users {
+			assert.Equal(s.T(), cluster.UserStatusFree, u.PoolStatus)
+		}
+	})
+}
+
+func (s *TestIntegrationSuite) TestAccounts() {
+	service, _, mockConfig := s.prepareService()
+	emeaClient := ibmcloudmock.NewMockIBMCloudClient()
+	usClient := ibmcloudmock.NewMockIBMCloudClient()
+	service.Accounts = []cluster.CloudAccount{
+		{
+			Account:  ibmcloud.Account{Name: "emea", Teams: []string{"@redhat.com"}, Zones: []string{"lon06"}, MaxClusters: 1},
+			Client:   emeaClient,
+			Identity: identity.NewCloudDirectoryProvider(mockConfig, emeaClient),
+		},
+		{
+			Account:  ibmcloud.Account{Name: "us"},
+			Client:   usClient,
+			Identity: identity.NewCloudDirectoryProvider(mockConfig, usClient),
+		},
+	}
+	emeaUsers, err := service.CreateUsers("emea", 2)
+	require.NoError(s.T(), err)
+	_, err = service.CreateUsers("us", 2)
+	require.NoError(s.T(), err)
+
+	s.Run("unknown account", func() {
+		_, err := service.CreateUsers("apac", 1)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		_, err = service.CreateNewRequest("jane@redhat.com", 1, "lon06", 100, false, 1, nil, "apac")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("zone not allowed", func() {
+		_, err := service.CreateNewRequest("jane@redhat.com", 1, "sng01", 100, false, 1, nil, "emea")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("routed by team", func() {
+		req, err := service.CreateNewRequest("jane@redhat.com", 1, "lon06", 100, false, 1, nil, "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "emea", req.Account)
+		r, err := waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		c := r.Clusters[0]
+		assert.Equal(s.T(), "emea", c.Account)
+		_, err = emeaClient.GetCluster(c.ID)
+		require.NoError(s.T(), err)
+		_, err = usClient.GetCluster(c.ID)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+		// The user is assigned from the pool of the account
+		u, err := cluster.GetUserByClusterID(c.ID)
+		require.NoError(s.T(), err)
+		assert.Contains(s.T(), []string{emeaUsers[0].ID, emeaUsers[1].ID}, u.ID)
+		assert.Equal(s.T(), "emea", u.Account)
+	})
+
+	s.Run("routed by quota headroom", func() {
+		// The team account is full
+		req, err := service.CreateNewRequest("jane@redhat.com", 1, "lon06", 100, false, 1, nil, "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "us", req.Account)
+		_, err = service.CreateNewRequest("jane@redhat.com", 1, "lon06", 100, false, 1, nil, "emea")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("routed explicitly", func() {
+		req, err := service.CreateNewRequest("johnsmith@domain.com", 1, "sng01", 100, false, 1, nil, "us")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "us", req.Account)
+	})
+
+	s.Run("account kept when ready and when failed to delete", func() {
+		req, err := service.CreateNewRequest("johnsmith@domain.com", 1, "sng01", 100, false, 1, nil, "us")
+		require.NoError(s.T(), err)
+		_, err = waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, usClient, req)
+		r, err := waitForClustersToGetProvisioned(service, req)
+		require.NoError(s.T(), err)
+		c, err := service.GetCluster(r.Clusters[0].ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "us", c.Account)
+
+		// The replaced cluster fails to get deleted
+		_, err = service.CreateUsers("us", 1)
+		require.NoError(s.T(), err)
+		usClient.SetDeleteClusterError(c.ID, errors.New("delete failed"))
+		replacement, err := service.ReplaceCluster(c.ID, "admin")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "us", replacement.Account)
+		failed, err := service.GetCluster(c.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusFailedToDelete, failed.Status)
+		assert.Equal(s.T(), "us", failed.Account)
+
+		// The deletion is retried in the account of the cluster
+		usClient.SetDeleteClusterError(c.ID, nil)
+		require.NoError(s.T(), service.RetryDeleteCluster(c.ID, "admin"))
+		_, err = usClient.GetCluster(c.ID)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+		deleted, err := service.GetCluster(c.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusDeleted, deleted.Status)
+		assert.Equal(s.T(), "us", deleted.Account)
+	})
+
+	s.Run("concurrent requests never exceed account quota", func() {
+		apacClient := ibmcloudmock.NewMockIBMCloudClient()
+		accounts := service.Accounts
+		defer func() {
+			service.Accounts = accounts
+		}()
+		service.Accounts = append([]cluster.CloudAccount{}, accounts...)
+		service.Accounts = append(service.Accounts, cluster.CloudAccount{
+			Account:  ibmcloud.Account{Name: "apac", Zones: []string{"sng01"}, MaxClusters: 2},
+			Client:   apacClient,
+			Identity: identity.NewCloudDirectoryProvider(mockConfig, apacClient),
+		})
+		_, err := service.CreateUsers("apac", 3)
+		require.NoError(s.T(), err)
+		var wg sync.WaitGroup
+		var mux sync.Mutex
+		created := 0
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.CreateNewRequest("johnsmith", 1, "sng01", 100, false, 1, nil, "apac")
+				mux.Lock()
+				defer mux.Unlock()
+				if err != nil {
+					assert.True(s.T(), devclustererr.IsBadRequest(err), err.Error())
+					return
+				}
+				created++
+			}()
+		}
+		wg.Wait()
+		assert.Equal(s.T(), 2, created)
+	})
+
+	s.Run("zones of all accounts", func() {
+		zones, err := service.GetZones()
+		require.NoError(s.T(), err)
+		ids := make([]string, 0, len(zones))
+		for _, z := range zones {
+			ids = append(ids, z.ID)
+		}
+		assert.ElementsMatch(s.T(), []string{"lon06", "sng01"}, ids)
+	})
+}
+
+func (s *TestIntegrationSuite) TestZones() {
+	service, _, _ := s.prepareService()
+
+	s.Run("not managed", func() {
+		zones, err := service.AvailableZones()
+		require.NoError(s.T(), err)
+		require.Len(s.T(), zones, 2)
+		assert.True(s.T(), zones[0].Available)
+		assert.True(s.T(), zones[1].Available)
+		_, err = service.DefaultZone()
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	require.NoError(s.T(), cluster.SeedZones([]string{"lon06", "wdc04"}))
+	// The zones are seeded only if none are stored
+	require.NoError(s.T(), cluster.SeedZones([]string{"sng01"}))
+	zones, err := service.Zones()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []cluster.Zone{{ID: "lon06", Enabled: true, Default: true}, {ID: "wdc04", Enabled: true}}, zones)
+
+	s.Run("default zone", func() {
+		zone, err := service.DefaultZone()
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "lon06", zone)
+
+		require.NoError(s.T(), service.SaveZone(cluster.Zone{ID: "wdc04", Enabled: true, Default: true}))
+		zone, err = service.DefaultZone()
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "wdc04", zone)
+		zones, err := service.Zones()
+		require.NoError(s.T(), err)
+		assert.False(s.T(), zones[0].Default)
+
+		assert.True(s.T(), devclustererr.IsBadRequest(service.SaveZone(cluster.Zone{ID: "sng01", Default: true})))
+		assert.True(s.T(), devclustererr.IsBadRequest(service.SaveZone(cluster.Zone{ID: "sng01", Enabled: true, MaxClusters: -1})))
+		assert.True(s.T(), devclustererr.IsBadRequest(service.SaveZone(cluster.Zone{Enabled: true})))
+	})
+
+	s.Run("zone not enabled", func() {
+		_, err := service.CreateNewRequest("johnsmith", 1, "sng01", 100, false, 1, nil, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		require.NoError(s.T(), service.SaveZone(cluster.Zone{ID: "sng01"}))
+		_, err = service.CreateNewRequest("johnsmith", 1, "sng01", 100, false, 1, nil, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+
+		// Only the enabled zones available in IBM Cloud are listed
+		zones, err := service.AvailableZones()
+		require.NoError(s.T(), err)
+		require.Len(s.T(), zones, 1)
+		assert.Equal(s.T(), "lon06", zones[0].ID)
+	})
+
+	s.Run("capacity", func() {
+		_, err := service.CreateUsers("", 2)
+		require.NoError(s.T(), err)
+		require.NoError(s.T(), service.SaveZone(cluster.Zone{ID: "lon06", Enabled: true, MaxClusters: 2}))
+		_, err = service.CreateNewRequest("johnsmith", 3, "lon06", 100, false, 1, nil, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+
+		req, err := service.CreateNewRequest("johnsmith", 2, "lon06", 100, false, 1, nil, "")
+		require.NoError(s.T(), err)
+		_, err = waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+
+		zones, err := service.AvailableZones()
+		require.NoError(s.T(), err)
+		require.Len(s.T(), zones, 1)
+		assert.Equal(s.T(), 2, zones[0].MaxClusters)
+		assert.Equal(s.T(), 2, zones[0].ActiveClusters)
+		assert.False(s.T(), zones[0].Available)
+		_, err = service.CreateNewRequest("johnsmith", 1, "lon06", 100, false, 1, nil, "")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+
+		// The reservations are released once the clusters are stored
+		err = wait.Poll(retryInterval, timeout, func() (bool, error) {
+			zones, err := service.Zones()
+			if err != nil {
+				return false, err
+			}
+			return zones[0].ID == "lon06" && zones[0].Reserved == 0, nil
+		})
+		require.NoError(s.T(), err)
+	})
+
+	s.Run("concurrent requests never exceed capacity", func() {
+		_, err := service.CreateUsers("", 3)
+		require.NoError(s.T(), err)
+		require.NoError(s.T(), service.SaveZone(cluster.Zone{ID: "sng01", Enabled: true, MaxClusters: 2}))
+		var wg sync.WaitGroup
+		var mux sync.Mutex
+		created := 0
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.CreateNewRequest("johnsmith", 1, "sng01", 100, false, 1, nil, "")
+				mux.Lock()
+				defer mux.Unlock()
+				if err != nil {
+					assert.True(s.T(), devclustererr.IsBadRequest(err), err.Error())
+					return
+				}
+				created++
+			}()
+		}
+		wg.Wait()
+		assert.Equal(s.T(), 2, created)
+	})
+
+	s.Run("reservations leaked by a crash are cleared on resume", func() {
+		// The process died before the reserved clusters were stored
+		_, err := mongodb.Zones().UpdateOne(context.Background(), bson.D{{"_id", "wdc04"}}, bson.D{{"$inc", bson.D{{"reserved", 2}}}})
+		require.NoError(s.T(), err)
+		_, err = mongodb.Accounts().UpdateOne(context.Background(), bson.D{{"_id", ibmcloud.DefaultAccount}}, bson.D{{"$inc", bson.D{{"reserved", 2}}}})
+		require.NoError(s.T(), err)
+		// Wait for the clusters of the previous requests to be stored so only the leaked reservations are left
+		err = wait.Poll(retryInterval, timeout, func() (bool, error) {
+			zones, err := service.Zones()
+			if err != nil {
+				return false, err
+			}
+			for _, z := range zones {
+				if z.ID != "wdc04" && z.Reserved != 0 {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		require.NoError(s.T(), err)
+
+		require.NoError(s.T(), service.ResumeProvisioningRequests())
+
+		zones, err := service.Zones()
+		require.NoError(s.T(), err)
+		for _, z := range zones {
+			assert.Equal(s.T(), 0, z.Reserved, z.ID)
+		}
+		var account bson.M
+		require.NoError(s.T(), mongodb.Accounts().FindOne(context.Background(), bson.D{{"_id", ibmcloud.DefaultAccount}}).Decode(&account))
+		assert.EqualValues(s.T(), 0, account["reserved"])
+	})
+
+	s.Run("delete", func() {
+		require.NoError(s.T(), service.DeleteZone("sng01"))
+		assert.True(s.T(), devclustererr.IsNotFound(service.DeleteZone("sng01")))
+	})
+}
+
+func (s *TestIntegrationSuite) TestBackupRestore() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 3)
+	_, reqWithClusters := s.provisionClusters(service, cl, 2, 100)
+
+	var buf bytes.Buffer
+	require.NoError(s.T(), cluster.Backup(&buf, "secret"))
+
+	// Lose the DB and one of the clusters in IBM Cloud
+	for _, collection := range []*mongo.Collection{mongodb.ClusterRequests(), mongodb.Clusters(), mongodb.Users()} {
+		_, err := collection.DeleteMany(context.Background(), bson.D{})
+		require.NoError(s.T(), err)
+	}
+	missing := reqWithClusters.Clusters[0]
+	require.NoError(s.T(), cl.DeleteCluster(missing.ID))
+
+	s.Run("wrong passphrase", func() {
+		_, err := service.Restore(bytes.NewReader(buf.Bytes()), "wrong")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		requests, _, err := service.ListRequests(cluster.ListOptions{})
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), requests)
+	})
+
+	s.Run("restored and reconciled", func() {
+		report, err := service.Restore(bytes.NewReader(buf.Bytes()), "secret")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), 1, report.Requests)
+		assert.Equal(s.T(), 2, report.Clusters)
+		assert.Equal(s.T(), 3, report.Users)
+		assert.Equal(s.T(), []string{missing.ID}, report.Drift.MissingClusters)
+
+		r, err := service.GetRequestWithClusters(reqWithClusters.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), reqWithClusters.RequestedBy, r.RequestedBy)
+		c, err := service.GetCluster(missing.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusDeleted, c.Status)
+		c, err = service.GetCluster(reqWithClusters.Clusters[1].ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), reqWithClusters.Clusters[1].User.ID, c.User.ID)
+	})
+}
+
+func (s *TestIntegrationSuite) TestArchive() {
+	service, _, config := s.prepareService()
+	old := time.Now().Add(-40 * 24 * time.Hour).Unix()
+	recent := time.Now().Add(-24 * time.Hour).Unix()
+	insertRequest := func(id, status string, created int64, clusterStatuses ...string) {
+		_, err := mongodb.ClusterRequests().InsertOne(context.Background(), bson.D{
+			{"_id", id},
+			{"status", status},
+			{"requested_by", "john"},
+			{"zone", "wdc04"},
+			{"created", created},
+			{"delete_in_hours", 2},
+		})
+		require.NoError(s.T(), err)
+		for i, status := range clusterStatuses {
+			_, err := mongodb.Clusters().InsertOne(context.Background(), bson.D{
+				{"_id", fmt.Sprintf("%s-cluster-%d", id, i)},
+				{"request_id", id},
+				{"status", status},
+				{"created", created},
+				{"deleted", created + 3600},
+			})
+			require.NoError(s.T(), err)
+		}
+	}
+	insertRequest("old-expired", cluster.StatusExpired, old, cluster.StatusDeleted, cluster.StatusDeleted)
+	insertRequest("old-failed", cluster.StatusFailed, old, cluster.StatusFailed)
+	insertRequest("old-failed-to-delete", cluster.StatusExpired, old, cluster.StatusDeleted, cluster.StatusFailedToDelete)
+	insertRequest("old-ready", cluster.StatusReady, old, cluster.StatusNormal)
+	insertRequest("recent-expired", cluster.StatusExpired, recent, cluster.StatusDeleted)
+
+	s.Run("archival disabled", func() {
+		archived, err := service.Archive()
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), archived)
+	})
+
+	s.Run("old requests in terminal states are archived", func() {
+		config.retentionDays = 30
+		usageBefore, err := service.UsageReport([]string{cluster.GroupByRequest}, "", "")
+		require.NoError(s.T(), err)
+
+		archived, err := service.Archive()
+		require.NoError(s.T(), err)
+		assert.ElementsMatch(s.T(), []string{"old-expired", "old-failed"}, archived)
+
+		// archiving again does nothing
+		archived, err = service.Archive()
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), archived)
+
+		requests, _, err := service.ListRequests(cluster.ListOptions{SortBy: "id"})
+		require.NoError(s.T(), err)
+		ids := make([]string, 0, len(requests))
+		for _, r := range requests {
+			ids = append(ids, r.ID)
+		}
+		assert.Equal(s.T(), []string{"old-failed-to-delete", "old-ready", "recent-expired"}, ids)
+		c, err := service.GetCluster("old-expired-cluster-0")
+		require.NoError(s.T(), err)
+		assert.Nil(s.T(), c)
+
+		archive, page, err := service.ListArchive(cluster.ListOptions{Status: cluster.StatusExpired})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 1, page.Total)
+		require.Len(s.T(), archive, 1)
+		assert.Equal(s.T(), "old-expired", archive[0].ID)
+		assert.Equal(s.T(), "john", archive[0].RequestedBy)
+		assert.NotZero(s.T(), archive[0].Archived)
+		assert.Len(s.T(), archive[0].Clusters, 2)
+
+		// the archived clusters are still reported
+		usageAfter, err := service.UsageReport([]string{cluster.GroupByRequest}, "", "")
+		require.NoError(s.T(), err)
+		assert.ElementsMatch(s.T(), usageBefore.Rows, usageAfter.Rows)
+	})
+}
+
 func (s *TestIntegrationSuite) TestGetZones() {
 	service, _, _ := s.prepareService()
 	s.Run("get zones OK", func() {
@@ -170,38 +708,700 @@ func (s *TestIntegrationSuite) TestGetZones() {
 		assert.NotEmpty(s.T(), zones)
 		assert.Equal(s.T(), expected, zones)
 	})
-}
+}
+
+func (s *TestIntegrationSuite) TestDeleteCluster() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 10)
+	s.Run("delete cluster OK", func() {
+		// Provision some clusters
+		req, reqWithClusters := s.provisionClusters(service, cl, 3, 100)
+
+		// Now delete one
+		toDelete := reqWithClusters.Clusters[1]
+		err := service.DeleteCluster(toDelete.ID, "admin")
+		require.NoError(s.T(), err)
+
+		// Check the deleted cluster
+		result, err := service.GetRequestWithClusters(req.ID)
+		require.NoError(s.T(), err)
+		assertClusterEquals(s.T(), cluster.Cluster{
+			ID:        toDelete.ID,
+			RequestID: req.ID,
+			Name:      toDelete.Name,
+			Hostname:  toDelete.Hostname,
+			MasterURL: toDelete.MasterURL,
+			Status:    "deleted",
+			Error:     "",
+		}, result.Clusters[1])
+
+		// Check the cluster was deleted in ibm cloud
+		_, err = service.IbmCloudClient.GetCluster(toDelete.ID)
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+}
+
+func (s *TestIntegrationSuite) TestMultipleUsersPerCluster() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 5)
+
+	s.Run("invalid number of users per cluster", func() {
+		_, err := service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, 0, nil, "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+
+		_, err = service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, cluster.MaxUsersPerCluster+1, nil, "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("users assigned and recycled", func() {
+		req, err := service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, 3, nil, "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), 3, req.UsersPerCluster)
+		_, err = waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, cl, req)
+		r, err := waitForRequest(service, req, requestReady, clustersReady)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), r.Clusters, 1)
+		c := r.Clusters[0]
+
+		// Every user gets its own project and workshop URL
+		require.Len(s.T(), c.Users, 3)
+		assert.Equal(s.T(), c.Users[0].User, c.User)
+		assert.Equal(s.T(), c.Users[0].WorkshopURL, c.WorkshopURL)
+		for _, u := range c.Users {
+			assert.Equal(s.T(), c.ID, u.ClusterID)
+			assert.NotEmpty(s.T(), u.PolicyID)
+			assert.Equal(s.T(), fmt.Sprintf("workshop-%s", u.ID), u.Project)
+			assert.Contains(s.T(), u.WorkshopURL, fmt.Sprintf("USERNAME=%s&", u.ID))
+			assert.Contains(s.T(), u.WorkshopURL, fmt.Sprintf("PROJECT=workshop-%s", u.ID))
+		}
+
+		// All the users are returned to the pool when the cluster is deleted
+		require.NoError(s.T(), service.DeleteCluster(c.ID, "admin"))
+		users, err := service.Users()
+		require.NoError(s.T(), err)
+		for _, u := range users {
+			assert.Empty(s.T(), u.ClusterID)
+			assert.Empty(s.T(), u.PolicyID)
+		}
+	})
+}
+
+func (s *TestIntegrationSuite) TestHandouts() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 5)
+
+	s.Run("unknown request", func() {
+		_, err := service.Handouts("unknown")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+
+	s.Run("one handout per user", func() {
+		req, err := service.CreateNewRequest("johnsmith@domain.com", 2, "lon06", 100, false, 2, nil, "")
+		require.NoError(s.T(), err)
+		_, err = waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, cl, req)
+		r, err := waitForRequest(service, req, requestReady)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), r.Clusters, 2)
+
+		// The deleted cluster is skipped
+		require.NoError(s.T(), service.DeleteCluster(r.Clusters[1].ID, "admin"))
+
+		handouts, err := service.Handouts(req.ID)
+		require.NoError(s.T(), err)
+		c := r.Clusters[0]
+		require.Len(s.T(), c.Users, 2)
+		expected := make([]export.Handout, 0, 2)
+		for _, u := range c.Users {
+			expected = append(expected, export.Handout{
+				ClusterName: c.Name,
+				ConsoleURL:  c.ConsoleURL,
+				LoginURL:    c.LoginURL,
+				Username:    u.ID,
+				Password:    u.Password,
+				WorkshopURL: u.WorkshopURL,
+			})
+		}
+		assert.Equal(s.T(), expected, handouts)
+	})
+}
+
+func (s *TestIntegrationSuite) TestUsageReport() {
+	service, cl, config := s.prepareService()
+	config.priceTable = `[{"machine_type": "b3c.4x16", "hourly_price": 0.5}]`
+	s.newUsers(service, 2)
+
+	s.Run("invalid params", func() {
+		_, err := service.UsageReport([]string{"zone", "team"}, "", "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		_, err = service.UsageReport(nil, "2021-13", "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("usage of provisioned and deleted clusters", func() {
+		started := time.Now().Unix()
+		req, r := s.provisionClusters(service, cl, 2, 100)
+		require.Len(s.T(), r.Clusters, 2)
+		for _, c := range r.Clusters {
+			assert.Equal(s.T(), ibmcloud.MachineType, c.MachineType)
+			assert.Equal(s.T(), ibmcloud.WorkerCount, c.WorkerCount)
+			assert.GreaterOrEqual(s.T(), c.Created, started)
+			assert.GreaterOrEqual(s.T(), c.Ready, c.Created)
+			assert.Empty(s.T(), c.Deleted)
+		}
+		require.NoError(s.T(), service.DeleteCluster(r.Clusters[1].ID, "admin"))
+		deleted, err := service.GetCluster(r.Clusters[1].ID)
+		require.NoError(s.T(), err)
+		assert.GreaterOrEqual(s.T(), deleted.Deleted, deleted.Ready)
+
+		report, err := service.UsageReport(nil, "", "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.DefaultUsageGroupBy, report.GroupBy)
+		assert.Equal(s.T(), "USD", report.Currency)
+		require.Len(s.T(), report.Rows, 1)
+		row := report.Rows[0]
+		assert.Equal(s.T(), req.RequestedBy, row.RequestedBy)
+		assert.Equal(s.T(), req.Zone, row.Zone)
+		assert.Equal(s.T(), time.Now().UTC().Format("2006-01"), row.Month)
+		assert.Empty(s.T(), row.RequestID)
+		assert.Equal(s.T(), 2, row.Clusters)
+		// Two workers per cluster for 0.5 per worker-hour
+		assert.InDelta(s.T(), row.ClusterHours, row.EstimatedCost, 0.01)
+		assert.Empty(s.T(), row.UnpricedHours)
+
+		s.Run("grouped by request", func() {
+			report, err := service.UsageReport([]string{cluster.GroupByRequest}, "", "")
+			require.NoError(s.T(), err)
+			require.Len(s.T(), report.Rows, 1)
+			r := report.Rows[0]
+			assert.Equal(s.T(), req.ID, r.RequestID)
+			assert.Empty(s.T(), r.RequestedBy)
+			assert.Empty(s.T(), r.Zone)
+			assert.Empty(s.T(), r.Month)
+			assert.Equal(s.T(), 2, r.Clusters)
+			assert.InDelta(s.T(), row.ClusterHours, r.ClusterHours, 0.02)
+		})
+
+		s.Run("out of the months", func() {
+			report, err := service.UsageReport(nil, time.Now().AddDate(0, 2, 0).Format("2006-01"), "")
+			require.NoError(s.T(), err)
+			assert.Empty(s.T(), report.Rows)
+		})
+
+		s.Run("no price", func() {
+			config.priceTable = `[{"machine_type": "b3c.8x32", "hourly_price": 0.5}]`
+			defer func() { config.priceTable = `[{"machine_type": "b3c.4x16", "hourly_price": 0.5}]` }()
+			report, err := service.UsageReport(nil, "", "")
+			require.NoError(s.T(), err)
+			require.Len(s.T(), report.Rows, 1)
+			assert.Empty(s.T(), report.Rows[0].EstimatedCost)
+			assert.Equal(s.T(), report.Rows[0].ClusterHours, report.Rows[0].UnpricedHours)
+		})
+
+		s.Run("csv", func() {
+			buf := &bytes.Buffer{}
+			require.NoError(s.T(), report.WriteCSV(buf))
+			assert.Equal(s.T(), fmt.Sprintf("Requested By,Zone,Month,Clusters,Cluster Hours,Estimated Cost (USD),Unpriced Hours\n%s,%s,%s,2,%.2f,%.2f,0.00\n",
+				row.RequestedBy, row.Zone, row.Month, row.ClusterHours, row.EstimatedCost), buf.String())
+		})
+	})
+}
+
+func (s *TestIntegrationSuite) TestEvents() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 1)
+
+	s.Run("unknown cluster and request", func() {
+		_, err := service.ClusterEvents("unknown")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+		_, err = service.RequestEvents("unknown")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+
+	s.Run("status changes are recorded", func() {
+		req, r := s.provisionClusters(service, cl, 1, 100)
+		_, err := waitForRequest(service, req, requestReady)
+		require.NoError(s.T(), err)
+		c := r.Clusters[0]
+		require.NoError(s.T(), service.DeleteCluster(c.ID, "admin"))
+
+		events, err := service.ClusterEvents(c.ID)
+		require.NoError(s.T(), err)
+		require.True(s.T(), len(events) >= 3)
+		// The events are chained: every event starts in the status the previous one ended with
+		previous := ""
+		for _, e := range events {
+			assert.Equal(s.T(), cluster.EventObjectCluster, e.Object)
+			assert.Equal(s.T(), c.ID, e.ObjectID)
+			assert.Equal(s.T(), req.ID, e.RequestID)
+			assert.Equal(s.T(), cluster.EventFieldStatus, e.Field)
+			assert.Equal(s.T(), previous, e.OldStatus)
+			assert.NotEmpty(s.T(), e.Timestamp)
+			previous = e.NewStatus
+		}
+		assert.Equal(s.T(), cluster.StatusProvisioning, events[0].NewStatus)
+		assert.Equal(s.T(), cluster.ActorSystem, events[0].Actor)
+		last := events[len(events)-1]
+		assert.Equal(s.T(), "normal", last.OldStatus)
+		assert.Equal(s.T(), cluster.StatusDeleted, last.NewStatus)
+		assert.Equal(s.T(), "admin", last.Actor)
+
+		reqEvents, err := service.RequestEvents(req.ID)
+		require.NoError(s.T(), err)
+		// The request events include the events of its clusters
+		assert.Len(s.T(), reqEvents, len(events)+2)
+		requestEvents := make([]cluster.Event, 0, 2)
+		for _, e := range reqEvents {
+			if e.Object == cluster.EventObjectRequest {
+				requestEvents = append(requestEvents, e)
+			}
+		}
+		require.Len(s.T(), requestEvents, 2)
+		assert.Empty(s.T(), requestEvents[0].OldStatus)
+		assert.Equal(s.T(), cluster.StatusProvisioning, requestEvents[0].NewStatus)
+		assert.Equal(s.T(), req.RequestedBy, requestEvents[0].Actor)
+		assert.Equal(s.T(), cluster.StatusProvisioning, requestEvents[1].OldStatus)
+		assert.Equal(s.T(), cluster.StatusReady, requestEvents[1].NewStatus)
+		assert.Equal(s.T(), cluster.ActorSystem, requestEvents[1].Actor)
+	})
+}
+
+func (s *TestIntegrationSuite) TestMigrations() {
+	// documents stored before the schema was versioned
+	_, err := mongodb.ClusterRequests().InsertOne(context.Background(), bson.D{
+		{"_id", "legacy-request"},
+		{"status", cluster.StatusReady},
+		{"requested", int32(1)},
+	})
+	require.NoError(s.T(), err)
+	_, err = mongodb.Clusters().InsertOne(context.Background(), bson.D{
+		{"_id", "legacy-cluster"},
+		{"request_id", "legacy-request"},
+		{"status", cluster.StatusDeleted},
+	})
+	require.NoError(s.T(), err)
+	_, err = mongodb.Users().InsertOne(context.Background(), bson.D{
+		{"_id", "legacy-user"},
+		{"email", "legacy@redhat.com"},
+	})
+	require.NoError(s.T(), err)
+
+	// run twice to check that the applied migrations are skipped
+	require.NoError(s.T(), cluster.RunMigrations())
+	require.NoError(s.T(), cluster.RunMigrations())
+
+	var req bson.M
+	require.NoError(s.T(), mongodb.ClusterRequests().FindOne(context.Background(), bson.D{{"_id", "legacy-request"}}).Decode(&req))
+	assert.EqualValues(s.T(), 1, req["schema_version"])
+	assert.EqualValues(s.T(), 1, req["users_per_cluster"])
+	assert.Equal(s.T(), bson.A{}, req["url_templates"])
+	assert.Equal(s.T(), "", req["zone"])
+
+	var c bson.M
+	require.NoError(s.T(), mongodb.Clusters().FindOne(context.Background(), bson.D{{"_id", "legacy-cluster"}}).Decode(&c))
+	assert.EqualValues(s.T(), 1, c["schema_version"])
+	assert.Equal(s.T(), bson.A{}, c["bootstrap_steps"])
+	assert.EqualValues(s.T(), 0, c["deleted"])
+
+	// the free user filter matches the legacy user now that it has an empty cluster ID and no reservation
+	var u bson.M
+	require.NoError(s.T(), mongodb.Users().FindOne(context.Background(), bson.D{{"_id", "legacy-user"}, {"cluster_id", ""}, {"reserved_for", ""}}).Decode(&u))
+	assert.EqualValues(s.T(), 1, u["schema_version"])
+
+	// the legacy documents belong to the default account
+	defaultAccount := bson.D{{"account", bson.D{{"$in", bson.A{"", ibmcloud.DefaultAccount}}}}}
+	for _, collection := range []*mongo.Collection{mongodb.ClusterRequests(), mongodb.Clusters(), mongodb.Users()} {
+		n, err := collection.CountDocuments(context.Background(), append(bson.D{{"_id", bson.D{{"$regex", "^legacy-"}}}}, defaultAccount...))
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 1, n, collection.Name())
+	}
+
+	applied, err := mongodb.Migrations().CountDocuments(context.Background(), bson.D{})
+	require.NoError(s.T(), err)
+	assert.EqualValues(s.T(), 3, applied)
+
+	// the documents written by the service have the current schema version
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 1)
+	req2, _ := s.provisionClusters(service, cl, 1, 100)
+	var stored bson.M
+	require.NoError(s.T(), mongodb.ClusterRequests().FindOne(context.Background(), bson.D{{"_id", req2.ID}}).Decode(&stored))
+	assert.EqualValues(s.T(), cluster.SchemaVersion, stored["schema_version"])
+}
+
+func (s *TestIntegrationSuite) TestURLTemplates() {
+	service, cl, config := s.prepareService()
+	config.urlTemplates = `[
+		{"name": "console", "template": "https://console.{{ .Cluster.Hostname }}"},
+		{"name": "status", "template": "https://status/{{ .Cluster.ID }}"},
+		{"name": "guide", "scope": "user", "template": "https://guide?user={{ .User.ID }}&console={{ index .URLs \"console\" | urlquery }}"}
+	]`
+	config.defaultTemplates = []string{"status"}
+	s.newUsers(service, 2)
+
+	s.Run("unknown template", func() {
+		_, err := service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, 1, []string{"console", "unknown"}, "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	provision := func(templates []string) cluster.Cluster {
+		req, err := service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, 1, templates, "")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), templates, req.URLTemplates)
+		_, err = waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, cl, req)
+		r, err := waitForRequest(service, req, requestReady)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), r.Clusters, 1)
+		return r.Clusters[0]
+	}
+
+	s.Run("selected templates", func() {
+		c := provision([]string{"console", "guide"})
+		assert.Equal(s.T(), []urls.URL{{Name: "console", URL: "https://console." + c.Hostname}}, c.URLs)
+		assert.Equal(s.T(), "https://console."+c.Hostname, c.ConsoleURL)
+		assert.Empty(s.T(), c.LoginURL)
+		require.Len(s.T(), c.Users, 1)
+		assert.Equal(s.T(), []urls.URL{{Name: "guide", URL: fmt.Sprintf("https://guide?user=%s&console=%s", c.User.ID, url.QueryEscape("https://console."+c.Hostname))}}, c.Users[0].URLs)
+		assert.Empty(s.T(), c.WorkshopURL)
+	})
+
+	s.Run("default templates", func() {
+		c := provision(nil)
+		assert.Equal(s.T(), []urls.URL{{Name: "status", URL: "https://status/" + c.ID}}, c.URLs)
+		assert.Empty(s.T(), c.ConsoleURL)
+		require.Len(s.T(), c.Users, 1)
+		assert.Empty(s.T(), c.Users[0].URLs)
+	})
+
+	s.Run("templates returned", func() {
+		templates, err := service.URLTemplates()
+		require.NoError(s.T(), err)
+		require.Len(s.T(), templates, 3)
+		assert.Equal(s.T(), "guide", templates[2].Name)
+		assert.Equal(s.T(), urls.ScopeUser, templates[2].Scope)
+	})
+}
+
+func (s *TestIntegrationSuite) TestBootstrap() {
+	service, cl, config := s.prepareService()
+	notifier := &mockNotifier{}
+	service.Notifier = notifier
+	runner := &mockBootstrapRunner{failures: map[string]error{"operators": errors.New("operator hub unavailable")}}
+	service.Bootstrap = runner
+	config.bootstrapSteps = `[{"name": "workshop", "manifests": "/etc/workshop"}, {"name": "operators", "command": ["install.sh"]}]`
+	s.newUsers(service, 2)
+
+	req := s.newRequest(service, 1, 100)
+	_, err := waitForClustersToStartProvisioning(service, req)
+	require.NoError(s.T(), err)
+	s.markClustersAsProvisioned(service, cl, req)
+
+	bootstrapStatus := func(status string) RequestCriterion {
+		return func(r *cluster.RequestWithClusters) (bool, error) {
+			return len(r.Clusters) == 1 && r.Clusters[0].BootstrapStatus == status, nil
+		}
+	}
+
+	s.Run("failed step", func() {
+		r, err := waitForRequest(service, req, bootstrapStatus(cluster.BootstrapFailed))
+		require.NoError(s.T(), err)
+		c := r.Clusters[0]
+		assert.Equal(s.T(), "provisioning", r.Status)
+		assert.Equal(s.T(), "operator hub unavailable", c.Error)
+		require.Len(s.T(), c.BootstrapSteps, 2)
+		assert.Equal(s.T(), cluster.BootstrapSucceeded, c.BootstrapSteps[0].Status)
+		assert.Equal(s.T(), "workshop done", c.BootstrapSteps[0].Logs)
+		assert.Equal(s.T(), cluster.BootstrapFailed, c.BootstrapSteps[1].Status)
+		assert.Equal(s.T(), "operators failed", c.BootstrapSteps[1].Logs)
+		assert.Equal(s.T(), "operator hub unavailable", c.BootstrapSteps[1].Error)
+		assert.NotEmpty(s.T(), c.BootstrapSteps[1].Finished)
+		assert.Equal(s.T(), []string{"Unable to bootstrap cluster for request " + req.ID}, notifier.subjects)
+	})
+
+	s.Run("retry unknown cluster", func() {
+		err := service.RetryBootstrap("unknown", "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+
+	s.Run("retry succeeds", func() {
+		runner.setFailure("operators", nil)
+		r, err := service.GetRequestWithClusters(req.ID)
+		require.NoError(s.T(), err)
+		require.NoError(s.T(), service.RetryBootstrap(r.Clusters[0].ID, "admin"))
+
+		r2, err := waitForRequest(service, req, requestReady, clustersReady, bootstrapStatus(cluster.BootstrapSucceeded))
+		require.NoError(s.T(), err)
+		c := r2.Clusters[0]
+		assert.Empty(s.T(), c.Error)
+		for _, step := range c.BootstrapSteps {
+			assert.Equal(s.T(), cluster.BootstrapSucceeded, step.Status)
+		}
+
+		// The bootstrap can't be retried for the bootstrapped cluster
+		err = service.RetryBootstrap(c.ID, "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		assert.Equal(s.T(), s.Config.GetIBMCloudAPIKey(), runner.apiKey(c.ID))
+	})
+
+	s.Run("second account", func() {
+		usClient := ibmcloudmock.NewMockIBMCloudClient()
+		service.Accounts = []cluster.CloudAccount{
+			{
+				Account:  ibmcloud.Account{Name: ibmcloud.DefaultAccount},
+				Client:   cl,
+				Identity: service.Identity,
+			},
+			{
+				Account:  ibmcloud.Account{Name: "us", APIKey: "us-api-key"},
+				Client:   usClient,
+				Identity: identity.NewCloudDirectoryProvider(config, usClient),
+			},
+		}
+		_, err := service.CreateUsers("us", 1)
+		require.NoError(s.T(), err)
+		usReq, err := service.CreateNewRequest("johnsmith@domain.com", 1, "lon06", 100, false, 1, nil, "us")
+		require.NoError(s.T(), err)
+		_, err = waitForClustersToStartProvisioning(service, usReq)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, usClient, usReq)
+
+		// The cluster is logged in with the API key of its account
+		r, err := waitForRequest(service, usReq, requestReady, clustersReady, bootstrapStatus(cluster.BootstrapSucceeded))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "us", r.Clusters[0].Account)
+		assert.Equal(s.T(), "us-api-key", runner.apiKey(r.Clusters[0].ID))
+	})
+}
+
+func (s *TestIntegrationSuite) TestRetryDeleteCluster() {
+	service, cl, config := s.prepareService()
+	notifier := &mockNotifier{}
+	service.Notifier = notifier
+	config.deleteMaxAttempts = 2
+	s.newUsers(service, 10)
+	_, reqWithClusters := s.provisionClusters(service, cl, 2, 100)
+	toDelete := reqWithClusters.Clusters[0]
+
+	s.Run("unknown cluster", func() {
+		err := service.RetryDeleteCluster("unknown", "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+
+	s.Run("cluster not failed to delete", func() {
+		err := service.RetryDeleteCluster(toDelete.ID, "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("retry fails and escalates", func() {
+		cl.SetDeleteClusterError(toDelete.ID, errors.New("delete failed"))
+		defer cl.SetDeleteClusterError(toDelete.ID, nil)
+		// The cluster has already failed to get deleted once
+		_, err := mongodb.Clusters().UpdateOne(
+			context.Background(),
+			bson.D{{"_id", toDelete.ID}},
+			bson.D{{"$set", bson.D{{"status", cluster.StatusFailedToDelete}}}},
+		)
+		require.NoError(s.T(), err)
+
+		// First attempt fails and the next one is scheduled
+		err = service.RetryDeleteCluster(toDelete.ID, "admin")
+		require.Error(s.T(), err)
+		c, err := service.GetCluster(toDelete.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusFailedToDelete, c.Status)
+		assert.Equal(s.T(), "delete failed", c.Error)
+		assert.Equal(s.T(), 1, c.DeleteAttempts)
+		assert.NotZero(s.T(), c.NextDeleteAttempt)
+		assert.Empty(s.T(), notifier.subjects)
+
+		// The max number of attempts is reached so the admins are notified
+		config.deleteMaxAttempts = 1
+		defer func() { config.deleteMaxAttempts = 2 }()
+		err = service.RetryDeleteCluster(toDelete.ID, "admin")
+		require.Error(s.T(), err)
+		c, err = service.GetCluster(toDelete.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusFailedToDelete, c.Status)
+		assert.Equal(s.T(), 1, c.DeleteAttempts)
+		assert.Zero(s.T(), c.NextDeleteAttempt)
+		assert.Equal(s.T(), []string{fmt.Sprintf("Unable to delete cluster %s", toDelete.Name)}, notifier.subjects)
+	})
+
+	s.Run("retry OK", func() {
+		err := service.RetryDeleteCluster(toDelete.ID, "admin")
+		require.NoError(s.T(), err)
+		c, err := service.GetCluster(toDelete.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusDeleted, c.Status)
+		assert.Empty(s.T(), c.Error)
+		assert.Equal(s.T(), 0, c.DeleteAttempts)
+	})
+
+	s.Run("delete cluster already gone from IBM Cloud", func() {
+		gone := reqWithClusters.Clusters[1]
+		require.NoError(s.T(), cl.DeleteCluster(gone.ID))
+		err := service.DeleteCluster(gone.ID, "admin")
+		require.NoError(s.T(), err)
+		c, err := service.GetCluster(gone.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusDeleted, c.Status)
+	})
+}
+
+func (s *TestIntegrationSuite) TestReplaceCluster() {
+	service, cl, config := s.prepareService()
+	notifier := &mockNotifier{}
+	service.Notifier = notifier
+	s.newUsers(service, 10)
+
+	s.Run("unknown cluster", func() {
+		_, err := service.ReplaceCluster("unknown", "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsNotFound(err))
+	})
+
+	s.Run("replace cluster OK", func() {
+		req, reqWithClusters := s.provisionClusters(service, cl, 2, 100)
+		toReplace := reqWithClusters.Clusters[0]
+
+		replacement, err := service.ReplaceCluster(toReplace.ID, "admin")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), req.ID, replacement.RequestID)
+		assert.Equal(s.T(), 1, replacement.ReplaceAttempt)
+
+		// The replaced cluster is deleted
+		replaced, err := service.GetCluster(toReplace.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusDeleted, replaced.Status)
+		assert.Equal(s.T(), replacement.ID, replaced.ReplacedBy)
+
+		// The request gets ready again when the replacement is provisioned
+		r, err := service.GetRequestWithClusters(req.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.StatusProvisioning, r.Status)
+		require.NoError(s.T(), cl.UpdateCluster(ibmcloud.Cluster{
+			ID:        replacement.ID,
+			State:     "normal",
+			Ingress:   ibmcloud.Ingress{Hostname: fmt.Sprintf("prefix-%s", replacement.Name)},
+			MasterURL: fmt.Sprintf("https://%s:100", replacement.Name),
+		}))
+		err = waitForRequestStatus(service, req.ID, cluster.StatusReady)
+		require.NoError(s.T(), err)
+
+		// Can't replace the same cluster twice
+		_, err = service.ReplaceCluster(toReplace.ID, "admin")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
 
-func (s *TestIntegrationSuite) TestDeleteCluster() {
-	service, cl, _ := s.prepareService()
-	s.newUsers(service, 10)
-	s.Run("delete cluster OK", func() {
-		// Provision some clusters
-		req, reqWithClusters := s.provisionClusters(service, cl, 3, 100)
+	s.Run("do not replace cluster of expired request", func() {
+		created := time.Now().Add(-time.Hour).Unix()
+		for _, status := range []string{cluster.StatusExpired, cluster.StatusFailedToExpire} {
+			id := fmt.Sprintf("replace-%s", strings.ReplaceAll(status, " ", "-"))
+			_, err := mongodb.ClusterRequests().InsertOne(context.Background(), bson.D{
+				{"_id", id},
+				{"status", status},
+				{"requested_by", "john"},
+				{"zone", "wdc04"},
+				{"created", created},
+				{"delete_in_hours", 24},
+			})
+			require.NoError(s.T(), err)
+			_, err = mongodb.Clusters().InsertOne(context.Background(), bson.D{
+				{"_id", id + "-cluster"},
+				{"request_id", id},
+				{"status", cluster.StatusFailed},
+				{"created", created},
+			})
+			require.NoError(s.T(), err)
 
-		// Now delete one
-		toDelete := reqWithClusters.Clusters[1]
-		err := service.DeleteCluster(toDelete.ID)
+			_, err = service.ReplaceCluster(id+"-cluster", "admin")
+			require.Error(s.T(), err)
+			assert.True(s.T(), devclustererr.IsBadRequest(err))
+
+			// The request is not brought back and no new cluster is provisioned
+			r, err := service.GetRequestWithClusters(id)
+			require.NoError(s.T(), err)
+			assert.Equal(s.T(), status, r.Status)
+			require.Len(s.T(), r.Clusters, 1)
+			assert.Empty(s.T(), r.Clusters[0].ReplacedBy)
+		}
+	})
+
+	s.Run("auto replace failed cluster", func() {
+		config.autoReplace = true
+		config.replaceMaxAttempts = 1
+		defer func() { config.autoReplace = false }()
+		req := s.newRequest(service, 1, 100)
+		r, err := waitForClustersToStartProvisioning(service, req)
 		require.NoError(s.T(), err)
+		failed := r.Clusters[0]
 
-		// Check the deleted cluster
-		result, err := service.GetRequestWithClusters(req.ID)
+		// Fail the cluster in IBM Cloud. It gets replaced.
+		require.NoError(s.T(), cl.UpdateCluster(ibmcloud.Cluster{ID: failed.ID, State: "deploy_failed"}))
+		replacement, err := waitForClusterToBeReplaced(service, failed.ID)
 		require.NoError(s.T(), err)
-		assertClusterEquals(s.T(), cluster.Cluster{
-			ID:        toDelete.ID,
-			RequestID: req.ID,
-			Name:      toDelete.Name,
-			Hostname:  toDelete.Hostname,
-			MasterURL: toDelete.MasterURL,
-			Status:    "deleted",
-			Error:     "",
-		}, result.Clusters[1])
+		assert.Equal(s.T(), 1, replacement.ReplaceAttempt)
 
-		// Check the cluster was deleted in ibm cloud
-		_, err = service.IbmCloudClient.GetCluster(toDelete.ID)
-		require.Error(s.T(), err)
-		assert.True(s.T(), devclustererr.IsNotFound(err))
+		// Fail the replacement too. It's not replaced anymore since the max number of attempts is reached.
+		require.NoError(s.T(), cl.UpdateCluster(ibmcloud.Cluster{ID: replacement.ID, State: "deploy_failed"}))
+		err = wait.Poll(retryInterval, timeout, func() (bool, error) {
+			c, err := service.GetCluster(replacement.ID)
+			if err != nil {
+				return false, err
+			}
+			return c.Status == cluster.StatusFailed, nil
+		})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{fmt.Sprintf("Unable to provision cluster for request %s", req.ID)}, notifier.subjects)
+		c, err := service.GetCluster(replacement.ID)
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), c.ReplacedBy)
+	})
+}
+
+func waitForRequestStatus(service *cluster.ClusterService, requestID, status string) error {
+	return wait.Poll(retryInterval, timeout, func() (bool, error) {
+		r, err := service.GetRequestWithClusters(requestID)
+		if err != nil {
+			return false, err
+		}
+		return r.Status == status, nil
+	})
+}
+
+func waitForClusterToBeReplaced(service *cluster.ClusterService, id string) (*cluster.Cluster, error) {
+	var replacement *cluster.Cluster
+	err := wait.Poll(retryInterval, timeout, func() (bool, error) {
+		c, err := service.GetCluster(id)
+		if err != nil {
+			return false, err
+		}
+		if c.ReplacedBy == "" {
+			return false, nil
+		}
+		replacement, err = service.GetCluster(c.ReplacedBy)
+		return replacement != nil, err
 	})
+	return replacement, err
 }
 
 func (s *TestIntegrationSuite) TestGetCluster() {
@@ -284,7 +1484,7 @@ func (s *TestIntegrationSuite) TestExpiredClusters() {
 
 		// 2. Start deleting clusters.
 		beforeDeleting := time.Now().Unix()
-		service.StartDeletingExpiredClusters(1)
+		service.StartDeletingExpiredClusters()
 
 		// 3. Check the expired one is deleted and the other one is not.
 		deletedReq, err := waitForRequest(service, reqExpired, requestExpired, clustersDeleted, usersRecycled)
@@ -332,7 +1532,7 @@ func (s *TestIntegrationSuite) TestExpiredClusters() {
 
 		s.Run("re-use recycled users", func() {
 			// Add one new user with the recycle timestamp not set so it should be used first before the recycled ones
-			newUsers, err := service.CreateUsers(1, 1000)
+			newUsers, err := service.CreateUsers("", 1)
 			require.NoError(s.T(), err)
 
 			// Provision new clusters which should use the new user and one of the recycled ones which were returned to the pull after the first request expired
@@ -372,21 +1572,109 @@ func (s *TestIntegrationSuite) TestExpiredClusters() {
 	})
 }
 
+func (s *TestIntegrationSuite) TestReconcile() {
+	service, cl, mockConfig := s.prepareService()
+	s.newUsers(service, 5)
+	_, reqWithClusters := s.provisionClusters(service, cl, 3, 100)
+
+	// Orphan cluster in IBM Cloud and a cluster which is not managed by the service
+	orphan, err := cl.CreateCluster("rhd-lon06-orphan", "lon06", false)
+	require.NoError(s.T(), err)
+	_, err = cl.CreateCluster("not-managed", "lon06", false)
+	require.NoError(s.T(), err)
+	// Cluster deleted in IBM Cloud but not in the DB
+	missing := reqWithClusters.Clusters[0]
+	require.NoError(s.T(), cl.DeleteCluster(missing.ID))
+	missingUserPolicy := missing.User.PolicyID
+	// Access policies with no user assignment. One old and one just created.
+	oldPolicy, err := cl.CreatePolicyWithCreationTime(mockConfig.GetIBMCloudAccountID(), reqWithClusters.Clusters[1].ID, time.Now().Add(-time.Hour))
+	require.NoError(s.T(), err)
+	newPolicy, err := cl.CreatePolicyWithCreationTime(mockConfig.GetIBMCloudAccountID(), reqWithClusters.Clusters[1].ID, time.Now())
+	require.NoError(s.T(), err)
+
+	s.Run("report only", func() {
+		report, err := service.Reconcile()
+		require.NoError(s.T(), err)
+
+		assert.Equal(s.T(), []string{orphan.ClusterID}, report.OrphanClusters)
+		assert.Empty(s.T(), report.AdoptedClusters)
+		assert.Equal(s.T(), []string{missing.ID}, report.MissingClusters)
+		assert.Equal(s.T(), []string{oldPolicy}, report.OrphanPolicies)
+		assert.Empty(s.T(), report.Errors)
+		assert.True(s.T(), report.Finished >= report.Started)
+
+		// The missing cluster is marked as deleted and its user is recycled
+		c, err := service.GetCluster(missing.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "deleted", c.Status)
+		_, err = cluster.GetUserByClusterID(missing.ID)
+		require.True(s.T(), devclustererr.IsNotFound(err))
+		assert.False(s.T(), cl.AccessPolicyExists(missingUserPolicy))
+		// The orphan cluster is not adopted
+		c, err = service.GetCluster(orphan.ClusterID)
+		require.NoError(s.T(), err)
+		assert.Nil(s.T(), c)
+		// The recent and the assigned policies are kept
+		assert.False(s.T(), cl.AccessPolicyExists(oldPolicy))
+		assert.True(s.T(), cl.AccessPolicyExists(newPolicy))
+		for _, c := range reqWithClusters.Clusters[1:] {
+			assert.True(s.T(), cl.AccessPolicyExists(c.User.PolicyID))
+		}
+
+		// The report is stored
+		reports, err := service.DriftReports(10)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), reports, 1)
+		assert.Equal(s.T(), *report, reports[0])
+	})
+
+	s.Run("adopt orphans", func() {
+		mockConfig.adoptOrphans = true
+		defer func() { mockConfig.adoptOrphans = false }()
+
+		report, err := service.Reconcile()
+		require.NoError(s.T(), err)
+
+		assert.Equal(s.T(), []string{orphan.ClusterID}, report.OrphanClusters)
+		assert.Equal(s.T(), []string{orphan.ClusterID}, report.AdoptedClusters)
+		assert.Empty(s.T(), report.MissingClusters)
+		assert.Empty(s.T(), report.OrphanPolicies)
+		c, err := service.GetCluster(orphan.ClusterID)
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), c)
+		assert.Equal(s.T(), "rhd-lon06-orphan", c.Name)
+
+		s.Run("no drift", func() {
+			report, err := service.Reconcile()
+			require.NoError(s.T(), err)
+			assert.Empty(s.T(), report.OrphanClusters)
+			assert.Empty(s.T(), report.MissingClusters)
+			assert.Empty(s.T(), report.OrphanPolicies)
+
+			reports, err := service.DriftReports(2)
+			require.NoError(s.T(), err)
+			assert.Len(s.T(), reports, 2)
+		})
+	})
+}
+
 func (s *TestIntegrationSuite) TestUsers() {
 	s.Run("request new users OK", func() {
 		mockClient := ibmcloudmock.NewMockIBMCloudClient()
+		mockConfig := &MockConfig{
+			config: s.Config,
+		}
 		service := &cluster.ClusterService{
 			IbmCloudClient: mockClient,
-			Config: &MockConfig{
-				config: s.Config,
-			},
+			Identity:       identity.NewCloudDirectoryProvider(mockConfig, mockClient),
+			Config:         mockConfig,
 		}
 
-		assertUsers := func(users []cluster.User, err error) {
+		assertUsers := func(firstIndex int, users []cluster.User, err error) {
 			require.NoError(s.T(), err)
 			require.Len(s.T(), users, 3)
 			for i := 0; i < 3; i++ {
-				assert.Equal(s.T(), fmt.Sprintf("rh-dev-%d", 1001+i), users[i].ID)
+				assert.Equal(s.T(), fmt.Sprintf("rh-dev-%d", firstIndex+i), users[i].ID)
 				assert.NotEmpty(s.T(), users[i].Email)
 				assert.NotEmpty(s.T(), users[i].Password)
 				assert.NotEmpty(s.T(), users[i].CloudDirectID)
@@ -397,21 +1685,315 @@ func (s *TestIntegrationSuite) TestUsers() {
 		}
 
 		// Request 3 new users and assert the result
-		assertUsers(service.CreateUsers(3, 1000))
+		users, err := service.CreateUsers("", 3)
+		assertUsers(1, users, err)
 
 		s.Run("get users", func() {
 			// assert the available users
-			assertUsers(service.Users())
+			users, err := service.Users()
+			assertUsers(1, users, err)
+		})
+
+		s.Run("indexes are not reused", func() {
+			// Delete the last user. Its index is not allocated again.
+			require.NoError(s.T(), service.DeleteUser("rh-dev-3"))
+			users, err := service.CreateUsers("", 3)
+			assertUsers(4, users, err)
+		})
+
+		s.Run("indexes do not collide with existing users", func() {
+			// Imitate a user created with a manually set index
+			_, err := mongodb.Users().InsertOne(context.Background(), bson.D{
+				{"_id", "rh-dev-1000"},
+				{"cluster_id", ""},
+			})
+			require.NoError(s.T(), err)
+			users, err := service.CreateUsers("", 3)
+			assertUsers(1001, users, err)
 		})
 	})
 }
 
+func (s *TestIntegrationSuite) TestUserPool() {
+	service, cl, _ := s.prepareService()
+	users := s.newUsers(service, 3)
+
+	poolStatus := func(id string) string {
+		users, err := service.UsersWithStatus()
+		require.NoError(s.T(), err)
+		for _, u := range users {
+			if u.ID == id {
+				return u.PoolStatus
+			}
+		}
+		return ""
+	}
+
+	s.Run("disabled user is not assigned to new clusters", func() {
+		require.NoError(s.T(), service.DisableUser(users[0].ID))
+		assert.Equal(s.T(), cluster.UserStatusDisabled, poolStatus(users[0].ID))
+
+		_, r := s.provisionClusters(service, cl, 2, 1)
+		for _, c := range r.Clusters {
+			assert.NotEqual(s.T(), users[0].ID, c.User.ID)
+		}
+		assert.Equal(s.T(), cluster.UserStatusDisabled, poolStatus(users[0].ID))
+		assert.Equal(s.T(), cluster.UserStatusAssigned, poolStatus(users[1].ID))
+		assert.Equal(s.T(), cluster.UserStatusAssigned, poolStatus(users[2].ID))
+
+		require.NoError(s.T(), service.EnableUser(users[0].ID))
+		assert.Equal(s.T(), cluster.UserStatusFree, poolStatus(users[0].ID))
+	})
+
+	s.Run("users which are not stuck can't be force recycled", func() {
+		err := service.ForceRecycleUser(users[0].ID)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		err = service.ForceRecycleUser(users[1].ID)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("users assigned to active clusters can't be deleted", func() {
+		err := service.DeleteUser(users[1].ID)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		assert.True(s.T(), cl.CloudDirectoryUserExists(users[1].CloudDirectID))
+	})
+
+	s.Run("force recycle stuck user", func() {
+		// Assign the user to an unknown cluster
+		_, err := mongodb.Users().UpdateOne(
+			context.Background(),
+			bson.D{
+				{"_id", users[0].ID},
+			},
+			bson.D{
+				{"$set", bson.D{
+					{"cluster_id", "unknown"},
+				}},
+			},
+		)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), cluster.UserStatusStuck, poolStatus(users[0].ID))
+
+		require.NoError(s.T(), service.ForceRecycleUser(users[0].ID))
+		assert.Equal(s.T(), cluster.UserStatusFree, poolStatus(users[0].ID))
+	})
+
+	s.Run("delete free user", func() {
+		require.NoError(s.T(), service.DeleteUser(users[0].ID))
+		assert.False(s.T(), cl.CloudDirectoryUserExists(users[0].CloudDirectID))
+		all, err := service.UsersWithStatus()
+		require.NoError(s.T(), err)
+		assert.Len(s.T(), all, 2)
+	})
+
+	s.Run("unknown user", func() {
+		assert.True(s.T(), devclustererr.IsNotFound(service.DisableUser("unknown")))
+		assert.True(s.T(), devclustererr.IsNotFound(service.ForceRecycleUser("unknown")))
+		assert.True(s.T(), devclustererr.IsNotFound(service.DeleteUser("unknown")))
+	})
+}
+
+func (s *TestIntegrationSuite) TestUserPoolAutoscaling() {
+	service, cl, config := s.prepareService()
+
+	s.Run("request rejected if autoscaling disabled", func() {
+		_, err := service.CreateNewRequest("johnsmith@domain.com", 2, "lon06", 100, false, 1, nil, "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	config.userPoolMinFree = 3
+	config.userPoolBatchSize = 2
+	defer func() { config.userPoolMinFree = 0 }()
+
+	s.Run("request rejected if shortage policy is reject", func() {
+		config.shortagePolicy = cluster.UserPoolShortageReject
+		defer func() { config.shortagePolicy = "" }()
+		_, err := service.CreateNewRequest("johnsmith@domain.com", 2, "lon06", 100, false, 1, nil, "")
+		require.Error(s.T(), err)
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+	})
+
+	s.Run("request queued until the pool is scaled up", func() {
+		config.shortagePolicy = cluster.UserPoolShortageQueue
+		defer func() { config.shortagePolicy = "" }()
+		req := s.newRequest(service, 2, 100)
+		_, err := waitForClustersToStartProvisioning(service, req)
+		require.NoError(s.T(), err)
+		s.markClustersAsProvisioned(service, cl, req)
+		r, err := waitForRequest(service, req, requestReady, clustersReady, usersAssigned)
+		require.NoError(s.T(), err)
+		assert.Len(s.T(), r.Clusters, 2)
+
+		// The pool is scaled up in batches to cover the request and to keep the min number of free users.
+		// The last batch is capped so exactly the missing 5 users are created.
+		users, err := service.UsersWithStatus()
+		require.NoError(s.T(), err)
+		assert.Len(s.T(), users, 5)
+		free := 0
+		for _, u := range users {
+			if u.PoolStatus == cluster.UserStatusFree {
+				free++
+			}
+		}
+		assert.Equal(s.T(), 3, free)
+	})
+}
+
+func (s *TestIntegrationSuite) TestUserPoolReservation() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 3)
+
+	s.Run("concurrent requests never share free users", func() {
+		var wg sync.WaitGroup
+		var mux sync.Mutex
+		created := make([]cluster.Request, 0)
+		rejected := 0
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r, err := service.CreateNewRequest("johnsmith@domain.com", 2, "lon06", 100, false, 1, nil, "")
+				mux.Lock()
+				defer mux.Unlock()
+				if err != nil {
+					assert.True(s.T(), devclustererr.IsBadRequest(err))
+					rejected++
+					return
+				}
+				created = append(created, r)
+			}()
+		}
+		wg.Wait()
+		// The pool covers one request only. The requests which reserved only some of the users are all rejected.
+		require.True(s.T(), len(created) <= 1, "%d requests created", len(created))
+		assert.Equal(s.T(), 3-len(created), rejected)
+
+		for _, req := range created {
+			_, err := waitForClustersToStartProvisioning(service, req)
+			require.NoError(s.T(), err)
+			s.markClustersAsProvisioned(service, cl, req)
+			_, err = waitForRequest(service, req, requestReady, clustersReady, usersAssigned)
+			require.NoError(s.T(), err)
+		}
+
+		// The rejected requests have released the users they reserved
+		free, err := mongodb.Users().CountDocuments(context.Background(), bson.D{{"cluster_id", ""}, {"reserved_for", ""}})
+		require.NoError(s.T(), err)
+		assert.EqualValues(s.T(), 3-2*len(created), free)
+	})
+}
+
+func (s *TestIntegrationSuite) TestRecoverPendingUserOperations() {
+	service, cl, _ := s.prepareService()
+	s.newUsers(service, 7)
+	_, r := s.provisionClusters(service, cl, 1, 100)
+	active := r.Clusters[0]
+
+	free := make([]cluster.User, 0)
+	all, err := service.UsersWithStatus()
+	require.NoError(s.T(), err)
+	for _, u := range all {
+		if u.PoolStatus == cluster.UserStatusFree {
+			free = append(free, u.User)
+		}
+	}
+	require.Len(s.T(), free, 6)
+
+	hostname, err := os.Hostname()
+	require.NoError(s.T(), err)
+	old := time.Now().Add(-time.Hour).Unix()
+	// Imitate the operations interrupted by a crash
+	interrupt := func(u cluster.User, clusterID, operation, owner string, since int64, policyID string) {
+		_, err := mongodb.Users().UpdateOne(
+			context.Background(),
+			bson.D{
+				{"_id", u.ID},
+			},
+			bson.D{
+				{"$set", bson.D{
+					{"cluster_id", clusterID},
+					{"pending_operation", operation},
+					{"pending_owner", owner},
+					{"pending_since", since},
+					{"policy_id", policyID},
+				}},
+			},
+		)
+		require.NoError(s.T(), err)
+	}
+	// The access has been granted to the users interrupted after the policy was recorded
+	granted, err := cl.CreateAccessPolicy("", "", active.ID)
+	require.NoError(s.T(), err)
+	grantedToUnknown, err := cl.CreateAccessPolicy("", "", "unknown")
+	require.NoError(s.T(), err)
+	policies, err := cl.GetAccessPolicies("")
+	require.NoError(s.T(), err)
+	interrupt(free[0], active.ID, cluster.UserOperationAssigning, "other-instance", old, "")
+	interrupt(free[1], "unknown", cluster.UserOperationAssigning, "other-instance", old, "")
+	interrupt(free[2], "unknown", cluster.UserOperationRecycling, hostname, time.Now().Unix(), "")
+	interrupt(free[3], "unknown", cluster.UserOperationRecycling, "other-instance", time.Now().Unix(), "")
+	interrupt(free[4], active.ID, cluster.UserOperationAssigning, "other-instance", old, granted)
+	interrupt(free[5], "unknown", cluster.UserOperationAssigning, "other-instance", old, grantedToUnknown)
+
+	recovered, err := service.RecoverPendingUserOperations()
+	require.NoError(s.T(), err)
+	assert.ElementsMatch(s.T(), []string{free[0].ID, free[1].ID, free[2].ID, free[4].ID, free[5].ID}, recovered)
+
+	byID := make(map[string]cluster.UserWithStatus)
+	all, err = service.UsersWithStatus()
+	require.NoError(s.T(), err)
+	for _, u := range all {
+		byID[u.ID] = u
+	}
+
+	// The assignment to the existing cluster is completed
+	completed := byID[free[0].ID]
+	assert.Equal(s.T(), cluster.UserStatusAssigned, completed.PoolStatus)
+	assert.Equal(s.T(), active.ID, completed.ClusterID)
+	assert.True(s.T(), cl.AccessPolicyExists(completed.PolicyID))
+	assert.Empty(s.T(), completed.PendingOperation)
+
+	// The assignment to the unknown cluster is rolled back
+	rolledBack := byID[free[1].ID]
+	assert.Equal(s.T(), cluster.UserStatusFree, rolledBack.PoolStatus)
+	assert.Empty(s.T(), rolledBack.PolicyID)
+
+	// The recorded policy is reused instead of granting another one
+	reused := byID[free[4].ID]
+	assert.Equal(s.T(), cluster.UserStatusAssigned, reused.PoolStatus)
+	assert.Equal(s.T(), granted, reused.PolicyID)
+	assert.True(s.T(), cl.AccessPolicyExists(granted))
+
+	// The recorded policy of the rolled back assignment is revoked
+	revoked := byID[free[5].ID]
+	assert.Equal(s.T(), cluster.UserStatusFree, revoked.PoolStatus)
+	assert.Empty(s.T(), revoked.PolicyID)
+	assert.False(s.T(), cl.AccessPolicyExists(grantedToUnknown))
+
+	// Only the user with no recorded policy got a new policy and the policy of the rolled back assignment is gone
+	after, err := cl.GetAccessPolicies("")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), after, len(policies))
+
+	// The recycle started by this instance is resumed
+	recycled := byID[free[2].ID]
+	assert.Equal(s.T(), cluster.UserStatusFree, recycled.PoolStatus)
+	assert.NotEqual(s.T(), free[2].Password, recycled.Password)
+	assert.NotEmpty(s.T(), recycled.Recycled)
+
+	// The recent recycle started by another instance may still be in progress and is not touched
+	inProgress := byID[free[3].ID]
+	assert.Equal(s.T(), cluster.UserStatusPending, inProgress.PoolStatus)
+	assert.Equal(s.T(), cluster.UserOperationRecycling, inProgress.PendingOperation)
+}
+
 func (s *TestIntegrationSuite) newRequest(service *cluster.ClusterService, n int, deleteIn int) cluster.Request {
 	return s.newRequestWithZone(service, n, deleteIn, "lon06")
 }
 
 func (s *TestIntegrationSuite) newRequestWithZone(service *cluster.ClusterService, n int, deleteIn int, zone string) cluster.Request {
-	req, err := service.CreateNewRequest("johnsmith@domain.com", n, zone, deleteIn, false)
+	req, err := service.CreateNewRequest("johnsmith@domain.com", n, zone, deleteIn, false, 1, nil, "")
 	require.NoError(s.T(), err)
 	assert.Equal(s.T(), "johnsmith@domain.com", req.RequestedBy)
 	assert.Equal(s.T(), n, req.Requested)
@@ -422,7 +2004,7 @@ func (s *TestIntegrationSuite) newRequestWithZone(service *cluster.ClusterServic
 }
 
 func (s *TestIntegrationSuite) newUsers(service *cluster.ClusterService, n int) []cluster.User {
-	users, err := service.CreateUsers(n, 0)
+	users, err := service.CreateUsers("", n)
 	require.NoError(s.T(), err)
 	return users
 }
@@ -434,6 +2016,7 @@ func (s *TestIntegrationSuite) prepareService() (*cluster.ClusterService, *ibmcl
 	}
 	service := &cluster.ClusterService{
 		IbmCloudClient: mockClient,
+		Identity:       identity.NewCloudDirectoryProvider(mockConfig, mockClient),
 		Config:         mockConfig,
 	}
 	return service, mockClient, mockConfig
@@ -641,8 +2224,20 @@ func waitForRequest(service *cluster.ClusterService, request cluster.Request, cr
 }
 
 type MockConfig struct {
-	config  *configuration.Config
-	timeout int
+	config             *configuration.Config
+	timeout            int
+	adoptOrphans       bool
+	deleteMaxAttempts  int
+	autoReplace        bool
+	replaceMaxAttempts int
+	userPoolMinFree    int
+	userPoolBatchSize  int
+	shortagePolicy     string
+	bootstrapSteps     string
+	urlTemplates       string
+	defaultTemplates   []string
+	priceTable         string
+	retentionDays      int
 }
 
 func (c *MockConfig) GetIBMCloudAPIKey() string {
@@ -671,3 +2266,184 @@ func (c *MockConfig) GetIBMCloudTenantID() string {
 func (c *MockConfig) GetIBMCloudIDPName() string {
 	return "devcluster"
 }
+
+func (c *MockConfig) GetIBMCloudRateLimitPerSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudMaxRetries() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudRequestTimeoutSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudCircuitBreakerThreshold() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudCircuitBreakerOpenSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetPasswordLength() int {
+	return 0
+}
+
+func (c *MockConfig) GetPasswordCharacterClasses() []string {
+	return nil
+}
+
+func (c *MockConfig) GetPasswordPassphraseWords() int {
+	return 0
+}
+
+func (c *MockConfig) GetReconcileAdoptOrphanClusters() bool {
+	return c.adoptOrphans
+}
+
+func (c *MockConfig) GetDeleteRetryBaseSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetDeleteRetryMaxAttempts() int {
+	if c.deleteMaxAttempts != 0 {
+		return c.deleteMaxAttempts
+	}
+	return 3
+}
+
+func (c *MockConfig) GetNotificationWebhookURL() string {
+	return ""
+}
+
+// mockBootstrapRunner records the run steps and fails the steps with configured errors
+type mockBootstrapRunner struct {
+	mux      sync.Mutex
+	runs     []string
+	apiKeys  map[string]string // API keys the clusters are logged in with by cluster ID
+	failures map[string]error
+}
+
+func (r *mockBootstrapRunner) Run(target bootstrap.Target, step bootstrap.Step) (string, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.runs = append(r.runs, fmt.Sprintf("%s/%s", target.ClusterID, step.Name))
+	if r.apiKeys == nil {
+		r.apiKeys = make(map[string]string)
+	}
+	r.apiKeys[target.ClusterID] = target.APIKey
+	if err := r.failures[step.Name]; err != nil {
+		return fmt.Sprintf("%s failed", step.Name), err
+	}
+	return fmt.Sprintf("%s done", step.Name), nil
+}
+
+func (r *mockBootstrapRunner) apiKey(clusterID string) string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.apiKeys[clusterID]
+}
+
+func (r *mockBootstrapRunner) setFailure(step string, err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.failures[step] = err
+}
+
+type mockNotifier struct {
+	subjects []string
+}
+
+func (n *mockNotifier) Notify(subject, _ string) error {
+	n.subjects = append(n.subjects, subject)
+	return nil
+}
+
+func (c *MockConfig) GetAutoReplaceFailedClusters() bool {
+	return c.autoReplace
+}
+
+func (c *MockConfig) GetAutoReplaceMaxAttempts() int {
+	return c.replaceMaxAttempts
+}
+
+func (c *MockConfig) GetIdentityProvider() string {
+	return identity.ProviderCloudDirectory
+}
+
+func (c *MockConfig) GetIdentityEmailDomain() string {
+	return "redhat.com"
+}
+
+func (c *MockConfig) GetUserPoolMinFree() int {
+	return c.userPoolMinFree
+}
+
+func (c *MockConfig) GetUserPoolBatchSize() int {
+	return c.userPoolBatchSize
+}
+
+func (c *MockConfig) GetUserPoolShortagePolicy() string {
+	return c.shortagePolicy
+}
+
+func (c *MockConfig) GetURLTemplates() string {
+	if c.urlTemplates != "" {
+		return c.urlTemplates
+	}
+	return c.config.GetURLTemplates()
+}
+
+func (c *MockConfig) GetURLDefaultTemplates() []string {
+	return c.defaultTemplates
+}
+
+func (c *MockConfig) GetBootstrapSteps() string {
+	return c.bootstrapSteps
+}
+
+func (c *MockConfig) GetBootstrapCLIPath() string {
+	return "oc"
+}
+
+func (c *MockConfig) GetBootstrapStepTimeoutSec() int {
+	return 10
+}
+
+func (c *MockConfig) GetCostPriceTable() string {
+	return c.priceTable
+}
+
+func (c *MockConfig) GetIBMCloudAccounts() string {
+	return ""
+}
+
+func (c *MockConfig) GetSecret(name string) string {
+	return c.config.GetSecret(name)
+}
+
+func (c *MockConfig) GetArchiveRetentionDays() int {
+	return c.retentionDays
+}
+
+func (c *MockConfig) GetReconcileIntervalSec() int {
+	return c.config.GetReconcileIntervalSec()
+}
+
+func (c *MockConfig) GetUserPoolCheckIntervalSec() int {
+	return c.config.GetUserPoolCheckIntervalSec()
+}
+
+func (c *MockConfig) GetArchiveIntervalSec() int {
+	return c.config.GetArchiveIntervalSec()
+}
+
+func (c *MockConfig) GetExpiryCheckIntervalSec() int {
+	return 1
+}
+
+func (c *MockConfig) GetCostCurrency() string {
+	return "USD"
+}
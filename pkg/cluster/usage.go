@@ -0,0 +1,248 @@
+package cluster
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/cost"
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+
+	"github.com/pkg/errors"
+)
+
+// Keys the usage report can be grouped by
+const (
+	GroupByRequest     = "request"
+	GroupByRequestedBy = "requested_by"
+	GroupByZone        = "zone"
+	GroupByMonth       = "month"
+)
+
+// DefaultUsageGroupBy is the default grouping of the usage report
+var DefaultUsageGroupBy = []string{GroupByRequestedBy, GroupByZone, GroupByMonth}
+
+// UsageReport represents the cluster-hours and the estimated cost of the clusters
+type UsageReport struct {
+	GroupBy  []string
+	Currency string
+	Rows     []UsageRow
+}
+
+// UsageRow represents the usage of one group of clusters.
+// Only the fields the report is grouped by are set.
+type UsageRow struct {
+	RequestID     string
+	RequestedBy   string
+	Zone          string
+	Month         string
+	Clusters      int     // number of the clusters which were running in the group
+	ClusterHours  float64 // total number of the hours the clusters were running
+	EstimatedCost float64 // estimated cost of the cluster-hours which have a price in the price table
+	UnpricedHours float64 // cluster-hours of the clusters with no price in the price table
+}
+
+// UsageReport returns the usage of all the clusters grouped by the given keys.
+// The optional from and to months (e.g. 2021-03) limit the report to the cluster-hours within these months.
+// The usage of a cluster starts when its provisioning is started and ends when the cluster is deleted.
+// The request creation and expiration time is used for the clusters stored before their usage was tracked.
+// The archived clusters are included.
+func (s *ClusterService) UsageReport(groupBy []string, from, to string) (*UsageReport, error) {
+	if len(groupBy) == 0 {
+		groupBy = DefaultUsageGroupBy
+	}
+	grouped := make(map[string]bool)
+	for _, key := range groupBy {
+		switch key {
+		case GroupByRequest, GroupByRequestedBy, GroupByZone, GroupByMonth:
+			grouped[key] = true
+		default:
+			return nil, devclustererr.NewBadRequestError(fmt.Sprintf("unable to group usage by %s", key),
+				fmt.Sprintf("supported keys: %s, %s, %s, %s", GroupByRequest, GroupByRequestedBy, GroupByZone, GroupByMonth))
+		}
+	}
+	for _, month := range []string{from, to} {
+		if _, err := time.Parse(cost.MonthFormat, month); month != "" && err != nil {
+			return nil, devclustererr.NewBadRequestError(fmt.Sprintf("invalid month: %s", month), "expected format: YYYY-MM")
+		}
+	}
+	prices, err := cost.ParsePriceTable(s.Config.GetCostPriceTable())
+	if err != nil {
+		return nil, err
+	}
+	requests, err := getAllRequests()
+	if err != nil {
+		return nil, err
+	}
+	archivedRequests, err := getAllArchivedRequests()
+	if err != nil {
+		return nil, err
+	}
+	requests = append(requests, archivedRequests...)
+	requestsByID := make(map[string]Request, len(requests))
+	for _, r := range requests {
+		requestsByID[r.ID] = r
+	}
+	clusters, err := getClustersWithFilter()
+	if err != nil {
+		return nil, err
+	}
+	archivedClusters, err := getAllArchivedClusters()
+	if err != nil {
+		return nil, err
+	}
+	clusters = append(clusters, archivedClusters...)
+
+	now := time.Now()
+	rows := make(map[UsageRow]*UsageRow)
+	clusterIDs := make(map[UsageRow]map[string]bool)
+	for _, c := range clusters {
+		r, hasRequest := requestsByID[c.RequestID]
+		start, end := usagePeriod(c, r, hasRequest, now)
+		if start.IsZero() {
+			continue
+		}
+		machineType, workers := c.MachineType, c.WorkerCount
+		if machineType == "" {
+			machineType = ibmcloud.MachineType
+		}
+		if workers == 0 {
+			workers = ibmcloud.WorkerCount
+		}
+		hourlyPrice, priced := prices.HourlyPrice(machineType, workers)
+		for month, hours := range cost.HoursByMonth(start, end) {
+			if (from != "" && month < from) || (to != "" && month > to) {
+				continue
+			}
+			key := UsageRow{}
+			if grouped[GroupByRequest] {
+				key.RequestID = c.RequestID
+			}
+			if grouped[GroupByRequestedBy] {
+				key.RequestedBy = r.RequestedBy
+			}
+			if grouped[GroupByZone] {
+				key.Zone = r.Zone
+			}
+			if grouped[GroupByMonth] {
+				key.Month = month
+			}
+			row, found := rows[key]
+			if !found {
+				row = &UsageRow{RequestID: key.RequestID, RequestedBy: key.RequestedBy, Zone: key.Zone, Month: key.Month}
+				rows[key] = row
+				clusterIDs[key] = make(map[string]bool)
+			}
+			clusterIDs[key][c.ID] = true
+			row.ClusterHours += hours
+			if priced {
+				row.EstimatedCost += hours * hourlyPrice
+			} else {
+				row.UnpricedHours += hours
+			}
+		}
+	}
+
+	report := &UsageReport{
+		GroupBy:  groupBy,
+		Currency: s.Config.GetCostCurrency(),
+		Rows:     make([]UsageRow, 0, len(rows)),
+	}
+	for key, row := range rows {
+		row.Clusters = len(clusterIDs[key])
+		row.ClusterHours = roundCents(row.ClusterHours)
+		row.EstimatedCost = roundCents(row.EstimatedCost)
+		row.UnpricedHours = roundCents(row.UnpricedHours)
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.RequestedBy != b.RequestedBy {
+			return a.RequestedBy < b.RequestedBy
+		}
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		return a.RequestID < b.RequestID
+	})
+	return report, nil
+}
+
+// usagePeriod returns the time when the cluster started and stopped being paid for.
+// Returns zero start if the period is unknown.
+func usagePeriod(c Cluster, r Request, hasRequest bool, now time.Time) (time.Time, time.Time) {
+	var start, end time.Time
+	if c.Created != 0 {
+		start = time.Unix(c.Created, 0)
+	} else if hasRequest {
+		start = time.Unix(r.Created, 0)
+	} else {
+		return time.Time{}, time.Time{}
+	}
+	switch {
+	case c.Deleted != 0:
+		end = time.Unix(c.Deleted, 0)
+	case c.Status != StatusDeleted:
+		end = now // still running
+	case hasRequest:
+		// Deleted before the deletion time was tracked. It was deleted when the request expired at the latest.
+		end = time.Unix(r.Created, 0).Add(time.Duration(r.DeleteInHours) * time.Hour)
+		if end.After(now) {
+			end = now
+		}
+	default:
+		return time.Time{}, time.Time{}
+	}
+	return start, end
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// WriteCSV writes the report as CSV with a column for every grouping key followed by the usage columns
+func (r *UsageReport) WriteCSV(w io.Writer) error {
+	header := make([]string, 0, len(r.GroupBy)+4)
+	for _, key := range r.GroupBy {
+		header = append(header, usageColumns[key])
+	}
+	header = append(header, "Clusters", "Cluster Hours", fmt.Sprintf("Estimated Cost (%s)", r.Currency), "Unpriced Hours")
+	records := [][]string{header}
+	for _, row := range r.Rows {
+		record := make([]string, 0, len(header))
+		for _, key := range r.GroupBy {
+			switch key {
+			case GroupByRequest:
+				record = append(record, row.RequestID)
+			case GroupByRequestedBy:
+				record = append(record, row.RequestedBy)
+			case GroupByZone:
+				record = append(record, row.Zone)
+			case GroupByMonth:
+				record = append(record, row.Month)
+			}
+		}
+		record = append(record,
+			strconv.Itoa(row.Clusters),
+			strconv.FormatFloat(row.ClusterHours, 'f', 2, 64),
+			strconv.FormatFloat(row.EstimatedCost, 'f', 2, 64),
+			strconv.FormatFloat(row.UnpricedHours, 'f', 2, 64))
+		records = append(records, record)
+	}
+	return errors.Wrap(csv.NewWriter(w).WriteAll(records), "unable to write CSV")
+}
+
+var usageColumns = map[string]string{
+	GroupByRequest:     "Request",
+	GroupByRequestedBy: "Requested By",
+	GroupByZone:        "Zone",
+	GroupByMonth:       "Month",
+}
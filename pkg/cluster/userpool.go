@@ -0,0 +1,293 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+)
+
+// Statuses of the users in the user pool
+const (
+	UserStatusFree     = "free"
+	UserStatusAssigned = "assigned"
+	UserStatusDisabled = "disabled"
+	// UserStatusPending is the status of the users with an assignment or a recycle in progress
+	UserStatusPending = "pending"
+	// UserStatusStuck is the status of the users assigned to a cluster which is deleted or unknown
+	UserStatusStuck = "stuck"
+)
+
+// Policies applied to the new requests which can't be covered by the free users in the pool
+const (
+	UserPoolShortageQueue  = "queue"
+	UserPoolShortageReject = "reject"
+)
+
+// userIDPrefix is the prefix of the IDs of the users in the pool. The prefix is followed by the user index.
+const userIDPrefix = "rh-dev-"
+
+// UserWithStatus represents a user with its status in the user pool
+type UserWithStatus struct {
+	User       `json:",inline"`
+	PoolStatus string
+}
+
+// UsersWithStatus returns all the users with their statuses in the user pool
+func (s *ClusterService) UsersWithStatus() ([]UserWithStatus, error) {
+	users, err := getAllUsers()
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := getClustersWithFilter()
+	if err != nil {
+		return nil, err
+	}
+	clustersByID := make(map[string]Cluster, len(clusters))
+	for _, c := range clusters {
+		clustersByID[c.ID] = c
+	}
+	result := make([]UserWithStatus, 0, len(users))
+	for _, u := range users {
+		var c *Cluster
+		if found, ok := clustersByID[u.ClusterID]; ok {
+			c = &found
+		}
+		result = append(result, UserWithStatus{
+			User:       u,
+			PoolStatus: userPoolStatus(u, c),
+		})
+	}
+	return result, nil
+}
+
+// userPoolStatus returns the status of the user in the user pool. c is the cluster the user is assigned to, if found.
+func userPoolStatus(u User, c *Cluster) string {
+	if u.PendingOperation != "" {
+		return UserStatusPending
+	}
+	if assignedToGoneCluster(u, c) {
+		return UserStatusStuck
+	}
+	if u.Disabled {
+		return UserStatusDisabled
+	}
+	if u.ClusterID != "" {
+		return UserStatusAssigned
+	}
+	return UserStatusFree
+}
+
+// assignedToGoneCluster returns true if the user is assigned to a cluster which is deleted or unknown.
+// c is the cluster the user is assigned to, if found.
+func assignedToGoneCluster(u User, c *Cluster) bool {
+	return u.ClusterID != "" && (c == nil || c.Status == StatusDeleted)
+}
+
+// DisableUser disables the user so it's not assigned to any new cluster.
+// If the user is currently assigned to a cluster then it keeps the access to that cluster.
+func (s *ClusterService) DisableUser(id string) error {
+	return s.setUserDisabled(id, true)
+}
+
+// EnableUser returns the disabled user back to the user pool
+func (s *ClusterService) EnableUser(id string) error {
+	return s.setUserDisabled(id, false)
+}
+
+func (s *ClusterService) setUserDisabled(id string, disabled bool) error {
+	return updateUserDisabled(id, disabled)
+}
+
+// ForceRecycleUser recycles the user which is stuck with a cluster which is deleted or unknown
+func (s *ClusterService) ForceRecycleUser(id string) error {
+	u, err := s.stuckUser(id)
+	if err != nil {
+		return err
+	}
+	log.Infof(nil, "force recycling user %s stuck with cluster %s", u.ID, u.ClusterID)
+	return s.recycle(u)
+}
+
+// stuckUser returns the user with the given ID if it's stuck with a deleted or unknown cluster
+// Returns a Bad Request error if the user is not stuck.
+func (s *ClusterService) stuckUser(id string) (*User, error) {
+	u, err := getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("user %s not found", id), "")
+	}
+	if u.ClusterID == "" {
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("user %s is not assigned to any cluster", id), "")
+	}
+	c, err := getCluster(u.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if !assignedToGoneCluster(*u, c) {
+		return nil, devclustererr.NewBadRequestError(fmt.Sprintf("user %s is assigned to the active cluster %s", id, u.ClusterID), "")
+	}
+	return u, nil
+}
+
+// DeleteUser decommissions the user: revokes the cluster access, deletes the user from the identity provider and removes the user from the pool.
+// Users assigned to an active cluster can't be deleted.
+func (s *ClusterService) DeleteUser(id string) error {
+	u, err := getUser(id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("user %s not found", id), "")
+	}
+	if u.ClusterID != "" {
+		if u, err = s.stuckUser(id); err != nil {
+			return err
+		}
+	}
+	a, err := s.cloudAccount(u.Account)
+	if err != nil {
+		return err
+	}
+	if err := a.Identity.RevokeAccess(u.PolicyID); err != nil {
+		return err
+	}
+	if err := a.Identity.DeleteUser(u.identity()); err != nil {
+		return err
+	}
+	log.Infof(nil, "user %s deleted", u.ID)
+	return deleteUser(u.ID)
+}
+
+// StartUserPoolAutoscaling starts a goroutine to check the number of free users in the pool of every account
+// and to create new users if there are less free users than the configured minimum.
+// The interval is read from the configuration before every check so it can be changed while running.
+func (s *ClusterService) StartUserPoolAutoscaling() {
+	go func() {
+		for {
+			interval := time.Duration(s.Config.GetUserPoolCheckIntervalSec()) * time.Second
+			s.routineIteration(RoutineUserPoolAutoscaling, interval)
+			for _, a := range s.cloudAccounts() {
+				if err := s.scaleUserPool(a, 0); err != nil {
+					log.Error(nil, err, fmt.Sprintf("unable to scale up the user pool of account %s", a.Name))
+				}
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *ClusterService) userPoolAutoscalingEnabled() bool {
+	return s.Config.GetUserPoolMinFree() > 0
+}
+
+// Prevents scaling up the user pool concurrently
+var userPoolMux sync.Mutex
+
+// scaleUserPool creates new users in the account in batches until there are at least the configured minimum of free users
+// plus the given number of extra users in the pool of the account. Does nothing if the user pool autoscaling is disabled.
+func (s *ClusterService) scaleUserPool(a CloudAccount, extra int) error {
+	if !s.userPoolAutoscalingEnabled() {
+		return nil
+	}
+	userPoolMux.Lock()
+	defer userPoolMux.Unlock()
+	free, err := countFreeUsers(s.withAccount(a))
+	if err != nil {
+		return err
+	}
+	missing := s.Config.GetUserPoolMinFree() + extra - free
+	if missing <= 0 {
+		return nil
+	}
+	batch := s.Config.GetUserPoolBatchSize()
+	if batch <= 0 {
+		batch = missing
+	}
+	log.Info(nil, fmt.Sprintf("%d free users found in the user pool of account %s; scaling up the pool by %d users in batches of %d", free, a.Name, missing, batch))
+	for created := 0; created < missing; created += batch {
+		// The last batch is capped so the pool is not scaled up beyond the target
+		n := batch
+		if missing-created < n {
+			n = missing - created
+		}
+		if _, err := s.CreateUsers(a.Name, n); err != nil {
+			return errors.Wrap(err, "unable to scale up the user pool")
+		}
+	}
+	return nil
+}
+
+// reserveUserPool atomically reserves n free users in the pool of the account for the request
+// so the users can't be taken by the other requests created at the same time.
+// Returns the number of the users which could not be reserved yet if the request has to wait for the pool to be scaled up
+// or a Bad Request error if the request can't be covered by the pool. No user is left reserved if an error is returned.
+func (s *ClusterService) reserveUserPool(a CloudAccount, requestID string, n int) (int, error) {
+	reserved := 0
+	for ; reserved < n; reserved++ {
+		ok, err := reserveFreeUser(requestID, s.withAccount(a))
+		if err != nil {
+			s.releaseUserPool(requestID)
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+	}
+	if reserved == n {
+		return 0, nil
+	}
+	if s.userPoolAutoscalingEnabled() && s.Config.GetUserPoolShortagePolicy() != UserPoolShortageReject {
+		log.Info(nil, fmt.Sprintf("%d clusters requested but only %d free users found in the user pool; the request is queued until the pool is scaled up", n, reserved))
+		return n - reserved, nil
+	}
+	s.releaseUserPool(requestID)
+	return 0, devclustererr.NewBadRequestError(fmt.Sprintf("not enough free users in the user pool: %d clusters requested but only %d free users available", n, reserved), "")
+}
+
+// releaseUserPool returns the users reserved for the request but not assigned to any of its clusters to the user pool
+func (s *ClusterService) releaseUserPool(requestID string) {
+	released, err := releaseReservedUsers(requestID)
+	if err != nil {
+		log.Error(nil, err, fmt.Sprintf("unable to release the users reserved for request %s", requestID))
+		return
+	}
+	if released > 0 {
+		log.Info(nil, fmt.Sprintf("%d users reserved for request %s released", released, requestID))
+	}
+}
+
+// highestUserIndex returns the highest index of the existing users or 0 if there are no users
+func highestUserIndex() (int, error) {
+	users, err := getAllUsers()
+	if err != nil {
+		return 0, err
+	}
+	highest := 0
+	for _, u := range users {
+		if i := userIndex(u.ID); i > highest {
+			highest = i
+		}
+	}
+	return highest, nil
+}
+
+// userIndex returns the index of the user with the given ID or 0 if the ID doesn't follow the rh-dev-<index> pattern
+func userIndex(id string) int {
+	if !strings.HasPrefix(id, userIDPrefix) {
+		return 0
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(id, userIDPrefix))
+	if err != nil {
+		return 0
+	}
+	return i
+}
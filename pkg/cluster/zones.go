@@ -0,0 +1,200 @@
+package cluster
+
+import (
+	"fmt"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+)
+
+// Zone is a zone the clusters can be requested in. The zones are managed by the admins and stored in the DB.
+// If no zones are stored then the zones are not managed and the clusters can be requested in any zone.
+type Zone struct {
+	ID          string `json:"id"`
+	Enabled     bool   `json:"enabled"`
+	MaxClusters int    `json:"max_clusters"` // max number of the active clusters in the zone. No limit if 0.
+	Default     bool   `json:"default"`      // the zone used if no zone is requested
+	Reserved    int    `json:"reserved"`     // number of the clusters admitted to the zone which are not provisioned yet
+}
+
+// ZoneAvailability is an enabled zone with its current capacity
+type ZoneAvailability struct {
+	ibmcloud.Location
+	Default        bool `json:"default"`
+	MaxClusters    int  `json:"max_clusters"`
+	ActiveClusters int  `json:"active_clusters"`
+	Available      bool `json:"available"` // true if there is room for at least one more cluster in the zone
+}
+
+// SeedZones stores the given zones if no zones are stored yet, e.g. on the first start.
+// All the zones are enabled with no limit and the first one is the default zone.
+func SeedZones(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	zones := make([]Zone, 0, len(ids))
+	for i, id := range ids {
+		zones = append(zones, Zone{ID: id, Enabled: true, Default: i == 0})
+	}
+	seeded, err := insertZones(zones)
+	if err != nil {
+		return err
+	}
+	if seeded {
+		log.Infof(nil, "Zones seeded: %s", fmt.Sprintf("%v", ids))
+	}
+	return nil
+}
+
+// Zones returns all the stored zones sorted by ID
+func (s *ClusterService) Zones() ([]Zone, error) {
+	return getZones()
+}
+
+// SaveZone stores the zone. If the zone is the default one then the other zones are not default anymore.
+// The reservations of the zone are not changed.
+// Returns a Bad Request error if the zone is invalid.
+func (s *ClusterService) SaveZone(z Zone) error {
+	if z.ID == "" {
+		return devclustererr.NewBadRequestError("zone ID is missing", "")
+	}
+	if z.MaxClusters < 0 {
+		return devclustererr.NewBadRequestError(fmt.Sprintf("max number of clusters in zone %s must not be negative", z.ID), "")
+	}
+	if z.Default && !z.Enabled {
+		return devclustererr.NewBadRequestError(fmt.Sprintf("default zone %s must be enabled", z.ID), "")
+	}
+	return replaceZone(z)
+}
+
+// DeleteZone deletes the zone. Returns a Not Found error if the zone is not found.
+func (s *ClusterService) DeleteZone(id string) error {
+	deleted, err := deleteZone(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return devclustererr.NewNotFoundError(fmt.Sprintf("zone %s not found", id), "")
+	}
+	return nil
+}
+
+// DefaultZone returns the default zone. If no zone is marked as default then the first enabled zone is used.
+// Returns a Bad Request error if there is no enabled zone.
+func (s *ClusterService) DefaultZone() (string, error) {
+	zones, err := getZones()
+	if err != nil {
+		return "", err
+	}
+	first := ""
+	for _, z := range zones {
+		if z.Enabled {
+			if z.Default {
+				return z.ID, nil
+			}
+			if first == "" {
+				first = z.ID
+			}
+		}
+	}
+	if first == "" {
+		return "", devclustererr.NewBadRequestError("no default zone configured", "")
+	}
+	return first, nil
+}
+
+// AvailableZones returns the IBM Cloud zones enabled for the new requests with their current capacity.
+// If the zones are not managed then all the IBM Cloud zones are returned with no limit.
+func (s *ClusterService) AvailableZones() ([]ZoneAvailability, error) {
+	locations, err := s.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := getZones()
+	if err != nil {
+		return nil, err
+	}
+	managed := make(map[string]Zone, len(zones))
+	for _, z := range zones {
+		managed[z.ID] = z
+	}
+	available := make([]ZoneAvailability, 0, len(locations))
+	for _, l := range locations {
+		z, found := managed[l.ID]
+		if len(zones) > 0 && (!found || !z.Enabled) {
+			continue
+		}
+		active, err := countActiveClusters(withZone(l.ID))
+		if err != nil {
+			return nil, err
+		}
+		available = append(available, ZoneAvailability{
+			Location:       l,
+			Default:        z.Default,
+			MaxClusters:    z.MaxClusters,
+			ActiveClusters: active,
+			Available:      z.MaxClusters == 0 || active+z.Reserved < z.MaxClusters,
+		})
+	}
+	if len(available) < len(zones) {
+		log.Error(nil, fmt.Errorf("not all enabled zones are available in IBM Cloud"), fmt.Sprintf("enabled zones: %v; available zones: %v", zones, locations))
+	}
+	return available, nil
+}
+
+// maxZoneReservationAttempts is the max number of attempts to reserve clusters in a zone changed concurrently
+const maxZoneReservationAttempts = 10
+
+// reserveZoneCapacity atomically reserves n clusters in the zone so the concurrent requests can't exceed the zone capacity
+// together. Every reserved cluster must be released by releaseZoneCapacity once it's stored or failed to get provisioned.
+// Returns a Bad Request error if the zone is not enabled or has no room for n more clusters.
+// Nothing is reserved if the zones are not managed.
+func (s *ClusterService) reserveZoneCapacity(zone string, n int) error {
+	for attempt := 0; attempt < maxZoneReservationAttempts; attempt++ {
+		z, err := getZoneDocument(zone)
+		if err != nil {
+			return err
+		}
+		if z == nil {
+			zones, err := getZones()
+			if err != nil {
+				return err
+			}
+			if len(zones) == 0 {
+				return nil
+			}
+		}
+		if z == nil || !z.Enabled {
+			return devclustererr.NewBadRequestError(fmt.Sprintf("zone %s is not enabled", zone), "")
+		}
+		if z.MaxClusters > 0 {
+			// The stored clusters are counted after the zone is read. A cluster stored in the meantime is counted twice
+			// until its reservation is released which changes the version so the reservation is attempted again.
+			active, err := countActiveClusters(withZone(zone))
+			if err != nil {
+				return err
+			}
+			if room := z.MaxClusters - active - z.Reserved; n > room {
+				if room < 0 {
+					room = 0
+				}
+				return devclustererr.NewBadRequestError(fmt.Sprintf("zone %s has room for %d more clusters only", zone, room), "")
+			}
+		}
+		reserved, err := reserveZone(zone, z.Version, n)
+		if err != nil || reserved {
+			return err
+		}
+	}
+	return errors.Errorf("unable to reserve %d clusters in zone %s; the zone is being changed concurrently", n, zone)
+}
+
+// releaseZoneCapacity releases n clusters reserved in the zone
+func (s *ClusterService) releaseZoneCapacity(zone string, n int) {
+	if err := releaseZone(zone, n); err != nil {
+		log.Error(nil, err, fmt.Sprintf("unable to release %d clusters reserved in zone %s", n, zone))
+	}
+}
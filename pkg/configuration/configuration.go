@@ -4,6 +4,7 @@ package configuration
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
@@ -102,113 +103,283 @@ const (
 	varIBMCloudApiCallTimeoutSec    = "ibmcloud.api_call_timeout_sec"
 	DefaultBMCloudApiCallTimeoutSec = 24 * 60 * 60 // 24 hours
 
+	// Transport used for all the IBM Cloud API calls
+	varIBMCloudRateLimitPerSec             = "ibmcloud.rate_limit_per_sec"
+	DefaultIBMCloudRateLimitPerSec         = 5
+	varIBMCloudMaxRetries                  = "ibmcloud.max_retries"
+	DefaultIBMCloudMaxRetries              = 3
+	varIBMCloudRequestTimeoutSec           = "ibmcloud.request_timeout_sec"
+	DefaultIBMCloudRequestTimeoutSec       = 60
+	varIBMCloudCircuitBreakerThreshold     = "ibmcloud.circuit_breaker_threshold"
+	DefaultIBMCloudCircuitBreakerThreshold = 5
+	varIBMCloudCircuitBreakerOpenSec       = "ibmcloud.circuit_breaker_open_sec"
+	DefaultIBMCloudCircuitBreakerOpenSec   = 60
+
 	// Tenant cluster authN
 	varIBMCloudAccountID   = "ibmcloud.account_id"
 	varIBMCloudTenantID    = "ibmcloud.tenant_id"
 	varIBMCloudIDPName     = "ibmcloud.idp_name"
 	DefaultIBMCloudIDPName = "devcluster"
 
+	// Multiple IBM Cloud accounts
+	varIBMCloudAccounts = "ibmcloud.accounts"
+
+	// Reconciliation of the DB state with IBM Cloud
+	varReconcileIntervalSec             = "reconcile.interval_sec"
+	DefaultReconcileIntervalSec         = 60 * 60 // 1 hour
+	varReconcileAdoptOrphanClusters     = "reconcile.adopt_orphan_clusters"
+	DefaultReconcileAdoptOrphanClusters = false
+
+	// Retrying failed cluster deletions
+	varDeleteRetryBaseSec         = "cluster.delete_retry_base_sec"
+	DefaultDeleteRetryBaseSec     = 10 * 60 // 10 minutes
+	varDeleteRetryMaxAttempts     = "cluster.delete_retry_max_attempts"
+	DefaultDeleteRetryMaxAttempts = 6
+
+	// Replacing the clusters which failed to get provisioned
+	varAutoReplaceFailedClusters     = "cluster.auto_replace_failed"
+	DefaultAutoReplaceFailedClusters = false
+	varAutoReplaceMaxAttempts        = "cluster.auto_replace_max_attempts"
+	DefaultAutoReplaceMaxAttempts    = 2
+
+	// Autoscaling of the user pool
+	varUserPoolMinFree              = "users.pool_min_free"
+	DefaultUserPoolMinFree          = 0 // autoscaling is disabled
+	varUserPoolBatchSize            = "users.pool_batch_size"
+	DefaultUserPoolBatchSize        = 10
+	varUserPoolCheckIntervalSec     = "users.pool_check_interval_sec"
+	DefaultUserPoolCheckIntervalSec = 5 * 60 // 5 minutes
+	varUserPoolShortagePolicy       = "users.pool_shortage_policy"
+	DefaultUserPoolShortagePolicy   = "queue"
+
+	// Identity provider of the tenant cluster users
+	varIdentityProvider        = "identity.provider"
+	DefaultIdentityProvider    = "cloud-directory"
+	varIdentityEmailDomain     = "identity.email_domain"
+	DefaultIdentityEmailDomain = "redhat.com"
+
+	// Policy of the passwords generated for the tenant cluster users
+	varPasswordLength               = "password.length"
+	DefaultPasswordLength           = 12
+	varPasswordCharacterClasses     = "password.character_classes"
+	DefaultPasswordCharacterClasses = "lower,upper,digits"
+	varPasswordPassphraseWords      = "password.passphrase_words"
+	DefaultPasswordPassphraseWords  = 0 // passphrases are disabled
+
+	// Bootstrap steps run against the provisioned clusters
+	varBootstrapSteps              = "bootstrap.steps"
+	varBootstrapCLIPath            = "bootstrap.cli_path"
+	DefaultBootstrapCLIPath        = "oc"
+	varBootstrapStepTimeoutSec     = "bootstrap.step_timeout_sec"
+	DefaultBootstrapStepTimeoutSec = 10 * 60 // 10 minutes
+
+	// Templates of the cluster and user URLs
+	varURLTemplates = "urls.templates"
+	// DefaultURLTemplates are the URLs of the IBM Cloud login and the OpenShift starter guides workshop
+	DefaultURLTemplates = `[
+	{"name": "identity-provider", "template": "https://cloud.ibm.com/authorize/{{ .IDPName }}"},
+	{"name": "login", "template": "https://iam.cloud.ibm.com/identity/devcluster/authorize?client_id=HOP55v1CCT&response_type=code&state={{ printf \"https://cloud.ibm.com/kubernetes/clusters/%s/overview\" .Cluster.ID | urlquery }}&redirect_uri={{ urlquery \"https://cloud.ibm.com/login/callback\" }}"},
+	{"name": "console", "template": "{{ if .Cluster.Hostname }}https://console-openshift-console.{{ .Cluster.Hostname }}{{ end }}"},
+	{"name": "workshop", "scope": "user", "template": "https://redhat-scholars.github.io/openshift-starter-guides/rhs-openshift-starter-guides/4.8/index.html?CLUSTER_SUBDOMAIN={{ .Cluster.Hostname }}&USERNAME={{ .User.ID }}&PASSWORD={{ .User.Password }}&LOGIN={{ index .URLs \"login\" | urlquery }}&PROJECT={{ .User.Project }}"}
+]`
+	varURLDefaultTemplates = "urls.default_templates"
+
+	// Estimated cost of the clusters
+	varCostPriceTable   = "cost.price_table"
+	varCostCurrency     = "cost.currency"
+	DefaultCostCurrency = "USD"
+
+	// Archival of the old requests
+	varArchiveRetentionDays     = "archive.retention_days"
+	DefaultArchiveRetentionDays = 0 // archival is disabled
+	varArchiveIntervalSec       = "archive.interval_sec"
+	DefaultArchiveIntervalSec   = 24 * 60 * 60 // 1 day
+
+	// Backups of the stored requests, clusters and users
+	varBackupPassphrase = "backup.passphrase"
+
+	// Notifications sent to the service admins
+	varNotificationWebhookURL = "notification.webhook_url"
+
 	varMongodbConnectionString = "mongodb.connection_string"
 	varMongodbDatabase         = "mongodb.database"
 	DefaultMongodbDatabase     = "devcluster"
 	varMongodbCA               = "mongodb.ca"
+
+	// Secrets provider the API keys and the Mongo credentials are read from
+	varSecretsProvider               = "secrets.provider"
+	varSecretsDir                    = "secrets.dir"
+	DefaultSecretsDir                = "/etc/devcluster/secrets"
+	varSecretsVaultAddress           = "secrets.vault_address"
+	varSecretsVaultToken             = "secrets.vault_token"
+	varSecretsVaultPath              = "secrets.vault_path"
+	DefaultSecretsVaultPath          = "secret/data/devcluster"
+	varSecretsRefreshIntervalSec     = "secrets.refresh_interval_sec"
+	DefaultSecretsRefreshIntervalSec = 60
+
+	// Optional YAML or JSON configuration file. The environment variables take precedence over the file.
+	varConfigFile = "config_file"
+
+	varExpiryCheckIntervalSec     = "expiry.check_interval_sec"
+	DefaultExpiryCheckIntervalSec = 10 * 60 // 10 minutes
+
+	// Comma separated zones stored as the initial zones the clusters can be requested in. The first one is the default zone.
+	varZonesAllowed     = "zones.allowed"
+	DefaultZonesAllowed = "wdc04,wdc06,wdc07,che01,fra02,fra04,fra05,ams03"
+
+	// Comma separated usernames of the service admins allowed to see the effective configuration
+	varAdminUsers = "admin.users"
 )
 
 // Config encapsulates the Viper configuration which stores the
 // configuration data in-memory.
 type Config struct {
-	v *viper.Viper
+	mux        sync.RWMutex
+	v          *viper.Viper
+	file       string
+	validators []Validator
+	secretsMux sync.RWMutex
+	secrets    SecretsProvider
+}
+
+// SecretsProvider provides the secrets by their names, e.g. "ibmcloud.apikey"
+type SecretsProvider interface {
+	// Get returns the current value of the secret and true if the secret is found
+	Get(name string) (string, bool)
 }
 
 // New creates a new configuration
 func New() *Config {
-	c := &Config{
-		v: viper.New(),
+	return &Config{
+		v: newViper(),
 	}
-	c.v.SetEnvPrefix(EnvPrefix)
-	c.v.AutomaticEnv()
-	c.v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	c.v.SetTypeByDefaultValue(true)
-	c.setConfigDefaults()
-	return c
+}
+
+// newViper returns a new Viper instance reading the environment variables and with the defaults set
+func newViper() *viper.Viper {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetTypeByDefaultValue(true)
+	setConfigDefaults(v)
+	return v
 }
 
 // GetViperInstance returns the underlying Viper instance.
+// The instance is replaced when the configuration file is reloaded.
 func (c *Config) GetViperInstance() *viper.Viper {
+	return c.viper()
+}
+
+func (c *Config) viper() *viper.Viper {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
 	return c.v
 }
 
-func (c *Config) setConfigDefaults() {
-	c.v.SetTypeByDefaultValue(true)
-
-	c.v.SetDefault(varHTTPAddress, DefaultHTTPAddress)
-	c.v.SetDefault(varHTTPCompressResponses, DefaultHTTPCompressResponses)
-	c.v.SetDefault(varHTTPWriteTimeout, DefaultHTTPWriteTimeout)
-	c.v.SetDefault(varHTTPReadTimeout, DefaultHTTPReadTimeout)
-	c.v.SetDefault(varHTTPIdleTimeout, DefaultHTTPIdleTimeout)
-	c.v.SetDefault(varEnvironment, DefaultEnvironment)
-	c.v.SetDefault(varLogLevel, DefaultLogLevel)
-	c.v.SetDefault(varLogJSON, DefaultLogJSON)
-	c.v.SetDefault(varGracefulTimeout, DefaultGracefulTimeout)
-	c.v.SetDefault(varAuthClientLibraryURL, DefaultAuthClientLibraryURL)
-	c.v.SetDefault(varAuthClientConfigRaw, DefaultAuthClientConfigRaw)
-	c.v.SetDefault(varAuthClientConfigContentType, DefaultAuthClientConfigContentType)
-	c.v.SetDefault(varAuthClientPublicKeysURL, DefaultAuthClientPublicKeysURL)
-	c.v.SetDefault(varNamespace, DefaultNamespace)
-	c.v.SetDefault(varMongodbDatabase, DefaultMongodbDatabase)
-	c.v.SetDefault(varIBMCloudApiCallRetrySec, DefaultBMCloudApiCallRetrySec)
-	c.v.SetDefault(varIBMCloudApiCallTimeoutSec, DefaultBMCloudApiCallTimeoutSec)
-	c.v.SetDefault(varIBMCloudIDPName, DefaultIBMCloudIDPName)
+func setConfigDefaults(v *viper.Viper) {
+	v.SetTypeByDefaultValue(true)
+
+	v.SetDefault(varHTTPAddress, DefaultHTTPAddress)
+	v.SetDefault(varHTTPCompressResponses, DefaultHTTPCompressResponses)
+	v.SetDefault(varHTTPWriteTimeout, DefaultHTTPWriteTimeout)
+	v.SetDefault(varHTTPReadTimeout, DefaultHTTPReadTimeout)
+	v.SetDefault(varHTTPIdleTimeout, DefaultHTTPIdleTimeout)
+	v.SetDefault(varEnvironment, DefaultEnvironment)
+	v.SetDefault(varLogLevel, DefaultLogLevel)
+	v.SetDefault(varLogJSON, DefaultLogJSON)
+	v.SetDefault(varGracefulTimeout, DefaultGracefulTimeout)
+	v.SetDefault(varAuthClientLibraryURL, DefaultAuthClientLibraryURL)
+	v.SetDefault(varAuthClientConfigRaw, DefaultAuthClientConfigRaw)
+	v.SetDefault(varAuthClientConfigContentType, DefaultAuthClientConfigContentType)
+	v.SetDefault(varAuthClientPublicKeysURL, DefaultAuthClientPublicKeysURL)
+	v.SetDefault(varNamespace, DefaultNamespace)
+	v.SetDefault(varMongodbDatabase, DefaultMongodbDatabase)
+	v.SetDefault(varSecretsDir, DefaultSecretsDir)
+	v.SetDefault(varSecretsVaultPath, DefaultSecretsVaultPath)
+	v.SetDefault(varSecretsRefreshIntervalSec, DefaultSecretsRefreshIntervalSec)
+	v.SetDefault(varIBMCloudApiCallRetrySec, DefaultBMCloudApiCallRetrySec)
+	v.SetDefault(varIBMCloudApiCallTimeoutSec, DefaultBMCloudApiCallTimeoutSec)
+	v.SetDefault(varIBMCloudRateLimitPerSec, DefaultIBMCloudRateLimitPerSec)
+	v.SetDefault(varIBMCloudMaxRetries, DefaultIBMCloudMaxRetries)
+	v.SetDefault(varIBMCloudRequestTimeoutSec, DefaultIBMCloudRequestTimeoutSec)
+	v.SetDefault(varIBMCloudCircuitBreakerThreshold, DefaultIBMCloudCircuitBreakerThreshold)
+	v.SetDefault(varIBMCloudCircuitBreakerOpenSec, DefaultIBMCloudCircuitBreakerOpenSec)
+	v.SetDefault(varIBMCloudIDPName, DefaultIBMCloudIDPName)
+	v.SetDefault(varReconcileIntervalSec, DefaultReconcileIntervalSec)
+	v.SetDefault(varReconcileAdoptOrphanClusters, DefaultReconcileAdoptOrphanClusters)
+	v.SetDefault(varDeleteRetryBaseSec, DefaultDeleteRetryBaseSec)
+	v.SetDefault(varDeleteRetryMaxAttempts, DefaultDeleteRetryMaxAttempts)
+	v.SetDefault(varAutoReplaceFailedClusters, DefaultAutoReplaceFailedClusters)
+	v.SetDefault(varAutoReplaceMaxAttempts, DefaultAutoReplaceMaxAttempts)
+	v.SetDefault(varUserPoolMinFree, DefaultUserPoolMinFree)
+	v.SetDefault(varUserPoolBatchSize, DefaultUserPoolBatchSize)
+	v.SetDefault(varUserPoolCheckIntervalSec, DefaultUserPoolCheckIntervalSec)
+	v.SetDefault(varUserPoolShortagePolicy, DefaultUserPoolShortagePolicy)
+	v.SetDefault(varIdentityProvider, DefaultIdentityProvider)
+	v.SetDefault(varIdentityEmailDomain, DefaultIdentityEmailDomain)
+	v.SetDefault(varPasswordLength, DefaultPasswordLength)
+	v.SetDefault(varPasswordCharacterClasses, DefaultPasswordCharacterClasses)
+	v.SetDefault(varPasswordPassphraseWords, DefaultPasswordPassphraseWords)
+	v.SetDefault(varBootstrapCLIPath, DefaultBootstrapCLIPath)
+	v.SetDefault(varURLTemplates, DefaultURLTemplates)
+	v.SetDefault(varBootstrapStepTimeoutSec, DefaultBootstrapStepTimeoutSec)
+	v.SetDefault(varCostCurrency, DefaultCostCurrency)
+	v.SetDefault(varArchiveRetentionDays, DefaultArchiveRetentionDays)
+	v.SetDefault(varArchiveIntervalSec, DefaultArchiveIntervalSec)
+	v.SetDefault(varExpiryCheckIntervalSec, DefaultExpiryCheckIntervalSec)
+	v.SetDefault(varZonesAllowed, DefaultZonesAllowed)
 }
 
 // GetHTTPAddress returns the HTTP address (as set via default, config file, or
 // environment variable) that the app-server binds to (e.g. "0.0.0.0:8080").
 func (c *Config) GetHTTPAddress() string {
-	return c.v.GetString(varHTTPAddress)
+	return c.viper().GetString(varHTTPAddress)
 }
 
 // GetHTTPCompressResponses returns true if HTTP responses should be compressed
 // for clients that support it via the 'Accept-Encoding' header.
 func (c *Config) GetHTTPCompressResponses() bool {
-	return c.v.GetBool(varHTTPCompressResponses)
+	return c.viper().GetBool(varHTTPCompressResponses)
 }
 
 // GetHTTPWriteTimeout returns the duration for the write timeout.
 func (c *Config) GetHTTPWriteTimeout() time.Duration {
-	return c.v.GetDuration(varHTTPWriteTimeout)
+	return c.viper().GetDuration(varHTTPWriteTimeout)
 }
 
 // GetHTTPReadTimeout returns the duration for the read timeout.
 func (c *Config) GetHTTPReadTimeout() time.Duration {
-	return c.v.GetDuration(varHTTPReadTimeout)
+	return c.viper().GetDuration(varHTTPReadTimeout)
 }
 
 // GetHTTPIdleTimeout returns the duration for the idle timeout.
 func (c *Config) GetHTTPIdleTimeout() time.Duration {
-	return c.v.GetDuration(varHTTPIdleTimeout)
+	return c.viper().GetDuration(varHTTPIdleTimeout)
 }
 
 // GetEnvironment returns the environment such as prod, stage, unit-tests, e2e-tests, dev, etc
 func (c *Config) GetEnvironment() string {
-	return c.v.GetString(varEnvironment)
+	return c.viper().GetString(varEnvironment)
 }
 
 // GetLogLevel returns the logging level (as set via config file or environment
 // variable).
 func (c *Config) GetLogLevel() string {
-	return c.v.GetString(varLogLevel)
+	return c.viper().GetString(varLogLevel)
 }
 
 // IsLogJSON returns if we should log json format (as set via config file or
 // environment variable).
 func (c *Config) IsLogJSON() bool {
-	return c.v.GetBool(varLogJSON)
+	return c.viper().GetBool(varLogJSON)
 }
 
 // GetGracefulTimeout returns the duration for which the server gracefully wait
 // for existing connections to finish - e.g. 15s or 1m.
 func (c *Config) GetGracefulTimeout() time.Duration {
-	return c.v.GetDuration(varGracefulTimeout)
+	return c.viper().GetDuration(varGracefulTimeout)
 }
 
 // IsTestingMode returns if the service runs in unit-tests environment
@@ -219,72 +390,349 @@ func (c *Config) IsTestingMode() bool {
 // GetAuthClientLibraryURL returns the auth library location (as set via
 // config file or environment variable).
 func (c *Config) GetAuthClientLibraryURL() string {
-	return c.v.GetString(varAuthClientLibraryURL)
+	return c.viper().GetString(varAuthClientLibraryURL)
 }
 
 // GetAuthClientConfigAuthContentType returns the auth config config content type (as
 // set via config file or environment variable).
 func (c *Config) GetAuthClientConfigAuthContentType() string {
-	return c.v.GetString(varAuthClientConfigContentType)
+	return c.viper().GetString(varAuthClientConfigContentType)
 }
 
 // GetAuthClientConfigAuthRaw returns the auth config config (as
 // set via config file or environment variable).
 func (c *Config) GetAuthClientConfigAuthRaw() string {
-	return c.v.GetString(varAuthClientConfigRaw)
+	return c.viper().GetString(varAuthClientConfigRaw)
 }
 
 // GetAuthClientPublicKeysURL returns the public keys URL (as set via config file
 // or environment variable).
 func (c *Config) GetAuthClientPublicKeysURL() string {
-	return c.v.GetString(varAuthClientPublicKeysURL)
+	return c.viper().GetString(varAuthClientPublicKeysURL)
 }
 
 // GetIBMCloudApiCallRetrySec returns the number of seconds to wait between retrying calling IBM API
 func (c *Config) GetIBMCloudApiCallRetrySec() int {
-	return c.v.GetInt(varIBMCloudApiCallRetrySec)
+	return c.viper().GetInt(varIBMCloudApiCallRetrySec)
 }
 
 // GetIBMCloudApiCallTimeoutSec returns the timeout of waitinf for the cluster to get ready in seconds
 func (c *Config) GetIBMCloudApiCallTimeoutSec() int {
-	return c.v.GetInt(varIBMCloudApiCallTimeoutSec)
+	return c.viper().GetInt(varIBMCloudApiCallTimeoutSec)
 }
 
 // GetNamespace returns the namespace in which the devcluster service and host operator is running
 func (c *Config) GetNamespace() string {
-	return c.v.GetString(varNamespace)
+	return c.viper().GetString(varNamespace)
+}
+
+// GetIBMCloudRateLimitPerSec returns the max number of calls per second to every IBM Cloud API endpoint. 0 means no limit.
+func (c *Config) GetIBMCloudRateLimitPerSec() int {
+	return c.viper().GetInt(varIBMCloudRateLimitPerSec)
+}
+
+// GetIBMCloudMaxRetries returns the max number of retries of an IBM Cloud API call which failed with 429 or 5xx.
+// The calls which create resources are retried only if they have not been processed by IBM Cloud.
+func (c *Config) GetIBMCloudMaxRetries() int {
+	return c.viper().GetInt(varIBMCloudMaxRetries)
+}
+
+// GetIBMCloudRequestTimeoutSec returns the timeout of a single attempt of an IBM Cloud API call. Every retry has its own timeout.
+func (c *Config) GetIBMCloudRequestTimeoutSec() int {
+	return c.viper().GetInt(varIBMCloudRequestTimeoutSec)
+}
+
+// GetIBMCloudCircuitBreakerThreshold returns the number of consecutive failed IBM Cloud API calls
+// after which IBM Cloud is considered degraded and the calls are paused
+func (c *Config) GetIBMCloudCircuitBreakerThreshold() int {
+	return c.viper().GetInt(varIBMCloudCircuitBreakerThreshold)
+}
+
+// GetIBMCloudCircuitBreakerOpenSec returns the number of seconds the IBM Cloud API calls are paused for when IBM Cloud is degraded
+func (c *Config) GetIBMCloudCircuitBreakerOpenSec() int {
+	return c.viper().GetInt(varIBMCloudCircuitBreakerOpenSec)
 }
 
 // GetIBMCloudAPIKey returns the IBM Cloud API Key
 func (c *Config) GetIBMCloudAPIKey() string {
-	return c.v.GetString(varIBMCloudAPIKey)
+	return c.GetSecret(varIBMCloudAPIKey)
 }
 
 // GetIBMCloudAccountID returns the main/parent IBM Cloud Account ID
 func (c *Config) GetIBMCloudAccountID() string {
-	return c.v.GetString(varIBMCloudAccountID)
+	return c.viper().GetString(varIBMCloudAccountID)
 }
 
 // GetIBMCloudTenantID returns the Cloud Directory ID
 func (c *Config) GetIBMCloudTenantID() string {
-	return c.v.GetString(varIBMCloudTenantID)
+	return c.viper().GetString(varIBMCloudTenantID)
+}
+
+// GetIBMCloudAccounts returns the JSON array of the IBM Cloud accounts with their own credentials, App ID tenant,
+// teams, zones and max number of clusters. If not set then the only account is the one configured by
+// the API key, the account ID and the tenant ID above.
+func (c *Config) GetIBMCloudAccounts() string {
+	return c.GetSecret(varIBMCloudAccounts)
 }
 
 // GetIBMCloudIDPName returns the IDP name
 func (c *Config) GetIBMCloudIDPName() string {
-	return c.v.GetString(varIBMCloudIDPName)
+	return c.viper().GetString(varIBMCloudIDPName)
+}
+
+// GetReconcileIntervalSec returns the interval in seconds between two runs of the DB vs IBM Cloud reconciliation
+func (c *Config) GetReconcileIntervalSec() int {
+	return c.viper().GetInt(varReconcileIntervalSec)
+}
+
+// GetReconcileAdoptOrphanClusters returns true if the clusters found in IBM Cloud but missing in the DB
+// should be added to the DB by the reconciliation. If false then such clusters are only reported.
+func (c *Config) GetReconcileAdoptOrphanClusters() bool {
+	return c.viper().GetBool(varReconcileAdoptOrphanClusters)
+}
+
+// GetDeleteRetryBaseSec returns the delay in seconds before the first retry of a failed cluster deletion.
+// The delay is doubled with every next failed attempt.
+func (c *Config) GetDeleteRetryBaseSec() int {
+	return c.viper().GetInt(varDeleteRetryBaseSec)
+}
+
+// GetDeleteRetryMaxAttempts returns the max number of attempts to delete a cluster before giving up and notifying the admins
+func (c *Config) GetDeleteRetryMaxAttempts() int {
+	return c.viper().GetInt(varDeleteRetryMaxAttempts)
+}
+
+// GetAutoReplaceFailedClusters returns true if the clusters which failed to get provisioned
+// should be automatically replaced by new clusters within the same request.
+func (c *Config) GetAutoReplaceFailedClusters() bool {
+	return c.viper().GetBool(varAutoReplaceFailedClusters)
+}
+
+// GetAutoReplaceMaxAttempts returns the max number of automatic replacements of a failed cluster
+func (c *Config) GetAutoReplaceMaxAttempts() int {
+	return c.viper().GetInt(varAutoReplaceMaxAttempts)
+}
+
+// GetUserPoolMinFree returns the min number of free users to be kept in the user pool.
+// Zero means the user pool autoscaling is disabled.
+func (c *Config) GetUserPoolMinFree() int {
+	return c.viper().GetInt(varUserPoolMinFree)
+}
+
+// GetUserPoolBatchSize returns the number of users created at once when scaling up the user pool
+func (c *Config) GetUserPoolBatchSize() int {
+	return c.viper().GetInt(varUserPoolBatchSize)
+}
+
+// GetUserPoolCheckIntervalSec returns the interval in seconds between two checks of the number of free users in the user pool
+func (c *Config) GetUserPoolCheckIntervalSec() int {
+	return c.viper().GetInt(varUserPoolCheckIntervalSec)
+}
+
+// GetUserPoolShortagePolicy returns what to do with the new requests which can't be covered by the free users in the pool:
+// "queue" the request until the pool is scaled up or "reject" it.
+// The requests are always rejected if the user pool autoscaling is disabled.
+func (c *Config) GetUserPoolShortagePolicy() string {
+	return c.viper().GetString(varUserPoolShortagePolicy)
+}
+
+// GetIdentityProvider returns the name of the identity provider which manages the tenant cluster users
+func (c *Config) GetIdentityProvider() string {
+	return c.viper().GetString(varIdentityProvider)
+}
+
+// GetIdentityEmailDomain returns the domain of the emails generated for the tenant cluster users
+// which are created without an email
+func (c *Config) GetIdentityEmailDomain() string {
+	return c.viper().GetString(varIdentityEmailDomain)
+}
+
+// GetPasswordLength returns the length of the generated passwords
+func (c *Config) GetPasswordLength() int {
+	return c.viper().GetInt(varPasswordLength)
+}
+
+// GetPasswordCharacterClasses returns the character classes used in the generated passwords.
+// Every generated password contains at least one character of every class.
+// The classes are set as a comma separated list of: lower, upper, digits, symbols
+func (c *Config) GetPasswordCharacterClasses() []string {
+	var classes []string
+	for _, class := range strings.Split(c.viper().GetString(varPasswordCharacterClasses), ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// GetPasswordPassphraseWords returns the number of words in the generated passphrases.
+// If set then human friendly passphrases are generated instead of random passwords.
+func (c *Config) GetPasswordPassphraseWords() int {
+	return c.viper().GetInt(varPasswordPassphraseWords)
+}
+
+// GetBootstrapSteps returns the JSON array of the steps run against every provisioned cluster before the cluster is considered ready.
+// If not set then the clusters are not bootstrapped.
+func (c *Config) GetBootstrapSteps() string {
+	return c.viper().GetString(varBootstrapSteps)
+}
+
+// GetBootstrapCLIPath returns the path to the oc CLI used to log in the clusters and to apply the bootstrap manifests
+func (c *Config) GetBootstrapCLIPath() string {
+	return c.viper().GetString(varBootstrapCLIPath)
+}
+
+// GetBootstrapStepTimeoutSec returns the default timeout of one bootstrap step in seconds
+func (c *Config) GetBootstrapStepTimeoutSec() int {
+	return c.viper().GetInt(varBootstrapStepTimeoutSec)
+}
+
+// GetURLTemplates returns the JSON array of the named Go templates of the cluster and user URLs
+func (c *Config) GetURLTemplates() string {
+	return c.viper().GetString(varURLTemplates)
+}
+
+// GetURLDefaultTemplates returns the names of the URL templates rendered for the requests which don't select any templates.
+// If not set then all the templates are rendered.
+func (c *Config) GetURLDefaultTemplates() []string {
+	var names []string
+	for _, name := range strings.Split(c.viper().GetString(varURLDefaultTemplates), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetCostPriceTable returns the JSON array of the hourly cluster prices by the machine type and the number of workers.
+// If not set then the usage reports contain the cluster-hours only.
+func (c *Config) GetCostPriceTable() string {
+	return c.viper().GetString(varCostPriceTable)
+}
+
+// GetCostCurrency returns the currency of the prices in the price table
+func (c *Config) GetCostCurrency() string {
+	return c.viper().GetString(varCostCurrency)
+}
+
+// GetArchiveRetentionDays returns the number of days after which the requests in the terminal states
+// are moved to the archive together with their clusters. The archival is disabled if 0.
+func (c *Config) GetArchiveRetentionDays() int {
+	return c.viper().GetInt(varArchiveRetentionDays)
+}
+
+// GetArchiveIntervalSec returns the interval in seconds between two runs of the archival
+func (c *Config) GetArchiveIntervalSec() int {
+	return c.viper().GetInt(varArchiveIntervalSec)
+}
+
+// GetBackupPassphrase returns the passphrase the backups are encrypted with.
+// If not set then the backups are not encrypted.
+func (c *Config) GetBackupPassphrase() string {
+	return c.viper().GetString(varBackupPassphrase)
+}
+
+// GetNotificationWebhookURL returns the URL of the webhook the admin notifications are posted to.
+// If not set then the notifications are only logged.
+func (c *Config) GetNotificationWebhookURL() string {
+	return c.viper().GetString(varNotificationWebhookURL)
 }
 
 func (c *Config) GetMongodbConnectionString() string {
-	return c.v.GetString(varMongodbConnectionString)
+	return c.GetSecret(varMongodbConnectionString)
 }
 
 // GetMongodbDatabase returns the mongo database name
 func (c *Config) GetMongodbDatabase() string {
-	return c.v.GetString(varMongodbDatabase)
+	return c.viper().GetString(varMongodbDatabase)
 }
 
 // GetMongodbCA returns the Certificate Authority which should be used to connect to the mongo database
 func (c *Config) GetMongodbCA() string {
-	return c.v.GetString(varMongodbCA)
+	return c.GetSecret(varMongodbCA)
+}
+
+// SetSecretsProvider sets the provider the secrets are read from
+func (c *Config) SetSecretsProvider(p SecretsProvider) {
+	c.secretsMux.Lock()
+	defer c.secretsMux.Unlock()
+	c.secrets = p
+}
+
+// GetSecret returns the current value of the secret with the given name, e.g. "ibmcloud.apikey".
+// The secret is read from the secrets provider if set and if the provider has the secret, otherwise from the configuration.
+func (c *Config) GetSecret(name string) string {
+	c.secretsMux.RLock()
+	p := c.secrets
+	c.secretsMux.RUnlock()
+	if p != nil {
+		if value, found := p.Get(name); found {
+			return value
+		}
+	}
+	return c.viper().GetString(name)
+}
+
+// GetSecretsProvider returns the kind of the provider the secrets are read from: "file" or "vault".
+// If not set then the secrets are read from the configuration like the other settings.
+func (c *Config) GetSecretsProvider() string {
+	return c.viper().GetString(varSecretsProvider)
+}
+
+// GetSecretsDir returns the directory the secret files are mounted to by the "file" secrets provider.
+// Every file contains one secret and is named after the secret, e.g. "ibmcloud.apikey".
+func (c *Config) GetSecretsDir() string {
+	return c.viper().GetString(varSecretsDir)
+}
+
+// GetSecretsVaultAddress returns the address of the Vault compatible server used by the "vault" secrets provider
+func (c *Config) GetSecretsVaultAddress() string {
+	return c.viper().GetString(varSecretsVaultAddress)
+}
+
+// GetSecretsVaultToken returns the token used to authenticate to Vault
+func (c *Config) GetSecretsVaultToken() string {
+	return c.viper().GetString(varSecretsVaultToken)
+}
+
+// GetSecretsVaultPath returns the path of the Vault key/value secret containing the secrets
+func (c *Config) GetSecretsVaultPath() string {
+	return c.viper().GetString(varSecretsVaultPath)
+}
+
+// GetSecretsRefreshIntervalSec returns the interval in seconds between two reloads of the secrets so the rotated secrets are picked up
+func (c *Config) GetSecretsRefreshIntervalSec() int {
+	return c.viper().GetInt(varSecretsRefreshIntervalSec)
+}
+
+// GetConfigFile returns the path of the optional YAML or JSON configuration file
+func (c *Config) GetConfigFile() string {
+	return c.viper().GetString(varConfigFile)
+}
+
+// GetExpiryCheckIntervalSec returns the interval in seconds between two checks for the expired clusters
+func (c *Config) GetExpiryCheckIntervalSec() int {
+	return c.viper().GetInt(varExpiryCheckIntervalSec)
+}
+
+// GetZonesAllowed returns the zones stored as the initial zones the clusters can be requested in if no zones are stored yet.
+// The first zone is the default one. The stored zones are managed by the admins.
+func (c *Config) GetZonesAllowed() []string {
+	return splitList(c.viper().GetString(varZonesAllowed))
+}
+
+// GetAdminUsers returns the usernames of the service admins
+func (c *Config) GetAdminUsers() []string {
+	return splitList(c.viper().GetString(varAdminUsers))
+}
+
+// splitList splits the comma separated list skipping the empty items
+func splitList(raw string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
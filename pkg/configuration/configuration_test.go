@@ -339,3 +339,474 @@ func (s *TestConfigurationSuite) TestGetNamespace() {
 		assert.Equal(s.T(), newVal, config.GetNamespace())
 	})
 }
+
+func (s *TestConfigurationSuite) TestGetReconcileIntervalSec() {
+	key := configuration.EnvPrefix + "_" + "RECONCILE_INTERVAL_SEC"
+	resetFunc := UnsetEnvVarAndRestore(s.T(), key)
+	defer resetFunc()
+
+	s.Run("default", func() {
+		resetFunc := UnsetEnvVarAndRestore(s.T(), key)
+		defer resetFunc()
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultReconcileIntervalSec, config.GetReconcileIntervalSec())
+	})
+
+	s.Run("env overwrite", func() {
+		err := os.Setenv(key, "120")
+		require.NoError(s.T(), err)
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 120, config.GetReconcileIntervalSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetReconcileAdoptOrphanClusters() {
+	key := configuration.EnvPrefix + "_" + "RECONCILE_ADOPT_ORPHAN_CLUSTERS"
+	resetFunc := UnsetEnvVarAndRestore(s.T(), key)
+	defer resetFunc()
+
+	s.Run("default", func() {
+		resetFunc := UnsetEnvVarAndRestore(s.T(), key)
+		defer resetFunc()
+		config := s.getDefaultConfiguration()
+		assert.False(s.T(), config.GetReconcileAdoptOrphanClusters())
+	})
+
+	s.Run("env overwrite", func() {
+		err := os.Setenv(key, "true")
+		require.NoError(s.T(), err)
+		config := s.getDefaultConfiguration()
+		assert.True(s.T(), config.GetReconcileAdoptOrphanClusters())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetDeleteRetry() {
+	baseKey := configuration.EnvPrefix + "_" + "CLUSTER_DELETE_RETRY_BASE_SEC"
+	maxKey := configuration.EnvPrefix + "_" + "CLUSTER_DELETE_RETRY_MAX_ATTEMPTS"
+	resetBase := UnsetEnvVarAndRestore(s.T(), baseKey)
+	defer resetBase()
+	resetMax := UnsetEnvVarAndRestore(s.T(), maxKey)
+	defer resetMax()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultDeleteRetryBaseSec, config.GetDeleteRetryBaseSec())
+		assert.Equal(s.T(), configuration.DefaultDeleteRetryMaxAttempts, config.GetDeleteRetryMaxAttempts())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(baseKey, "30"))
+		require.NoError(s.T(), os.Setenv(maxKey, "3"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 30, config.GetDeleteRetryBaseSec())
+		assert.Equal(s.T(), 3, config.GetDeleteRetryMaxAttempts())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetAutoReplace() {
+	enabledKey := configuration.EnvPrefix + "_" + "CLUSTER_AUTO_REPLACE_FAILED"
+	maxKey := configuration.EnvPrefix + "_" + "CLUSTER_AUTO_REPLACE_MAX_ATTEMPTS"
+	resetEnabled := UnsetEnvVarAndRestore(s.T(), enabledKey)
+	defer resetEnabled()
+	resetMax := UnsetEnvVarAndRestore(s.T(), maxKey)
+	defer resetMax()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.False(s.T(), config.GetAutoReplaceFailedClusters())
+		assert.Equal(s.T(), configuration.DefaultAutoReplaceMaxAttempts, config.GetAutoReplaceMaxAttempts())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(enabledKey, "true"))
+		require.NoError(s.T(), os.Setenv(maxKey, "5"))
+		config := s.getDefaultConfiguration()
+		assert.True(s.T(), config.GetAutoReplaceFailedClusters())
+		assert.Equal(s.T(), 5, config.GetAutoReplaceMaxAttempts())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetUserPool() {
+	keys := map[string]string{
+		"min":      configuration.EnvPrefix + "_" + "USERS_POOL_MIN_FREE",
+		"batch":    configuration.EnvPrefix + "_" + "USERS_POOL_BATCH_SIZE",
+		"interval": configuration.EnvPrefix + "_" + "USERS_POOL_CHECK_INTERVAL_SEC",
+		"policy":   configuration.EnvPrefix + "_" + "USERS_POOL_SHORTAGE_POLICY",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultUserPoolMinFree, config.GetUserPoolMinFree())
+		assert.Equal(s.T(), configuration.DefaultUserPoolBatchSize, config.GetUserPoolBatchSize())
+		assert.Equal(s.T(), configuration.DefaultUserPoolCheckIntervalSec, config.GetUserPoolCheckIntervalSec())
+		assert.Equal(s.T(), configuration.DefaultUserPoolShortagePolicy, config.GetUserPoolShortagePolicy())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["min"], "20"))
+		require.NoError(s.T(), os.Setenv(keys["batch"], "5"))
+		require.NoError(s.T(), os.Setenv(keys["interval"], "30"))
+		require.NoError(s.T(), os.Setenv(keys["policy"], "reject"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 20, config.GetUserPoolMinFree())
+		assert.Equal(s.T(), 5, config.GetUserPoolBatchSize())
+		assert.Equal(s.T(), 30, config.GetUserPoolCheckIntervalSec())
+		assert.Equal(s.T(), "reject", config.GetUserPoolShortagePolicy())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetIdentity() {
+	providerKey := configuration.EnvPrefix + "_" + "IDENTITY_PROVIDER"
+	domainKey := configuration.EnvPrefix + "_" + "IDENTITY_EMAIL_DOMAIN"
+	resetProvider := UnsetEnvVarAndRestore(s.T(), providerKey)
+	defer resetProvider()
+	resetDomain := UnsetEnvVarAndRestore(s.T(), domainKey)
+	defer resetDomain()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultIdentityProvider, config.GetIdentityProvider())
+		assert.Equal(s.T(), configuration.DefaultIdentityEmailDomain, config.GetIdentityEmailDomain())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(providerKey, "htpasswd"))
+		require.NoError(s.T(), os.Setenv(domainKey, "example.com"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), "htpasswd", config.GetIdentityProvider())
+		assert.Equal(s.T(), "example.com", config.GetIdentityEmailDomain())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetPasswordPolicy() {
+	keys := map[string]string{
+		"length":     configuration.EnvPrefix + "_" + "PASSWORD_LENGTH",
+		"classes":    configuration.EnvPrefix + "_" + "PASSWORD_CHARACTER_CLASSES",
+		"passphrase": configuration.EnvPrefix + "_" + "PASSWORD_PASSPHRASE_WORDS",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultPasswordLength, config.GetPasswordLength())
+		assert.Equal(s.T(), []string{"lower", "upper", "digits"}, config.GetPasswordCharacterClasses())
+		assert.Equal(s.T(), configuration.DefaultPasswordPassphraseWords, config.GetPasswordPassphraseWords())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["length"], "20"))
+		require.NoError(s.T(), os.Setenv(keys["classes"], "lower, symbols"))
+		require.NoError(s.T(), os.Setenv(keys["passphrase"], "4"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 20, config.GetPasswordLength())
+		assert.Equal(s.T(), []string{"lower", "symbols"}, config.GetPasswordCharacterClasses())
+		assert.Equal(s.T(), 4, config.GetPasswordPassphraseWords())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetBootstrap() {
+	keys := map[string]string{
+		"steps":   configuration.EnvPrefix + "_" + "BOOTSTRAP_STEPS",
+		"cli":     configuration.EnvPrefix + "_" + "BOOTSTRAP_CLI_PATH",
+		"timeout": configuration.EnvPrefix + "_" + "BOOTSTRAP_STEP_TIMEOUT_SEC",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetBootstrapSteps())
+		assert.Equal(s.T(), configuration.DefaultBootstrapCLIPath, config.GetBootstrapCLIPath())
+		assert.Equal(s.T(), configuration.DefaultBootstrapStepTimeoutSec, config.GetBootstrapStepTimeoutSec())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["steps"], `[{"name":"workshop","manifests":"/etc/workshop"}]`))
+		require.NoError(s.T(), os.Setenv(keys["cli"], "/usr/local/bin/oc"))
+		require.NoError(s.T(), os.Setenv(keys["timeout"], "120"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), `[{"name":"workshop","manifests":"/etc/workshop"}]`, config.GetBootstrapSteps())
+		assert.Equal(s.T(), "/usr/local/bin/oc", config.GetBootstrapCLIPath())
+		assert.Equal(s.T(), 120, config.GetBootstrapStepTimeoutSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetURLTemplates() {
+	keys := map[string]string{
+		"templates": configuration.EnvPrefix + "_" + "URLS_TEMPLATES",
+		"default":   configuration.EnvPrefix + "_" + "URLS_DEFAULT_TEMPLATES",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultURLTemplates, config.GetURLTemplates())
+		assert.Empty(s.T(), config.GetURLDefaultTemplates())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["templates"], `[{"name":"guide","template":"https://guide"}]`))
+		require.NoError(s.T(), os.Setenv(keys["default"], "guide, console"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), `[{"name":"guide","template":"https://guide"}]`, config.GetURLTemplates())
+		assert.Equal(s.T(), []string{"guide", "console"}, config.GetURLDefaultTemplates())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetCost() {
+	keys := map[string]string{
+		"prices":   configuration.EnvPrefix + "_" + "COST_PRICE_TABLE",
+		"currency": configuration.EnvPrefix + "_" + "COST_CURRENCY",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetCostPriceTable())
+		assert.Equal(s.T(), configuration.DefaultCostCurrency, config.GetCostCurrency())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["prices"], `[{"machine_type":"b3c.4x16","hourly_price":0.5}]`))
+		require.NoError(s.T(), os.Setenv(keys["currency"], "EUR"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), `[{"machine_type":"b3c.4x16","hourly_price":0.5}]`, config.GetCostPriceTable())
+		assert.Equal(s.T(), "EUR", config.GetCostCurrency())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetArchive() {
+	keys := map[string]string{
+		"retention": configuration.EnvPrefix + "_" + "ARCHIVE_RETENTION_DAYS",
+		"interval":  configuration.EnvPrefix + "_" + "ARCHIVE_INTERVAL_SEC",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultArchiveRetentionDays, config.GetArchiveRetentionDays())
+		assert.Equal(s.T(), configuration.DefaultArchiveIntervalSec, config.GetArchiveIntervalSec())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["retention"], "90"))
+		require.NoError(s.T(), os.Setenv(keys["interval"], "3600"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 90, config.GetArchiveRetentionDays())
+		assert.Equal(s.T(), 3600, config.GetArchiveIntervalSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetIBMCloudAccounts() {
+	key := configuration.EnvPrefix + "_" + "IBMCLOUD_ACCOUNTS"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetIBMCloudAccounts())
+	})
+
+	s.Run("env overwrite", func() {
+		accounts := `[{"name":"emea","apikey":"key","account_id":"account","tenant_id":"tenant"}]`
+		require.NoError(s.T(), os.Setenv(key, accounts))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), accounts, config.GetIBMCloudAccounts())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetBackupPassphrase() {
+	key := configuration.EnvPrefix + "_" + "BACKUP_PASSPHRASE"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetBackupPassphrase())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(key, "correct horse battery staple"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), "correct horse battery staple", config.GetBackupPassphrase())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetSecrets() {
+	keys := map[string]string{
+		"provider": configuration.EnvPrefix + "_" + "SECRETS_PROVIDER",
+		"dir":      configuration.EnvPrefix + "_" + "SECRETS_DIR",
+		"address":  configuration.EnvPrefix + "_" + "SECRETS_VAULT_ADDRESS",
+		"token":    configuration.EnvPrefix + "_" + "SECRETS_VAULT_TOKEN",
+		"path":     configuration.EnvPrefix + "_" + "SECRETS_VAULT_PATH",
+		"interval": configuration.EnvPrefix + "_" + "SECRETS_REFRESH_INTERVAL_SEC",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetSecretsProvider())
+		assert.Equal(s.T(), configuration.DefaultSecretsDir, config.GetSecretsDir())
+		assert.Empty(s.T(), config.GetSecretsVaultAddress())
+		assert.Empty(s.T(), config.GetSecretsVaultToken())
+		assert.Equal(s.T(), configuration.DefaultSecretsVaultPath, config.GetSecretsVaultPath())
+		assert.Equal(s.T(), configuration.DefaultSecretsRefreshIntervalSec, config.GetSecretsRefreshIntervalSec())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["provider"], "vault"))
+		require.NoError(s.T(), os.Setenv(keys["dir"], "/run/secrets"))
+		require.NoError(s.T(), os.Setenv(keys["address"], "https://vault.example.com"))
+		require.NoError(s.T(), os.Setenv(keys["token"], "s.token"))
+		require.NoError(s.T(), os.Setenv(keys["path"], "kv/devcluster"))
+		require.NoError(s.T(), os.Setenv(keys["interval"], "30"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), "vault", config.GetSecretsProvider())
+		assert.Equal(s.T(), "/run/secrets", config.GetSecretsDir())
+		assert.Equal(s.T(), "https://vault.example.com", config.GetSecretsVaultAddress())
+		assert.Equal(s.T(), "s.token", config.GetSecretsVaultToken())
+		assert.Equal(s.T(), "kv/devcluster", config.GetSecretsVaultPath())
+		assert.Equal(s.T(), 30, config.GetSecretsRefreshIntervalSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetSecret() {
+	key := configuration.EnvPrefix + "_" + "IBMCLOUD_APIKEY"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+	require.NoError(s.T(), os.Setenv(key, "configured-key"))
+
+	config := s.getDefaultConfiguration()
+	assert.Equal(s.T(), "configured-key", config.GetIBMCloudAPIKey())
+
+	s.Run("read from provider", func() {
+		provider := secretsProvider{"ibmcloud.apikey": "key-1"}
+		config.SetSecretsProvider(provider)
+		assert.Equal(s.T(), "key-1", config.GetIBMCloudAPIKey())
+
+		// Rotated
+		provider["ibmcloud.apikey"] = "key-2"
+		assert.Equal(s.T(), "key-2", config.GetIBMCloudAPIKey())
+	})
+
+	s.Run("missing in provider", func() {
+		config.SetSecretsProvider(secretsProvider{})
+		assert.Equal(s.T(), "configured-key", config.GetIBMCloudAPIKey())
+	})
+}
+
+type secretsProvider map[string]string
+
+func (p secretsProvider) Get(name string) (string, bool) {
+	value, found := p[name]
+	return value, found
+}
+
+func (s *TestConfigurationSuite) TestGetExpiryCheckIntervalSec() {
+	key := configuration.EnvPrefix + "_" + "EXPIRY_CHECK_INTERVAL_SEC"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultExpiryCheckIntervalSec, config.GetExpiryCheckIntervalSec())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(key, "60"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 60, config.GetExpiryCheckIntervalSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetZonesAllowed() {
+	key := configuration.EnvPrefix + "_" + "ZONES_ALLOWED"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), []string{"wdc04", "wdc06", "wdc07", "che01", "fra02", "fra04", "fra05", "ams03"}, config.GetZonesAllowed())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(key, "lon06, ,wdc04"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), []string{"lon06", "wdc04"}, config.GetZonesAllowed())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetAdminUsers() {
+	key := configuration.EnvPrefix + "_" + "ADMIN_USERS"
+	reset := UnsetEnvVarAndRestore(s.T(), key)
+	defer reset()
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Empty(s.T(), config.GetAdminUsers())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(key, "john,jane"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), []string{"john", "jane"}, config.GetAdminUsers())
+	})
+}
+
+func (s *TestConfigurationSuite) TestGetIBMCloudTransport() {
+	keys := map[string]string{
+		"rate":      configuration.EnvPrefix + "_" + "IBMCLOUD_RATE_LIMIT_PER_SEC",
+		"retries":   configuration.EnvPrefix + "_" + "IBMCLOUD_MAX_RETRIES",
+		"timeout":   configuration.EnvPrefix + "_" + "IBMCLOUD_REQUEST_TIMEOUT_SEC",
+		"threshold": configuration.EnvPrefix + "_" + "IBMCLOUD_CIRCUIT_BREAKER_THRESHOLD",
+		"open":      configuration.EnvPrefix + "_" + "IBMCLOUD_CIRCUIT_BREAKER_OPEN_SEC",
+	}
+	for _, key := range keys {
+		reset := UnsetEnvVarAndRestore(s.T(), key)
+		defer reset()
+	}
+
+	s.Run("default", func() {
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), configuration.DefaultIBMCloudRateLimitPerSec, config.GetIBMCloudRateLimitPerSec())
+		assert.Equal(s.T(), configuration.DefaultIBMCloudMaxRetries, config.GetIBMCloudMaxRetries())
+		assert.Equal(s.T(), configuration.DefaultIBMCloudRequestTimeoutSec, config.GetIBMCloudRequestTimeoutSec())
+		assert.Equal(s.T(), configuration.DefaultIBMCloudCircuitBreakerThreshold, config.GetIBMCloudCircuitBreakerThreshold())
+		assert.Equal(s.T(), configuration.DefaultIBMCloudCircuitBreakerOpenSec, config.GetIBMCloudCircuitBreakerOpenSec())
+	})
+
+	s.Run("env overwrite", func() {
+		require.NoError(s.T(), os.Setenv(keys["rate"], "10"))
+		require.NoError(s.T(), os.Setenv(keys["retries"], "1"))
+		require.NoError(s.T(), os.Setenv(keys["timeout"], "15"))
+		require.NoError(s.T(), os.Setenv(keys["threshold"], "2"))
+		require.NoError(s.T(), os.Setenv(keys["open"], "120"))
+		config := s.getDefaultConfiguration()
+		assert.Equal(s.T(), 10, config.GetIBMCloudRateLimitPerSec())
+		assert.Equal(s.T(), 1, config.GetIBMCloudMaxRetries())
+		assert.Equal(s.T(), 15, config.GetIBMCloudRequestTimeoutSec())
+		assert.Equal(s.T(), 2, config.GetIBMCloudCircuitBreakerThreshold())
+		assert.Equal(s.T(), 120, config.GetIBMCloudCircuitBreakerOpenSec())
+	})
+}
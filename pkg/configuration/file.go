@@ -0,0 +1,169 @@
+package configuration
+
+import (
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted is the value shown instead of the secrets in the effective configuration
+const Redacted = "[redacted]"
+
+// secretKeys are the keys of the secrets which are never shown
+var secretKeys = map[string]bool{
+	varIBMCloudAPIKey:          true,
+	varIBMCloudAccounts:        true,
+	varMongodbConnectionString: true,
+	varMongodbCA:               true,
+	varSecretsVaultToken:       true,
+	varBackupPassphrase:        true,
+	varNotificationWebhookURL:  true,
+}
+
+// secretKeyParts are the parts of the key names which are considered secrets if found in the configuration file
+var secretKeyParts = []string{"apikey", "token", "password", "passphrase", "secret"}
+
+// Validator checks the configuration read from a file before the configuration is applied
+type Validator func(config *Config) error
+
+// AddValidator adds the validator of the settings parsed by the components, like the IBM Cloud accounts
+// or the bootstrap steps. The validators are run against every loaded and reloaded configuration file
+// so the configuration the components would reject on startup is never applied.
+func (c *Config) AddValidator(validator Validator) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.validators = append(c.validators, validator)
+}
+
+// LoadFile loads the YAML or JSON configuration file. The format is detected by the file extension.
+// The environment variables take precedence over the file.
+// Returns an error and keeps the current configuration if the file can't be read or the configuration is invalid.
+func (c *Config) LoadFile(path string) error {
+	v, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if err := c.runValidators(v); err != nil {
+		return errors.Wrapf(err, "invalid configuration file %s", path)
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.v = v
+	c.file = path
+	return nil
+}
+
+// Reload reads the loaded configuration file again and replaces the whole configuration at once.
+// Returns an error and keeps the current configuration if the file can't be read or the configuration is invalid.
+func (c *Config) Reload() error {
+	c.mux.RLock()
+	path := c.file
+	c.mux.RUnlock()
+	if path == "" {
+		return errors.New("no configuration file loaded")
+	}
+	return c.LoadFile(path)
+}
+
+// WatchFile watches the loaded configuration file and reloads the configuration when the file changes.
+// The reloaded function is called after every reload with the error if the new configuration has been rejected.
+// The values read by the components on every use, like the intervals and the zones, are applied right away;
+// the reloaded function applies the rest, like the log level.
+func (c *Config) WatchFile(reloaded func(err error)) error {
+	c.mux.RLock()
+	path := c.file
+	c.mux.RUnlock()
+	if path == "" {
+		return errors.New("no configuration file loaded")
+	}
+	// The watcher is a separate Viper instance so the watched file is never read in place of the current configuration
+	watcher := viper.New()
+	watcher.SetConfigFile(path)
+	watcher.OnConfigChange(func(fsnotify.Event) {
+		reloaded(c.Reload())
+	})
+	watcher.WatchConfig()
+	return nil
+}
+
+// readFile returns a new Viper instance with the environment variables, the defaults and the configuration file
+func readFile(path string) (*viper.Viper, error) {
+	v := newViper()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read configuration file %s", path)
+	}
+	if err := validate(v); err != nil {
+		return nil, errors.Wrapf(err, "invalid configuration file %s", path)
+	}
+	return v, nil
+}
+
+// validate checks the values which can't be used by the components
+func validate(v *viper.Viper) error {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(v.GetString(varLogLevel))); err != nil {
+		return errors.Errorf("invalid log level: %s", v.GetString(varLogLevel))
+	}
+	for _, key := range []string{varIBMCloudApiCallRetrySec, varReconcileIntervalSec, varUserPoolCheckIntervalSec, varArchiveIntervalSec, varExpiryCheckIntervalSec} {
+		if v.GetInt(key) <= 0 {
+			return errors.Errorf("%s must be positive", key)
+		}
+	}
+	for _, key := range []string{varUserPoolMinFree, varUserPoolBatchSize, varArchiveRetentionDays, varDeleteRetryMaxAttempts, varAutoReplaceMaxAttempts} {
+		if v.GetInt(key) < 0 {
+			return errors.Errorf("%s must not be negative", key)
+		}
+	}
+	if len(splitList(v.GetString(varZonesAllowed))) == 0 {
+		return errors.Errorf("%s must not be empty", varZonesAllowed)
+	}
+	return nil
+}
+
+// runValidators runs the validators against the configuration read from a file
+func (c *Config) runValidators(v *viper.Viper) error {
+	c.mux.RLock()
+	validators := c.validators
+	c.mux.RUnlock()
+	c.secretsMux.RLock()
+	candidate := &Config{v: v, secrets: c.secrets}
+	c.secretsMux.RUnlock()
+	for _, validator := range validators {
+		if err := validator(candidate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Effective returns the effective configuration by key, e.g. "log.level", with the secrets redacted
+func (c *Config) Effective() map[string]interface{} {
+	v := c.viper()
+	keys := v.AllKeys()
+	settings := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if isSecret(key) {
+			settings[key] = Redacted
+			continue
+		}
+		settings[key] = v.Get(key)
+	}
+	return settings
+}
+
+func isSecret(key string) bool {
+	if secretKeys[key] {
+		return true
+	}
+	name := key[strings.LastIndex(key, ".")+1:]
+	for _, part := range secretKeyParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
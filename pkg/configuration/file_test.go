@@ -0,0 +1,155 @@
+package configuration_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	. "github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *TestConfigurationSuite) TestLoadFile() {
+	for _, key := range []string{"LOG_LEVEL", "RECONCILE_INTERVAL_SEC", "ZONES_ALLOWED"} {
+		reset := UnsetEnvVarAndRestore(s.T(), configuration.EnvPrefix+"_"+key)
+		defer reset()
+	}
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(s.T(), ioutil.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	s.Run("yaml", func() {
+		config := s.getDefaultConfiguration()
+		path := writeFile("config.yaml", "log:\n  level: debug\nreconcile:\n  interval_sec: 120\nzones:\n  allowed: lon06,wdc04\n")
+		require.NoError(s.T(), config.LoadFile(path))
+		assert.Equal(s.T(), "debug", config.GetLogLevel())
+		assert.Equal(s.T(), 120, config.GetReconcileIntervalSec())
+		assert.Equal(s.T(), []string{"lon06", "wdc04"}, config.GetZonesAllowed())
+		// Defaults are kept
+		assert.Equal(s.T(), configuration.DefaultUserPoolCheckIntervalSec, config.GetUserPoolCheckIntervalSec())
+	})
+
+	s.Run("json", func() {
+		config := s.getDefaultConfiguration()
+		path := writeFile("config.json", `{"log": {"level": "error"}}`)
+		require.NoError(s.T(), config.LoadFile(path))
+		assert.Equal(s.T(), "error", config.GetLogLevel())
+	})
+
+	s.Run("env overwrites file", func() {
+		require.NoError(s.T(), os.Setenv(configuration.EnvPrefix+"_LOG_LEVEL", "warn"))
+		defer os.Unsetenv(configuration.EnvPrefix + "_LOG_LEVEL")
+		config := s.getDefaultConfiguration()
+		path := writeFile("config.yaml", "log:\n  level: debug\n")
+		require.NoError(s.T(), config.LoadFile(path))
+		assert.Equal(s.T(), "warn", config.GetLogLevel())
+	})
+
+	s.Run("invalid configuration rejected", func() {
+		for _, content := range []string{
+			"log:\n  level: verbose\n",
+			"reconcile:\n  interval_sec: 0\n",
+			"users:\n  pool_min_free: -1\n",
+			"zones:\n  allowed: ' , '\n",
+			"not: [valid",
+		} {
+			config := s.getDefaultConfiguration()
+			path := writeFile("config.yaml", content)
+			assert.Error(s.T(), config.LoadFile(path), content)
+			assert.Equal(s.T(), configuration.DefaultLogLevel, config.GetLogLevel())
+		}
+		config := s.getDefaultConfiguration()
+		assert.Error(s.T(), config.LoadFile(filepath.Join(dir, "missing.yaml")))
+	})
+
+	s.Run("reload", func() {
+		config := s.getDefaultConfiguration()
+		require.Error(s.T(), config.Reload())
+		path := writeFile("config.yaml", "reconcile:\n  interval_sec: 120\n")
+		require.NoError(s.T(), config.LoadFile(path))
+
+		writeFile("config.yaml", "reconcile:\n  interval_sec: 240\n")
+		require.NoError(s.T(), config.Reload())
+		assert.Equal(s.T(), 240, config.GetReconcileIntervalSec())
+
+		// The invalid configuration is rejected as a whole
+		writeFile("config.yaml", "reconcile:\n  interval_sec: 480\nlog:\n  level: verbose\n")
+		require.Error(s.T(), config.Reload())
+		assert.Equal(s.T(), 240, config.GetReconcileIntervalSec())
+	})
+
+	s.Run("validators", func() {
+		config := s.getDefaultConfiguration()
+		config.AddValidator(func(c *configuration.Config) error {
+			if c.GetReconcileIntervalSec() > 1000 {
+				return errors.New("reconcile interval too long")
+			}
+			return nil
+		})
+		path := writeFile("config.yaml", "reconcile:\n  interval_sec: 120\n")
+		require.NoError(s.T(), config.LoadFile(path))
+
+		// The configuration rejected by the validator is not applied
+		writeFile("config.yaml", "reconcile:\n  interval_sec: 2000\n")
+		err := config.Reload()
+		require.Error(s.T(), err)
+		assert.Contains(s.T(), err.Error(), "reconcile interval too long")
+		assert.Equal(s.T(), 120, config.GetReconcileIntervalSec())
+	})
+}
+
+func (s *TestConfigurationSuite) TestWatchFile() {
+	reset := UnsetEnvVarAndRestore(s.T(), configuration.EnvPrefix+"_RECONCILE_INTERVAL_SEC")
+	defer reset()
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(s.T(), ioutil.WriteFile(path, []byte("reconcile:\n  interval_sec: 120\n"), 0600))
+
+	config := s.getDefaultConfiguration()
+	require.Error(s.T(), config.WatchFile(func(error) {}))
+	require.NoError(s.T(), config.LoadFile(path))
+	reloaded := make(chan error, 10)
+	require.NoError(s.T(), config.WatchFile(func(err error) {
+		reloaded <- err
+	}))
+
+	require.NoError(s.T(), ioutil.WriteFile(path, []byte("reconcile:\n  interval_sec: 240\n"), 0600))
+	select {
+	case err := <-reloaded:
+		require.NoError(s.T(), err)
+	case <-time.After(5 * time.Second):
+		require.Fail(s.T(), "configuration file not reloaded")
+	}
+	assert.Equal(s.T(), 240, config.GetReconcileIntervalSec())
+}
+
+func (s *TestConfigurationSuite) TestEffective() {
+	reset := UnsetEnvVarAndRestore(s.T(), configuration.EnvPrefix+"_IBMCLOUD_APIKEY")
+	defer reset()
+	require.NoError(s.T(), os.Setenv(configuration.EnvPrefix+"_IBMCLOUD_APIKEY", "secretkey"))
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(s.T(), ioutil.WriteFile(path, []byte("ibmcloud:\n  apikey: filekey\nsmtp:\n  password: pwd\nlog:\n  level: debug\n"), 0600))
+
+	config := s.getDefaultConfiguration()
+	require.NoError(s.T(), config.LoadFile(path))
+	settings := config.Effective()
+	assert.Equal(s.T(), "debug", settings["log.level"])
+	assert.Equal(s.T(), configuration.DefaultReconcileIntervalSec, settings["reconcile.interval_sec"])
+	assert.Equal(s.T(), configuration.Redacted, settings["ibmcloud.apikey"])
+	assert.Equal(s.T(), configuration.Redacted, settings["smtp.password"])
+}
@@ -1,17 +1,19 @@
 package controller
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/codeready-toolchain/devcluster/pkg/cluster"
 	"github.com/codeready-toolchain/devcluster/pkg/configuration"
 	"github.com/codeready-toolchain/devcluster/pkg/context"
 	devclustererrors "github.com/codeready-toolchain/devcluster/pkg/errors"
-	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/export"
 	"github.com/codeready-toolchain/devcluster/pkg/log"
 
 	"github.com/gin-gonic/gin"
@@ -41,8 +43,17 @@ func (r *ClusterRequest) PostHandler(ctx *gin.Context) {
 
 	zone := ctx.PostForm("zone")
 	if zone == "" {
-		log.Info(ctx, "WARNING: no zone parameter specified. \"wdc04\" will be used by default to create a new request")
-		zone = "wdc04"
+		zone, err = cluster.DefaultClusterService.DefaultZone()
+		if err != nil {
+			log.Error(ctx, err, "error requesting clusters; no zone parameter specified and no default zone")
+			code := http.StatusInternalServerError
+			if devclustererrors.IsBadRequest(err) {
+				code = http.StatusBadRequest
+			}
+			devclustererrors.AbortWithError(ctx, code, err, "error requesting clusters; no zone parameter specified and no default zone")
+			return
+		}
+		log.Infof(ctx, "WARNING: no zone parameter specified. The default zone %s will be used to create a new request", zone)
 	}
 
 	deleteIns := ctx.PostForm("delete-in-hours")
@@ -58,23 +69,127 @@ func (r *ClusterRequest) PostHandler(ctx *gin.Context) {
 
 	noSubnet := ctx.PostForm("no-subnet") != ""
 
-	req, err := cluster.DefaultClusterService.CreateNewRequest(requestedBy, n, zone, deleteInHours, noSubnet)
+	usersPerCluster := 1
+	if upc := ctx.PostForm("users-per-cluster"); upc != "" {
+		usersPerCluster, err = strconv.Atoi(upc)
+		if err != nil {
+			log.Error(ctx, err, "error requesting clusters; users-per-cluster param is invalid")
+			devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error requesting clusters; users-per-cluster param is invalid")
+			return
+		}
+	}
+
+	var urlTemplates []string
+	for _, name := range strings.Split(ctx.PostForm("url-templates"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			urlTemplates = append(urlTemplates, name)
+		}
+	}
+
+	// The request is routed to an IBM Cloud account automatically if no account is given
+	account := ctx.PostForm("account")
+
+	req, err := cluster.DefaultClusterService.CreateNewRequest(requestedBy, n, zone, deleteInHours, noSubnet, usersPerCluster, urlTemplates, account)
 	if err != nil {
 		log.Error(ctx, err, "error requesting clusters")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error requesting clusters")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error requesting clusters")
 		return
 	}
 	ctx.JSON(http.StatusAccepted, req)
 }
 
-// GetHandler returns ClusterRequest resources
+// Headers of the responses of the list endpoints
+const (
+	// TotalCountHeader is the number of all the items matching the filters
+	TotalCountHeader = "X-Total-Count"
+	// NextCursorHeader is the cursor of the next page. Not set for the last page.
+	NextCursorHeader = "X-Next-Cursor"
+)
+
+// parseListOptions parses the query params of the list endpoints:
+// status, requested_by, zone, name_prefix, created_from and created_to (YYYY-MM-DD or RFC 3339),
+// sort, order (asc or desc), limit and cursor (the X-Next-Cursor header of the previous page)
+func parseListOptions(ctx *gin.Context) (cluster.ListOptions, error) {
+	opts := cluster.ListOptions{
+		Status:      ctx.Query("status"),
+		RequestedBy: ctx.Query("requested_by"),
+		Zone:        ctx.Query("zone"),
+		NamePrefix:  ctx.Query("name_prefix"),
+		SortBy:      ctx.Query("sort"),
+		Cursor:      ctx.Query("cursor"),
+	}
+	switch order := ctx.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, devclustererrors.NewBadRequestError(fmt.Sprintf("invalid order: %s", order), "expected asc or desc")
+	}
+	if l := ctx.Query("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return opts, devclustererrors.NewBadRequestError(fmt.Sprintf("invalid limit: %s", l), "limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+	var err error
+	if opts.CreatedFrom, err = parseDate(ctx.Query("created_from")); err != nil {
+		return opts, err
+	}
+	if opts.CreatedTo, err = parseDate(ctx.Query("created_to")); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseDate parses the date (YYYY-MM-DD, UTC) or the time (RFC 3339) as unix time. Returns 0 for an empty string.
+func parseDate(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, devclustererrors.NewBadRequestError(fmt.Sprintf("invalid date: %s", value), "expected format: YYYY-MM-DD or RFC 3339")
+}
+
+// abortListing aborts the list request with 400 for the invalid list options and with 500 otherwise
+func abortListing(ctx *gin.Context, err error, details string) {
+	log.Error(ctx, err, details)
+	code := http.StatusInternalServerError
+	if devclustererrors.IsBadRequest(err) {
+		code = http.StatusBadRequest
+	}
+	devclustererrors.AbortWithError(ctx, code, err, details)
+}
+
+// setPageHeaders sets the total count and the next cursor headers of the page
+func setPageHeaders(ctx *gin.Context, page cluster.PageInfo) {
+	ctx.Header(TotalCountHeader, strconv.FormatInt(page.Total, 10))
+	if page.Next != "" {
+		ctx.Header(NextCursorHeader, page.Next)
+	}
+}
+
+// GetHandler returns ClusterRequest resources as an array (JSON) filtered, sorted and paginated by the list query params
 func (r *ClusterRequest) GetHandler(ctx *gin.Context) {
-	reqs, err := cluster.DefaultClusterService.Requests()
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		abortListing(ctx, err, "error fetching cluster requests")
+		return
+	}
+	reqs, page, err := cluster.DefaultClusterService.ListRequests(opts)
 	if err != nil {
-		log.Error(ctx, err, "error fetching cluster requests")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching cluster requests")
+		abortListing(ctx, err, "error fetching cluster requests")
 		return
 	}
+	setPageHeaders(ctx, page)
 	ctx.JSON(http.StatusOK, reqs)
 }
 
@@ -96,49 +211,171 @@ func (r *ClusterRequest) GetHandlerClusterReq(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, req)
 }
 
-// GetHandlerClusters returns not deleted Cluster resources for the given zone
+// GetHandlerClusterReqEvents returns the status changes of the request and of its clusters as an array (JSON)
+func (r *ClusterRequest) GetHandlerClusterReqEvents(ctx *gin.Context) {
+	events, err := cluster.DefaultClusterService.RequestEvents(ctx.Param("id"))
+	if err != nil {
+		log.Error(ctx, err, "error fetching cluster request events")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error fetching cluster request events")
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// GetHandlerClusterEvents returns the status changes of the cluster as an array (JSON)
+func (r *ClusterRequest) GetHandlerClusterEvents(ctx *gin.Context) {
+	events, err := cluster.DefaultClusterService.ClusterEvents(ctx.Param("id"))
+	if err != nil {
+		log.Error(ctx, err, "error fetching cluster events")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error fetching cluster events")
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// GetHandlerClusters returns Cluster resources as an array (JSON) filtered, sorted and paginated by the list query params.
+// The deleted clusters are only returned if filtered by the deleted status.
 func (r *ClusterRequest) GetHandlerClusters(ctx *gin.Context) {
-	zone := ctx.Query("zone")
-	clusters, err := cluster.DefaultClusterService.GetClusters(zone)
+	opts, err := parseListOptions(ctx)
 	if err != nil {
-		log.Error(ctx, err, "error fetching clusters")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching clusters")
+		abortListing(ctx, err, "error fetching clusters")
 		return
 	}
+	clusters, page, err := cluster.DefaultClusterService.ListClusters(opts)
+	if err != nil {
+		abortListing(ctx, err, "error fetching clusters")
+		return
+	}
+	setPageHeaders(ctx, page)
 	ctx.JSON(http.StatusOK, clusters)
 }
 
-// GetHandlerZones returns Zones resource
-func (r *ClusterRequest) GetHandlerZones(ctx *gin.Context) {
-	zones, err := cluster.DefaultClusterService.GetZones()
+// GetHandlerExport exports the handouts with the credentials of the attendees for the given request
+// in the format from the "format" query param: csv (default), json or pdf.
+// The optional "header" query param is printed at the top of every PDF page.
+func (r *ClusterRequest) GetHandlerExport(ctx *gin.Context) {
+	reqID := ctx.Param("id")
+	format := ctx.DefaultQuery("format", export.FormatCSV)
+	contentType, err := export.ContentType(format)
 	if err != nil {
-		log.Error(ctx, err, "error fetching zones")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching zones")
+		log.Error(ctx, err, "error exporting cluster request")
+		devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error exporting cluster request")
+		return
+	}
+	handouts, err := cluster.DefaultClusterService.Handouts(reqID)
+	if err != nil {
+		log.Error(ctx, err, "error exporting cluster request")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error exporting cluster request")
 		return
 	}
-	ctx.JSON(http.StatusOK, r.filterZones(ctx, zones))
+	buf := &bytes.Buffer{}
+	if err := export.Write(buf, format, ctx.Query("header"), handouts); err != nil {
+		log.Error(ctx, err, "error exporting cluster request")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error exporting cluster request")
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"request-%s.%s\"", reqID, format))
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
 }
 
-var allowedDCs = map[string]bool{"wdc04": true, "wdc06": true, "wdc07": true, "che01": true, "fra02": true, "fra04": true, "fra05": true, "ams03": true}
-
-// filterZones returns the filtered array of the zones/DCs allowed to be used by the client
-func (r *ClusterRequest) filterZones(ctx *gin.Context, zones []ibmcloud.Location) []ibmcloud.Location {
-	result := make([]ibmcloud.Location, 0, len(allowedDCs))
-	for _, z := range zones {
-		if allowedDCs[z.ID] {
-			result = append(result, z)
+// GetHandlerUsageReport returns the cluster-hours and the estimated cost of the clusters
+// grouped by the keys from the comma separated "group_by" query param (requested_by, zone and month by default).
+// The optional "from" and "to" query params (e.g. 2021-03) limit the report to the given months.
+// The report is returned in the format from the "format" query param: json (default) or csv.
+func (r *ClusterRequest) GetHandlerUsageReport(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", export.FormatJSON)
+	if format != export.FormatJSON && format != export.FormatCSV {
+		err := fmt.Errorf("unsupported report format: %s", format)
+		log.Error(ctx, err, "error fetching usage report")
+		devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error fetching usage report")
+		return
+	}
+	var groupBy []string
+	for _, key := range strings.Split(ctx.Query("group_by"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			groupBy = append(groupBy, key)
 		}
 	}
-	if len(result) < len(allowedDCs) {
-		log.Error(ctx, errors.New("not all allowed zones are available"), fmt.Sprintf("allowed zones: %v; available zones: %v", allowedDCs, zones))
+	report, err := cluster.DefaultClusterService.UsageReport(groupBy, ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		log.Error(ctx, err, "error fetching usage report")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error fetching usage report")
+		return
+	}
+	if format == export.FormatJSON {
+		ctx.JSON(http.StatusOK, report)
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err := report.WriteCSV(buf); err != nil {
+		log.Error(ctx, err, "error fetching usage report")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching usage report")
+		return
+	}
+	contentType, _ := export.ContentType(export.FormatCSV)
+	ctx.Header("Content-Disposition", "attachment; filename=\"usage.csv\"")
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GetHandlerArchive returns the archived requests with their clusters as an array (JSON)
+// filtered, sorted and paginated by the list query params
+func (r *ClusterRequest) GetHandlerArchive(ctx *gin.Context) {
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		abortListing(ctx, err, "error fetching archived requests")
+		return
+	}
+	requests, page, err := cluster.DefaultClusterService.ListArchive(opts)
+	if err != nil {
+		abortListing(ctx, err, "error fetching archived requests")
+		return
+	}
+	setPageHeaders(ctx, page)
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// GetHandlerURLTemplates returns the configured URL templates
+func (r *ClusterRequest) GetHandlerURLTemplates(ctx *gin.Context) {
+	templates, err := cluster.DefaultClusterService.URLTemplates()
+	if err != nil {
+		log.Error(ctx, err, "error fetching URL templates")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching URL templates")
+		return
+	}
+	ctx.JSON(http.StatusOK, templates)
+}
+
+// GetHandlerZones returns the zones enabled for the new requests with their current capacity
+func (r *ClusterRequest) GetHandlerZones(ctx *gin.Context) {
+	zones, err := cluster.DefaultClusterService.AvailableZones()
+	if err != nil {
+		log.Error(ctx, err, "error fetching zones")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching zones")
+		return
 	}
-	return result
+	ctx.JSON(http.StatusOK, zones)
 }
 
 // DeleteHandlerCluster deletes Cluster resource
 func (r *ClusterRequest) DeleteHandlerCluster(ctx *gin.Context) {
 	id := ctx.Param("id")
-	err := cluster.DefaultClusterService.DeleteCluster(id)
+	err := cluster.DefaultClusterService.DeleteCluster(id, ctx.GetString(context.UsernameKey))
 	if err != nil {
 		log.Error(ctx, err, "error deleting cluster")
 		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error deleting cluster")
@@ -166,9 +403,10 @@ func (r *ClusterRequest) DeleteHandlerClusters(ctx *gin.Context) {
 		}
 	}
 	// Start deleting the clusters but do not wait and return 202
+	actor := ctx.GetString(context.UsernameKey)
 	go func() {
 		for _, id := range ids {
-			if err := cluster.DefaultClusterService.DeleteCluster(id); err != nil {
+			if err := cluster.DefaultClusterService.DeleteCluster(id, actor); err != nil {
 				log.Error(ctx, err, fmt.Sprintf("error deleting cluster with id=%s", id))
 			}
 		}
@@ -186,35 +424,210 @@ func (r *ClusterRequest) PostUsersHandler(ctx *gin.Context) {
 		return
 	}
 
-	sIndex := ctx.PostForm("start-index")
-	var startIndex int
-	if sIndex != "" {
-		startIndex, err = strconv.Atoi(sIndex)
-		if err != nil {
-			log.Error(ctx, err, "error requesting users; start-index param is not an integer")
-			devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error requesting users; start-index param is not an integer")
-			return
-		}
-	}
-
 	log.Infof(ctx, "Requested creating %s users", ns)
-	users, err := cluster.DefaultClusterService.CreateUsers(n, startIndex)
+	// The users are created in the default IBM Cloud account if no account is given
+	users, err := cluster.DefaultClusterService.CreateUsers(ctx.PostForm("account"), n)
 	if err != nil {
 		log.Error(ctx, err, "error requesting users")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error requesting users")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error requesting users")
 		return
 	}
 	ctx.JSON(http.StatusAccepted, users)
 }
 
-// GetUsersHandler returns all users as an array (JSON)
+// GetUsersHandler returns users as an array (JSON) filtered, sorted and paginated by the list query params
 func (r *ClusterRequest) GetUsersHandler(ctx *gin.Context) {
 	log.Infof(ctx, "Obtaining users")
-	users, err := cluster.DefaultClusterService.Users()
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		abortListing(ctx, err, "error obtaining users")
+		return
+	}
+	users, page, err := cluster.DefaultClusterService.ListUsers(opts)
 	if err != nil {
-		log.Error(ctx, err, "error obtaining users")
-		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error obtaining users")
+		abortListing(ctx, err, "error obtaining users")
 		return
 	}
+	setPageHeaders(ctx, page)
 	ctx.JSON(http.StatusAccepted, users)
 }
+
+// defaultDriftReportsLimit is the default max number of drift reports returned by GetDriftReportsHandler
+const defaultDriftReportsLimit = 10
+
+// GetDriftReportsHandler returns the latest drift reports as an array (JSON). Only the service admins are allowed.
+func (r *ClusterRequest) GetDriftReportsHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error fetching drift reports") {
+		return
+	}
+	limit := defaultDriftReportsLimit
+	if l := ctx.Query("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			if err == nil {
+				err = errors.New("limit must be a positive integer")
+			}
+			log.Error(ctx, err, "error fetching drift reports; limit param is invalid")
+			devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error fetching drift reports; limit param is invalid")
+			return
+		}
+	}
+	reports, err := cluster.DefaultClusterService.DriftReports(limit)
+	if err != nil {
+		log.Error(ctx, err, "error fetching drift reports")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching drift reports")
+		return
+	}
+	ctx.JSON(http.StatusOK, reports)
+}
+
+// PostReconcileHandler reconciles the DB with IBM Cloud and returns the drift report (JSON). Only the service admins are allowed.
+func (r *ClusterRequest) PostReconcileHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error reconciling clusters") {
+		return
+	}
+	log.Info(ctx, "Requested reconciliation")
+	report, err := cluster.DefaultClusterService.Reconcile()
+	if err != nil {
+		log.Error(ctx, err, "error reconciling clusters")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error reconciling clusters")
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// PostRetryDeleteHandler retries deleting the cluster which failed to get deleted. Only the service admins are allowed.
+func (r *ClusterRequest) PostRetryDeleteHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error retrying deleting cluster") {
+		return
+	}
+	id := ctx.Param("id")
+	log.Infof(ctx, "Requested retry of deleting cluster %s", id)
+	err := cluster.DefaultClusterService.RetryDeleteCluster(id, ctx.GetString(context.UsernameKey))
+	if err != nil {
+		log.Error(ctx, err, "error retrying deleting cluster")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		} else if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error retrying deleting cluster")
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// PostBootstrapHandler runs the bootstrap steps again for the cluster which failed to get bootstrapped.
+// Only the service admins are allowed.
+func (r *ClusterRequest) PostBootstrapHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error retrying bootstrapping cluster") {
+		return
+	}
+	id := ctx.Param("id")
+	log.Infof(ctx, "Requested retry of bootstrapping cluster %s", id)
+	err := cluster.DefaultClusterService.RetryBootstrap(id, ctx.GetString(context.UsernameKey))
+	if err != nil {
+		log.Error(ctx, err, "error retrying bootstrapping cluster")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		} else if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error retrying bootstrapping cluster")
+		return
+	}
+	ctx.JSON(http.StatusAccepted, nil)
+}
+
+// PostReplaceHandler provisions a new cluster within the same request to replace the given cluster and deletes the given cluster.
+// Returns the new cluster (JSON). Only the service admins are allowed.
+func (r *ClusterRequest) PostReplaceHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error replacing cluster") {
+		return
+	}
+	id := ctx.Param("id")
+	log.Infof(ctx, "Requested replacing cluster %s", id)
+	c, err := cluster.DefaultClusterService.ReplaceCluster(id, ctx.GetString(context.UsernameKey))
+	if err != nil {
+		log.Error(ctx, err, "error replacing cluster")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		} else if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error replacing cluster")
+		return
+	}
+	ctx.JSON(http.StatusAccepted, c)
+}
+
+// UserOperationResult represents the result of an operation with a single user in a bulk request
+type UserOperationResult struct {
+	ID    string
+	Error string `json:",omitempty"`
+}
+
+// bulkUserOperation applies the operation to all the users with the IDs from the "ids" query param (comma-separated)
+// and returns the results per user (JSON)
+func bulkUserOperation(ctx *gin.Context, name string, op func(id string) error) {
+	idsParam := ctx.Query("ids")
+	if idsParam == "" {
+		err := errors.New("ids param is missing")
+		log.Error(ctx, err, fmt.Sprintf("error %s users", name))
+		devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, fmt.Sprintf("error %s users; ids param is missing", name))
+		return
+	}
+	ids := strings.Split(idsParam, ",")
+	log.Infof(ctx, "Requested %s users: %s", name, idsParam)
+	results := make([]UserOperationResult, 0, len(ids))
+	for _, id := range ids {
+		result := UserOperationResult{ID: id}
+		if err := op(id); err != nil {
+			log.Error(ctx, err, fmt.Sprintf("error %s user with id=%s", name, id))
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	ctx.JSON(http.StatusOK, results)
+}
+
+// DeleteUsersHandler decommissions the users with the given IDs. Only the service admins are allowed.
+func (r *ClusterRequest) DeleteUsersHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error deleting users") {
+		return
+	}
+	bulkUserOperation(ctx, "deleting", cluster.DefaultClusterService.DeleteUser)
+}
+
+// PostDisableUsersHandler disables the users with the given IDs. Only the service admins are allowed.
+func (r *ClusterRequest) PostDisableUsersHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error disabling users") {
+		return
+	}
+	bulkUserOperation(ctx, "disabling", cluster.DefaultClusterService.DisableUser)
+}
+
+// PostEnableUsersHandler enables the users with the given IDs. Only the service admins are allowed.
+func (r *ClusterRequest) PostEnableUsersHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error enabling users") {
+		return
+	}
+	bulkUserOperation(ctx, "enabling", cluster.DefaultClusterService.EnableUser)
+}
+
+// PostRecycleUsersHandler force recycles the users with the given IDs which are stuck with deleted clusters.
+// Only the service admins are allowed.
+func (r *ClusterRequest) PostRecycleUsersHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, r.config, "error recycling users") {
+		return
+	}
+	bulkUserOperation(ctx, "recycling", cluster.DefaultClusterService.ForceRecycleUser)
+}
@@ -1,12 +1,18 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
-	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/context"
 	"github.com/codeready-toolchain/devcluster/test"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -18,26 +24,119 @@ func TestRunClusterReqSuite(t *testing.T) {
 	suite.Run(t, &TestClusterReqSuite{test.UnitTestSuite{}})
 }
 
-func (s *TestClusterReqSuite) TestFilterZones() {
-	r := &ClusterRequest{}
-	expectedZones := []ibmcloud.Location{
-		dc("wdc04"), dc("wdc06"), dc("wdc07"),
-		dc("che01"),
-		dc("fra02"), dc("fra04"), dc("fra05"),
-		dc("ams03"),
+func (s *TestClusterReqSuite) TestBulkUserOperation() {
+	op := func(id string) error {
+		if id == "bad" {
+			return errors.New("failed")
+		}
+		return nil
 	}
-	extraZones := []ibmcloud.Location{
-		dc("syd01"), dc("mil01"),
-	}
-	result := r.filterZones(nil, append(extraZones, expectedZones...))
-	assert.Equal(s.T(), expectedZones, result)
+
+	s.Run("ids param is missing", func() {
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/disable", nil)
+
+		bulkUserOperation(ctx, "disabling", op)
+
+		assert.Equal(s.T(), http.StatusBadRequest, rr.Code)
+	})
+
+	s.Run("per user results", func() {
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/disable?ids=good,bad", nil)
+
+		bulkUserOperation(ctx, "disabling", op)
+
+		require.Equal(s.T(), http.StatusOK, rr.Code)
+		var results []UserOperationResult
+		require.NoError(s.T(), json.Unmarshal(rr.Body.Bytes(), &results))
+		assert.Equal(s.T(), []UserOperationResult{{ID: "good"}, {ID: "bad", Error: "failed"}}, results)
+	})
 }
 
-func dc(name string) ibmcloud.Location {
-	return ibmcloud.Location{
-		ID:          name,
-		Name:        name,
-		Kind:        "dc",
-		DisplayName: "DC-" + name,
+func (s *TestClusterReqSuite) TestAdminOnly() {
+	s.Config.GetViperInstance().Set("admin.users", "admin")
+	defer s.Config.GetViperInstance().Set("admin.users", "")
+	ctrl := NewClusterRequest(s.Config)
+
+	for name, handler := range map[string]gin.HandlerFunc{
+		"delete users":  ctrl.DeleteUsersHandler,
+		"disable users": ctrl.PostDisableUsersHandler,
+		"enable users":  ctrl.PostEnableUsersHandler,
+		"recycle users": ctrl.PostRecycleUsersHandler,
+		"drift reports": ctrl.GetDriftReportsHandler,
+		"reconcile":     ctrl.PostReconcileHandler,
+		"retry delete":  ctrl.PostRetryDeleteHandler,
+		"replace":       ctrl.PostReplaceHandler,
+		"bootstrap":     ctrl.PostBootstrapHandler,
+	} {
+		s.Run(name, func() {
+			rr := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rr)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/recycle?ids=1", nil)
+			ctx.Params = gin.Params{{Key: "id", Value: "1"}}
+			ctx.Set(context.UsernameKey, "johnsmith")
+
+			handler(ctx)
+
+			assert.Equal(s.T(), http.StatusForbidden, rr.Code)
+		})
 	}
 }
+
+func (s *TestClusterReqSuite) TestExportUnsupportedFormat() {
+	rr := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rr)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/cluster-req/123/export?format=xls", nil)
+	ctx.Params = gin.Params{{Key: "id", Value: "123"}}
+
+	(&ClusterRequest{}).GetHandlerExport(ctx)
+
+	assert.Equal(s.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (s *TestClusterReqSuite) TestUsageReportUnsupportedFormat() {
+	rr := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rr)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/reports/usage?format=pdf", nil)
+
+	(&ClusterRequest{}).GetHandlerUsageReport(ctx)
+
+	assert.Equal(s.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (s *TestClusterReqSuite) TestParseListOptions() {
+	s.T().Run("all params", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet,
+			"/api/v1/clusters?status=normal&requested_by=john&zone=wdc04&name_prefix=rhd-&created_from=2021-03-01&created_to=2021-03-02T10:00:00Z&sort=name&order=desc&limit=20&cursor=abc", nil)
+
+		opts, err := parseListOptions(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, "normal", opts.Status)
+		assert.Equal(t, "john", opts.RequestedBy)
+		assert.Equal(t, "wdc04", opts.Zone)
+		assert.Equal(t, "rhd-", opts.NamePrefix)
+		assert.Equal(t, int64(1614556800), opts.CreatedFrom)
+		assert.Equal(t, int64(1614679200), opts.CreatedTo)
+		assert.Equal(t, "name", opts.SortBy)
+		assert.True(t, opts.Descending)
+		assert.Equal(t, 20, opts.Limit)
+		assert.Equal(t, "abc", opts.Cursor)
+	})
+
+	s.T().Run("invalid params", func(t *testing.T) {
+		for _, query := range []string{"order=down", "limit=0", "limit=x", "created_from=03/01/2021", "created_to=yesterday"} {
+			rr := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rr)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/cluster-reqs?"+query, nil)
+
+			(&ClusterRequest{}).GetHandler(ctx)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/context"
+	devclustererrors "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config implements the admin endpoint showing the effective configuration
+type Config struct {
+	config *configuration.Config
+}
+
+// NewConfig returns a new Config instance.
+func NewConfig(config *configuration.Config) *Config {
+	return &Config{
+		config: config,
+	}
+}
+
+// GetHandler returns the effective configuration with the secrets redacted. Only the service admins are allowed.
+func (c *Config) GetHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, c.config, "error fetching configuration") {
+		return
+	}
+	ctx.JSON(http.StatusOK, c.config.Effective())
+}
+
+// requireAdmin aborts with the Forbidden error and returns false if the user is not one of the service admins
+func requireAdmin(ctx *gin.Context, config *configuration.Config, msg string) bool {
+	username := ctx.GetString(context.UsernameKey)
+	if username != "" {
+		for _, admin := range config.GetAdminUsers() {
+			if admin == username {
+				return true
+			}
+		}
+	}
+	err := errors.New("only the service admins are allowed")
+	log.Error(ctx, err, msg)
+	devclustererrors.AbortWithError(ctx, http.StatusForbidden, err, msg)
+	return false
+}
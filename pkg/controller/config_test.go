@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/context"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestConfigSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunConfigSuite(t *testing.T) {
+	suite.Run(t, &TestConfigSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestConfigSuite) TestConfigHandler() {
+	s.Config.GetViperInstance().Set("admin.users", "admin,other-admin")
+	s.Config.GetViperInstance().Set("ibmcloud.apikey", "secretkey")
+	defer s.Config.GetViperInstance().Set("admin.users", "")
+	defer s.Config.GetViperInstance().Set("ibmcloud.apikey", "")
+	handler := gin.HandlerFunc(NewConfig(s.Config).GetHandler)
+
+	get := func(username string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/config", nil)
+		require.NoError(s.T(), err)
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = req
+		if username != "" {
+			ctx.Set(context.UsernameKey, username)
+		}
+		handler(ctx)
+		return rr
+	}
+
+	s.Run("admin", func() {
+		rr := get("admin")
+		require.Equal(s.T(), http.StatusOK, rr.Code)
+		var settings map[string]interface{}
+		require.NoError(s.T(), json.Unmarshal(rr.Body.Bytes(), &settings))
+		assert.Equal(s.T(), configuration.DefaultLogLevel, settings["log.level"])
+		assert.Equal(s.T(), configuration.Redacted, settings["ibmcloud.apikey"])
+		assert.NotContains(s.T(), rr.Body.String(), "secretkey")
+	})
+
+	s.Run("not admin", func() {
+		assert.Equal(s.T(), http.StatusForbidden, get("johnsmith").Code)
+		assert.Equal(s.T(), http.StatusForbidden, get("").Code)
+	})
+}
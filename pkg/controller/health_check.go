@@ -1,9 +1,16 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/codeready-toolchain/devcluster/pkg/auth"
+	"github.com/codeready-toolchain/devcluster/pkg/cluster"
 	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/mongodb"
 	"github.com/codeready-toolchain/toolchain-common/pkg/status"
 
 	"github.com/gin-gonic/gin"
@@ -27,18 +34,28 @@ func NewHealthCheck(config HealthCheckConfig, checker HealthChecker) *HealthChec
 	}
 }
 
+// Health represents the health info of the service
+type Health struct {
+	status.Health `json:",inline"`
+	// IBMCloudAPI is the state of the circuit breaker guarding the IBM Cloud API calls: closed, open or half-open
+	IBMCloudAPI string `json:"ibmCloudApi,omitempty"`
+}
+
 // getHealthInfo returns the health info.
-func (hc *HealthCheck) getHealthInfo() *status.Health {
-	return &status.Health{
-		Alive:       hc.checker.Alive(),
-		Environment: hc.config.GetEnvironment(),
-		Revision:    configuration.Commit,
-		BuildTime:   configuration.BuildTime,
-		StartTime:   configuration.StartTime,
+func (hc *HealthCheck) getHealthInfo() *Health {
+	return &Health{
+		Health: status.Health{
+			Alive:       hc.checker.Alive(),
+			Environment: hc.config.GetEnvironment(),
+			Revision:    configuration.Commit,
+			BuildTime:   configuration.BuildTime,
+			StartTime:   configuration.StartTime,
+		},
+		IBMCloudAPI: hc.checker.IBMCloudAPIState(),
 	}
 }
 
-// GetHandler returns a default heath check result.
+// GetHandler returns a default heath check result. It's used as the liveness probe.
 func (hc *HealthCheck) GetHandler(ctx *gin.Context) {
 	// Default handler for system health
 	healthInfo := hc.getHealthInfo()
@@ -49,8 +66,51 @@ func (hc *HealthCheck) GetHandler(ctx *gin.Context) {
 	}
 }
 
+// GetReadinessHandler returns the readiness of the service and its dependencies. It's used as the readiness probe.
+func (hc *HealthCheck) GetReadinessHandler(ctx *gin.Context) {
+	readiness := hc.checker.Ready()
+	if readiness.Ready {
+		ctx.JSON(http.StatusOK, readiness)
+	} else {
+		ctx.JSON(http.StatusServiceUnavailable, readiness)
+	}
+}
+
+// Readiness represents the readiness of the service broken down per component
+type Readiness struct {
+	Ready      bool                       `json:"ready"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// ComponentStatus represents the readiness of a single component
+type ComponentStatus struct {
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewReadiness returns the readiness which is ready only if all the given components are ready
+func NewReadiness(components map[string]ComponentStatus) *Readiness {
+	ready := true
+	for _, c := range components {
+		ready = ready && c.Ready
+	}
+	return &Readiness{
+		Ready:      ready,
+		Components: components,
+	}
+}
+
+func componentStatus(err error) ComponentStatus {
+	if err != nil {
+		return ComponentStatus{Message: err.Error()}
+	}
+	return ComponentStatus{Ready: true}
+}
+
 type HealthChecker interface {
 	Alive() bool
+	Ready() *Readiness
+	IBMCloudAPIState() string
 }
 
 func NewHealthChecker(config HealthCheckConfig) HealthChecker {
@@ -61,7 +121,59 @@ type healthCheckerImpl struct {
 	config HealthCheckConfig
 }
 
+// Alive returns true while the service is running. The configuration is validated on startup and on every reload
+// and the dependencies are checked by the readiness probe.
 func (c *healthCheckerImpl) Alive() bool {
-	// TODO check if there are errors in configuration
 	return true
 }
+
+// IBMCloudAPIState returns the state of the circuit breaker guarding the IBM Cloud API calls
+func (c *healthCheckerImpl) IBMCloudAPIState() string {
+	if cluster.DefaultClusterService == nil || cluster.DefaultClusterService.IbmCloudClient == nil {
+		return ""
+	}
+	return cluster.DefaultClusterService.IbmCloudClient.CircuitBreakerState()
+}
+
+// readinessCheckTimeout is the timeout of a single dependency check
+const readinessCheckTimeout = 3 * time.Second
+
+func ibmCloudComponent(account string) string {
+	if account == ibmcloud.DefaultAccount {
+		return "ibmcloud"
+	}
+	return "ibmcloud:" + account
+}
+
+// Ready checks MongoDB, all the IBM Cloud accounts, the key manager and the background routines
+func (c *healthCheckerImpl) Ready() *Readiness {
+	components := make(map[string]ComponentStatus)
+	ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+	defer cancel()
+	components["mongodb"] = componentStatus(mongodb.Ping(ctx))
+	components["keymanager"] = componentStatus(auth.CheckDefaultKeyManager())
+
+	service := cluster.DefaultClusterService
+	if service == nil || service.IbmCloudClient == nil {
+		components["ibmcloud"] = ComponentStatus{Message: "cluster service is not initialized"}
+		for _, name := range cluster.BackgroundRoutines {
+			components["routine:"+name] = ComponentStatus{Message: "cluster service is not initialized"}
+		}
+		return NewReadiness(components)
+	}
+	// The credentials of every IBM Cloud account are checked. The default account is reported as "ibmcloud".
+	for account, err := range service.CheckAccounts() {
+		components[ibmCloudComponent(account)] = componentStatus(err)
+	}
+	for _, name := range cluster.BackgroundRoutines {
+		status := service.RoutineStatus(name)
+		if status.Running {
+			components["routine:"+name] = ComponentStatus{Ready: true}
+		} else if status.LastRun == 0 {
+			components["routine:"+name] = ComponentStatus{Message: "not started"}
+		} else {
+			components["routine:"+name] = ComponentStatus{Message: fmt.Sprintf("stuck; last run: %s", time.Unix(status.LastRun, 0).UTC().Format(time.RFC3339))}
+		}
+	}
+	return NewReadiness(components)
+}
@@ -109,6 +109,76 @@ func (s *TestHealthCheckSuite) TestHealthCheckHandler() {
 
 		assertHealth(s.T(), false, "testServiceUnavailable", data)
 	})
+
+	s.Run("IBM Cloud API degraded", func() {
+		healthCheckCtrl := controller.NewHealthCheck(s.Config, &mockHealthChecker{alive: true, ibmCloudAPIState: "open"})
+		handler := gin.HandlerFunc(healthCheckCtrl.GetHandler)
+
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = req
+
+		handler(ctx)
+
+		assert.Equal(s.T(), http.StatusOK, rr.Code, "handler returned wrong status code")
+		data := &controller.Health{}
+		err := json.Unmarshal(rr.Body.Bytes(), &data)
+		require.NoError(s.T(), err)
+		assertHealth(s.T(), true, "testServiceUnavailable", &data.Health)
+		assert.Equal(s.T(), "open", data.IBMCloudAPI)
+	})
+}
+
+func (s *TestHealthCheckSuite) TestReadinessHandler() {
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/health/ready", nil)
+	require.NoError(s.T(), err)
+
+	s.Run("ready", func() {
+		healthCheckCtrl := controller.NewHealthCheck(s.Config, &mockHealthChecker{readiness: controller.NewReadiness(map[string]controller.ComponentStatus{
+			"mongodb":  {Ready: true},
+			"ibmcloud": {Ready: true},
+		})})
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = req
+
+		healthCheckCtrl.GetReadinessHandler(ctx)
+
+		assert.Equal(s.T(), http.StatusOK, rr.Code, "handler returned wrong status code")
+		data := &controller.Readiness{}
+		require.NoError(s.T(), json.Unmarshal(rr.Body.Bytes(), &data))
+		assert.True(s.T(), data.Ready)
+		assert.Len(s.T(), data.Components, 2)
+	})
+
+	s.Run("one component not ready", func() {
+		healthCheckCtrl := controller.NewHealthCheck(s.Config, &mockHealthChecker{readiness: controller.NewReadiness(map[string]controller.ComponentStatus{
+			"mongodb":  {Ready: true},
+			"ibmcloud": {Message: "unable to obtain access token"},
+		})})
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = req
+
+		healthCheckCtrl.GetReadinessHandler(ctx)
+
+		assert.Equal(s.T(), http.StatusServiceUnavailable, rr.Code, "handler returned wrong status code")
+		data := &controller.Readiness{}
+		require.NoError(s.T(), json.Unmarshal(rr.Body.Bytes(), &data))
+		assert.False(s.T(), data.Ready)
+		assert.Equal(s.T(), controller.ComponentStatus{Ready: true}, data.Components["mongodb"])
+		assert.Equal(s.T(), controller.ComponentStatus{Message: "unable to obtain access token"}, data.Components["ibmcloud"])
+	})
+
+	s.Run("dependencies not initialized", func() {
+		readiness := controller.NewHealthChecker(s.Config).Ready()
+		assert.False(s.T(), readiness.Ready)
+		assert.Equal(s.T(), "MongoDB client is not initialized", readiness.Components["mongodb"].Message)
+		assert.False(s.T(), readiness.Components["keymanager"].Ready)
+		assert.False(s.T(), readiness.Components["ibmcloud"].Ready)
+		assert.False(s.T(), readiness.Components["routine:reconciliation"].Ready)
+		assert.False(s.T(), readiness.Components["routine:expired-clusters-deletion"].Ready)
+	})
 }
 
 func assertHealth(t *testing.T, expectedAlive bool, expectedEnvironment string, actual *status.Health) {
@@ -120,9 +190,19 @@ func assertHealth(t *testing.T, expectedAlive bool, expectedEnvironment string,
 }
 
 type mockHealthChecker struct {
-	alive bool
+	alive            bool
+	ibmCloudAPIState string
+	readiness        *controller.Readiness
+}
+
+func (c *mockHealthChecker) Ready() *controller.Readiness {
+	return c.readiness
 }
 
 func (c *mockHealthChecker) Alive() bool {
 	return c.alive
 }
+
+func (c *mockHealthChecker) IBMCloudAPIState() string {
+	return c.ibmCloudAPIState
+}
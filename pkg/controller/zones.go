@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/codeready-toolchain/devcluster/pkg/cluster"
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	devclustererrors "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Zones implements the admin endpoints managing the zones the clusters can be requested in
+type Zones struct {
+	config *configuration.Config
+}
+
+// NewZones returns a new Zones instance.
+func NewZones(config *configuration.Config) *Zones {
+	return &Zones{
+		config: config,
+	}
+}
+
+// GetHandler returns all the stored zones
+func (z *Zones) GetHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, z.config, "error fetching zones") {
+		return
+	}
+	zones, err := cluster.DefaultClusterService.Zones()
+	if err != nil {
+		log.Error(ctx, err, "error fetching zones")
+		devclustererrors.AbortWithError(ctx, http.StatusInternalServerError, err, "error fetching zones")
+		return
+	}
+	ctx.JSON(http.StatusOK, zones)
+}
+
+// zoneRequest is the body of the request storing the zone
+type zoneRequest struct {
+	Enabled     bool `json:"enabled"`
+	MaxClusters int  `json:"max_clusters"`
+	Default     bool `json:"default"`
+}
+
+// PutHandler stores the zone with the ID from the path: {"enabled": true, "max_clusters": 10, "default": false}
+func (z *Zones) PutHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, z.config, "error saving zone") {
+		return
+	}
+	var body zoneRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		log.Error(ctx, err, "error saving zone; invalid body")
+		devclustererrors.AbortWithError(ctx, http.StatusBadRequest, err, "error saving zone; invalid body")
+		return
+	}
+	zone := cluster.Zone{
+		ID:          ctx.Param("id"),
+		Enabled:     body.Enabled,
+		MaxClusters: body.MaxClusters,
+		Default:     body.Default,
+	}
+	if err := cluster.DefaultClusterService.SaveZone(zone); err != nil {
+		log.Error(ctx, err, "error saving zone")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsBadRequest(err) {
+			code = http.StatusBadRequest
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error saving zone")
+		return
+	}
+	ctx.JSON(http.StatusOK, zone)
+}
+
+// DeleteHandler deletes the zone with the ID from the path
+func (z *Zones) DeleteHandler(ctx *gin.Context) {
+	if !requireAdmin(ctx, z.config, "error deleting zone") {
+		return
+	}
+	if err := cluster.DefaultClusterService.DeleteZone(ctx.Param("id")); err != nil {
+		log.Error(ctx, err, "error deleting zone")
+		code := http.StatusInternalServerError
+		if devclustererrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		devclustererrors.AbortWithError(ctx, code, err, "error deleting zone")
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
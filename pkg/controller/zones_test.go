@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/context"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestZonesSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunZonesSuite(t *testing.T) {
+	suite.Run(t, &TestZonesSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestZonesSuite) TestAdminOnly() {
+	s.Config.GetViperInstance().Set("admin.users", "admin")
+	defer s.Config.GetViperInstance().Set("admin.users", "")
+	ctrl := NewZones(s.Config)
+
+	call := func(handler gin.HandlerFunc, method, body, username string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v1/admin/zones/lon06", strings.NewReader(body))
+		require.NoError(s.T(), err)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rr)
+		ctx.Request = req
+		ctx.Params = gin.Params{{Key: "id", Value: "lon06"}}
+		ctx.Set(context.UsernameKey, username)
+		handler(ctx)
+		return rr
+	}
+
+	s.Run("not admin", func() {
+		assert.Equal(s.T(), http.StatusForbidden, call(ctrl.GetHandler, http.MethodGet, "", "johnsmith").Code)
+		assert.Equal(s.T(), http.StatusForbidden, call(ctrl.PutHandler, http.MethodPut, `{"enabled": true}`, "johnsmith").Code)
+		assert.Equal(s.T(), http.StatusForbidden, call(ctrl.DeleteHandler, http.MethodDelete, "", "johnsmith").Code)
+	})
+
+	s.Run("invalid body", func() {
+		assert.Equal(s.T(), http.StatusBadRequest, call(ctrl.PutHandler, http.MethodPut, `{"max_clusters": "ten"}`, "admin").Code)
+	})
+}
@@ -0,0 +1,102 @@
+// Package cost estimates the cost of the clusters from the configured hourly prices
+// and splits the time the clusters were running into calendar months.
+package cost
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Configuration represents a partition of the configuration that is used to estimate the cost of the clusters
+type Configuration interface {
+	GetCostPriceTable() string
+	GetCostCurrency() string
+}
+
+// MonthFormat is the format of the months the cluster-hours are reported for
+const MonthFormat = "2006-01"
+
+// Price is the hourly price of a cluster with the given machine type.
+// If the number of workers is set then it's the price of the whole cluster with exactly that number of workers.
+// Otherwise it's the price of one worker and it's multiplied by the number of the workers of the cluster.
+type Price struct {
+	MachineType string  `json:"machine_type"`
+	Workers     int     `json:"workers,omitempty"`
+	HourlyPrice float64 `json:"hourly_price"`
+}
+
+// PriceTable is the table of the hourly prices
+type PriceTable struct {
+	prices []Price
+}
+
+// ParsePriceTable parses the price table from the given JSON array
+func ParsePriceTable(raw string) (*PriceTable, error) {
+	prices := make([]Price, 0)
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &prices); err != nil {
+			return nil, errors.Wrap(err, "unable to parse price table")
+		}
+	}
+	keys := make(map[Price]bool)
+	for i, p := range prices {
+		if p.MachineType == "" {
+			return nil, errors.Errorf("price #%d has no machine type", i+1)
+		}
+		if p.Workers < 0 {
+			return nil, errors.Errorf("price of %s has negative number of workers", p.MachineType)
+		}
+		if p.HourlyPrice < 0 {
+			return nil, errors.Errorf("price of %s is negative", p.MachineType)
+		}
+		key := Price{MachineType: p.MachineType, Workers: p.Workers}
+		if keys[key] {
+			return nil, errors.Errorf("duplicate price of %s with %d workers", p.MachineType, p.Workers)
+		}
+		keys[key] = true
+	}
+	return &PriceTable{prices: prices}, nil
+}
+
+// HourlyPrice returns the hourly price of the cluster with the given machine type and number of workers.
+// The price of the whole cluster with the same number of workers takes precedence over the price per worker.
+// Returns false if there is no price for the machine type in the table.
+func (t *PriceTable) HourlyPrice(machineType string, workers int) (float64, bool) {
+	var perWorker *Price
+	for i, p := range t.prices {
+		if p.MachineType != machineType {
+			continue
+		}
+		if p.Workers == workers {
+			return p.HourlyPrice, true
+		}
+		if p.Workers == 0 {
+			perWorker = &t.prices[i]
+		}
+	}
+	if perWorker == nil {
+		return 0, false
+	}
+	return perWorker.HourlyPrice * float64(workers), true
+}
+
+// HoursByMonth splits the time between start and end into the calendar months (UTC)
+// and returns the number of hours in every month keyed by the month in MonthFormat.
+// Returns an empty map if end is not after start.
+func HoursByMonth(start, end time.Time) map[string]float64 {
+	hours := make(map[string]float64)
+	start = start.UTC()
+	end = end.UTC()
+	for start.Before(end) {
+		nextMonth := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		if nextMonth.After(end) {
+			nextMonth = end
+		}
+		hours[start.Format(MonthFormat)] += nextMonth.Sub(start).Hours()
+		start = nextMonth
+	}
+	return hours
+}
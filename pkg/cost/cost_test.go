@@ -0,0 +1,101 @@
+package cost_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/cost"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestCostSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunCostSuite(t *testing.T) {
+	suite.Run(t, &TestCostSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestCostSuite) TestParsePriceTable() {
+	s.T().Run("no prices", func(t *testing.T) {
+		table, err := cost.ParsePriceTable("")
+		require.NoError(t, err)
+		_, found := table.HourlyPrice("b3c.4x16", 2)
+		assert.False(t, found)
+	})
+
+	s.T().Run("invalid prices", func(t *testing.T) {
+		for raw, msg := range map[string]string{
+			`{"machine_type": "b3c.4x16"}`:                                     "unable to parse price table",
+			`[{"hourly_price": 1}]`:                                            "price #1 has no machine type",
+			`[{"machine_type": "b3c.4x16", "workers": -1, "hourly_price": 1}]`: "price of b3c.4x16 has negative number of workers",
+			`[{"machine_type": "b3c.4x16", "hourly_price": -1}]`:               "price of b3c.4x16 is negative",
+			`[{"machine_type": "b3c.4x16", "hourly_price": 1}, {"machine_type": "b3c.4x16", "hourly_price": 2}]`:          "duplicate price of b3c.4x16 with 0 workers",
+			`[{"machine_type": "b3c.4x16", "workers": 2, "hourly_price": 1}, {"machine_type": "b3c.4x16", "workers": 2}]`: "duplicate price of b3c.4x16 with 2 workers",
+		} {
+			_, err := cost.ParsePriceTable(raw)
+			require.Error(t, err, raw)
+			assert.Contains(t, err.Error(), msg)
+		}
+	})
+}
+
+func (s *TestCostSuite) TestHourlyPrice() {
+	table, err := cost.ParsePriceTable(`[
+		{"machine_type": "b3c.4x16", "hourly_price": 0.25},
+		{"machine_type": "b3c.4x16", "workers": 3, "hourly_price": 0.6},
+		{"machine_type": "b3c.8x32", "workers": 2, "hourly_price": 1.1}
+	]`)
+	require.NoError(s.T(), err)
+
+	s.T().Run("price per worker", func(t *testing.T) {
+		price, found := table.HourlyPrice("b3c.4x16", 2)
+		require.True(t, found)
+		assert.Equal(t, 0.5, price)
+	})
+
+	s.T().Run("price of the cluster takes precedence", func(t *testing.T) {
+		price, found := table.HourlyPrice("b3c.4x16", 3)
+		require.True(t, found)
+		assert.Equal(t, 0.6, price)
+	})
+
+	s.T().Run("no price for the number of workers", func(t *testing.T) {
+		_, found := table.HourlyPrice("b3c.8x32", 3)
+		assert.False(t, found)
+	})
+
+	s.T().Run("unknown machine type", func(t *testing.T) {
+		_, found := table.HourlyPrice("m3c.8x64", 2)
+		assert.False(t, found)
+	})
+}
+
+func (s *TestCostSuite) TestHoursByMonth() {
+	s.T().Run("within one month", func(t *testing.T) {
+		start := time.Date(2021, 3, 10, 8, 0, 0, 0, time.UTC)
+		assert.Equal(t, map[string]float64{"2021-03": 5.5}, cost.HoursByMonth(start, start.Add(5*time.Hour+30*time.Minute)))
+	})
+
+	s.T().Run("across months and years", func(t *testing.T) {
+		start := time.Date(2021, 12, 31, 22, 0, 0, 0, time.UTC)
+		end := time.Date(2022, 2, 1, 3, 0, 0, 0, time.UTC)
+		assert.Equal(t, map[string]float64{"2021-12": 2, "2022-01": 31 * 24, "2022-02": 3}, cost.HoursByMonth(start, end))
+	})
+
+	s.T().Run("months are in UTC", func(t *testing.T) {
+		zone := time.FixedZone("UTC+2", 2*60*60)
+		start := time.Date(2021, 4, 1, 1, 0, 0, 0, zone) // 2021-03-31 23:00 UTC
+		assert.Equal(t, map[string]float64{"2021-03": 1, "2021-04": 1}, cost.HoursByMonth(start, start.Add(2*time.Hour)))
+	})
+
+	s.T().Run("end before start", func(t *testing.T) {
+		start := time.Date(2021, 3, 10, 8, 0, 0, 0, time.UTC)
+		assert.Empty(t, cost.HoursByMonth(start, start.Add(-time.Hour)))
+		assert.Empty(t, cost.HoursByMonth(start, start))
+	})
+}
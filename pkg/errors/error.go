@@ -33,6 +33,15 @@ func NewNotFoundError(message, details string) *Error {
 	}
 }
 
+func NewBadRequestError(message, details string) *Error {
+	return &Error{
+		Status:  http.StatusText(http.StatusBadRequest),
+		Code:    http.StatusBadRequest,
+		Message: message,
+		Details: details,
+	}
+}
+
 func NewInternalServerError(message, details string) *Error {
 	return &Error{
 		Status:  http.StatusText(http.StatusInternalServerError),
@@ -56,6 +65,16 @@ func IsNotFound(err error) bool {
 	return false
 }
 
+func IsBadRequest(err error) bool {
+	switch t := err.(type) {
+	case Error:
+		return t.Code == http.StatusBadRequest
+	case *Error:
+		return t.Code == http.StatusBadRequest
+	}
+	return false
+}
+
 func IsInternalServerError(err error) bool {
 	switch t := err.(type) {
 	case Error:
@@ -78,4 +78,21 @@ func (s *TestErrorsSuite) TestErrors() {
 		err = devclustererr.NewNotFoundError("some message", "some details")
 		assert.False(s.T(), devclustererr.IsInternalServerError(err))
 	})
+
+	s.Run("IsBadRequest", func() {
+		err := devclustererr.NewBadRequestError("some message", "some details")
+		assert.True(s.T(), devclustererr.IsBadRequest(err))
+		assert.True(s.T(), devclustererr.IsBadRequest(*err))
+
+		err = devclustererr.NewBadRequestError("some message", "some details")
+		err.Code = http.StatusNotFound
+		assert.False(s.T(), devclustererr.IsBadRequest(err))
+		assert.False(s.T(), devclustererr.IsBadRequest(*err))
+
+		e := errors.New("some error")
+		assert.False(s.T(), devclustererr.IsBadRequest(e))
+
+		err = devclustererr.NewNotFoundError("some message", "some details")
+		assert.False(s.T(), devclustererr.IsBadRequest(err))
+	})
 }
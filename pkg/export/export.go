@@ -0,0 +1,78 @@
+// Package export renders the handouts with the cluster credentials of the workshop attendees
+// in the formats which can be shared with the attendees or printed.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Supported export formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatPDF  = "pdf"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatJSON: "application/json; charset=utf-8",
+	FormatPDF:  "application/pdf",
+}
+
+// Handout is the information handed out to one workshop attendee
+type Handout struct {
+	ClusterName string `json:"cluster_name"`
+	ConsoleURL  string `json:"console_url"`
+	LoginURL    string `json:"login_url"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	WorkshopURL string `json:"workshop_url"`
+}
+
+// ContentType returns the content type of the given format or an error if the format is not supported
+func ContentType(format string) (string, error) {
+	contentType, found := contentTypes[format]
+	if !found {
+		return "", errors.Errorf("unsupported export format: %s", format)
+	}
+	return contentType, nil
+}
+
+// Write writes the handouts in the given format.
+// The header text is printed at the top of every PDF page and is included in the JSON document.
+// CSV contains only the handouts so it can be imported to spreadsheets as is.
+func Write(w io.Writer, format, header string, handouts []Handout) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, handouts)
+	case FormatJSON:
+		return writeJSON(w, header, handouts)
+	case FormatPDF:
+		return writePDF(w, header, handouts)
+	}
+	return errors.Errorf("unsupported export format: %s", format)
+}
+
+func writeCSV(w io.Writer, handouts []Handout) error {
+	writer := csv.NewWriter(w)
+	records := [][]string{{"Cluster Name", "Console URL", "Login URL", "Username", "Password", "Workshop URL"}}
+	for _, h := range handouts {
+		records = append(records, []string{h.ClusterName, h.ConsoleURL, h.LoginURL, h.Username, h.Password, h.WorkshopURL})
+	}
+	return errors.Wrap(writer.WriteAll(records), "unable to write CSV")
+}
+
+type jsonDocument struct {
+	Header   string    `json:"header,omitempty"`
+	Handouts []Handout `json:"handouts"`
+}
+
+func writeJSON(w io.Writer, header string, handouts []Handout) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.Wrap(encoder.Encode(jsonDocument{Header: header, Handouts: handouts}), "unable to write JSON")
+}
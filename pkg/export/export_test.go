@@ -0,0 +1,124 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/export"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestExportSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunExportSuite(t *testing.T) {
+	suite.Run(t, &TestExportSuite{test.UnitTestSuite{}})
+}
+
+var handouts = []export.Handout{
+	{
+		ClusterName: "rhd-lon06-abc",
+		ConsoleURL:  "https://console-openshift-console.abc.example.com",
+		LoginURL:    "https://login",
+		Username:    "rh-dev-1",
+		Password:    "pass(1)",
+		WorkshopURL: "https://workshop/" + strings.Repeat("x", 100),
+	},
+	{
+		ClusterName: "rhd-lon06-abc",
+		Username:    "rh-dev-2",
+		Password:    "pass,2",
+	},
+}
+
+func (s *TestExportSuite) TestContentType() {
+	for format, expected := range map[string]string{
+		export.FormatCSV:  "text/csv; charset=utf-8",
+		export.FormatJSON: "application/json; charset=utf-8",
+		export.FormatPDF:  "application/pdf",
+	} {
+		contentType, err := export.ContentType(format)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), expected, contentType)
+	}
+	_, err := export.ContentType("xls")
+	require.EqualError(s.T(), err, "unsupported export format: xls")
+}
+
+func (s *TestExportSuite) TestCSV() {
+	buf := &bytes.Buffer{}
+	require.NoError(s.T(), export.Write(buf, export.FormatCSV, "Workshop", handouts))
+	assert.Equal(s.T(), "Cluster Name,Console URL,Login URL,Username,Password,Workshop URL\n"+
+		"rhd-lon06-abc,https://console-openshift-console.abc.example.com,https://login,rh-dev-1,pass(1),https://workshop/"+strings.Repeat("x", 100)+"\n"+
+		"rhd-lon06-abc,,,rh-dev-2,\"pass,2\",\n", buf.String())
+}
+
+func (s *TestExportSuite) TestJSON() {
+	buf := &bytes.Buffer{}
+	require.NoError(s.T(), export.Write(buf, export.FormatJSON, "Workshop", handouts))
+	var doc struct {
+		Header   string           `json:"header"`
+		Handouts []export.Handout `json:"handouts"`
+	}
+	require.NoError(s.T(), json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(s.T(), "Workshop", doc.Header)
+	assert.Equal(s.T(), handouts, doc.Handouts)
+}
+
+func (s *TestExportSuite) TestPDF() {
+	s.T().Run("one page per attendee", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, export.Write(buf, export.FormatPDF, "OpenShift (Workshop)", handouts))
+		pdf := buf.String()
+		assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+		assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+		assert.Contains(t, pdf, "/Count 2")
+		assert.Equal(t, 2, strings.Count(pdf, "/Type /Page "))
+		assert.Equal(t, 2, strings.Count(pdf, "(OpenShift \\(Workshop\\)) Tj"))
+		assert.Contains(t, pdf, "(pass\\(1\\)) Tj")
+		assert.Contains(t, pdf, "(rh-dev-2) Tj")
+		// Long values are wrapped
+		assert.Contains(t, pdf, "(https://workshop/"+strings.Repeat("x", 82-len("https://workshop/"))+") Tj")
+		assertValidXref(t, pdf)
+	})
+
+	s.T().Run("no attendees", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, export.Write(buf, export.FormatPDF, "", nil))
+		pdf := buf.String()
+		assert.Contains(t, pdf, "/Count 1")
+		assert.Contains(t, pdf, "(No attendees) Tj")
+		assertValidXref(t, pdf)
+	})
+}
+
+func (s *TestExportSuite) TestUnsupportedFormat() {
+	err := export.Write(&bytes.Buffer{}, "xls", "", handouts)
+	require.EqualError(s.T(), err, "unsupported export format: xls")
+}
+
+// assertValidXref checks that the cross-reference table points to the objects
+func assertValidXref(t *testing.T, pdf string) {
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	require.Len(t, startxref, 2)
+	xref, err := strconv.Atoi(startxref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(pdf[xref:], "xref\n"))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+}
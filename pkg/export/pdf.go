@@ -0,0 +1,177 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The handouts are rendered as a minimal PDF document with one A4 page per attendee.
+// Only the standard PDF fonts are used so no fonts need to be embedded.
+const (
+	pageWidth      = 595 // A4 in points
+	pageHeight     = 842
+	margin         = 50
+	headerFontSize = 18
+	labelFontSize  = 11
+	valueFontSize  = 10
+	lineHeight     = 14
+	// Courier is a monospaced font with the glyph width of 0.6 of the font size
+	maxValueChars = (pageWidth - 2*margin) * 10 / (6 * valueFontSize)
+)
+
+// Object numbers of the objects shared by all the pages
+const (
+	catalogObject = 1
+	pagesObject   = 2
+	boldFont      = 3
+	monoFont      = 4
+	firstPage     = 5
+)
+
+func writePDF(w io.Writer, header string, handouts []Handout) error {
+	pages := make([]string, 0, len(handouts))
+	for _, h := range handouts {
+		pages = append(pages, handoutPage(header, h))
+	}
+	if len(pages) == 0 {
+		pages = append(pages, textPage(header, []pageLine{{text: "No attendees", font: "F1", size: labelFontSize}}))
+	}
+
+	doc := &pdfDocument{}
+	doc.writeHeader()
+	doc.writeObject(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	kids := make([]string, 0, len(pages))
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	doc.writeObject(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.writeObject(boldFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	doc.writeObject(monoFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		pageObject := firstPage + 2*i
+		contentObject := pageObject + 1
+		doc.writeObject(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObject, pageWidth, pageHeight, boldFont, monoFont, contentObject))
+		doc.writeObject(contentObject, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	doc.writeTrailer()
+	_, err := w.Write(doc.buf.Bytes())
+	return errors.Wrap(err, "unable to write PDF")
+}
+
+type pageLine struct {
+	text string
+	font string // F1 - bold labels, F2 - monospaced values
+	size int
+	gap  int // extra space before the line
+}
+
+// handoutPage returns the content stream of the page with the handout of one attendee
+func handoutPage(header string, h Handout) string {
+	fields := []struct {
+		label string
+		value string
+	}{
+		{"Cluster", h.ClusterName},
+		{"Username", h.Username},
+		{"Password", h.Password},
+		{"Console URL", h.ConsoleURL},
+		{"Login URL", h.LoginURL},
+		{"Workshop URL", h.WorkshopURL},
+	}
+	lines := make([]pageLine, 0)
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		lines = append(lines, pageLine{text: f.label, font: "F1", size: labelFontSize, gap: lineHeight / 2})
+		for _, part := range wrap(f.value, maxValueChars) {
+			lines = append(lines, pageLine{text: part, font: "F2", size: valueFontSize})
+		}
+	}
+	return textPage(header, lines)
+}
+
+// textPage returns the content stream of a page with the header and the given lines
+func textPage(header string, lines []pageLine) string {
+	content := &strings.Builder{}
+	y := pageHeight - margin
+	if header != "" {
+		for _, part := range wrap(header, (pageWidth-2*margin)*10/(6*headerFontSize)) {
+			y -= headerFontSize + 4
+			writeText(content, "F1", headerFontSize, y, part)
+		}
+		y -= lineHeight
+	}
+	for _, line := range lines {
+		y -= lineHeight + line.gap
+		if y < margin {
+			break // the rest doesn't fit the page
+		}
+		writeText(content, line.font, line.size, y, line.text)
+	}
+	return content.String()
+}
+
+func writeText(content *strings.Builder, font string, size, y int, text string) {
+	fmt.Fprintf(content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, margin, y, escape(text))
+}
+
+// wrap splits the text to the lines of max n characters
+func wrap(text string, n int) []string {
+	runes := []rune(text)
+	lines := make([]string, 0, len(runes)/n+1)
+	for len(runes) > n {
+		lines = append(lines, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return append(lines, string(runes))
+}
+
+// escape escapes the PDF string delimiters and replaces the characters which can't be represented in the WinAnsi encoding
+func escape(text string) string {
+	b := &strings.Builder{}
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfDocument writes the PDF objects and keeps their offsets for the cross-reference table
+type pdfDocument struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (d *pdfDocument) writeHeader() {
+	d.buf.WriteString("%PDF-1.4\n")
+}
+
+func (d *pdfDocument) writeObject(n int, body string) {
+	for len(d.offsets) < n {
+		d.offsets = append(d.offsets, 0)
+	}
+	d.offsets[n-1] = d.buf.Len()
+	fmt.Fprintf(&d.buf, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+func (d *pdfDocument) writeTrailer() {
+	xref := d.buf.Len()
+	fmt.Fprintf(&d.buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, offset := range d.offsets {
+		fmt.Fprintf(&d.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&d.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, catalogObject, xref)
+}
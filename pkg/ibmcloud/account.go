@@ -0,0 +1,85 @@
+package ibmcloud
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultAccount is the name of the account configured by the top level ibmcloud.apikey, ibmcloud.account_id
+// and ibmcloud.tenant_id if no accounts are configured explicitly
+const DefaultAccount = "default"
+
+// Account is an IBM Cloud account the clusters are provisioned in and the users are created in
+type Account struct {
+	Name   string `json:"name"`
+	APIKey string `json:"apikey"`
+	// APIKeySecret is the name of the secret containing the API key. Used instead of the API key so the key can be rotated.
+	APIKeySecret string `json:"apikey_secret,omitempty"`
+	AccountID    string `json:"account_id"`
+	TenantID     string `json:"tenant_id"` // ID of the App ID (Cloud Directory) instance the users are created in
+	// Teams are the requesters routed to this account. Either usernames or email domains like "@redhat.com".
+	Teams []string `json:"teams,omitempty"`
+	// Zones are the zones the clusters can be provisioned in. All the zones if empty.
+	Zones []string `json:"zones,omitempty"`
+	// MaxClusters is the max number of the active clusters in the account. No limit if 0.
+	MaxClusters int `json:"max_clusters,omitempty"`
+}
+
+// ParseAccounts parses the accounts from the given JSON array.
+// If no accounts are configured then the given default account is the only account.
+func ParseAccounts(raw string, defaultAccount Account) ([]Account, error) {
+	if strings.TrimSpace(raw) == "" {
+		defaultAccount.Name = DefaultAccount
+		return []Account{defaultAccount}, nil
+	}
+	accounts := make([]Account, 0)
+	if err := json.Unmarshal([]byte(raw), &accounts); err != nil {
+		return nil, errors.Wrap(err, "unable to parse IBM Cloud accounts")
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no IBM Cloud accounts configured")
+	}
+	names := make(map[string]bool, len(accounts))
+	for i, a := range accounts {
+		if a.Name == "" {
+			return nil, errors.Errorf("IBM Cloud account #%d has no name", i+1)
+		}
+		if names[a.Name] {
+			return nil, errors.Errorf("duplicate IBM Cloud account %s", a.Name)
+		}
+		names[a.Name] = true
+		if (a.APIKey == "" && a.APIKeySecret == "") || a.AccountID == "" || a.TenantID == "" {
+			return nil, errors.Errorf("IBM Cloud account %s must have an API key or an API key secret, an account ID and a tenant ID", a.Name)
+		}
+		if a.MaxClusters < 0 {
+			return nil, errors.Errorf("IBM Cloud account %s has negative max number of clusters", a.Name)
+		}
+	}
+	return accounts, nil
+}
+
+// AllowsZone returns true if the clusters can be provisioned in the given zone in the account
+func (a Account) AllowsZone(zone string) bool {
+	if len(a.Zones) == 0 {
+		return true
+	}
+	for _, z := range a.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTeam returns true if the given requester is routed to the account,
+// i.e. the username or the email domain of the requester is one of the account teams
+func (a Account) HasTeam(requestedBy string) bool {
+	for _, t := range a.Teams {
+		if t == requestedBy || (strings.HasPrefix(t, "@") && strings.HasSuffix(requestedBy, t)) {
+			return true
+		}
+	}
+	return false
+}
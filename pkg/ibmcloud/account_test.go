@@ -0,0 +1,69 @@
+package ibmcloud
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestAccountSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunAccountSuite(t *testing.T) {
+	suite.Run(t, &TestAccountSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestAccountSuite) TestParseAccounts() {
+	legacy := Account{APIKey: "key", AccountID: "account", TenantID: "tenant"}
+
+	s.Run("default account", func() {
+		accounts, err := ParseAccounts(" ", legacy)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), []Account{{Name: DefaultAccount, APIKey: "key", AccountID: "account", TenantID: "tenant"}}, accounts)
+	})
+
+	s.Run("configured accounts", func() {
+		accounts, err := ParseAccounts(`[
+			{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1", "teams": ["@redhat.com"], "zones": ["lon06"], "max_clusters": 10},
+			{"name": "us", "apikey_secret": "ibmcloud.us.apikey", "account_id": "a2", "tenant_id": "t2"}
+		]`, legacy)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), accounts, 2)
+		assert.Equal(s.T(), Account{Name: "emea", APIKey: "k1", AccountID: "a1", TenantID: "t1", Teams: []string{"@redhat.com"}, Zones: []string{"lon06"}, MaxClusters: 10}, accounts[0])
+		assert.Equal(s.T(), Account{Name: "us", APIKeySecret: "ibmcloud.us.apikey", AccountID: "a2", TenantID: "t2"}, accounts[1])
+	})
+
+	s.Run("invalid accounts", func() {
+		for _, raw := range []string{
+			`not json`,
+			`[]`,
+			`[{"apikey": "k1", "account_id": "a1", "tenant_id": "t1"}]`,
+			`[{"name": "emea", "account_id": "a1", "tenant_id": "t1"}]`,
+			`[{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1", "max_clusters": -1}]`,
+			`[{"name": "emea", "apikey": "k1", "account_id": "a1", "tenant_id": "t1"}, {"name": "emea", "apikey": "k2", "account_id": "a2", "tenant_id": "t2"}]`,
+		} {
+			_, err := ParseAccounts(raw, legacy)
+			assert.Error(s.T(), err, raw)
+		}
+	})
+}
+
+func (s *TestAccountSuite) TestAllowsZone() {
+	assert.True(s.T(), Account{}.AllowsZone("lon06"))
+	assert.True(s.T(), Account{Zones: []string{"wdc04", "lon06"}}.AllowsZone("lon06"))
+	assert.False(s.T(), Account{Zones: []string{"wdc04"}}.AllowsZone("lon06"))
+}
+
+func (s *TestAccountSuite) TestHasTeam() {
+	a := Account{Teams: []string{"john", "@redhat.com"}}
+	assert.True(s.T(), a.HasTeam("john"))
+	assert.True(s.T(), a.HasTeam("jane@redhat.com"))
+	assert.False(s.T(), a.HasTeam("johnsmith"))
+	assert.False(s.T(), a.HasTeam("jane@example.com"))
+	assert.False(s.T(), Account{}.HasTeam("john"))
+}
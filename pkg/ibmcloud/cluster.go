@@ -14,6 +14,7 @@ import (
 
 	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
 	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/password"
 	"github.com/codeready-toolchain/devcluster/pkg/rest"
 
 	"github.com/pkg/errors"
@@ -26,6 +27,12 @@ type Configuration interface {
 	GetIBMCloudAccountID() string
 	GetIBMCloudTenantID() string
 	GetIBMCloudIDPName() string
+	GetIBMCloudRateLimitPerSec() int
+	GetIBMCloudMaxRetries() int
+	GetIBMCloudRequestTimeoutSec() int
+	GetIBMCloudCircuitBreakerThreshold() int
+	GetIBMCloudCircuitBreakerOpenSec() int
+	password.Configuration
 }
 
 type ICClient interface {
@@ -33,26 +40,49 @@ type ICClient interface {
 	GetZones() ([]Location, error)
 	CreateCluster(name, zone string, noSubnet bool) (*IBMCloudClusterRequest, error)
 	GetCluster(id string) (*Cluster, error)
+	GetClusters() ([]Cluster, error)
 	DeleteCluster(id string) error
-	CreateCloudDirectoryUser(username string) (*CloudDirectoryUser, error)
+	CreateCloudDirectoryUser(username, email string) (*CloudDirectoryUser, error)
 	UpdateCloudDirectoryUserPassword(id string) (*CloudDirectoryUser, error)
+	DeleteCloudDirectoryUser(id string) error
 	GetIAMUserByUserID(userID string) (*IAMUser, error)
+	DeleteIAMUser(id string) error
 	CreateAccessPolicy(accountID, userID, clusterID string) (string, error)
+	GetAccessPolicies(accountID string) ([]AccessPolicy, error)
 	DeleteAccessPolicy(id string) error
+	CircuitBreakerState() string
+	Token() (TokenSet, error)
 }
 
 type Client struct {
-	config   Configuration
-	token    *TokenSet
-	tokenMux sync.RWMutex
+	config     Configuration
+	token      *TokenSet
+	tokenKey   string // API key the token was obtained with
+	tokenMux   sync.RWMutex
+	transport  *transport
+	httpClient *http.Client
+	passwords  password.Generator
 }
 
 func NewClient(config Configuration) *Client {
+	t := newTransport(config)
 	return &Client{
-		config: config,
+		config:    config,
+		transport: t,
+		passwords: password.NewGenerator(config),
+		// The timeout is applied by the transport to every attempt instead of the whole call with all its retries
+		httpClient: &http.Client{
+			Transport: t,
+		},
 	}
 }
 
+// CircuitBreakerState returns the state of the circuit breaker guarding the IBM Cloud API calls: closed, open or half-open.
+// The breaker is open while IBM Cloud is considered degraded.
+func (c *Client) CircuitBreakerState() string {
+	return c.transport.State()
+}
+
 func (c *Client) GetToken() TokenSet {
 	defer c.tokenMux.RUnlock()
 	c.tokenMux.RLock()
@@ -61,18 +91,21 @@ func (c *Client) GetToken() TokenSet {
 
 // Token returns IBM Cloud Token.
 // If the token is expired or not obtained yet it will obtain a new one.
+// The token is also obtained again if the API key has been rotated since the token was obtained.
 func (c *Client) Token() (TokenSet, error) {
+	apiKey := c.config.GetIBMCloudAPIKey()
 	c.tokenMux.RLock()
-	if tokenExpired(c.token) {
+	if tokenExpired(c.token) || c.tokenKey != apiKey {
 		c.tokenMux.RUnlock()
 		c.tokenMux.Lock()
 		defer c.tokenMux.Unlock()
-		if tokenExpired(c.token) {
-			var err error
-			c.token, err = c.obtainNewToken()
+		if tokenExpired(c.token) || c.tokenKey != apiKey {
+			token, err := c.obtainNewToken(apiKey)
 			if err != nil {
 				return TokenSet{}, err
 			}
+			c.token = token
+			c.tokenKey = apiKey
 		}
 		return *c.token, nil
 	}
@@ -123,12 +156,12 @@ func (c *Client) GetVlans(zone string) ([]Vlan, error) {
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://containers.cloud.ibm.com/global/v1/datacenters/%s/vlans", zone), nil)
+	req, err := newRequest("GET", "/global/v1/datacenters/{zone}/vlans", fmt.Sprintf("https://containers.cloud.ibm.com/global/v1/datacenters/%s/vlans", zone), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get vlans")
 	}
@@ -159,12 +192,12 @@ func (c *Client) GetZones() ([]Location, error) {
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("GET", "https://containers.cloud.ibm.com/global/v1/locations", nil)
+	req, err := newRequest("GET", "/global/v1/locations", "https://containers.cloud.ibm.com/global/v1/locations", nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get zones")
 	}
@@ -197,17 +230,23 @@ type ID struct {
 	ID string `json:"id"`
 }
 
+// Machine type and number of workers of the created clusters
+const (
+	MachineType = "b3c.4x16"
+	WorkerCount = 2
+)
+
 const ClusterConfigTemplate = `
 {
   "dataCenter": "%s",
   "disableAutoUpdate": true,
-  "machineType": "b3c.4x16",
+  "machineType": "%s",
   "masterVersion": "4.8_openshift",
   "name": "%s",
   "publicVlan": "%s",
   "privateVlan": "%s",
   "noSubnet": %t,
-  "workerNum": 2
+  "workerNum": %d
 }`
 
 type IBMCloudClusterRequest struct {
@@ -239,14 +278,14 @@ func (c *Client) CreateCluster(name, zone string, noSubnet bool) (*IBMCloudClust
 		log.Infof(nil, "WARNING: no public vlan found for zone %s. New vlan will be created", zone)
 	}
 
-	body := bytes.NewBuffer([]byte(fmt.Sprintf(ClusterConfigTemplate, zone, name, public, private, noSubnet)))
-	req, err := http.NewRequest("POST", "https://containers.cloud.ibm.com/global/v1/clusters", body)
+	body := bytes.NewBuffer([]byte(fmt.Sprintf(ClusterConfigTemplate, zone, MachineType, name, public, private, noSubnet, WorkerCount)))
+	req, err := newRequest("POST", "/global/v1/clusters", "https://containers.cloud.ibm.com/global/v1/clusters", body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
 	req.Header.Add("Content-Type", "application/json")
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create cluster")
 	}
@@ -311,12 +350,12 @@ func (c *Client) GetCluster(id string) (*Cluster, error) {
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://containers.cloud.ibm.com/global/v2/getCluster?cluster=%s", id), nil)
+	req, err := newRequest("GET", "/global/v2/getCluster", fmt.Sprintf("https://containers.cloud.ibm.com/global/v2/getCluster?cluster=%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get cluster")
 	}
@@ -337,18 +376,47 @@ func (c *Client) GetCluster(id string) (*Cluster, error) {
 	return &cluster, nil
 }
 
+// GetClusters fetches all the clusters available in the account
+func (c *Client) GetClusters() ([]Cluster, error) {
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest("GET", "/global/v1/clusters", "https://containers.cloud.ibm.com/global/v1/clusters", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get clusters")
+	}
+	defer rest.CloseResponse(res)
+	bodyString := rest.ReadBody(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, responseErr(res, "unable to get clusters", bodyString)
+	}
+
+	var clusters []Cluster
+	err = json.Unmarshal([]byte(bodyString), &clusters)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error when unmarshal json with clusters %s ", bodyString)
+	}
+	return clusters, nil
+}
+
 // DeleteCluster deletes the cluster with the given ID/name
 func (c *Client) DeleteCluster(id string) error {
 	token, err := c.Token()
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("https://containers.cloud.ibm.com/global/v1/clusters/%s?deleteResources=true", id), nil)
+	req, err := newRequest("DELETE", "/global/v1/clusters/{id}", fmt.Sprintf("https://containers.cloud.ibm.com/global/v1/clusters/%s?deleteResources=true", id), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "unable to delete cluster")
 	}
@@ -365,12 +433,16 @@ func (c *Client) DeleteCluster(id string) error {
 
 // obtainNewToken obtains an access token
 // Returns the access token string and the time when the token is going to expire
-func (c *Client) obtainNewToken() (*TokenSet, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.PostForm("https://iam.cloud.ibm.com/identity/token", url.Values{
+func (c *Client) obtainNewToken(apiKey string) (*TokenSet, error) {
+	req, err := newRequest("POST", "/identity/token", "https://iam.cloud.ibm.com/identity/token", strings.NewReader(url.Values{
 		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
-		"apikey":     {c.config.GetIBMCloudAPIKey()},
-	})
+		"apikey":     {apiKey},
+	}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +123,42 @@ func (s *TestClusterSuite) TestGetCluster() {
 	})
 }
 
+func (s *TestClusterSuite) TestGetClusters() {
+	cl := newClient(s.T(), s.mockConfig)
+	s.T().Run("OK", func(t *testing.T) {
+		defer gock.OffAll()
+
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/clusters").
+			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
+			Persist().
+			Reply(200).
+			BodyString(`[{"id": "id-1", "name": "rhd-wdc04-1", "state": "normal"},{"id": "id-2", "name": "other", "state": "deploying"}]`)
+
+		clusters, err := cl.GetClusters()
+		require.NoError(t, err)
+		assert.Equal(t, []Cluster{
+			{ID: "id-1", Name: "rhd-wdc04-1", State: "normal"},
+			{ID: "id-2", Name: "other", State: "deploying"},
+		}, clusters)
+	})
+
+	s.T().Run("Error getting clusters", func(t *testing.T) {
+		defer gock.OffAll()
+
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/clusters").
+			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
+			Persist().
+			Reply(500).
+			BodyString(`something went wrong`).
+			SetHeader("X-Request-Id", "1234509876")
+
+		_, err := cl.GetClusters()
+		require.EqualError(t, err, "unable to get clusters. x-request-id: 1234509876, Response status: 500 Internal Server Error. Response body: something went wrong")
+	})
+}
+
 func (s *TestClusterSuite) TestCreateCluster() {
 	cl := newClient(s.T(), s.mockConfig)
 	s.T().Run("Vlan is available", func(t *testing.T) {
@@ -137,7 +173,7 @@ func (s *TestClusterSuite) TestCreateCluster() {
 		gock.New("https://containers.cloud.ibm.com").
 			Post("global/v1/clusters").
 			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
-			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", "john", "54321", "12345", false)).
+			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", MachineType, "john", "54321", "12345", false, WorkerCount)).
 			Persist().
 			Reply(201).
 			BodyString(`{"id": "some-id"}`).
@@ -165,7 +201,7 @@ func (s *TestClusterSuite) TestCreateCluster() {
 		gock.New("https://containers.cloud.ibm.com").
 			Post("global/v1/clusters").
 			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
-			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", "john", "54321", "12345", false)).
+			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", MachineType, "john", "54321", "12345", false, WorkerCount)).
 			Persist().
 			Reply(201).
 			BodyString(`{"id": "some-id"}`).
@@ -193,7 +229,7 @@ func (s *TestClusterSuite) TestCreateCluster() {
 		gock.New("https://containers.cloud.ibm.com").
 			Post("global/v1/clusters").
 			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
-			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", "john", "", "", true)).
+			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", MachineType, "john", "", "", true, WorkerCount)).
 			Persist().
 			Reply(201).
 			BodyString(`{"id": "some-id"}`)
@@ -215,7 +251,7 @@ func (s *TestClusterSuite) TestCreateCluster() {
 		gock.New("https://containers.cloud.ibm.com").
 			Post("global/v1/clusters").
 			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
-			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", "john", "54321", "12345", false)).
+			JSON(fmt.Sprintf(ClusterConfigTemplate, "zone-1", MachineType, "john", "54321", "12345", false, WorkerCount)).
 			Persist().
 			Reply(500).
 			SetHeader("X-Request-Id", "1234509876").
@@ -285,6 +321,29 @@ func (s *TestClusterSuite) TestToken() {
 			TokenType:    "Bearer",
 		}, token)
 	})
+
+	s.T().Run("rotated api key", func(t *testing.T) {
+		config := &MockConfig{apiKey: "key-1"}
+		cl := newClient(s.T(), config)
+		config.apiKey = "key-2"
+
+		defer gock.OffAll()
+		gock.New("https://iam.cloud.ibm.com").
+			Post("identity/token").
+			BodyString("apikey=key-2").
+			Reply(200).
+			BodyString(`{"access_token": "rotated-token","refresh_token":"qwerty","ims_user_id": 4778951,"token_type": "Bearer","expires_in": 3600,"expiration": 1998983098,"scope":"ibm openid"}`)
+
+		token, err := cl.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "rotated-token", token.AccessToken)
+		assert.True(t, gock.IsDone())
+
+		// The token obtained with the current key is cached
+		token, err = cl.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "rotated-token", token.AccessToken)
+	})
 }
 
 func newClient(t *testing.T, c Configuration) *Client {
@@ -315,9 +374,13 @@ func setNewToken(t *testing.T, cl *Client, expiration int64) {
 }
 
 type MockConfig struct {
+	apiKey string
 }
 
 func (c *MockConfig) GetIBMCloudAPIKey() string {
+	if c.apiKey != "" {
+		return c.apiKey
+	}
 	return "secretkey"
 }
 
@@ -340,3 +403,35 @@ func (c *MockConfig) GetIBMCloudTenantID() string {
 func (c *MockConfig) GetIBMCloudIDPName() string {
 	return "devcluster"
 }
+
+func (c *MockConfig) GetIBMCloudRateLimitPerSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudMaxRetries() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudRequestTimeoutSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudCircuitBreakerThreshold() int {
+	return 0
+}
+
+func (c *MockConfig) GetIBMCloudCircuitBreakerOpenSec() int {
+	return 0
+}
+
+func (c *MockConfig) GetPasswordLength() int {
+	return 0
+}
+
+func (c *MockConfig) GetPasswordCharacterClasses() []string {
+	return nil
+}
+
+func (c *MockConfig) GetPasswordPassphraseWords() int {
+	return 0
+}
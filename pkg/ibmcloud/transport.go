@@ -0,0 +1,338 @@
+package ibmcloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned for all the IBM Cloud API calls while IBM Cloud is considered degraded
+var ErrCircuitOpen = errors.New("IBM Cloud API is degraded; calls are paused")
+
+// maxRetryDelay is the max delay between two retries of a failed call
+const maxRetryDelay = 30 * time.Second
+
+// transport wraps the default http transport and adds per-endpoint rate limiting,
+// retries of the failed calls, a timeout of every attempt and a circuit breaker
+type transport struct {
+	config         Configuration
+	limiters       map[string]*rate.Limiter
+	limitersMux    sync.Mutex
+	breaker        *circuitBreaker
+	retryBaseDelay time.Duration
+}
+
+func newTransport(config Configuration) *transport {
+	return &transport{
+		config:         config,
+		limiters:       make(map[string]*rate.Limiter),
+		breaker:        newCircuitBreaker(config.GetIBMCloudCircuitBreakerThreshold(), time.Duration(config.GetIBMCloudCircuitBreakerOpenSec())*time.Second),
+		retryBaseDelay: time.Second,
+	}
+}
+
+// RoundTrip executes the request. Every attempt is limited by the request timeout.
+// Requests failed with a network error, 429 or 5xx are retried with exponential backoff and jitter if they are idempotent.
+// POST requests are retried only if the server has not processed them (429 or 503) so nothing is created twice.
+// If the response contains the Retry-After header then it's used as the delay before the next retry.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter(endpoint(req)).Wait(req.Context()); err != nil {
+			t.breaker.cancel()
+			return nil, err
+		}
+		res, err := t.roundTripAttempt(req)
+		if isCancelled(err) && req.Context().Err() != nil {
+			t.breaker.cancel()
+			return nil, err
+		}
+		if !shouldRetry(req, res, err) || attempt >= t.config.GetIBMCloudMaxRetries() {
+			// The call counts against the breaker whether it's retried or not, e.g. the POST failed with 500
+			if callFailed(res, err) {
+				t.breaker.failure()
+			} else {
+				t.breaker.success()
+			}
+			return res, err
+		}
+		delay := t.retryDelay(res, attempt)
+		if res != nil {
+			log.Info(nil, fmt.Sprintf("%s %s failed with status %s; will retry in %s", req.Method, req.URL.Path, res.Status, delay))
+			res.Body.Close()
+		} else {
+			log.Info(nil, fmt.Sprintf("%s %s failed with error %s; will retry in %s", req.Method, req.URL.Path, err.Error(), delay))
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			t.breaker.cancel()
+			return nil, err
+		}
+		if req, err = rewindBody(req); err != nil {
+			t.breaker.cancel()
+			return nil, err
+		}
+	}
+}
+
+// roundTripAttempt sends the request once. The attempt is cancelled if the request timeout is exceeded
+// before the response body is closed.
+func (t *transport) roundTripAttempt(req *http.Request) (*http.Response, error) {
+	timeout := time.Duration(t.config.GetIBMCloudRequestTimeoutSec()) * time.Second
+	if timeout <= 0 {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := http.DefaultTransport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody releases the context of the attempt when the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// State returns the state of the circuit breaker
+func (t *transport) State() string {
+	return t.breaker.State()
+}
+
+// limiter returns the rate limiter for the given endpoint
+func (t *transport) limiter(endpoint string) *rate.Limiter {
+	t.limitersMux.Lock()
+	defer t.limitersMux.Unlock()
+	l, found := t.limiters[endpoint]
+	if !found {
+		limit := rate.Inf
+		perSec := t.config.GetIBMCloudRateLimitPerSec()
+		if perSec > 0 {
+			limit = rate.Limit(perSec)
+		}
+		l = rate.NewLimiter(limit, perSec)
+		t.limiters[endpoint] = l
+	}
+	return l
+}
+
+// retryDelay returns the delay before the next retry. Retry-After header is honored if present.
+func (t *transport) retryDelay(res *http.Response, attempt int) time.Duration {
+	if res != nil {
+		if after := res.Header.Get("Retry-After"); after != "" {
+			if sec, err := strconv.Atoi(after); err == nil {
+				return capRetryDelay(time.Duration(sec) * time.Second)
+			}
+			if date, err := http.ParseTime(after); err == nil {
+				return capRetryDelay(time.Until(date))
+			}
+		}
+	}
+	delay := t.retryBaseDelay << uint(attempt)
+	// Add up to 50% of jitter
+	if delay > 0 {
+		delay = delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+	}
+	return capRetryDelay(delay)
+}
+
+func capRetryDelay(delay time.Duration) time.Duration {
+	if delay < 0 {
+		return 0
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func isCancelled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// callFailed returns true if the call failed with a network error or 5xx which means IBM Cloud may be degraded
+func callFailed(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= http.StatusInternalServerError
+}
+
+// shouldRetry returns true if the idempotent call failed with a network error, 429 or 5xx.
+// Other calls, like POST, are retried only if they failed with 429 or 503 which means they have not been processed.
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if !isIdempotent(req.Method) {
+		return err == nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable)
+	}
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type endpointKey struct{}
+
+// newRequest returns a new IBM Cloud API request. The endpoint is the path template, e.g. "/global/v1/clusters/{id}",
+// which together with the method and the host identifies the endpoint the calls are rate limited by.
+func newRequest(method, endpoint, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return req.WithContext(context.WithValue(req.Context(), endpointKey{}, endpoint)), nil
+}
+
+// endpoint returns the key of the endpoint the request is sent to.
+// The path is used if the request has been created without the endpoint template.
+func endpoint(req *http.Request) string {
+	path, ok := req.Context().Value(endpointKey{}).(string)
+	if !ok {
+		path = req.URL.Path
+	}
+	return req.Method + " " + req.URL.Host + path
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rewindBody returns a copy of the request with the body reset so the request can be sent again
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("unable to retry the request; the request body can't be reset")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq := req.Clone(req.Context())
+	newReq.Body = body
+	return newReq, nil
+}
+
+// circuitBreaker opens after the given number of consecutive failures and rejects all the calls
+// until the open period is over. Then one trial call is allowed (half-open).
+// If the trial call succeeds then the breaker is closed, otherwise it opens again.
+type circuitBreaker struct {
+	mux          sync.Mutex
+	threshold    int
+	openDuration time.Duration
+	failures     int
+	openedAt     time.Time
+	open         bool
+	trial        bool
+}
+
+func newCircuitBreaker(threshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:    threshold,
+		openDuration: openDuration,
+	}
+}
+
+// allow returns true if the call can be made
+func (b *circuitBreaker) allow() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state() {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.trial {
+			// Only one trial call at a time
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.open {
+		log.Info(nil, "IBM Cloud API has recovered; closing the circuit breaker")
+	}
+	b.failures = 0
+	b.open = false
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.failures++
+	b.trial = false
+	if b.threshold > 0 && (b.open || b.failures >= b.threshold) {
+		if !b.open {
+			log.Info(nil, fmt.Sprintf("IBM Cloud API failed %d times in a row; opening the circuit breaker for %s", b.failures, b.openDuration))
+		}
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+// cancel is called if the call was cancelled before it's known whether it succeeded or not
+func (b *circuitBreaker) cancel() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.trial = false
+}
+
+// State returns the current state of the circuit breaker: closed, open or half-open
+func (b *circuitBreaker) State() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.state()
+}
+
+func (b *circuitBreaker) state() string {
+	if !b.open {
+		return CircuitClosed
+	}
+	if time.Since(b.openedAt) < b.openDuration {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
@@ -0,0 +1,277 @@
+package ibmcloud
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/h2non/gock.v1"
+)
+
+type TestTransportSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunTransportSuite(t *testing.T) {
+	suite.Run(t, &TestTransportSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestTransportSuite) TestRetry() {
+	s.T().Run("retry 5xx and 429 until OK", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Reply(503)
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Reply(429).
+			SetHeader("Retry-After", "0")
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Reply(200)
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 2, threshold: 5})
+		res, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.True(t, gock.IsDone())
+	})
+
+	s.T().Run("give up after max retries", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Times(2).
+			Reply(500)
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 1, threshold: 5})
+		res, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.True(t, gock.IsDone())
+	})
+
+	s.T().Run("do not retry 4xx", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Reply(404)
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 3, threshold: 5})
+		res, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	s.T().Run("do not retry POST which may have been processed", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Post("global/v1/clusters").
+			Reply(500)
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 3, threshold: 5})
+		res, err := client.Post("https://containers.cloud.ibm.com/global/v1/clusters", "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.True(t, gock.IsDone())
+	})
+
+	s.T().Run("retry POST which has not been processed", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Post("global/v1/clusters").
+			Reply(503)
+		gock.New("https://containers.cloud.ibm.com").
+			Post("global/v1/clusters").
+			Reply(429)
+		gock.New("https://containers.cloud.ibm.com").
+			Post("global/v1/clusters").
+			Reply(201)
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 3, threshold: 5})
+		res, err := client.Post("https://containers.cloud.ibm.com/global/v1/clusters", "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.True(t, gock.IsDone())
+	})
+
+	s.T().Run("timeout applies to every attempt", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				// The first attempt times out
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := newTestHTTPClient(&transportMockConfig{retries: 1, threshold: 5, timeout: 1})
+		res, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
+
+func (s *TestTransportSuite) TestRetryDelay() {
+	tr := newTransport(&transportMockConfig{})
+	tr.retryBaseDelay = time.Second
+
+	s.T().Run("retry after seconds", func(t *testing.T) {
+		res := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+		assert.Equal(t, 7*time.Second, tr.retryDelay(res, 0))
+	})
+
+	s.T().Run("retry after is capped", func(t *testing.T) {
+		res := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+		assert.Equal(t, maxRetryDelay, tr.retryDelay(res, 0))
+	})
+
+	s.T().Run("exponential backoff with jitter", func(t *testing.T) {
+		res := &http.Response{Header: http.Header{}}
+		delay := tr.retryDelay(res, 2)
+		assert.True(t, delay >= 4*time.Second && delay <= 6*time.Second, "unexpected delay: %s", delay)
+	})
+}
+
+func (s *TestTransportSuite) TestCircuitBreaker() {
+	s.T().Run("open after threshold and close after recovery", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Times(2).
+			Reply(500)
+
+		tr := newTransport(&transportMockConfig{threshold: 2})
+		tr.breaker.openDuration = 100 * time.Millisecond
+		client := &http.Client{Transport: tr}
+		for i := 0; i < 2; i++ {
+			res, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+			require.NoError(t, err)
+			res.Body.Close()
+		}
+		assert.Equal(t, CircuitOpen, tr.State())
+
+		// Calls are rejected while open
+		_, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+		// A trial call is allowed after the open period
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(t, CircuitHalfOpen, tr.State())
+		gock.New("https://containers.cloud.ibm.com").
+			Get("global/v1/locations").
+			Reply(200)
+		res, err := client.Get("https://containers.cloud.ibm.com/global/v1/locations")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, CircuitClosed, tr.State())
+	})
+
+	s.T().Run("open after failed calls which are not retried", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://containers.cloud.ibm.com").
+			Post("global/v1/clusters").
+			Times(2).
+			Reply(500)
+
+		tr := newTransport(&transportMockConfig{retries: 3, threshold: 2})
+		tr.breaker.openDuration = time.Minute
+		client := &http.Client{Transport: tr}
+		for i := 0; i < 2; i++ {
+			res, err := client.Post("https://containers.cloud.ibm.com/global/v1/clusters", "application/json", strings.NewReader("{}"))
+			require.NoError(t, err)
+			res.Body.Close()
+		}
+		assert.True(t, gock.IsDone())
+		assert.Equal(t, CircuitOpen, tr.State())
+	})
+
+	s.T().Run("failed trial opens again", func(t *testing.T) {
+		b := newCircuitBreaker(1, 50*time.Millisecond)
+		b.failure()
+		assert.Equal(t, CircuitOpen, b.State())
+		time.Sleep(60 * time.Millisecond)
+		require.True(t, b.allow())
+		assert.False(t, b.allow()) // only one trial at a time
+		b.failure()
+		assert.Equal(t, CircuitOpen, b.State())
+	})
+}
+
+func (s *TestTransportSuite) TestRateLimit() {
+	s.T().Run("limit per endpoint", func(t *testing.T) {
+		tr := newTransport(&transportMockConfig{rate: 2})
+		policies := tr.limiter("POST iam.cloud.ibm.com/v1/policies")
+		assert.Same(t, policies, tr.limiter("POST iam.cloud.ibm.com/v1/policies"))
+		assert.NotSame(t, policies, tr.limiter("POST iam.cloud.ibm.com/identity/token"))
+		assert.Equal(t, 2, policies.Burst())
+		assert.True(t, policies.Allow())
+		assert.True(t, policies.Allow())
+		assert.False(t, policies.Allow())
+	})
+
+	s.T().Run("endpoint of request", func(t *testing.T) {
+		req, err := newRequest("DELETE", "/v1/policies/{id}", "https://iam.cloud.ibm.com/v1/policies/123", nil)
+		require.NoError(t, err)
+		other, err := newRequest("DELETE", "/v1/policies/{id}", "https://iam.cloud.ibm.com/v1/policies/456", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "DELETE iam.cloud.ibm.com/v1/policies/{id}", endpoint(req))
+		assert.Equal(t, endpoint(req), endpoint(other))
+
+		req, err = http.NewRequest("GET", "https://containers.cloud.ibm.com/global/v1/locations", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "GET containers.cloud.ibm.com/global/v1/locations", endpoint(req))
+	})
+}
+
+func newTestHTTPClient(config Configuration) *http.Client {
+	tr := newTransport(config)
+	tr.retryBaseDelay = 0
+	return &http.Client{Transport: tr}
+}
+
+type transportMockConfig struct {
+	MockConfig
+	rate      int
+	retries   int
+	threshold int
+	timeout   int
+}
+
+func (c *transportMockConfig) GetIBMCloudRateLimitPerSec() int {
+	return c.rate
+}
+
+func (c *transportMockConfig) GetIBMCloudMaxRetries() int {
+	return c.retries
+}
+
+func (c *transportMockConfig) GetIBMCloudCircuitBreakerThreshold() int {
+	return c.threshold
+}
+
+func (c *transportMockConfig) GetIBMCloudRequestTimeoutSec() int {
+	return c.timeout
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"time"
 
@@ -40,9 +39,10 @@ func (u *CloudDirectoryUser) Email() string {
 
 const CloudDirectoryUserTemplate = `{"active":true, "emails":[{"value":"%s", "primary":true}], "userName":"%s", "password":"%s"}`
 
-// CreateCloudDirectoryUser creates a new cloud directory user with the given username and generated email and password.
+// CreateCloudDirectoryUser creates a new cloud directory user with the given username, email and generated password.
 // If the given username is an empty string then it will be generated too.
-func (c *Client) CreateCloudDirectoryUser(username string) (*CloudDirectoryUser, error) {
+// If the given email is an empty string then <username>@redhat.com is used.
+func (c *Client) CreateCloudDirectoryUser(username, email string) (*CloudDirectoryUser, error) {
 	token, err := c.Token()
 	if err != nil {
 		return nil, err
@@ -50,17 +50,22 @@ func (c *Client) CreateCloudDirectoryUser(username string) (*CloudDirectoryUser,
 	if username == "" {
 		username = auth.GenerateShortID("dev")
 	}
-	email := fmt.Sprintf("%s@redhat.com", username)
-	password := generatePassword(8)
+	if email == "" {
+		email = fmt.Sprintf("%s@redhat.com", username)
+	}
+	password, err := c.passwords.Generate()
+	if err != nil {
+		return nil, err
+	}
 	body := bytes.NewBuffer([]byte(fmt.Sprintf(CloudDirectoryUserTemplate, email, username, password)))
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/sign_up?shouldCreateProfile=true&language=en", apiRegion, c.config.GetIBMCloudTenantID()), body)
+	req, err := newRequest("POST", "/management/v4/{tenant}/cloud_directory/sign_up", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/sign_up?shouldCreateProfile=true&language=en", apiRegion, c.config.GetIBMCloudTenantID()), body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create cloud directory user")
 	}
@@ -86,13 +91,13 @@ func (c *Client) getCloudDirectoryUser(id string) (*CloudDirectoryUser, error) {
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/Users/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), nil)
+	req, err := newRequest("GET", "/management/v4/{tenant}/cloud_directory/Users/{id}", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/Users/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get cloud directory user")
 	}
@@ -120,16 +125,19 @@ func (c *Client) UpdateCloudDirectoryUserPassword(id string) (*CloudDirectoryUse
 	if err != nil {
 		return nil, err
 	}
-	password := generatePassword(8)
+	password, err := c.passwords.Generate()
+	if err != nil {
+		return nil, err
+	}
 	body := bytes.NewBuffer([]byte(fmt.Sprintf(CloudDirectoryUserTemplate, user.Email(), user.Username, password)))
-	req, err := http.NewRequest("PUT", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/Users/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), body)
+	req, err := newRequest("PUT", "/management/v4/{tenant}/cloud_directory/Users/{id}", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/Users/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to update cloud directory user")
 	}
@@ -154,12 +162,12 @@ func (c *Client) DeleteCloudDirectoryUser(id string) error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/remove/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), nil)
+	req, err := newRequest("DELETE", "/management/v4/{tenant}/cloud_directory/remove/{id}", fmt.Sprintf("https://%s.appid.cloud.ibm.com/management/v4/%s/cloud_directory/remove/%s", apiRegion, c.config.GetIBMCloudTenantID(), id), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "unable to delete cloud directory user")
 	}
@@ -191,7 +199,7 @@ func (c *Client) GetIAMUserByUserID(userID string) (*IAMUser, error) {
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://user-management.cloud.ibm.com/v2/accounts/%s/users", c.config.GetIBMCloudAccountID()), nil)
+	req, err := newRequest("GET", "/v2/accounts/{account}/users", fmt.Sprintf("https://user-management.cloud.ibm.com/v2/accounts/%s/users", c.config.GetIBMCloudAccountID()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +208,7 @@ func (c *Client) GetIAMUserByUserID(userID string) (*IAMUser, error) {
 	req.URL.RawQuery = params.Encode()
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get IAM users")
 	}
@@ -231,12 +239,12 @@ func (c *Client) DeleteIAMUser(id string) error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("https://user-management.cloud.ibm.com/v2/accounts/%s/users/%s", c.config.GetIBMCloudAccountID(), id), nil)
+	req, err := newRequest("DELETE", "/v2/accounts/{account}/users/{id}", fmt.Sprintf("https://user-management.cloud.ibm.com/v2/accounts/%s/users/%s", c.config.GetIBMCloudAccountID(), id), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "unable to delete IAM user")
 	}
@@ -268,10 +276,47 @@ const AccessPolicyTemplate = `
    ]
 }`
 
+type AccessPolicyList struct {
+	Policies []AccessPolicy `json:"policies"`
+}
+
 type AccessPolicy struct {
-	TotalResults int       `json:"total_results"`
-	Limit        int       `json:"limit"`
-	Resources    []IAMUser `json:"resources"`
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt string                 `json:"created_at"`
+	Resources []AccessPolicyResource `json:"resources"`
+}
+
+type AccessPolicyResource struct {
+	Attributes []AccessPolicyAttribute `json:"attributes"`
+}
+
+type AccessPolicyAttribute struct {
+	Name     string `json:"name"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// ServiceInstance returns the value of the "serviceInstance" resource attribute which is the cluster ID
+// for the policies created by CreateAccessPolicy(). Returns "" if the policy has no such attribute.
+func (p AccessPolicy) ServiceInstance() string {
+	for _, r := range p.Resources {
+		for _, a := range r.Attributes {
+			if a.Name == "serviceInstance" {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Created returns the time when the policy was created. Returns zero time if the creation time is unknown.
+func (p AccessPolicy) Created() time.Time {
+	t, err := time.Parse(time.RFC3339, p.CreatedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
 }
 
 // CreateAccessPolicy creates an access policy for the cluster and assigns it to the user.
@@ -286,14 +331,14 @@ func (c *Client) CreateAccessPolicy(accountID, userID, clusterID string) (string
 		return "", err
 	}
 	body := bytes.NewBuffer([]byte(fmt.Sprintf(AccessPolicyTemplate, iamUser.IAMID, accountID, clusterID)))
-	req, err := http.NewRequest("POST", "https://iam.cloud.ibm.com/v1/policies", body)
+	req, err := newRequest("POST", "/v1/policies", "https://iam.cloud.ibm.com/v1/policies", body)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to create access policy")
 	}
@@ -311,18 +356,53 @@ func (c *Client) CreateAccessPolicy(accountID, userID, clusterID string) (string
 	return idObj.ID, nil
 }
 
+// GetAccessPolicies fetches all the access policies for the containers-kubernetes service in the given account.
+func (c *Client) GetAccessPolicies(accountID string) ([]AccessPolicy, error) {
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest("GET", "/v1/policies", "https://iam.cloud.ibm.com/v1/policies", nil)
+	if err != nil {
+		return nil, err
+	}
+	params := req.URL.Query()
+	params.Add("account_id", accountID)
+	params.Add("type", "access")
+	params.Add("service_name", "containers-kubernetes")
+	req.URL.RawQuery = params.Encode()
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get access policies")
+	}
+	defer rest.CloseResponse(res)
+	bodyString := rest.ReadBody(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to get access policies. Response status: %s. Response body: %s", res.Status, bodyString)
+	}
+
+	var policies AccessPolicyList
+	err = json.Unmarshal([]byte(bodyString), &policies)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error when unmarshal json with access policies %s ", bodyString)
+	}
+	return policies.Policies, nil
+}
+
 // DeleteAccessPolicy deletes the access policy with the specified ID.
 func (c *Client) DeleteAccessPolicy(id string) error {
 	token, err := c.Token()
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("https://iam.cloud.ibm.com/v1/policies/%s", id), nil)
+	req, err := newRequest("DELETE", "/v1/policies/{id}", fmt.Sprintf("https://iam.cloud.ibm.com/v1/policies/%s", id), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "unable to delete access policy")
 	}
@@ -333,14 +413,3 @@ func (c *Client) DeleteAccessPolicy(id string) error {
 	}
 	return nil
 }
-
-var letters = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
-
-func generatePassword(n int) string {
-	rand.Seed(time.Now().UnixNano())
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
-	}
-	return string(b)
-}
@@ -3,6 +3,7 @@ package ibmcloud
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
 	"github.com/codeready-toolchain/devcluster/test"
@@ -54,7 +55,7 @@ func (s *TestUserSuite) TestCloudDirectoryUser() {
 			Reply(201).
 			BodyString(cloudDirectoryUserExample)
 
-		user, err := cl.CreateCloudDirectoryUser("myUsername")
+		user, err := cl.CreateCloudDirectoryUser("myUsername", "user@domain.com")
 		require.NoError(t, err)
 		assert.Equal(t, "1029a9cb-7b8a-4d18-ba91-fa4830ae0860", user.ID)
 		assert.Equal(t, "myUsername", user.Username)
@@ -227,6 +228,38 @@ func (s *TestUserSuite) TestIAMUser() {
 	})
 }
 
+const accessPoliciesExample = `
+{
+    "policies": [
+        {
+            "id": "policy-1",
+            "type": "access",
+            "created_at": "2021-09-01T10:20:30Z",
+            "resources": [
+                {
+                    "attributes": [
+                        {"name": "accountId", "operator": "stringEquals", "value": "0123456789"},
+                        {"name": "serviceName", "value": "containers-kubernetes"},
+                        {"name": "serviceInstance", "value": "135790"}
+                    ]
+                }
+            ]
+        },
+        {
+            "id": "policy-2",
+            "type": "access",
+            "resources": [
+                {
+                    "attributes": [
+                        {"name": "accountId", "operator": "stringEquals", "value": "0123456789"},
+                        {"name": "serviceName", "value": "containers-kubernetes"}
+                    ]
+                }
+            ]
+        }
+    ]
+}`
+
 func (s *TestUserSuite) TestAccessPolicy() {
 	cl := newClient(s.T(), s.mockConfig)
 	s.T().Run("Create OK", func(t *testing.T) {
@@ -255,6 +288,31 @@ func (s *TestUserSuite) TestAccessPolicy() {
 		assert.Equal(t, "some-id", id)
 	})
 
+	s.T().Run("List OK", func(t *testing.T) {
+		defer gock.OffAll()
+
+		gock.New("https://iam.cloud.ibm.com").
+			Get("v1/policies").
+			MatchParam("account_id", s.mockConfig.GetIBMCloudAccountID()).
+			MatchParam("type", "access").
+			MatchParam("service_name", "containers-kubernetes").
+			MatchHeader("Authorization", "Bearer "+cl.token.AccessToken).
+			MatchHeader("Accept", "application/json").
+			Persist().
+			Reply(200).
+			BodyString(accessPoliciesExample)
+
+		policies, err := cl.GetAccessPolicies(s.mockConfig.GetIBMCloudAccountID())
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+		assert.Equal(t, "policy-1", policies[0].ID)
+		assert.Equal(t, "135790", policies[0].ServiceInstance())
+		assert.Equal(t, time.Date(2021, 9, 1, 10, 20, 30, 0, time.UTC), policies[0].Created().UTC())
+		assert.Equal(t, "policy-2", policies[1].ID)
+		assert.Empty(t, policies[1].ServiceInstance())
+		assert.True(t, policies[1].Created().IsZero())
+	})
+
 	s.T().Run("Delete OK", func(t *testing.T) {
 		defer gock.OffAll()
 
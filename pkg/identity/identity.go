@@ -0,0 +1,121 @@
+package identity
+
+import (
+	"fmt"
+
+	devclustererr "github.com/codeready-toolchain/devcluster/pkg/errors"
+	"github.com/codeready-toolchain/devcluster/pkg/ibmcloud"
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+
+	"github.com/pkg/errors"
+)
+
+// Supported identity providers
+const (
+	ProviderCloudDirectory = "cloud-directory"
+)
+
+// Configuration represents a partition of the configuration that is used by the identity providers
+type Configuration interface {
+	GetIBMCloudAccountID() string
+	GetIdentityProvider() string
+	GetIdentityEmailDomain() string
+}
+
+// User represents a tenant cluster user in the identity provider
+type User struct {
+	ID         string // username used to log in to the clusters
+	ExternalID string // ID of the user in the identity provider
+	Email      string
+	Password   string
+}
+
+// Provider manages the tenant cluster users and their access to the clusters
+type Provider interface {
+	// CreateUser creates a new user with the given username and generated password.
+	// If the given email is an empty string then it's generated from the username.
+	CreateUser(username, email string) (*User, error)
+	// RotateCredentials sets a new generated password for the user and returns the new password
+	RotateCredentials(user User) (string, error)
+	// GrantAccess grants the user access to the cluster and returns the ID of the access grant
+	GrantAccess(user User, clusterID string) (string, error)
+	// RevokeAccess revokes the access grant with the given ID. Does nothing if the ID is empty.
+	RevokeAccess(accessID string) error
+	// DeleteUser deletes the user from the identity provider
+	DeleteUser(user User) error
+}
+
+// NewProvider returns the configured identity provider
+func NewProvider(config Configuration, client ibmcloud.ICClient) (Provider, error) {
+	switch config.GetIdentityProvider() {
+	case ProviderCloudDirectory:
+		return NewCloudDirectoryProvider(config, client), nil
+	default:
+		return nil, errors.Errorf("unknown identity provider: %s", config.GetIdentityProvider())
+	}
+}
+
+// NewCloudDirectoryProvider returns an identity provider which manages the users in IBM App ID Cloud Directory
+// and grants them access to the clusters via IAM access policies
+func NewCloudDirectoryProvider(config Configuration, client ibmcloud.ICClient) Provider {
+	return &cloudDirectory{
+		config: config,
+		client: client,
+	}
+}
+
+type cloudDirectory struct {
+	config Configuration
+	client ibmcloud.ICClient
+}
+
+func (p *cloudDirectory) CreateUser(username, email string) (*User, error) {
+	if email == "" {
+		email = fmt.Sprintf("%s@%s", username, p.config.GetIdentityEmailDomain())
+	}
+	cdu, err := p.client.CreateCloudDirectoryUser(username, email)
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		ID:         cdu.Username,
+		ExternalID: cdu.ID,
+		Email:      cdu.Email(),
+		Password:   cdu.Password,
+	}, nil
+}
+
+func (p *cloudDirectory) RotateCredentials(user User) (string, error) {
+	cdu, err := p.client.UpdateCloudDirectoryUserPassword(user.ExternalID)
+	if err != nil {
+		return "", err
+	}
+	return cdu.Password, nil
+}
+
+func (p *cloudDirectory) GrantAccess(user User, clusterID string) (string, error) {
+	return p.client.CreateAccessPolicy(p.config.GetIBMCloudAccountID(), user.ID, clusterID)
+}
+
+func (p *cloudDirectory) RevokeAccess(accessID string) error {
+	if accessID == "" {
+		return nil
+	}
+	return p.client.DeleteAccessPolicy(accessID)
+}
+
+// DeleteUser deletes the Cloud Directory user and the corresponding IAM user
+func (p *cloudDirectory) DeleteUser(user User) error {
+	if err := p.client.DeleteCloudDirectoryUser(user.ExternalID); err != nil {
+		return err
+	}
+	iamUser, err := p.client.GetIAMUserByUserID(user.ID)
+	if err != nil {
+		if !devclustererr.IsNotFound(err) {
+			return err
+		}
+		log.Infof(nil, "no IAM user found for user %s", user.ID)
+		return nil
+	}
+	return p.client.DeleteIAMUser(iamUser.ID)
+}
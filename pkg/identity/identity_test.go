@@ -0,0 +1,102 @@
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/identity"
+	"github.com/codeready-toolchain/devcluster/test"
+	ibmcloudmock "github.com/codeready-toolchain/devcluster/test/ibmcloud"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestIdentitySuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunIdentitySuite(t *testing.T) {
+	suite.Run(t, &TestIdentitySuite{test.UnitTestSuite{}})
+}
+
+func (s *TestIdentitySuite) TestNewProvider() {
+	s.T().Run("cloud directory", func(t *testing.T) {
+		p, err := identity.NewProvider(&mockConfig{provider: identity.ProviderCloudDirectory}, ibmcloudmock.NewMockIBMCloudClient())
+		require.NoError(t, err)
+		assert.NotNil(t, p)
+	})
+
+	s.T().Run("unknown", func(t *testing.T) {
+		_, err := identity.NewProvider(&mockConfig{provider: "unknown"}, ibmcloudmock.NewMockIBMCloudClient())
+		require.EqualError(t, err, "unknown identity provider: unknown")
+	})
+}
+
+func (s *TestIdentitySuite) TestCloudDirectory() {
+	client := ibmcloudmock.NewMockIBMCloudClient()
+	p := identity.NewCloudDirectoryProvider(&mockConfig{provider: identity.ProviderCloudDirectory, domain: "example.com"}, client)
+
+	s.T().Run("create user with generated email", func(t *testing.T) {
+		u, err := p.CreateUser("rh-dev-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, "rh-dev-1", u.ID)
+		assert.Equal(t, "rh-dev-1@example.com", u.Email)
+		assert.NotEmpty(t, u.ExternalID)
+		assert.NotEmpty(t, u.Password)
+		assert.True(t, client.CloudDirectoryUserExists(u.ExternalID))
+	})
+
+	s.T().Run("create user with email", func(t *testing.T) {
+		u, err := p.CreateUser("rh-dev-2", "attendee@domain.com")
+		require.NoError(t, err)
+		assert.Equal(t, "attendee@domain.com", u.Email)
+	})
+
+	s.T().Run("rotate credentials", func(t *testing.T) {
+		u, err := p.CreateUser("rh-dev-3", "")
+		require.NoError(t, err)
+		password, err := p.RotateCredentials(*u)
+		require.NoError(t, err)
+		assert.NotEmpty(t, password)
+		assert.NotEqual(t, u.Password, password)
+	})
+
+	s.T().Run("grant and revoke access", func(t *testing.T) {
+		u, err := p.CreateUser("rh-dev-4", "")
+		require.NoError(t, err)
+		accessID, err := p.GrantAccess(*u, "cluster-1")
+		require.NoError(t, err)
+		assert.True(t, client.AccessPolicyExists(accessID))
+
+		require.NoError(t, p.RevokeAccess(accessID))
+		assert.False(t, client.AccessPolicyExists(accessID))
+		require.NoError(t, p.RevokeAccess(""))
+	})
+
+	s.T().Run("delete user", func(t *testing.T) {
+		u, err := p.CreateUser("rh-dev-5", "")
+		require.NoError(t, err)
+		require.NoError(t, p.DeleteUser(*u))
+		assert.False(t, client.CloudDirectoryUserExists(u.ExternalID))
+		_, err = client.GetIAMUserByUserID(u.ID)
+		assert.Error(t, err)
+	})
+}
+
+type mockConfig struct {
+	provider string
+	domain   string
+}
+
+func (c *mockConfig) GetIBMCloudAccountID() string {
+	return "account-id"
+}
+
+func (c *mockConfig) GetIdentityProvider() string {
+	return c.provider
+}
+
+func (c *mockConfig) GetIdentityEmailDomain() string {
+	return c.domain
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/go-logr/logr"
 	sync "github.com/matryer/resync"
 	"github.com/spf13/pflag"
+	uzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -22,6 +24,8 @@ import (
 var (
 	logger *Logger
 	once   sync.Once
+	// level can be changed while running unless another level is set by the options passed to Init
+	level = uzap.NewAtomicLevelAt(zapcore.InfoLevel)
 )
 
 // Logger implements log.Logger
@@ -51,7 +55,7 @@ func Init(withName string, opts ...zap.Opts) {
 		// implementing the logr.Logger interface. This logger will
 		// be propagated through the whole operator, generating
 		// uniform and structured logs.
-		logf.SetLogger(zap.New(opts...))
+		logf.SetLogger(zap.New(append([]zap.Opts{zap.Level(level)}, opts...)...))
 		logger = newLogger(withName)
 	})
 }
@@ -63,6 +67,16 @@ func newLogger(withName string) *Logger {
 	}
 }
 
+// SetLevel changes the log level, e.g. "debug" or "info"
+func SetLevel(name string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
 // Info logs a non-error message.
 func Info(ctx *gin.Context, msg string) {
 	logger.Info(ctx, msg)
@@ -14,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -98,6 +99,22 @@ func TestLog(t *testing.T) {
 		assert.Contains(t, value, `"timestamp":"`)
 	})
 
+	t.Run("set level", func(t *testing.T) {
+		defer func() {
+			require.NoError(t, SetLevel("info"))
+		}()
+		buf.Reset()
+
+		require.NoError(t, SetLevel("error"))
+		Info(nil, "filtered out")
+		Error(nil, errors.New("test error"), "not filtered out")
+		value := buf.String()
+		assert.NotContains(t, value, `"msg":"filtered out"`)
+		assert.Contains(t, value, `"msg":"not filtered out"`)
+
+		assert.Error(t, SetLevel("unknown"))
+	})
+
 	t.Run("log infof with http request", func(t *testing.T) {
 		buf.Reset()
 		rr := httptest.NewRecorder()
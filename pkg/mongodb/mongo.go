@@ -11,6 +11,7 @@ import (
 
 	errs "github.com/pkg/errors"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -79,3 +80,68 @@ func Clusters() *mongo.Collection {
 func Users() *mongo.Collection {
 	return Devcluster().Collection("users")
 }
+
+func DriftReports() *mongo.Collection {
+	return Devcluster().Collection("driftReports")
+}
+
+func Events() *mongo.Collection {
+	return Devcluster().Collection("events")
+}
+
+func Counters() *mongo.Collection {
+	return Devcluster().Collection("counters")
+}
+
+func ArchivedClusterRequests() *mongo.Collection {
+	return Devcluster().Collection("archivedClusterRequests")
+}
+
+func ArchivedClusters() *mongo.Collection {
+	return Devcluster().Collection("archivedClusters")
+}
+
+func Migrations() *mongo.Collection {
+	return Devcluster().Collection("migrations")
+}
+
+func Zones() *mongo.Collection {
+	return Devcluster().Collection("zones")
+}
+
+func Accounts() *mongo.Collection {
+	return Devcluster().Collection("accounts")
+}
+
+// indexes are the indexes of the fields the clusters and the users are looked up by
+var indexes = []struct {
+	collection func() *mongo.Collection
+	fields     []string
+}{
+	{Clusters, []string{"request_id", "status", "name", "account"}},
+	{Users, []string{"cluster_id", "recycled", "account"}},
+	{ArchivedClusters, []string{"request_id"}},
+}
+
+// EnsureIndexes creates the indexes which don't exist yet. Creating an existing index is a no-op.
+func EnsureIndexes(ctx context.Context) error {
+	for _, index := range indexes {
+		models := make([]mongo.IndexModel, 0, len(index.fields))
+		for _, field := range index.fields {
+			models = append(models, mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}})
+		}
+		collection := index.collection()
+		if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+			return errs.Wrapf(err, "unable to create indexes of %s", collection.Name())
+		}
+	}
+	return nil
+}
+
+// Ping checks that the MongoDB server is reachable
+func Ping(ctx context.Context) error {
+	if defaultClient == nil {
+		return errors.New("MongoDB client is not initialized")
+	}
+	return defaultClient.client.Ping(ctx, nil)
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/rest"
+
+	"github.com/pkg/errors"
+)
+
+// Configuration represents a partition of the configuration that is used by the notifier
+type Configuration interface {
+	GetNotificationWebhookURL() string
+}
+
+// Notifier sends notifications about the events which require attention of the service admins
+type Notifier interface {
+	Notify(subject, message string) error
+}
+
+// NewNotifier returns a notifier which posts the notifications to the configured webhook.
+// If no webhook is configured then the returned notifier only logs the notifications.
+func NewNotifier(config Configuration) Notifier {
+	if config.GetNotificationWebhookURL() == "" {
+		return &logNotifier{}
+	}
+	return &webhookNotifier{
+		url:    config.GetNotificationWebhookURL(),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type logNotifier struct {
+}
+
+func (n *logNotifier) Notify(subject, message string) error {
+	log.Info(nil, fmt.Sprintf("NOTIFICATION: %s: %s", subject, message))
+	return nil
+}
+
+// webhookNotifier posts notifications to a Slack compatible incoming webhook
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Text string `json:"text"`
+}
+
+func (n *webhookNotifier) Notify(subject, message string) error {
+	body, err := json.Marshal(webhookPayload{Text: fmt.Sprintf("*%s*\n%s", subject, message)})
+	if err != nil {
+		return err
+	}
+	res, err := n.client.Post(n.url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to send notification")
+	}
+	defer rest.CloseResponse(res)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("unable to send notification. Response status: %s. Response body: %s", res.Status, rest.ReadBody(res.Body))
+	}
+	return nil
+}
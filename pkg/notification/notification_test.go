@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/h2non/gock.v1"
+)
+
+type TestNotificationSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunNotificationSuite(t *testing.T) {
+	suite.Run(t, &TestNotificationSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestNotificationSuite) TestNotify() {
+	s.T().Run("no webhook", func(t *testing.T) {
+		n := NewNotifier(&mockConfig{})
+		assert.IsType(t, &logNotifier{}, n)
+		require.NoError(t, n.Notify("subject", "message"))
+	})
+
+	s.T().Run("webhook OK", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://hooks.example.com").
+			Post("services/12345").
+			MatchHeader("Content-Type", "application/json").
+			JSON(`{"text": "*subject*\nmessage"}`).
+			Reply(200)
+
+		n := NewNotifier(&mockConfig{url: "https://hooks.example.com/services/12345"})
+		require.NoError(t, n.Notify("subject", "message"))
+		assert.True(t, gock.IsDone())
+	})
+
+	s.T().Run("webhook error", func(t *testing.T) {
+		defer gock.OffAll()
+		gock.New("https://hooks.example.com").
+			Post("services/12345").
+			Reply(500).
+			BodyString("oops")
+
+		n := NewNotifier(&mockConfig{url: "https://hooks.example.com/services/12345"})
+		err := n.Notify("subject", "message")
+		require.EqualError(t, err, "unable to send notification. Response status: 500 Internal Server Error. Response body: oops")
+	})
+}
+
+type mockConfig struct {
+	url string
+}
+
+func (c *mockConfig) GetNotificationWebhookURL() string {
+	return c.url
+}
@@ -0,0 +1,178 @@
+package password
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Character classes which can be used in the generated passwords
+const (
+	ClassLower   = "lower"
+	ClassUpper   = "upper"
+	ClassDigits  = "digits"
+	ClassSymbols = "symbols"
+)
+
+var classes = map[string]string{
+	ClassLower:  "abcdefghijklmnopqrstuvwxyz",
+	ClassUpper:  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	ClassDigits: "0123456789",
+	// Quotes and backslash are not used so the password can be safely embedded into JSON
+	ClassSymbols: "!#$%&*+-=?@^_~",
+}
+
+// Defaults used if the policy is not configured
+const (
+	DefaultLength = 12
+)
+
+// DefaultClasses are the character classes used if no classes are configured
+var DefaultClasses = []string{ClassLower, ClassUpper, ClassDigits}
+
+// App ID Cloud Directory password rules
+const (
+	MinLength = 8
+	MaxLength = 64
+)
+
+// passphraseSeparator separates the words of the generated passphrases
+const passphraseSeparator = "-"
+
+// Configuration represents a partition of the configuration that is used by the password generator
+type Configuration interface {
+	GetPasswordLength() int
+	GetPasswordCharacterClasses() []string
+	GetPasswordPassphraseWords() int
+}
+
+// Generator generates passwords according to the configured policy
+type Generator interface {
+	Generate() (string, error)
+}
+
+// NewGenerator returns a generator which generates random passwords of the configured length and character classes.
+// If the number of passphrase words is configured then human friendly passphrases are generated instead,
+// for example "maple-river-orbit-42".
+func NewGenerator(config Configuration) Generator {
+	return &generator{config: config}
+}
+
+type generator struct {
+	config Configuration
+}
+
+// Generate generates a new password and validates it against the App ID rules
+func (g *generator) Generate() (string, error) {
+	var password string
+	var err error
+	if words := g.config.GetPasswordPassphraseWords(); words > 0 {
+		password, err = passphrase(words)
+	} else {
+		password, err = g.random()
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "unable to generate password")
+	}
+	if err := Validate(password); err != nil {
+		return "", errors.Wrap(err, "generated password does not satisfy the password rules")
+	}
+	return password, nil
+}
+
+// random generates a random password which contains at least one character of every configured class
+func (g *generator) random() (string, error) {
+	length := g.config.GetPasswordLength()
+	if length <= 0 {
+		length = DefaultLength
+	}
+	classNames := g.config.GetPasswordCharacterClasses()
+	if len(classNames) == 0 {
+		classNames = DefaultClasses
+	}
+	if length < len(classNames) {
+		return "", errors.Errorf("password length %d is less than the number of character classes %d", length, len(classNames))
+	}
+	var all strings.Builder
+	b := make([]byte, 0, length)
+	for _, name := range classNames {
+		chars, found := classes[name]
+		if !found {
+			return "", errors.Errorf("unknown character class: %s", name)
+		}
+		all.WriteString(chars)
+		c, err := randomChar(chars)
+		if err != nil {
+			return "", err
+		}
+		b = append(b, c)
+	}
+	for len(b) < length {
+		c, err := randomChar(all.String())
+		if err != nil {
+			return "", err
+		}
+		b = append(b, c)
+	}
+	// Shuffle so the mandatory characters are not always at the beginning
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b), nil
+}
+
+// passphrase generates a passphrase of the given number of random words followed by a two digit number
+func passphrase(n int) (string, error) {
+	parts := make([]string, 0, n+1)
+	for i := 0; i < n; i++ {
+		w, err := randomInt(len(words))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, words[w])
+	}
+	num, err := randomInt(90)
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, fmt.Sprintf("%d", 10+num))
+	return strings.Join(parts, passphraseSeparator), nil
+}
+
+// Validate checks the password against the App ID Cloud Directory rules:
+// the length must be between MinLength and MaxLength and only printable ASCII characters except whitespaces, quotes and backslash are allowed.
+func Validate(password string) error {
+	if len(password) < MinLength || len(password) > MaxLength {
+		return errors.Errorf("the password length must be between %d and %d characters", MinLength, MaxLength)
+	}
+	for _, c := range password {
+		if c <= ' ' || c > '~' || c == '"' || c == '\'' || c == '\\' {
+			return errors.Errorf("the password contains not allowed character: %q", c)
+		}
+	}
+	return nil
+}
+
+func randomChar(chars string) (byte, error) {
+	i, err := randomInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// randomInt returns a uniform random int in [0, n) using crypto/rand
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
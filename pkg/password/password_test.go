@@ -0,0 +1,112 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestPasswordSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunPasswordSuite(t *testing.T) {
+	suite.Run(t, &TestPasswordSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestPasswordSuite) TestGenerate() {
+	s.T().Run("defaults", func(t *testing.T) {
+		p, err := NewGenerator(&mockConfig{}).Generate()
+		require.NoError(t, err)
+		assert.Len(t, p, DefaultLength)
+		assertContainsClasses(t, p, DefaultClasses...)
+	})
+
+	s.T().Run("configured length and classes", func(t *testing.T) {
+		g := NewGenerator(&mockConfig{length: 20, classes: []string{ClassLower, ClassSymbols}})
+		for i := 0; i < 100; i++ {
+			p, err := g.Generate()
+			require.NoError(t, err)
+			assert.Len(t, p, 20)
+			assertContainsClasses(t, p, ClassLower, ClassSymbols)
+			assert.False(t, strings.ContainsAny(p, classes[ClassUpper]+classes[ClassDigits]))
+		}
+	})
+
+	s.T().Run("passwords do not repeat", func(t *testing.T) {
+		g := NewGenerator(&mockConfig{})
+		generated := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			p, err := g.Generate()
+			require.NoError(t, err)
+			require.False(t, generated[p], "password repeated: %s", p)
+			generated[p] = true
+		}
+	})
+
+	s.T().Run("passphrase", func(t *testing.T) {
+		p, err := NewGenerator(&mockConfig{passphraseWords: 3}).Generate()
+		require.NoError(t, err)
+		parts := strings.Split(p, passphraseSeparator)
+		require.Len(t, parts, 4)
+		for _, w := range parts[:3] {
+			assert.Contains(t, words, w)
+		}
+		assert.Len(t, parts[3], 2)
+	})
+
+	s.T().Run("too short", func(t *testing.T) {
+		_, err := NewGenerator(&mockConfig{length: 6}).Generate()
+		require.Error(t, err)
+	})
+
+	s.T().Run("too many classes", func(t *testing.T) {
+		_, err := NewGenerator(&mockConfig{length: 2, classes: []string{ClassLower, ClassUpper, ClassDigits}}).Generate()
+		require.Error(t, err)
+	})
+
+	s.T().Run("unknown class", func(t *testing.T) {
+		_, err := NewGenerator(&mockConfig{classes: []string{"emoji"}}).Generate()
+		require.EqualError(t, err, "unable to generate password: unknown character class: emoji")
+	})
+}
+
+func (s *TestPasswordSuite) TestValidate() {
+	assert.NoError(s.T(), Validate("abcd1234"))
+	assert.NoError(s.T(), Validate("maple-river-orbit-42"))
+	assert.Error(s.T(), Validate("abc123"))
+	assert.Error(s.T(), Validate(strings.Repeat("a", MaxLength+1)))
+	assert.Error(s.T(), Validate("abcd 1234"))
+	assert.Error(s.T(), Validate(`abcd"1234`))
+	assert.Error(s.T(), Validate(`abcd\1234`))
+	assert.Error(s.T(), Validate("abcdé1234"))
+}
+
+func assertContainsClasses(t *testing.T, p string, classNames ...string) {
+	for _, name := range classNames {
+		assert.True(t, strings.ContainsAny(p, classes[name]), "password %s does not contain %s characters", p, name)
+	}
+}
+
+type mockConfig struct {
+	length          int
+	classes         []string
+	passphraseWords int
+}
+
+func (c *mockConfig) GetPasswordLength() int {
+	return c.length
+}
+
+func (c *mockConfig) GetPasswordCharacterClasses() []string {
+	return c.classes
+}
+
+func (c *mockConfig) GetPasswordPassphraseWords() int {
+	return c.passphraseWords
+}
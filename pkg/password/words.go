@@ -0,0 +1,31 @@
+package password
+
+// words is the list of the words used in the generated passphrases
+var words = []string{
+	"acorn", "admiral", "agent", "alpine", "amber", "anchor", "apple", "arctic", "arrow", "atlas",
+	"autumn", "badge", "bagel", "bamboo", "banjo", "basil", "beacon", "beaver", "bison", "blossom",
+	"bluff", "bonsai", "breeze", "brick", "bridge", "brook", "bubble", "buffalo", "butter", "cabin",
+	"cactus", "camel", "canal", "candle", "canyon", "carbon", "cargo", "castle", "cedar", "cello",
+	"cherry", "chess", "cider", "cinder", "citrus", "clover", "cobalt", "coffee", "comet", "copper",
+	"coral", "cosmos", "cotton", "cougar", "crane", "crater", "cricket", "crystal", "cypress",
+	"dahlia", "daisy", "delta", "denim", "desert", "dingo", "dolphin", "dragon", "drift", "eagle",
+	"echo", "eclipse", "ember", "emerald", "engine", "falcon", "fennel", "fern", "fiddle", "finch",
+	"fjord", "flame", "flint", "forest", "fossil", "fox", "galaxy", "garden", "garnet", "gecko",
+	"geyser", "ginger", "glacier", "globe", "granite", "grape", "gravel", "harbor", "harvest",
+	"hazel", "heron", "hickory", "honey", "horizon", "husky", "iceberg", "igloo", "indigo", "island",
+	"ivory", "jacket", "jaguar", "jasmine", "jelly", "jigsaw", "jungle", "kayak", "kelp", "kernel",
+	"kettle", "kiwi", "koala", "lagoon", "lantern", "lava", "lemon", "lilac", "linen", "lizard",
+	"llama", "lobster", "lotus", "lunar", "magnet", "mango", "maple", "marble", "meadow", "melon",
+	"meteor", "mint", "mirror", "mosaic", "moss", "mountain", "mustard", "nectar", "needle", "nickel",
+	"nova", "nugget", "oasis", "ocean", "olive", "onyx", "opal", "orbit", "orchid", "otter", "oyster",
+	"paddle", "palm", "panda", "papaya", "parrot", "pebble", "pelican", "pepper", "piano", "pilot",
+	"pine", "pixel", "planet", "plum", "polar", "pond", "poppy", "prairie", "puffin", "pumpkin",
+	"quartz", "quill", "rabbit", "radar", "rain", "raven", "reef", "ribbon", "river", "robin",
+	"rocket", "saffron", "sage", "salmon", "sapphire", "satellite", "scarlet", "seal", "sequoia",
+	"shadow", "shell", "sierra", "silver", "sketch", "sky", "sloth", "spark", "sparrow", "spruce",
+	"squid", "stone", "storm", "summit", "sunset", "swan", "tango", "teal", "thistle", "thunder",
+	"tiger", "timber", "topaz", "tornado", "trail", "tulip", "tundra", "turtle", "umber", "valley",
+	"velvet", "violet", "volcano", "walnut", "walrus", "wave", "willow", "window", "winter", "wizard",
+	"wombat", "yarrow", "yeti", "zebra", "zenith", "zephyr", "acacia", "bayou", "blizzard", "boulder",
+	"breaker", "caravan", "cascade", "chimney", "cobble", "dune",
+}
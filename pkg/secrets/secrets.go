@@ -0,0 +1,236 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/log"
+	"github.com/codeready-toolchain/devcluster/pkg/rest"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of the secrets providers
+const (
+	ProviderNone  = ""
+	ProviderFile  = "file"
+	ProviderVault = "vault"
+)
+
+// Configuration represents a partition of the configuration that is used by the secrets providers
+type Configuration interface {
+	GetSecretsProvider() string
+	GetSecretsDir() string
+	GetSecretsVaultAddress() string
+	GetSecretsVaultToken() string
+	GetSecretsVaultPath() string
+	GetSecretsRefreshIntervalSec() int
+}
+
+// Provider provides the secrets by their names, e.g. "ibmcloud.apikey".
+// The secrets are refreshed in the background so the rotated secrets are returned without restarting the service.
+type Provider interface {
+	// Get returns the current value of the secret and true if the secret is found
+	Get(name string) (string, bool)
+	// Close stops refreshing the secrets
+	Close()
+}
+
+// NewProvider returns the configured secrets provider.
+// If no provider is configured then the returned provider has no secrets and the secrets are read from the configuration.
+func NewProvider(config Configuration) (Provider, error) {
+	interval := time.Duration(config.GetSecretsRefreshIntervalSec()) * time.Second
+	switch config.GetSecretsProvider() {
+	case ProviderNone:
+		return &noProvider{}, nil
+	case ProviderFile:
+		return NewFileProvider(config.GetSecretsDir(), interval)
+	case ProviderVault:
+		return NewVaultProvider(config.GetSecretsVaultAddress(), config.GetSecretsVaultToken(), config.GetSecretsVaultPath(), interval)
+	default:
+		return nil, errors.Errorf("unknown secrets provider: %s", config.GetSecretsProvider())
+	}
+}
+
+type noProvider struct {
+}
+
+func (p *noProvider) Get(string) (string, bool) {
+	return "", false
+}
+
+func (p *noProvider) Close() {
+}
+
+// refreshingProvider keeps the secrets loaded by the load function and reloads them every interval
+type refreshingProvider struct {
+	mux     sync.RWMutex
+	secrets map[string]string
+	load    func() (map[string]string, error)
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// newRefreshingProvider loads the secrets and starts reloading them every interval if the interval is positive.
+// Returns an error if the secrets can't be loaded. The reload failures are logged and the last loaded secrets are kept.
+func newRefreshingProvider(load func() (map[string]string, error), interval time.Duration) (*refreshingProvider, error) {
+	p := &refreshingProvider{
+		load: load,
+		stop: make(chan struct{}),
+	}
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-p.stop:
+					return
+				case <-ticker.C:
+					if err := p.refresh(); err != nil {
+						log.Error(nil, err, "unable to refresh secrets")
+					}
+				}
+			}
+		}()
+	}
+	return p, nil
+}
+
+func (p *refreshingProvider) refresh() error {
+	secrets, err := p.load()
+	if err != nil {
+		return err
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.secrets != nil {
+		for name, value := range secrets {
+			if old, found := p.secrets[name]; found && old != value {
+				log.Infof(nil, "secret %s rotated", name)
+			}
+		}
+	}
+	p.secrets = secrets
+	return nil
+}
+
+func (p *refreshingProvider) Get(name string) (string, bool) {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	value, found := p.secrets[name]
+	return value, found
+}
+
+func (p *refreshingProvider) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+}
+
+// NewFileProvider returns the provider reading the secrets from the files in the directory, one secret per file
+// named after the secret, as mounted from a Kubernetes secret. The files are re-read every interval.
+func NewFileProvider(dir string, interval time.Duration) (Provider, error) {
+	if dir == "" {
+		return nil, errors.New("no secrets directory configured")
+	}
+	return newRefreshingProvider(func() (map[string]string, error) {
+		return readSecretFiles(dir)
+	}, interval)
+}
+
+// readSecretFiles reads all the files in the directory. The hidden files and directories are skipped,
+// e.g. the "..data" directory the Kubernetes secret files are linked to.
+func readSecretFiles(dir string) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read secrets directory %s", dir)
+	}
+	secrets := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		// Follow the symlinks
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read secret %s", e.Name())
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read secret %s", e.Name())
+		}
+		secrets[e.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+	return secrets, nil
+}
+
+// NewVaultProvider returns the provider reading the secrets from the path of a Vault compatible key/value secrets engine.
+// Both the KV version 1 and version 2 responses are supported. The secrets are re-read every interval.
+func NewVaultProvider(address, token, path string, interval time.Duration) (Provider, error) {
+	if address == "" || path == "" {
+		return nil, errors.New("no Vault address or secrets path configured")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(address, "/"), strings.TrimPrefix(path, "/"))
+	return newRefreshingProvider(func() (map[string]string, error) {
+		return readVaultSecrets(client, url, token)
+	}, interval)
+}
+
+// vaultResponse is the response of the Vault read secret API.
+// The secrets are in data.data for the KV version 2 and in data for the KV version 1.
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+func readVaultSecrets(client *http.Client, url, token string) (map[string]string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read secrets from Vault")
+	}
+	defer rest.CloseResponse(res)
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to read secrets from Vault. Response status: %s. Response body: %s", res.Status, rest.ReadBody(res.Body))
+	}
+	var body vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "unable to decode secrets from Vault")
+	}
+	data := body.Data
+	if _, found := data["metadata"]; found {
+		// KV version 2 with the "data" and "metadata" fields
+		data = nil
+		if err := json.Unmarshal(body.Data["data"], &data); err != nil {
+			return nil, errors.Wrap(err, "unable to decode secrets from Vault")
+		}
+	}
+	secrets := make(map[string]string, len(data))
+	for name, raw := range data {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, errors.Errorf("secret %s from Vault is not a string", name)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
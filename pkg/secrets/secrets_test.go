@@ -0,0 +1,162 @@
+package secrets_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/devcluster/pkg/secrets"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestSecretsSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunSecretsSuite(t *testing.T) {
+	suite.Run(t, &TestSecretsSuite{test.UnitTestSuite{}})
+}
+
+func (s *TestSecretsSuite) TestNoProvider() {
+	p, err := secrets.NewProvider(&mockConfig{})
+	require.NoError(s.T(), err)
+	_, found := p.Get("ibmcloud.apikey")
+	assert.False(s.T(), found)
+	p.Close()
+
+	_, err = secrets.NewProvider(&mockConfig{provider: "unknown"})
+	assert.Error(s.T(), err)
+}
+
+func (s *TestSecretsSuite) TestFileProvider() {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	writeSecret := func(name, value string) {
+		require.NoError(s.T(), ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0600))
+	}
+	writeSecret("ibmcloud.apikey", "key-1\n")
+	writeSecret(".hidden", "hidden")
+	require.NoError(s.T(), os.Mkdir(filepath.Join(dir, "..data"), 0700))
+
+	p, err := secrets.NewProvider(&mockConfig{provider: secrets.ProviderFile, dir: dir, interval: 1})
+	require.NoError(s.T(), err)
+	defer p.Close()
+
+	value, found := p.Get("ibmcloud.apikey")
+	assert.True(s.T(), found)
+	assert.Equal(s.T(), "key-1", value)
+	_, found = p.Get(".hidden")
+	assert.False(s.T(), found)
+
+	s.Run("rotated", func() {
+		writeSecret("ibmcloud.apikey", "key-2")
+		assert.Eventually(s.T(), func() bool {
+			value, _ := p.Get("ibmcloud.apikey")
+			return value == "key-2"
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	s.Run("missing directory", func() {
+		_, err := secrets.NewFileProvider(filepath.Join(dir, "missing"), 0)
+		assert.Error(s.T(), err)
+		_, err = secrets.NewFileProvider("", 0)
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *TestSecretsSuite) TestVaultProvider() {
+	// Local stand-in for the Vault KV secrets engine
+	var mux sync.Mutex
+	apiKey := "key-1"
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.URL.Path {
+		case "/v1/secret/data/devcluster":
+			fmt.Fprintf(w, `{"data": {"data": {"ibmcloud.apikey": "%s"}, "metadata": {"version": 1}}}`, apiKey)
+		case "/v1/kv/devcluster":
+			fmt.Fprintf(w, `{"data": {"ibmcloud.apikey": "%s"}}`, apiKey)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	s.Run("kv version 2", func() {
+		p, err := secrets.NewProvider(&mockConfig{provider: secrets.ProviderVault, address: vault.URL, token: "token", path: "secret/data/devcluster", interval: 1})
+		require.NoError(s.T(), err)
+		defer p.Close()
+		value, found := p.Get("ibmcloud.apikey")
+		assert.True(s.T(), found)
+		assert.Equal(s.T(), "key-1", value)
+
+		mux.Lock()
+		apiKey = "key-2"
+		mux.Unlock()
+		assert.Eventually(s.T(), func() bool {
+			value, _ := p.Get("ibmcloud.apikey")
+			return value == "key-2"
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	s.Run("kv version 1", func() {
+		p, err := secrets.NewVaultProvider(vault.URL+"/", "token", "/kv/devcluster", 0)
+		require.NoError(s.T(), err)
+		defer p.Close()
+		_, found := p.Get("ibmcloud.apikey")
+		assert.True(s.T(), found)
+	})
+
+	s.Run("invalid token", func() {
+		_, err := secrets.NewVaultProvider(vault.URL, "wrong", "secret/data/devcluster", 0)
+		assert.Error(s.T(), err)
+	})
+}
+
+type mockConfig struct {
+	provider string
+	dir      string
+	address  string
+	token    string
+	path     string
+	interval int
+}
+
+func (c *mockConfig) GetSecretsProvider() string {
+	return c.provider
+}
+
+func (c *mockConfig) GetSecretsDir() string {
+	return c.dir
+}
+
+func (c *mockConfig) GetSecretsVaultAddress() string {
+	return c.address
+}
+
+func (c *mockConfig) GetSecretsVaultToken() string {
+	return c.token
+}
+
+func (c *mockConfig) GetSecretsVaultPath() string {
+	return c.path
+}
+
+func (c *mockConfig) GetSecretsRefreshIntervalSec() int {
+	return c.interval
+}
@@ -66,6 +66,8 @@ func (srv *DevClusterServer) SetupRoutes() error {
 		authConfigCtrl := controller.NewAuthConfig(srv.Config())
 
 		clusterReqCtrl := controller.NewClusterRequest(srv.Config())
+		configCtrl := controller.NewConfig(srv.Config())
+		zonesCtrl := controller.NewZones(srv.Config())
 
 		// create the auth middleware
 		var authMiddleware *middleware.JWTMiddleware
@@ -78,20 +80,45 @@ func (srv *DevClusterServer) SetupRoutes() error {
 		// unsecured routes
 		unsecuredV1 := srv.router.Group("/api/v1")
 		unsecuredV1.GET("/health", healthCheckCtrl.GetHandler)
+		unsecuredV1.GET("/health/live", healthCheckCtrl.GetHandler)
+		unsecuredV1.GET("/health/ready", healthCheckCtrl.GetReadinessHandler)
 		unsecuredV1.GET("/authconfig", authConfigCtrl.GetHandler)
 
 		// secured routes
 		securedV1 := srv.router.Group("/api/v1")
 		securedV1.Use(authMiddleware.HandlerFunc())
 		securedV1.POST("/cluster-req", clusterReqCtrl.PostHandler)
+		// The list endpoints accept ?status=&requested_by=&zone=&name_prefix=&created_from=&created_to=&sort=&order=asc|desc&limit=<n>&cursor=<X-Next-Cursor>
+		// and return the X-Total-Count and X-Next-Cursor headers
 		securedV1.GET("/cluster-reqs", clusterReqCtrl.GetHandler)
 		securedV1.GET("/clusters", clusterReqCtrl.GetHandlerClusters)
+		securedV1.GET("/archive", clusterReqCtrl.GetHandlerArchive) // the archived requests with their clusters
 		securedV1.GET("/cluster-req/:id", clusterReqCtrl.GetHandlerClusterReq)
+		securedV1.GET("/cluster-req/:id/events", clusterReqCtrl.GetHandlerClusterReqEvents)
+		securedV1.GET("/cluster-req/:id/export", clusterReqCtrl.GetHandlerExport) // GET /cluster-req/:id/export?format=csv|json|pdf&header=<text>
 		securedV1.GET("/zones", clusterReqCtrl.GetHandlerZones)
+		securedV1.GET("/url-templates", clusterReqCtrl.GetHandlerURLTemplates)
+		securedV1.GET("/cluster/:id/events", clusterReqCtrl.GetHandlerClusterEvents)
 		securedV1.DELETE("/cluster/:id", clusterReqCtrl.DeleteHandlerCluster)
 		securedV1.DELETE("/clusters", clusterReqCtrl.DeleteHandlerClusters) // DELETE /clusters?ids=<id1>,<id2>,<id3>...
 		securedV1.POST("/users", clusterReqCtrl.PostUsersHandler)
 		securedV1.GET("/users", clusterReqCtrl.GetUsersHandler)
+		securedV1.GET("/reports/usage", clusterReqCtrl.GetHandlerUsageReport) // GET /reports/usage?group_by=requested_by,zone,month&from=<YYYY-MM>&to=<YYYY-MM>&format=json|csv
+		// The user pool management, the reconciliation and the cluster recovery; admins only
+		securedV1.DELETE("/users", clusterReqCtrl.DeleteUsersHandler)            // DELETE /users?ids=<id1>,<id2>,<id3>...
+		securedV1.POST("/users/disable", clusterReqCtrl.PostDisableUsersHandler) // POST /users/disable?ids=<id1>,<id2>...
+		securedV1.POST("/users/enable", clusterReqCtrl.PostEnableUsersHandler)   // POST /users/enable?ids=<id1>,<id2>...
+		securedV1.POST("/users/recycle", clusterReqCtrl.PostRecycleUsersHandler) // POST /users/recycle?ids=<id1>,<id2>...
+		securedV1.GET("/drift-reports", clusterReqCtrl.GetDriftReportsHandler)   // GET /drift-reports?limit=<n>
+		securedV1.POST("/reconcile", clusterReqCtrl.PostReconcileHandler)
+		securedV1.POST("/cluster/:id/retry-delete", clusterReqCtrl.PostRetryDeleteHandler)
+		securedV1.POST("/cluster/:id/replace", clusterReqCtrl.PostReplaceHandler)
+		securedV1.POST("/cluster/:id/bootstrap", clusterReqCtrl.PostBootstrapHandler)
+		securedV1.GET("/config", configCtrl.GetHandler) // the effective configuration with the secrets redacted; admins only
+		// The zones the clusters can be requested in; admins only
+		securedV1.GET("/admin/zones", zonesCtrl.GetHandler)
+		securedV1.PUT("/admin/zones/:id", zonesCtrl.PutHandler) // PUT /admin/zones/:id {"enabled": true, "max_clusters": <n>, "default": false}
+		securedV1.DELETE("/admin/zones/:id", zonesCtrl.DeleteHandler)
 
 		// if we are in testing mode, we also add a secured health route for testing
 		if srv.Config().IsTestingMode() {
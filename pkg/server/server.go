@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/controller"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
@@ -25,10 +26,10 @@ type DevClusterServer struct {
 
 // New creates a new DevClusterServer object with reasonable defaults.
 func New(config *configuration.Config) *DevClusterServer {
-	// Disable logging for the /api/v1/health endpoint so that our logs aren't overwhelmed
+	// Disable logging for the /api/v1/health endpoints so that our logs aren't overwhelmed
 	ginRouter := gin.New()
 	ginRouter.Use(
-		gin.LoggerWithWriter(gin.DefaultWriter, "/api/v1/health"),
+		gin.LoggerWithWriter(gin.DefaultWriter, "/api/v1/health", "/api/v1/health/live", "/api/v1/health/ready"),
 		gin.Recovery(),
 	)
 	srv := &DevClusterServer{
@@ -54,7 +55,7 @@ func New(config *configuration.Config) *DevClusterServer {
 		//AllowOrigins:  []string{"https://foo.com"},
 		AllowMethods:     []string{"PUT", "PATCH", "POST", "GET", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin, Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", controller.TotalCountHeader, controller.NextCursorHeader},
 		AllowCredentials: true,
 		//AllowOriginFunc: func(origin string) bool {
 		//	return origin == "https://github.com"
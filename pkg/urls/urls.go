@@ -0,0 +1,157 @@
+// Package urls renders the URLs of the clusters and of the cluster users from the configured Go templates,
+// for example the console URL or the URL of the workshop guide.
+package urls
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Scopes of the URL templates
+const (
+	// ScopeCluster templates are rendered once per cluster
+	ScopeCluster = "cluster"
+	// ScopeUser templates are rendered for every user assigned to the cluster
+	ScopeUser = "user"
+)
+
+// Configuration represents a partition of the configuration that is used by the URL templates
+type Configuration interface {
+	GetURLTemplates() string
+	GetURLDefaultTemplates() []string
+}
+
+// Template is a named Go template of a URL
+type Template struct {
+	Name     string `json:"name"`
+	Scope    string `json:"scope,omitempty"` // "cluster" if not set
+	Template string `json:"template"`
+	tmpl     *template.Template
+}
+
+// URL is a URL rendered from the template with the given name
+type URL struct {
+	Name string
+	URL  string
+}
+
+// Set is an ordered set of parsed URL templates
+type Set struct {
+	templates []Template
+}
+
+// Parse parses the templates from the given JSON array
+func Parse(raw string) (*Set, error) {
+	templates := make([]Template, 0)
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &templates); err != nil {
+			return nil, errors.Wrap(err, "unable to parse URL templates")
+		}
+	}
+	names := make(map[string]bool)
+	for i, t := range templates {
+		if t.Name == "" {
+			return nil, errors.Errorf("URL template #%d has no name", i+1)
+		}
+		if names[t.Name] {
+			return nil, errors.Errorf("duplicate URL template %s", t.Name)
+		}
+		names[t.Name] = true
+		if t.Scope == "" {
+			templates[i].Scope = ScopeCluster
+		} else if t.Scope != ScopeCluster && t.Scope != ScopeUser {
+			return nil, errors.Errorf("URL template %s has unknown scope: %s", t.Name, t.Scope)
+		}
+		tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Template)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse URL template %s", t.Name)
+		}
+		templates[i].tmpl = tmpl
+	}
+	return &Set{templates: templates}, nil
+}
+
+// Templates returns the templates of the set
+func (s *Set) Templates() []Template {
+	return s.templates
+}
+
+// Validate returns an error if there is no template with any of the given names in the set
+func (s *Set) Validate(names []string) error {
+	for _, name := range names {
+		if s.find(name) == nil {
+			return errors.Errorf("unknown URL template: %s", name)
+		}
+	}
+	return nil
+}
+
+// Select returns a set with the templates with the given names keeping the order of the templates.
+// Returns the whole set if no names are given.
+func (s *Set) Select(names []string) *Set {
+	if len(names) == 0 {
+		return s
+	}
+	selected := make(map[string]bool)
+	for _, name := range names {
+		selected[name] = true
+	}
+	templates := make([]Template, 0, len(names))
+	for _, t := range s.templates {
+		if selected[t.Name] {
+			templates = append(templates, t)
+		}
+	}
+	return &Set{templates: templates}
+}
+
+// Render renders the templates with the given scope using the given data.
+// The templates rendered to an empty string are omitted.
+func (s *Set) Render(scope string, data interface{}) ([]URL, error) {
+	result := make([]URL, 0, len(s.templates))
+	for _, t := range s.templates {
+		if t.Scope != scope {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := t.tmpl.Execute(buf, data); err != nil {
+			return nil, errors.Wrapf(err, "unable to render URL template %s", t.Name)
+		}
+		if u := strings.TrimSpace(buf.String()); u != "" {
+			result = append(result, URL{Name: t.Name, URL: u})
+		}
+	}
+	return result, nil
+}
+
+func (s *Set) find(name string) *Template {
+	for i := range s.templates {
+		if s.templates[i].Name == name {
+			return &s.templates[i]
+		}
+	}
+	return nil
+}
+
+// Get returns the URL with the given name or an empty string if there is no such URL
+func Get(urls []URL, name string) string {
+	for _, u := range urls {
+		if u.Name == name {
+			return u.URL
+		}
+	}
+	return ""
+}
+
+// ToMap returns the URLs as a map of the template names to the URLs
+func ToMap(urls []URL) map[string]string {
+	m := make(map[string]string, len(urls))
+	for _, u := range urls {
+		m[u.Name] = u.URL
+	}
+	return m
+}
@@ -0,0 +1,141 @@
+package urls_test
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/codeready-toolchain/devcluster/pkg/configuration"
+	"github.com/codeready-toolchain/devcluster/pkg/urls"
+	"github.com/codeready-toolchain/devcluster/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestURLsSuite struct {
+	test.UnitTestSuite
+}
+
+func TestRunURLsSuite(t *testing.T) {
+	suite.Run(t, &TestURLsSuite{test.UnitTestSuite{}})
+}
+
+type cluster struct {
+	ID       string
+	Hostname string
+}
+
+type user struct {
+	ID       string
+	Password string
+	Project  string
+}
+
+type data struct {
+	Cluster cluster
+	User    user
+	URLs    map[string]string
+	IDPName string
+}
+
+func (s *TestURLsSuite) TestParse() {
+	s.T().Run("valid templates", func(t *testing.T) {
+		set, err := urls.Parse(`[{"name": "console", "template": "https://console"}, {"name": "guide", "scope": "user", "template": "https://guide"}]`)
+		require.NoError(t, err)
+		require.Len(t, set.Templates(), 2)
+		assert.Equal(t, urls.ScopeCluster, set.Templates()[0].Scope)
+		assert.Equal(t, urls.ScopeUser, set.Templates()[1].Scope)
+	})
+
+	s.T().Run("no templates", func(t *testing.T) {
+		set, err := urls.Parse("")
+		require.NoError(t, err)
+		assert.Empty(t, set.Templates())
+	})
+
+	s.T().Run("invalid templates", func(t *testing.T) {
+		for raw, msg := range map[string]string{
+			`{"name": "console"}`:                                                              "unable to parse URL templates",
+			`[{"template": "https://console"}]`:                                                "URL template #1 has no name",
+			`[{"name": "a", "template": "{{ .Cluster"}]`:                                       "unable to parse URL template a",
+			`[{"name": "a", "scope": "team", "template": "https://a"}]`:                        "URL template a has unknown scope: team",
+			`[{"name": "a", "template": "https://a"}, {"name": "a", "template": "https://b"}]`: "duplicate URL template a",
+		} {
+			_, err := urls.Parse(raw)
+			require.Error(t, err, raw)
+			assert.Contains(t, err.Error(), msg)
+		}
+	})
+}
+
+func (s *TestURLsSuite) TestSelectAndRender() {
+	set, err := urls.Parse(`[
+		{"name": "console", "template": "{{ if .Cluster.Hostname }}https://console.{{ .Cluster.Hostname }}{{ end }}"},
+		{"name": "api", "template": "https://api/{{ .Cluster.ID }}"},
+		{"name": "guide", "scope": "user", "template": "https://guide/{{ .User.ID }}"},
+		{"name": "broken", "scope": "user", "template": "https://guide/{{ .User.Unknown }}"}
+	]`)
+	require.NoError(s.T(), err)
+
+	s.T().Run("validate", func(t *testing.T) {
+		require.NoError(t, set.Validate([]string{"console", "guide"}))
+		require.EqualError(t, set.Validate([]string{"console", "unknown"}), "unknown URL template: unknown")
+	})
+
+	s.T().Run("render all cluster templates", func(t *testing.T) {
+		rendered, err := set.Render(urls.ScopeCluster, data{Cluster: cluster{ID: "c1", Hostname: "c1.example.com"}})
+		require.NoError(t, err)
+		assert.Equal(t, []urls.URL{{Name: "console", URL: "https://console.c1.example.com"}, {Name: "api", URL: "https://api/c1"}}, rendered)
+		assert.Equal(t, "https://api/c1", urls.Get(rendered, "api"))
+		assert.Empty(t, urls.Get(rendered, "guide"))
+		assert.Equal(t, map[string]string{"console": "https://console.c1.example.com", "api": "https://api/c1"}, urls.ToMap(rendered))
+	})
+
+	s.T().Run("empty URLs are omitted", func(t *testing.T) {
+		rendered, err := set.Render(urls.ScopeCluster, data{Cluster: cluster{ID: "c1"}})
+		require.NoError(t, err)
+		assert.Equal(t, []urls.URL{{Name: "api", URL: "https://api/c1"}}, rendered)
+	})
+
+	s.T().Run("render selected user templates", func(t *testing.T) {
+		rendered, err := set.Select([]string{"api", "guide"}).Render(urls.ScopeUser, data{User: user{ID: "rh-dev-1"}})
+		require.NoError(t, err)
+		assert.Equal(t, []urls.URL{{Name: "guide", URL: "https://guide/rh-dev-1"}}, rendered)
+	})
+
+	s.T().Run("render fails", func(t *testing.T) {
+		_, err := set.Render(urls.ScopeUser, data{User: user{ID: "rh-dev-1"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to render URL template broken")
+	})
+}
+
+func (s *TestURLsSuite) TestDefaultTemplates() {
+	set, err := urls.Parse(configuration.DefaultURLTemplates)
+	require.NoError(s.T(), err)
+	d := data{
+		Cluster: cluster{ID: "c1", Hostname: "c1.example.com"},
+		IDPName: "devcluster",
+	}
+	clusterURLs, err := set.Render(urls.ScopeCluster, d)
+	require.NoError(s.T(), err)
+
+	dashboard := url.QueryEscape("https://cloud.ibm.com/kubernetes/clusters/c1/overview")
+	redirect := url.QueryEscape("https://cloud.ibm.com/login/callback")
+	loginURL := fmt.Sprintf("https://iam.cloud.ibm.com/identity/devcluster/authorize?client_id=HOP55v1CCT&response_type=code&state=%s&redirect_uri=%s", dashboard, redirect)
+	assert.Equal(s.T(), []urls.URL{
+		{Name: "identity-provider", URL: "https://cloud.ibm.com/authorize/devcluster"},
+		{Name: "login", URL: loginURL},
+		{Name: "console", URL: "https://console-openshift-console.c1.example.com"},
+	}, clusterURLs)
+
+	d.URLs = urls.ToMap(clusterURLs)
+	d.User = user{ID: "rh-dev-1", Password: "secret", Project: "workshop"}
+	userURLs, err := set.Render(urls.ScopeUser, d)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []urls.URL{
+		{Name: "workshop", URL: fmt.Sprintf("https://redhat-scholars.github.io/openshift-starter-guides/rhs-openshift-starter-guides/4.8/index.html?CLUSTER_SUBDOMAIN=c1.example.com&USERNAME=rh-dev-1&PASSWORD=secret&LOGIN=%s&PROJECT=workshop", url.QueryEscape(loginURL))},
+	}, userURLs)
+}
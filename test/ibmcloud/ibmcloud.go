@@ -20,7 +20,10 @@ type MockIBMCloudClient struct {
 	clustersByName map[string]*ibmcloud.Cluster
 	cldUserByID    map[string]*ibmcloud.CloudDirectoryUser
 	aimUserByID    map[string]*ibmcloud.IAMUser
-	policyByID     map[string]*string
+	policyByID     map[string]*ibmcloud.AccessPolicy
+	deleteErrByID  map[string]error
+	tokenMux       sync.RWMutex
+	tokenErr       error
 }
 
 func NewMockIBMCloudClient() *MockIBMCloudClient {
@@ -29,7 +32,8 @@ func NewMockIBMCloudClient() *MockIBMCloudClient {
 		clustersByID:   make(map[string]*ibmcloud.Cluster),
 		cldUserByID:    make(map[string]*ibmcloud.CloudDirectoryUser),
 		aimUserByID:    make(map[string]*ibmcloud.IAMUser),
-		policyByID:     make(map[string]*string),
+		policyByID:     make(map[string]*ibmcloud.AccessPolicy),
+		deleteErrByID:  make(map[string]error),
 	}
 }
 
@@ -88,9 +92,24 @@ func (c *MockIBMCloudClient) GetCluster(id string) (*ibmcloud.Cluster, error) {
 	return c.clustersByID[id], nil
 }
 
+func (c *MockIBMCloudClient) GetClusters() ([]ibmcloud.Cluster, error) {
+	defer c.clusterMux.RUnlock()
+	c.clusterMux.RLock()
+	clusters := make([]ibmcloud.Cluster, 0, len(c.clustersByID))
+	for _, clst := range c.clustersByID {
+		if clst != nil {
+			clusters = append(clusters, *clst)
+		}
+	}
+	return clusters, nil
+}
+
 func (c *MockIBMCloudClient) DeleteCluster(id string) error {
 	defer c.clusterMux.Unlock()
 	c.clusterMux.Lock()
+	if err := c.deleteErrByID[id]; err != nil {
+		return err
+	}
 	cluster := c.clustersByID[id]
 	if cluster != nil {
 		c.clustersByID[id] = nil
@@ -101,6 +120,14 @@ func (c *MockIBMCloudClient) DeleteCluster(id string) error {
 	return nil
 }
 
+// SetDeleteClusterError makes all the following attempts to delete the cluster fail with the given error.
+// Pass nil to make the deletion succeed again.
+func (c *MockIBMCloudClient) SetDeleteClusterError(id string, err error) {
+	defer c.clusterMux.Unlock()
+	c.clusterMux.Lock()
+	c.deleteErrByID[id] = err
+}
+
 func (c *MockIBMCloudClient) UpdateCluster(cluster ibmcloud.Cluster) error {
 	defer c.clusterMux.Unlock()
 	c.clusterMux.Lock()
@@ -114,13 +141,16 @@ func (c *MockIBMCloudClient) UpdateCluster(cluster ibmcloud.Cluster) error {
 	return nil
 }
 
-func (c *MockIBMCloudClient) CreateCloudDirectoryUser(username string) (*ibmcloud.CloudDirectoryUser, error) {
+func (c *MockIBMCloudClient) CreateCloudDirectoryUser(username, email string) (*ibmcloud.CloudDirectoryUser, error) {
 	defer c.cldUserMux.Unlock()
 	c.cldUserMux.Lock()
+	if email == "" {
+		email = uuid.NewV4().String()
+	}
 	user := &ibmcloud.CloudDirectoryUser{
 		ID:        uuid.NewV4().String(),
 		Username:  username,
-		Emails:    []ibmcloud.Value{{uuid.NewV4().String()}},
+		Emails:    []ibmcloud.Value{{email}},
 		ProfileID: uuid.NewV4().String(),
 		Password:  uuid.NewV4().String(),
 	}
@@ -151,19 +181,76 @@ func (c *MockIBMCloudClient) GetIAMUserByUserID(userID string) (*ibmcloud.IAMUse
 	c.cldUserMux.RLock()
 	found := c.aimUserByID[userID]
 	if found == nil {
-		return nil, errors.New("user not found")
+		return nil, devclustererr.NewNotFoundError(fmt.Sprintf("IAM user with user_id=%s not found", userID), "")
 	}
 	return found, nil
 }
 
-func (c *MockIBMCloudClient) CreateAccessPolicy(_, _, _ string) (string, error) {
+func (c *MockIBMCloudClient) DeleteCloudDirectoryUser(id string) error {
+	defer c.cldUserMux.Unlock()
+	c.cldUserMux.Lock()
+	if c.cldUserByID[id] == nil {
+		return errors.New("user not found")
+	}
+	delete(c.cldUserByID, id)
+	return nil
+}
+
+func (c *MockIBMCloudClient) DeleteIAMUser(id string) error {
+	defer c.cldUserMux.Unlock()
+	c.cldUserMux.Lock()
+	for userID, u := range c.aimUserByID {
+		if u.ID == id {
+			delete(c.aimUserByID, userID)
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+// CloudDirectoryUserExists returns true if the cloud directory user with the given ID exists
+func (c *MockIBMCloudClient) CloudDirectoryUserExists(id string) bool {
+	defer c.cldUserMux.RUnlock()
+	c.cldUserMux.RLock()
+	return c.cldUserByID[id] != nil
+}
+
+func (c *MockIBMCloudClient) CreateAccessPolicy(accountID, _, clusterID string) (string, error) {
+	return c.CreatePolicyWithCreationTime(accountID, clusterID, time.Now())
+}
+
+// CreatePolicyWithCreationTime creates a new access policy for the given cluster with the given creation timestamp
+func (c *MockIBMCloudClient) CreatePolicyWithCreationTime(accountID, clusterID string, created time.Time) (string, error) {
 	defer c.policyMux.Unlock()
 	c.policyMux.Lock()
 	id := uuid.NewV4().String()
-	c.policyByID[id] = &id
+	c.policyByID[id] = &ibmcloud.AccessPolicy{
+		ID:        id,
+		Type:      "access",
+		CreatedAt: created.UTC().Format(time.RFC3339),
+		Resources: []ibmcloud.AccessPolicyResource{{
+			Attributes: []ibmcloud.AccessPolicyAttribute{
+				{Name: "accountId", Operator: "stringEquals", Value: accountID},
+				{Name: "serviceName", Value: "containers-kubernetes"},
+				{Name: "serviceInstance", Value: clusterID},
+			},
+		}},
+	}
 	return id, nil
 }
 
+func (c *MockIBMCloudClient) GetAccessPolicies(_ string) ([]ibmcloud.AccessPolicy, error) {
+	defer c.policyMux.RUnlock()
+	c.policyMux.RLock()
+	policies := make([]ibmcloud.AccessPolicy, 0, len(c.policyByID))
+	for _, p := range c.policyByID {
+		if p != nil {
+			policies = append(policies, *p)
+		}
+	}
+	return policies, nil
+}
+
 func (c *MockIBMCloudClient) DeleteAccessPolicy(id string) error {
 	defer c.policyMux.Unlock()
 	c.policyMux.Lock()
@@ -176,3 +263,23 @@ func (c *MockIBMCloudClient) AccessPolicyExists(id string) bool {
 	c.policyMux.RLock()
 	return c.policyByID[id] != nil
 }
+
+func (c *MockIBMCloudClient) CircuitBreakerState() string {
+	return ibmcloud.CircuitClosed
+}
+
+func (c *MockIBMCloudClient) Token() (ibmcloud.TokenSet, error) {
+	defer c.tokenMux.RUnlock()
+	c.tokenMux.RLock()
+	if c.tokenErr != nil {
+		return ibmcloud.TokenSet{}, c.tokenErr
+	}
+	return ibmcloud.TokenSet{AccessToken: "mock-token"}, nil
+}
+
+// SetTokenError makes the client fail to obtain the token with the given error, e.g. if the API key is invalid
+func (c *MockIBMCloudClient) SetTokenError(err error) {
+	defer c.tokenMux.Unlock()
+	c.tokenMux.Lock()
+	c.tokenErr = err
+}
@@ -10,7 +10,7 @@ import Snackbar from '@material-ui/core/Snackbar';
 import IconButton from '@material-ui/core/IconButton';
 import CloseIcon from '@material-ui/icons/Close';
 
-import { getZones, getClusterRequests, getClusterRequest, deleteCluster, requestClusters } from './services/backend';
+import { getZones, getURLTemplates, getClusterRequests, getClusterRequest, exportClusterRequest, deleteCluster, requestClusters } from './services/backend';
 
 import RequestForm from './components/requestform';
 import RequestTable from './components/requesttable';
@@ -47,11 +47,18 @@ const useStyles = makeStyles((theme) => ({
   },
 }));
 
+// number of the cluster requests loaded at once
+const requestsPageSize = 50;
+
 export default function ClustersPanel() {
   const classes = useStyles();
 
   const [zones, setZones] = React.useState([])
+  const [urlTemplates, setURLTemplates] = React.useState([])
   const [requests, setRequests] = React.useState([]);
+  const [requestsTotal, setRequestsTotal] = React.useState(0);
+  const [requestsNext, setRequestsNext] = React.useState();
+  const [requestsSort, setRequestsSort] = React.useState({ sort: 'created', order: 'desc' });
   const [selectedRequest, setSelectedRequest] = React.useState();
   const [clusters, setClusters] = React.useState([]);
   const [snackOpen, setSnackOpen] = React.useState(false);
@@ -64,28 +71,58 @@ export default function ClustersPanel() {
     setSnackOpen(false);
   };
 
+  // loads the first page of the requests or the next page after the cursor
+  const loadRequests = async (sort, cursor) => {
+    try {
+      let page = await getClusterRequests({ limit: requestsPageSize, cursor: cursor, sort: sort.sort, order: sort.order });
+      setRequests((loaded) => cursor ? loaded.concat(page.items) : page.items);
+      setRequestsTotal(page.total);
+      setRequestsNext(page.next);
+    } catch (e) {
+      console.error('error fetching cluster requests', e.message);
+      setSnackMessage('Error fetching cluster requests: ' + e.message);
+      setSnackOpen(true);
+    }
+  }
+
+  const onSortRequests = (sort) => {
+    setRequestsSort(sort);
+    loadRequests(sort);
+  }
+
+  // loads the enabled zones with their live availability
+  const loadZones = async () => {
+    try {
+      let zones = await getZones();
+      setZones(zones);
+    } catch (e) {
+      console.error('error fetching zones', e.message);
+      setSnackMessage('Error fetching zones: ' + e.message);
+      setSnackOpen(true);
+    }
+  }
+
   React.useEffect(() => {
     async function fetchData() {
       // fetch zones
+      await loadZones();
+      // fetch URL templates
       try {
-        let zones = await getZones();
-        setZones(zones);
+        let urlTemplates = await getURLTemplates();
+        setURLTemplates(urlTemplates);
       } catch (e) {
-        console.error('error fetching zones', e.message);
-        setSnackMessage('Error fetching zones: ' + e.message);
+        console.error('error fetching URL templates', e.message);
+        setSnackMessage('Error fetching URL templates: ' + e.message);
         setSnackOpen(true);
       }
-      // fetch requests
-      try {
-        let requests = await getClusterRequests();
-        setRequests(requests);
-      } catch (e) {
-        console.error('error fetching cluster requests', e.message);
-        setSnackMessage('Error fetching cluster requests: ' + e.message);
-        setSnackOpen(true);
-      }  
+      // fetch the first page of requests
+      await loadRequests(requestsSort);
     }
     fetchData();
+    // keep the availability of the zones up to date
+    const interval = setInterval(loadZones, 60000);
+    return () => clearInterval(interval);
+  // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
   const onSelectRequest = async (request) => {
@@ -103,7 +140,7 @@ export default function ClustersPanel() {
   const onSubmitRequest = (request) => {
     confirmAlert({
       title: 'Confirm to create clusters',
-      message: 'Please confirm creating ' + request.numberOfClusters + ' clusters with ttl of ' + request.deleteInHours + ' hours in availability zone ' + request.zone + '.',
+      message: 'Please confirm creating ' + request.numberOfClusters + ' clusters with ' + request.usersPerCluster + ' user(s) per cluster and ttl of ' + request.deleteInHours + ' hours in availability zone ' + request.zone + '.',
       buttons: [
         {
           label: 'Create Clusters',
@@ -118,21 +155,15 @@ export default function ClustersPanel() {
 
   const onConfirmSubmitRequest = async (request) => {
     try {
-      await requestClusters(request.numberOfClusters, request.zone, request.deleteInHours);
+      await requestClusters(request.numberOfClusters, request.zone, request.deleteInHours, request.usersPerCluster, request.urlTemplates);
     } catch (e) {
       console.error('error requesting clusters', e.message);
       setSnackMessage('Error requesting clusters: ' + e.message);
       setSnackOpen(true);
     }
-    // refresh requests
-    try {
-      let requests = await getClusterRequests();
-      setRequests(requests);
-    } catch (e) {
-      console.error('error fetching cluster requests', e.message);
-      setSnackMessage('Error fetching cluster requests: ' + e.message);
-      setSnackOpen(true);
-    }      
+    // refresh requests and the availability of the zones
+    await loadRequests(requestsSort);
+    await loadZones();
   }
 
   const onExportRequest = (request) => {      
@@ -140,13 +171,20 @@ export default function ClustersPanel() {
         let exportData = [];
         let clusters = result.Clusters;
         clusters.map((cluster) => {
-          return exportData.push({
-            'Cluster ID': cluster.ID,
-            'Cluster Name': cluster.Name,
-            'Username': cluster.User.ID,
-            'User Password': cluster.User.Password,
-            'Login URL': cluster.LoginURL,
-            'Workshop URL': cluster.WorkshopURL,
+          // One row per user assigned to the cluster
+          let users = cluster.Users && cluster.Users.length > 0 ? cluster.Users : [{...cluster.User, WorkshopURL: cluster.WorkshopURL}];
+          return users.map((user) => {
+            let data = {
+              'Cluster ID': cluster.ID,
+              'Cluster Name': cluster.Name,
+              'Username': user.ID,
+              'User Password': user.Password,
+              'Login URL': cluster.LoginURL,
+              'Workshop URL': user.WorkshopURL,
+            };
+            // All the URLs rendered from the templates selected for the request
+            (cluster.URLs || []).concat(user.URLs || []).forEach((u) => { data[u.Name + ' URL'] = u.URL; });
+            return exportData.push(data);
           });
         });
         const options = { 
@@ -164,6 +202,24 @@ export default function ClustersPanel() {
       })
   }
 
+  const onExportHandouts = async (request) => {
+    let header = window.prompt('Header printed on every handout page (optional)', '');
+    if (header === null)
+      return;
+    try {
+      let pdf = await exportClusterRequest(request.ID, 'pdf', header);
+      let link = document.createElement('a');
+      link.href = window.URL.createObjectURL(pdf);
+      link.download = 'request-' + request.ID + '.pdf';
+      link.click();
+      window.URL.revokeObjectURL(link.href);
+    } catch (e) {
+      console.error('error exporting handouts', e.message);
+      setSnackMessage('Error exporting handouts: ' + e.message);
+      setSnackOpen(true);
+    }
+  }
+
   const onDeleteClusters = (clusters) => {
     if (!clusters || clusters.length === 0)
       return;
@@ -213,11 +269,13 @@ export default function ClustersPanel() {
     <div style={{ display: 'flex', flex: 1, height: '100%' }}>
       <div className={classes.clusterpanel}>
         <div className={classes.form}>
-            <RequestForm zones={zones} onSubmit={onSubmitRequest} />
+            <RequestForm zones={zones} urlTemplates={urlTemplates} onSubmit={onSubmitRequest} />
         </div>
         <div className={classes.tables}>
           <div className={classes.table}>
-            <RequestTable rows={requests} onSelect={onSelectRequest} onExport={onExportRequest} />
+            <RequestTable rows={requests} total={requestsTotal} sort={requestsSort} onSort={onSortRequests}
+              hasMore={!!requestsNext} onLoadMore={() => loadRequests(requestsSort, requestsNext)}
+              onSelect={onSelectRequest} onExport={onExportRequest} onExportHandouts={onExportHandouts} />
           </div>
           <div className={classes.table}>
             <ClusterTable rows={clusters} onDeleteClusters={onDeleteClusters} />
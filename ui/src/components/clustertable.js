@@ -23,6 +23,7 @@ import KeyboardArrowUpIcon from '@material-ui/icons/KeyboardArrowUp';
 import PasswordField from 'material-ui-password-field';
 import { CopyToClipboard } from 'react-copy-to-clipboard';
 import { TextField } from '@material-ui/core';
+import { getClusterEvents } from '../services/backend';
 
 const useStyles = makeStyles({
     container: {
@@ -90,7 +91,14 @@ const headCells = [
 function Row(props) {
   const { row, onSelect, selected } = props;
   const [open, setOpen] = React.useState(false);
+  const [events, setEvents] = React.useState([]);
   const classes = useRowStyles();
+
+  React.useEffect(() => {
+    if (open) {
+      getClusterEvents(row.ID).then(setEvents).catch(() => setEvents([]));
+    }
+  }, [open, row.ID, row.Status]);
   return (
     <React.Fragment>
       <TableRow className={classes.root} key={row.ID} hover onClick={() => onSelect(row)} selected={selected}>
@@ -121,6 +129,7 @@ function Row(props) {
                       <tr><td><Typography>Name:</Typography></td><td>{row.Name}</td></tr>
                       <tr><td><Typography>Status:</Typography></td><td>{row.Status}</td></tr>
                       <tr><td><Typography>Error Message:</Typography></td><td>{row.Error?row.Error:'n/a'}</td></tr>
+                      <tr><td><Typography>Bootstrap:</Typography></td><td>{row.BootstrapStatus?row.BootstrapStatus:'n/a'}{row.BootstrapSteps?' (' + row.BootstrapSteps.map((step) => step.Name + ': ' + step.Status).join(', ') + ')':''}</td></tr>
                       <tr>
                         <td><Typography>Hostname:</Typography></td>
                         <td className={classes.copyFlex}>
@@ -130,15 +139,6 @@ function Row(props) {
                           </CopyToClipboard>
                         </td>
                       </tr>
-                      <tr>
-                        <td><Typography>Console URL:</Typography></td>
-                        <td className={classes.copyFlex}>
-                          <div className={classes.oneLineTable}>{!row.ConsoleURL?'n/a':row.ConsoleURL}</div>
-                          <CopyToClipboard text={row.ConsoleURL}>
-                            <IconButton className={classes.copyButton} size="small"><FileCopyIcon /></IconButton>
-                          </CopyToClipboard>
-                        </td>
-                      </tr>
                       <tr>
                         <td><Typography>Master URL:</Typography></td>
                         <td className={classes.copyFlex}>
@@ -148,24 +148,28 @@ function Row(props) {
                           </CopyToClipboard>
                         </td>
                       </tr>
-                      <tr>
-                        <td><Typography>Login URL:</Typography></td>
-                        <td className={classes.copyFlex}>
-                          <div className={classes.oneLineTable}>{!row.LoginURL?'n/a':row.LoginURL}</div>
-                          <CopyToClipboard text={row.LoginURL}>
-                            <IconButton className={classes.copyButton} size="small"><FileCopyIcon /></IconButton>
-                          </CopyToClipboard>
-                        </td>
-                      </tr>
-                      <tr>
-                        <td><Typography>Workshop URL:</Typography></td>
-                        <td className={classes.copyFlex}>
-                          <div className={classes.oneLineTable}>{!row.WorkshopURL?'n/a':row.WorkshopURL}</div>
-                          <CopyToClipboard text={row.WorkshopURL}>
-                            <IconButton className={classes.copyButton} size="small"><FileCopyIcon /></IconButton>
-                          </CopyToClipboard>
-                        </td>
-                      </tr>
+                      {row.URLs?row.URLs.map((u) => (
+                        <tr key={'url-' + u.Name}>
+                          <td><Typography>{u.Name} URL:</Typography></td>
+                          <td className={classes.copyFlex}>
+                            <div className={classes.oneLineTable}>{u.URL}</div>
+                            <CopyToClipboard text={u.URL}>
+                              <IconButton className={classes.copyButton} size="small"><FileCopyIcon /></IconButton>
+                            </CopyToClipboard>
+                          </td>
+                        </tr>
+                      )):null}
+                      {row.Users?row.Users.map((user) => user.URLs?user.URLs.map((u) => (
+                        <tr key={'url-' + user.ID + '-' + u.Name}>
+                          <td><Typography>{user.ID} {u.Name} URL:</Typography></td>
+                          <td className={classes.copyFlex}>
+                            <div className={classes.oneLineTable}>{u.URL}</div>
+                            <CopyToClipboard text={u.URL}>
+                              <IconButton className={classes.copyButton} size="small"><FileCopyIcon /></IconButton>
+                            </CopyToClipboard>
+                          </td>
+                        </tr>
+                      )):null):null}
                       <tr>
                           <td><Typography>Username:</Typography></td>
                           <td className={classes.copyFlex}>
@@ -186,6 +190,23 @@ function Row(props) {
                       </tr>
                   </tbody>
               </Table>
+              {events.length > 0 ? (
+                <React.Fragment>
+                  <Typography variant="h6" gutterBottom component="div">Timeline</Typography>
+                  <Table size="small">
+                    <tbody>
+                      {events.map((e) => (
+                        <tr key={e.ID}>
+                          <td>{new Date(e.Timestamp * 1000).toLocaleString()}</td>
+                          <td>{e.Field === 'bootstrap_status' ? 'bootstrap: ' : ''}{e.OldStatus ? e.OldStatus : 'created'} &rarr; {e.NewStatus}</td>
+                          <td>{e.Actor}</td>
+                          <td className={classes.oneLineTable}>{e.Error}</td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </Table>
+                </React.Fragment>
+              ) : null}
             </Box>
           </Collapse>
         </TableCell>
@@ -18,24 +18,35 @@ const useStyles = makeStyles((theme) => ({
   },
 }));
 
-export default function RequestForm({ zones, onSubmit }) {
+export default function RequestForm({ zones, urlTemplates, onSubmit }) {
   const classes = useStyles();
   
   const [numberOfClusters, setNumberOfClusters] = React.useState(10);
   const [deleteInHours, setDeleteInHours] = React.useState(155);
   const [zone, setZone] = React.useState('');
+  const [usersPerCluster, setUsersPerCluster] = React.useState(1);
+  const [selectedTemplates, setSelectedTemplates] = React.useState([]);
 
   const onClickRequest = () => {
     onSubmit({
         numberOfClusters: numberOfClusters,
         zone: zone,
         deleteInHours: deleteInHours,
+        usersPerCluster: usersPerCluster,
+        urlTemplates: selectedTemplates,
     })
   }
 
+  // select the default zone or the first available one unless the selected zone is still available
   React.useEffect(() => {
-    if (zones && zones[0])
-      setZone(zones[0].id);
+    if (!zones || zones.length === 0)
+      return;
+    setZone((selected) => {
+      if (zones.find((z) => z.id === selected && z.available))
+        return selected;
+      let zone = zones.find((z) => z.default && z.available) || zones.find((z) => z.available) || zones[0];
+      return zone.id;
+    });
   }, [zones]);
 
   return (
@@ -68,11 +79,35 @@ export default function RequestForm({ zones, onSubmit }) {
                 onChange={(event) => event.target.value<1?setDeleteInHours(1):event.target.value>170?setDeleteInHours(170):setDeleteInHours(event.target.value)}
             />
         </FormControl>
+        <FormControl className={classes.formControl} style={{minWidth: '220px'}}>
+            <TextField
+                value={usersPerCluster}
+                label="Users per Cluster"
+                type="number"
+                InputProps={{
+                  inputProps: { min: 1, max: 10 },
+                }}
+                InputLabelProps={{
+                  shrink: true,
+                }}
+                onChange={(event) => event.target.value<1?setUsersPerCluster(1):event.target.value>10?setUsersPerCluster(10):setUsersPerCluster(event.target.value)}
+            />
+        </FormControl>
         <FormControl className={classes.formControl} style={{minWidth: '220px'}}>
             <InputLabel id='zone-label'>Zone</InputLabel>
             <Select labelId='zone-label' id='zone-select' value={zone} onChange={(event) => setZone(event.target.value)}>
                 {zones?zones.map((zone, index) =>
-                    <MenuItem key={index} value={zone.id}>{zone['display_name']}</MenuItem>
+                    <MenuItem key={index} value={zone.id} disabled={!zone.available}>
+                      {zone['display_name']}{zone['max_clusters'] > 0 ? ' (' + zone['active_clusters'] + '/' + zone['max_clusters'] + ')' : ''}{zone.available ? '' : ' - full'}
+                    </MenuItem>
+                ):null}
+            </Select>
+        </FormControl>
+        <FormControl className={classes.formControl} style={{minWidth: '220px'}}>
+            <InputLabel id='url-templates-label'>URL Templates (default if none)</InputLabel>
+            <Select labelId='url-templates-label' id='url-templates-select' multiple value={selectedTemplates} onChange={(event) => setSelectedTemplates(event.target.value)}>
+                {urlTemplates?urlTemplates.map((template, index) =>
+                    <MenuItem key={index} value={template.name}>{template.name}</MenuItem>
                 ):null}
             </Select>
         </FormControl>
@@ -5,6 +5,7 @@ import { makeStyles } from '@material-ui/core/styles';
 import Paper from '@material-ui/core/Paper';
 import IconButton from '@material-ui/core/IconButton';
 import CloudDownloadIcon from '@material-ui/icons/CloudDownload';
+import PictureAsPdfIcon from '@material-ui/icons/PictureAsPdf';
 import Table from '@material-ui/core/Table';
 import TableBody from '@material-ui/core/TableBody';
 import TableCell from '@material-ui/core/TableCell';
@@ -15,6 +16,7 @@ import TableSortLabel from '@material-ui/core/TableSortLabel';
 import Toolbar from '@material-ui/core/Toolbar';
 import Checkbox from '@material-ui/core/Checkbox';
 import Box from '@material-ui/core/Box';
+import Button from '@material-ui/core/Button';
 import Typography from '@material-ui/core/Typography';
 import Collapse from '@material-ui/core/Collapse';
 import KeyboardArrowDownIcon from '@material-ui/icons/KeyboardArrowDown';
@@ -65,17 +67,18 @@ const useToolbarStyles = makeStyles({
   },
 });
 
+// the ids are the keys the requests are sorted by on the server
 const headCells = [
-  { id: 'ID', numeric: false, disablePadding: false, label: 'Id' },
-  { id: 'Created', numeric: false, disablePadding: false, label: 'Created' },
-  { id: 'Requested', numeric: true, disablePadding: false, label: '# of Clusters' },
-  { id: 'RequestedBy', numeric: false, disablePadding: false, label: 'Requested by' },
-  { id: 'DeleteInHours', numeric: true, disablePadding: false, label: 'Delete in hours' },
-  { id: 'Status', numeric: false, disablePadding: false, label: 'Status' },
+  { id: 'id', numeric: false, disablePadding: false, label: 'Id' },
+  { id: 'created', numeric: false, disablePadding: false, label: 'Created' },
+  { id: 'requested', numeric: true, disablePadding: false, label: '# of Clusters' },
+  { id: 'requested_by', numeric: false, disablePadding: false, label: 'Requested by' },
+  { id: 'delete_in_hours', numeric: true, disablePadding: false, label: 'Delete in hours' },
+  { id: 'status', numeric: false, disablePadding: false, label: 'Status' },
 ];
 
 function Row(props) {
-  const { row, onSelect, onExport, selected } = props;
+  const { row, onSelect, onExport, onExportHandouts, selected } = props;
   const [open, setOpen] = React.useState(false);
   const classes = useRowStyles();
   let rowDate = new Date(0);
@@ -105,6 +108,9 @@ function Row(props) {
           <IconButton aria-label='export' color='primary' onClick={() => onExport(row)}>
             <CloudDownloadIcon/>
           </IconButton>
+          <IconButton aria-label='export handouts' color='primary' onClick={(event) => {event.stopPropagation(); onExportHandouts(row);}}>
+            <PictureAsPdfIcon/>
+          </IconButton>
         </TableCell>
       </TableRow>
       <TableRow>
@@ -174,7 +180,7 @@ function EnhancedTableHead(props) {
 
 const EnhancedTableToolbar = (props) => {
   const classes = useToolbarStyles();
-  const { numSelected } = props;
+  const { numSelected, loaded, total } = props;
   return (
     <Toolbar
       className={clsx(classes.root, {
@@ -186,26 +192,24 @@ const EnhancedTableToolbar = (props) => {
         </Typography>
       ) : (
         <Typography className={classes.title} variant="h6" id="tableTitle" component="div">
-          Cluster Requests
+          Cluster Requests ({loaded} of {total})
         </Typography>
       )}
     </Toolbar>
   );
 };
 
-export default function RequestTable({ rows, onSelect, onExport }) {
+export default function RequestTable({ rows, total, sort, onSort, hasMore, onLoadMore, onSelect, onExport, onExportHandouts }) {
   const classes = useStyles();
 
-  const [order, setOrder] = React.useState('desc');
-  const [orderBy, setOrderBy] = React.useState('Created');
   const [selected, setSelected] = React.useState([]);
 
   const isSelected = (name) => selected.indexOf(name) !== -1;
 
+  // the requests are sorted on the server so that the loaded pages are in the same order
   const handleRequestSort = (event, property) => {
-    const isAsc = orderBy === property && order === 'asc';
-    setOrder(isAsc ? 'desc' : 'asc');
-    setOrderBy(property);
+    const isAsc = sort.sort === property && sort.order === 'asc';
+    onSort({ sort: property, order: isAsc ? 'desc' : 'asc' });
   };
 
   const handleClick = (event, id) => {
@@ -220,55 +224,31 @@ export default function RequestTable({ rows, onSelect, onExport }) {
     }
   };
 
-  const descendingComparator = (a, b, orderBy) => {
-    if (b[orderBy] < a[orderBy]) {
-      return -1;
-    }
-    if (b[orderBy] > a[orderBy]) {
-      return 1;
-    }
-    return 0;
-  }
-      
-  const getComparator = (order, orderBy) => {
-    return order === 'desc'
-      ? (a, b) => descendingComparator(a, b, orderBy)
-      : (a, b) => -descendingComparator(a, b, orderBy);
-  }
-      
-  const stableSort = (array, comparator) => {
-    const stabilizedThis = array.map((el, index) => [el, index]);
-    stabilizedThis.sort((a, b) => {
-      const order = comparator(a[0], b[0]);
-      if (order !== 0) return order;
-      return a[1] - b[1];
-    });
-    return stabilizedThis.map((el) => el[0]);
-  }
-
   return (
     <div className={classes.container}>
       <Paper className={classes.paper}>
-        <EnhancedTableToolbar numSelected={selected.length}/>
+        <EnhancedTableToolbar numSelected={selected.length} loaded={rows.length} total={total}/>
         <TableContainer component={Paper} style={{ overflow: "auto" }}>
           <Table stickyHeader className={classes.table}>
             <EnhancedTableHead
               classes={classes}
-              order={order}
-              orderBy={orderBy}
+              order={sort.order}
+              orderBy={sort.sort}
               onRequestSort={handleRequestSort}
             />
             <TableBody>
-              {stableSort(rows, getComparator(order, orderBy))
-                .map((row) => {
+              {rows.map((row) => {
                   return (
-                    <Row key={row.ID} row={row} selected={isSelected(row.ID)} onSelect={(event) => handleClick(event, row.ID)} onExport={onExport}/>
+                    <Row key={row.ID} row={row} selected={isSelected(row.ID)} onSelect={(event) => handleClick(event, row.ID)} onExport={onExport} onExportHandouts={onExportHandouts}/>
                   );
                 })
               }
             </TableBody>
           </Table>
         </TableContainer>
+        {hasMore ? (
+          <Button fullWidth color='primary' onClick={onLoadMore}>Load more</Button>
+        ) : null}
       </Paper>
     </div>
   );
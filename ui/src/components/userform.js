@@ -7,7 +7,6 @@ import FormGroup from '@material-ui/core/FormGroup';
 import FormControl from '@material-ui/core/FormControl';
 import Slider from '@material-ui/core/Slider';
 import InputLabel from '@material-ui/core/InputLabel';
-import TextField from '@material-ui/core/TextField';
 import Button from '@material-ui/core/Button';
 
 const useStyles = makeStyles((theme) => ({
@@ -36,12 +35,10 @@ export default function UserForm({ onSubmit, onExport }) {
   const classes = useStyles();
   
   const [numberOfUsers, setNumberOfUsers] = React.useState(10);
-  const [startIndex, setStartIndex] = React.useState(0);
 
   const onClickRequest = () => {
     onSubmit({
         numberOfUsers: numberOfUsers,
-        startIndex: startIndex,
     })
   }
 
@@ -62,17 +59,6 @@ export default function UserForm({ onSubmit, onExport }) {
                     onChange={(event, newValue) => setNumberOfUsers(newValue)}
                 />
             </FormControl>
-            <FormControl className={classes.formControl} style={{minWidth: '220px'}}>
-                <TextField
-                    label="Start Index"
-                    defaultValue={0}
-                    type="number"
-                    InputLabelProps={{
-                      shrink: true,
-                    }}
-                    onChange={(event) => setStartIndex(event.target.value)}
-                />
-            </FormControl>
             <FormControl className={classes.formControl}>
                 <Button variant='contained' onClick={() => onClickRequest()}>Request Users</Button>
             </FormControl>
@@ -2,6 +2,7 @@ import React from 'react';
 
 import { makeStyles } from '@material-ui/core/styles';
 import Paper from '@material-ui/core/Paper';
+import Button from '@material-ui/core/Button';
 import IconButton from '@material-ui/core/IconButton';
 import Table from '@material-ui/core/Table';
 import TableBody from '@material-ui/core/TableBody';
@@ -87,7 +88,7 @@ function Row(props) {
     );
   }
   
-export default function UserTable({ users, onSelect }) {
+export default function UserTable({ users, total, hasMore, onLoadMore, onSelect }) {
 
     const classes = useStyles();
 
@@ -120,6 +121,9 @@ export default function UserTable({ users, onSelect }) {
             }):null}
         </TableBody>
         </Table>
+        {hasMore ? (
+            <Button fullWidth color='primary' onClick={onLoadMore}>Load more ({users.length} of {total})</Button>
+        ) : null}
         </TableContainer>
     );
 }
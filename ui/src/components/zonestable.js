@@ -56,6 +56,8 @@ function Row(props) {
           <TableCell align="right">{row.zoneID}</TableCell>
           <TableCell align="right">{row.zoneName}</TableCell>
           <TableCell align="right">{row.activeClusters}</TableCell>
+          <TableCell align="right">{row.maxClusters > 0 ? row.maxClusters : 'unlimited'}</TableCell>
+          <TableCell align="right">{row.isDefault ? 'yes' : ''}</TableCell>
         </TableRow>
       </React.Fragment>
     );
@@ -85,6 +87,8 @@ export default function ZonesTable({ zones, inProgress, onSelect }) {
                     <TableCell align="right" style={{width: '40px'}}>Id</TableCell>
                     <TableCell align="right">Name</TableCell>
                     <TableCell align="right">Active Clusters Total</TableCell>
+                    <TableCell align="right">Max Clusters</TableCell>
+                    <TableCell align="right">Default</TableCell>
                     <TableCell/>
                 </TableRow>
             </TableHead>
@@ -17,11 +17,13 @@ export const getZones = async () => {
   }
 }
   
-// gets the cluster requests once.
-export const getClusterRequests = async () => {
+// exports the handouts of the cluster request in the given format (csv, json or pdf) as a blob.
+export const exportClusterRequest = async (id, format, header) => {
   let resp = await axios({
     method: 'GET',
-    url: baseUrl + '/api/v1/cluster-reqs',
+    url: baseUrl + '/api/v1/cluster-req/' + id + '/export',
+    params: { format: format, header: header },
+    responseType: 'blob',
   });
   if (resp.status >= 200 && resp.status < 300) {
     return Promise.resolve(resp.data);
@@ -30,6 +32,45 @@ export const getClusterRequests = async () => {
     return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
   }
 }
+
+// gets the configured URL templates.
+export const getURLTemplates = async () => {
+  let resp = await axios({
+    method: 'GET',
+    url: baseUrl + '/api/v1/url-templates',
+  });
+  if (resp.status >= 200 && resp.status < 300) {
+    return Promise.resolve(resp.data);
+  }
+  else {
+    return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
+  }
+}
+
+// converts the response of a list endpoint to a page: the items, the total number of the matching items
+// and the cursor of the next page (undefined for the last page).
+const toPage = (resp) => {
+  return {
+    items: resp.data,
+    total: parseInt(resp.headers['x-total-count'], 10),
+    next: resp.headers['x-next-cursor'],
+  };
+}
+
+// gets a page of the cluster requests. params are the list query params: limit, cursor, sort, order and the filters.
+export const getClusterRequests = async (params) => {
+  let resp = await axios({
+    method: 'GET',
+    url: baseUrl + '/api/v1/cluster-reqs',
+    params: params,
+  });
+  if (resp.status >= 200 && resp.status < 300) {
+    return Promise.resolve(toPage(resp));
+  }
+  else {
+    return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
+  }
+}
   
 // gets the cluster request.
 export const getClusterRequest = async (id) => {
@@ -49,6 +90,20 @@ export const getClusterRequest = async (id) => {
   }
 }
 
+// gets the status changes of the cluster.
+export const getClusterEvents = async (id) => {
+  let resp = await axios({
+    method: 'GET',
+    url: baseUrl + '/api/v1/cluster/' + id + '/events',
+  });
+  if (resp.status >= 200 && resp.status < 300) {
+    return Promise.resolve(resp.data);
+  }
+  else {
+    return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
+  }
+}
+
 // deletes the cluster.
 export const deleteCluster = async (id) => {
   var bodyFormData = new FormData();
@@ -68,11 +123,13 @@ export const deleteCluster = async (id) => {
 }
 
 // requests clusters.
-export const requestClusters = async (n, zone, deleteInHours) => {
+export const requestClusters = async (n, zone, deleteInHours, usersPerCluster, urlTemplates) => {
   var bodyFormData = new FormData();
   bodyFormData.append('number-of-clusters', n);
   bodyFormData.append('zone', zone);
   bodyFormData.append('delete-in-hours', deleteInHours);
+  bodyFormData.append('users-per-cluster', usersPerCluster);
+  bodyFormData.append('url-templates', urlTemplates.join(','));
   let resp = await axios({
     method: 'POST',
     url: baseUrl + '/api/v1/cluster-req',
@@ -88,10 +145,9 @@ export const requestClusters = async (n, zone, deleteInHours) => {
 }
 
 // requests users.
-export const requestUsers = async (n, startIndex) => {
+export const requestUsers = async (n) => {
   var bodyFormData = new FormData();
   bodyFormData.append('number-of-users', n);
-  bodyFormData.append('start-index', startIndex);
   let resp = await axios({
     method: 'POST',
     url: baseUrl + '/api/v1/users',
@@ -106,32 +162,15 @@ export const requestUsers = async (n, startIndex) => {
   }
 }
     
-// get users
-export const getUsers = async () => {
+// gets a page of the users. params are the list query params: limit, cursor, sort, order and the filters.
+export const getUsers = async (params) => {
   let resp = await axios({
     method: 'GET',
     url: baseUrl + '/api/v1/users',
+    params: params,
   });
   if (resp.status >= 200 && resp.status < 300) {
-    return Promise.resolve(resp.data);
-  }
-  else {
-    return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
-  }
-}
-
-// gets the all clusters in a zone
-export const getClustersRequestsByZone = async (zoneID) => {
-  var bodyFormData = new FormData();
-  bodyFormData.append('zone', zoneID);
-  let resp = await axios({
-    method: 'GET',
-    url: baseUrl + '/api/v1/clusters?zone=' + zoneID,
-    data: bodyFormData,
-    headers: {'Content-Type': 'multipart/form-data' },
-  });
-  if (resp.status >= 200 && resp.status < 300) {
-    return Promise.resolve(resp.data);
+    return Promise.resolve(toPage(resp));
   }
   else {
     return Promise.reject(new Error('' + resp.status + ' ' + resp.statusText));
@@ -43,10 +43,15 @@ const useStyles = makeStyles((theme) => ({
   },
 }));
 
+// number of the users loaded at once
+const usersPageSize = 100;
+
 export default function UsersPanel() {
   const classes = useStyles();
 
   const [users, setUsers] = React.useState([]);
+  const [usersTotal, setUsersTotal] = React.useState(0);
+  const [usersNext, setUsersNext] = React.useState();
   const [snackOpen, setSnackOpen] = React.useState(false);
   const [snackMessage, setSnackMessage] = React.useState();
 
@@ -57,19 +62,23 @@ export default function UsersPanel() {
     setSnackOpen(false);
   };
 
-  React.useEffect(() => {
-    async function fetchData() {
-      // fetch users
-      try {
-        let users = await getUsers();
-        setUsers(users);
-      } catch (e) {
-        console.error('error fetching user requests', e.message);
-        setSnackMessage('Error fetching user requests: ' + e.message);
-        setSnackOpen(true);
-      }  
+  // loads the first page of the users or the next page after the cursor
+  const loadUsers = async (cursor) => {
+    try {
+      let page = await getUsers({ limit: usersPageSize, cursor: cursor });
+      setUsers((loaded) => cursor ? loaded.concat(page.items) : page.items);
+      setUsersTotal(page.total);
+      setUsersNext(page.next);
+    } catch (e) {
+      console.error('error fetching user requests', e.message);
+      setSnackMessage('Error fetching user requests: ' + e.message);
+      setSnackOpen(true);
     }
-    fetchData();
+  }
+
+  React.useEffect(() => {
+    loadUsers();
+  // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
   const onSelectUser = async (user) => {
@@ -78,26 +87,29 @@ export default function UsersPanel() {
 
   const onSubmitRequest = async (request) => {
     try {
-      await requestUsers(request.numberOfUsers, request.startIndex);
+      await requestUsers(request.numberOfUsers);
     } catch (e) {
       console.error('error requesting users', e.message);
       setSnackMessage('Error requesting users: ' + e.message);
       setSnackOpen(true);
     }
     // refresh requests
-    try {
-      let users = await getUsers();
-      setUsers(users);
-    } catch (e) {
-      console.error('error fetching user requests', e.message);
-      setSnackMessage('Error fetching user requests: ' + e.message);
-      setSnackOpen(true);
-    }      
+    await loadUsers();
   }
 
-  const onExportUsers = () => {      
+  const onExportUsers = async () => {
+      // export all the users, not only the loaded pages
+      let all;
+      try {
+        all = (await getUsers()).items;
+      } catch (e) {
+        console.error('error fetching users', e.message);
+        setSnackMessage('Error fetching users: ' + e.message);
+        setSnackOpen(true);
+        return;
+      }
       let exportData = [];
-      users.map((user) => {
+      all.map((user) => {
         return exportData.push({
           'User Id': user.ID,
           'User E-Mail': user.Email,
@@ -128,7 +140,7 @@ export default function UsersPanel() {
         </div>
         <div className={classes.tables}>
           <div className={classes.table}>
-            <UserTable users={users} onSelect={onSelectUser} />
+            <UserTable users={users} total={usersTotal} hasMore={!!usersNext} onLoadMore={() => loadUsers(usersNext)} onSelect={onSelectUser} />
           </div>
         </div>
       </div>
@@ -4,7 +4,7 @@ import Snackbar from '@material-ui/core/Snackbar';
 import IconButton from '@material-ui/core/IconButton';
 import CloseIcon from '@material-ui/icons/Close';
 
-import { getZones, getClustersRequestsByZone } from './services/backend';
+import { getZones } from './services/backend';
 
 import ZonesTable from './components/zonestable';
 
@@ -30,11 +30,12 @@ export default function ZonesPanel() {
         let zones = await getZones();
         let zonesDetails = [];
         for (let i=0; i<zones.length; i++) {
-          let clusters = await getClustersRequestsByZone(zones[i].id);
           zonesDetails.push({
             zoneID: zones[i]['id'],
             zoneName: zones[i]['display_name'],
-            activeClusters: clusters.length,
+            activeClusters: zones[i]['active_clusters'],
+            maxClusters: zones[i]['max_clusters'],
+            isDefault: zones[i]['default'],
           })
         }
         setInProgress(false);